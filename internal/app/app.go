@@ -1,63 +1,153 @@
 package application
 
 import (
+	"context"
 	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
-	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/lang"
 	"github.com/ttrtcixy/demo/internal/app/theme"
+	"github.com/ttrtcixy/demo/internal/backup"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/numfmt"
 	"github.com/ttrtcixy/demo/internal/storage"
-	"log"
+	"sync"
 )
 
 type App struct {
-	db    *storage.DB
+	partners  storage.PartnerRepository
+	sales     storage.SalesRepository
+	dashboard storage.DashboardRepository
+	ratings   storage.RatingRepository
+	contacts  storage.ContactRepository
+	notes     storage.NoteRepository
+	catalog   storage.CatalogRepository
+	materials storage.MaterialCalculator
+	plans     storage.PlanRepository
+	audit     storage.AuditRepository
+	users     storage.UserRepository
+	// encryption — nil, если хранилище не шифрует личные данные.
+	encryption storage.EncryptionRepository
+	privacy    storage.PrivacyRepository
+	// backups — каталог резервных копий; nil, если база их не поддерживает (PostgreSQL).
+	backups *backup.Manager
+
+	// dataListeners вызываются после изменения данных, чтобы вкладки перечитали их.
+	dataListeners []func()
+
+	// user — вошедший пользователь, от имени которого выполняются операции.
+	user models.User
+	// phonesReported — список нераспознанных телефонов уже показан после входа.
+	phonesReported bool
+	// attachments — каталог сеанса с открытыми файлами партнеров; пусто, пока файлы не открывались.
+	attachments string
+
 	app   fyne.App
 	w     fyne.Window
 	theme fyne.Theme
+
+	// ctx отменяется при закрытии окна и прерывает все фоновые загрузки.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// loads считает фоновые загрузки, результат которых еще не применен к UI.
+	loads sync.WaitGroup
+	// runOnMain выполняет результат фоновой загрузки в потоке UI. Тесты подменяют его,
+	// чтобы результаты выполнялись в горутине теста, которая работает с виджетами.
+	runOnMain func(f func())
+}
+
+// Repositories — зависимости App. В приложении все поля заполняются одним *storage.DB,
+// в тестах — memory.Store.
+type Repositories struct {
+	Partners  storage.PartnerRepository
+	Sales     storage.SalesRepository
+	Dashboard storage.DashboardRepository
+	Ratings   storage.RatingRepository
+	Contacts  storage.ContactRepository
+	Notes     storage.NoteRepository
+	Catalog   storage.CatalogRepository
+	Materials storage.MaterialCalculator
+	Plans     storage.PlanRepository
+	Audit     storage.AuditRepository
+	Users     storage.UserRepository
+	// Encryption — шифрование личных данных партнеров; пароль спрашивается при запуске до входа.
+	Encryption storage.EncryptionRepository
+	Privacy    storage.PrivacyRepository
+	// Backups — резервные копии базы; nil, если база их не поддерживает.
+	Backups *backup.Manager
 }
 
-func NewApp() *App {
+func NewApp(fyneApp fyne.App, repos Repositories) *App {
+	systemLocale := string(lang.SystemLocale())
+	numfmt.SetLocale(numfmt.ForLanguage(systemLocale))
+	i18n.SetLanguage(fyneApp.Preferences().StringWithFallback(languagePreference, i18n.ForLanguage(systemLocale)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+
 	return &App{
-		db:    storage.NewDB(),
-		app:   app.New(),
-		theme: theme.NewTheme(),
+		partners:   repos.Partners,
+		sales:      repos.Sales,
+		dashboard:  repos.Dashboard,
+		ratings:    repos.Ratings,
+		contacts:   repos.Contacts,
+		notes:      repos.Notes,
+		catalog:    repos.Catalog,
+		materials:  repos.Materials,
+		plans:      repos.Plans,
+		audit:      repos.Audit,
+		users:      repos.Users,
+		encryption: repos.Encryption,
+		privacy:    repos.Privacy,
+		backups:    repos.Backups,
+		app:        fyneApp,
+		theme:      loadTheme(fyneApp.Preferences()),
+		ctx:        ctx,
+		cancel:     cancel,
+		runOnMain:  runOnMain,
 	}
 }
 
 func (a *App) LoadTheme() {
-	iconResource, err := fyne.LoadResourceFromPath("./icon.ico")
-	if err != nil {
-		log.Println(err)
-	}
-	a.w.SetIcon(iconResource)
+	a.w.SetIcon(theme.AppIcon())
 
 	a.app.Settings().SetTheme(a.theme)
 }
 
-func (a *App) InitTabs() *container.AppTabs {
-	partnersTable, err := a.partnersTable()
-	if err != nil {
-		log.Println(err)
-	}
+// onDataChanged подписывает f на изменения данных. Подписки живут, пока не пересоздан интерфейс.
+func (a *App) onDataChanged(f func()) {
+	a.dataListeners = append(a.dataListeners, f)
+}
 
-	if partnersTable.partners == nil {
-		dialog.ShowInformation("Нет данных", "Партнеры не найдены. Добавьте нового партнера.", a.w)
+// dataChanged сообщает вкладкам, что данные в хранилище изменились.
+func (a *App) dataChanged() {
+	for _, f := range a.dataListeners {
+		f()
 	}
+}
+
+func (a *App) InitTabs() *container.AppTabs {
+	// Вкладки пересоздаются при смене языка, подписки старых вкладок больше не нужны.
+	a.dataListeners = nil
+	partnersTable := a.partnersTable()
 
 	scrollContainer := container.NewHScroll(partnersTable.table)
 	scrollContainer.SetMinSize(fyne.NewSize(800, 400))
 
 	tabs := container.NewAppTabs(
-		container.NewTabItem("Партнеры", container.NewBorder(
-			nil,
-			container.NewHBox(partnersTable.addButton, partnersTable.deleteButton),
+		container.NewTabItem(i18n.T("tab.dashboard"), a.createDashboardTab()),
+		container.NewTabItem(i18n.T("tab.partners"), container.NewBorder(
+			container.NewVBox(partnersTable.loader.view(), partnersTable.auditLoader.view(), partnersTable.exportLoader.view()),
+			container.NewHBox(partnersTable.addButton, partnersTable.deleteButton, partnersTable.historyButton, partnersTable.ratingButton, partnersTable.exportButton, partnersTable.auditButton, partnersTable.phonesButton),
 			nil, nil,
 			scrollContainer,
 		)),
-		container.NewTabItem("Продажи", a.createSalesTab()),
-		container.NewTabItem("Расчет материалов", a.createMaterialsCalcTab()),
+		container.NewTabItem(i18n.T("tab.sales"), a.createSalesTab()),
+		container.NewTabItem(i18n.T("tab.materials"), a.createMaterialsCalcTab()),
+		container.NewTabItem(i18n.T("tab.plan"), a.createProductionPlanTab()),
 	)
+	if a.can(models.PermManageUsers) {
+		tabs.Append(container.NewTabItem(i18n.T("tab.users"), a.createUsersTab()))
+	}
 
 	tabs.SetTabLocation(container.TabLocationTop)
 
@@ -66,13 +156,22 @@ func (a *App) InitTabs() *container.AppTabs {
 
 func (a *App) Run() {
 
-	a.w = a.app.NewWindow("Управление Партнерами")
+	a.w = a.app.NewWindow(i18n.T("app.title"))
+	a.w.SetOnClosed(a.cancel)
+	a.w.SetMainMenu(a.mainMenu())
 
 	a.LoadTheme()
 
-	tabs := a.InitTabs()
-
-	a.w.SetContent(tabs)
+	// Вкладки создаются только после входа: от роли зависит, какие действия доступны.
 	a.w.Resize(fyne.NewSize(1200, 600))
+	// Без пароля шифрования личные данные не прочитать, поэтому он спрашивается до входа.
+	a.unlock(func() {
+		a.login(func() {
+			// Пункты меню, как и вкладки, зависят от роли.
+			a.w.SetMainMenu(a.mainMenu())
+			a.w.SetContent(a.InitTabs())
+		})
+	})
 	a.w.ShowAndRun()
+	a.removeAttachments()
 }
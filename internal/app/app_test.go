@@ -0,0 +1,171 @@
+package application
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/numfmt"
+	"github.com/ttrtcixy/demo/internal/storage/memory"
+	"github.com/ttrtcixy/demo/internal/storage/storagetest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestApp создает App на тестовом драйвере Fyne с хранилищем в памяти. Пользователь — администратор.
+func newTestApp(t *testing.T) (*App, *memory.Store) {
+	t.Helper()
+
+	return newTestAppAs(t, models.RoleAdmin)
+}
+
+// newTestAppAs создает App от имени пользователя с ролью role. Пустая роль — пользователь еще не вошел.
+func newTestAppAs(t *testing.T, role string) (*App, *memory.Store) {
+	t.Helper()
+
+	store := storagetest.NewMemory(storagetest.Default)
+	a := NewApp(test.NewTempApp(t), Repositories{
+		Partners:   store,
+		Sales:      store,
+		Dashboard:  store,
+		Ratings:    store,
+		Contacts:   store,
+		Notes:      store,
+		Catalog:    store,
+		Materials:  store,
+		Plans:      store,
+		Audit:      store,
+		Users:      store,
+		Encryption: store,
+		Privacy:    store,
+	})
+	// Тексты в тестах рассчитаны на русский язык независимо от системного.
+	numfmt.SetLocale(numfmt.Russian)
+	i18n.SetLanguage(i18n.Russian)
+	a.w = test.NewTempWindow(t, nil)
+	a.w.Resize(fyne.NewSize(1200, 600))
+	// Результаты загрузок выполняет горутина теста в waitLoads, как главный поток в приложении.
+	queue := make(chan func())
+	mainQueues.Store(a, queue)
+	a.runOnMain = func(f func()) { queue <- f }
+	t.Cleanup(func() {
+		a.cancel()
+		waitLoads(t, a)
+		mainQueues.Delete(a)
+	})
+	if role != "" {
+		a.setUser(models.User{Id: 1, Login: role, Role: role})
+	}
+	return a, store
+}
+
+// mainQueues хранит для каждого тестового App очередь результатов фоновых загрузок.
+var mainQueues sync.Map
+
+// waitFor выполняет фоновые загрузки App и проверяет, что UI пришел в состояние cond.
+func waitFor(t *testing.T, a *App, what string, cond func() bool) {
+	t.Helper()
+
+	waitLoads(t, a)
+	if !cond() {
+		t.Fatalf("не дождались: %s", what)
+	}
+}
+
+// waitLoads выполняет в горутине теста результаты фоновых загрузок App, пока они не закончатся,
+// включая загрузки, запущенные из этих результатов. Виджеты меняет только горутина теста,
+// поэтому тест читает их без гонок.
+func waitLoads(t *testing.T, a *App) {
+	t.Helper()
+
+	queue, _ := mainQueues.Load(a)
+	done := make(chan struct{})
+	go func() {
+		a.loads.Wait()
+		close(done)
+	}()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case f := <-queue.(chan func()):
+			f()
+		case <-done:
+			return
+		case <-timeout:
+			t.Fatal("фоновые загрузки не завершились")
+		}
+	}
+}
+
+// find возвращает все объекты типа T в дереве o, включая содержимое виджетов.
+func find[T fyne.CanvasObject](o fyne.CanvasObject) []T {
+	var found []T
+	var walk func(o fyne.CanvasObject)
+	walk = func(o fyne.CanvasObject) {
+		if o == nil {
+			return
+		}
+		if v, ok := o.(T); ok {
+			found = append(found, v)
+		}
+		switch v := o.(type) {
+		case *fyne.Container:
+			for _, child := range v.Objects {
+				walk(child)
+			}
+		case fyne.Widget:
+			for _, child := range test.WidgetRenderer(v).Objects() {
+				walk(child)
+			}
+		}
+	}
+	walk(o)
+	return found
+}
+
+func findButton(t *testing.T, o fyne.CanvasObject, text string) *widget.Button {
+	t.Helper()
+
+	for _, b := range find[*widget.Button](o) {
+		if b.Text == text {
+			return b
+		}
+	}
+	t.Fatalf("кнопка %q не найдена", text)
+	return nil
+}
+
+// texts собирает текст всех надписей в дереве o.
+func texts(o fyne.CanvasObject) string {
+	var b strings.Builder
+	for _, l := range find[*widget.Label](o) {
+		b.WriteString(l.Text)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// topDialog возвращает открытый диалог окна или nil.
+func topDialog(a *App) fyne.CanvasObject {
+	return a.w.Canvas().Overlays().Top()
+}
+
+// waitDialog ждет диалог с текстом text.
+func waitDialog(t *testing.T, a *App, text string) fyne.CanvasObject {
+	t.Helper()
+
+	waitFor(t, a, "диалог "+text, func() bool {
+		d := topDialog(a)
+		return d != nil && strings.Contains(texts(d), text)
+	})
+	return topDialog(a)
+}
+
+func dismissDialogs(a *App) {
+	for topDialog(a) != nil {
+		a.w.Canvas().Overlays().Remove(topDialog(a))
+	}
+}
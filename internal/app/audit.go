@@ -0,0 +1,191 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/models"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// auditFieldNames — поля, которые показываются в истории изменений. Подпись поля —
+// сообщение "audit.field.<поле>".
+var auditFieldNames = map[string]bool{
+	"CompanyName": true,
+	"PartnerType": true,
+	"Director":    true,
+	"Phone":       true,
+	"Rating":      true,
+	"Email":       true,
+	"Address":     true,
+	"Name":        true,
+	"Login":       true,
+	"Role":        true,
+	"ProductName": true,
+	"Quantity":    true,
+	"SaleDate":    true,
+}
+
+func auditActionName(action string) string {
+	return i18n.T("audit.action." + action)
+}
+
+func auditEntityName(entity string) string {
+	return i18n.T("audit.entity." + entity)
+}
+
+func auditFieldName(field string) string {
+	return i18n.T("audit.field." + field)
+}
+
+func partnerHistoryHeader() []string {
+	return []string{i18n.T("col.date"), i18n.T("audit.col.user"), i18n.T("audit.col.action"), i18n.T("audit.col.changes")}
+}
+
+// auditChanges описывает изменение полей записи журнала в виде "Рейтинг: 7 → 9".
+func auditChanges(e models.AuditEntry) string {
+	before, after := auditFields(e.Before), auditFields(e.After)
+
+	keys := make([]string, 0, len(auditFieldNames))
+	for key := range auditFieldNames {
+		if _, ok := before[key]; ok {
+			keys = append(keys, key)
+		} else if _, ok := after[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []string
+	for _, key := range keys {
+		oldValue, hadOld := before[key]
+		newValue, hasNew := after[key]
+		switch {
+		case hadOld && hasNew && oldValue != newValue:
+			changes = append(changes, fmt.Sprintf("%s: %s → %s", auditFieldName(key), oldValue, newValue))
+		case !hadOld && hasNew:
+			changes = append(changes, fmt.Sprintf("%s: %s", auditFieldName(key), newValue))
+		case hadOld && !hasNew:
+			changes = append(changes, fmt.Sprintf("%s: %s", auditFieldName(key), oldValue))
+		}
+	}
+	return strings.Join(changes, "; ")
+}
+
+func auditFields(data string) map[string]string {
+	fields := map[string]string{}
+	if data == "" {
+		return fields
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(data), &raw); err != nil {
+		log.Println(err)
+		return fields
+	}
+	for key, value := range raw {
+		if auditFieldNames[key] {
+			fields[key] = fmt.Sprint(value)
+		}
+	}
+	return fields
+}
+
+// showPartnerHistory открывает окно "История изменений" партнера.
+func (a *App) showPartnerHistory(partnerId int) {
+	w := a.app.NewWindow(i18n.T("audit.history_title", map[string]any{"Id": partnerId}))
+	header := partnerHistoryHeader()
+
+	var history []models.AuditEntry
+	table := widget.NewTable(
+		func() (int, int) {
+			return len(history) + 1, len(header)
+		},
+		func() fyne.CanvasObject {
+			return container.NewHScroll(widget.NewLabel("template"))
+		},
+		func(i widget.TableCellID, o fyne.CanvasObject) {
+			scrollContainer := o.(*container.Scroll)
+			label := scrollContainer.Content.(*widget.Label)
+			if i.Row == 0 {
+				label.SetText(header[i.Col])
+				return
+			}
+			if i.Row-1 < len(history) {
+				label.SetText(partnerHistoryRow(history[i.Row-1])[i.Col])
+			}
+		},
+	)
+	table.SetColumnWidth(0, 140)
+	table.SetColumnWidth(1, 120)
+	table.SetColumnWidth(2, 100)
+	table.SetColumnWidth(3, 600)
+
+	loader := newLoader()
+	load(a, loader, i18n.T("audit.loading_history"),
+		func(ctx context.Context) ([]models.AuditEntry, error) {
+			return a.audit.GetEntityHistory(ctx, models.EntityPartner, partnerId)
+		},
+		func(entries []models.AuditEntry) {
+			history = entries
+			table.Refresh()
+		},
+		func(err error) {
+			showError(err, w)
+			log.Println(err)
+		},
+	)
+
+	exportBtn := widget.NewButton(i18n.T("common.export_csv"), func() {
+		rows := make([][]string, 0, len(history))
+		for _, e := range history {
+			rows = append(rows, partnerHistoryRow(e))
+		}
+		exportCSV(w, fmt.Sprintf("partner_%d_history.csv", partnerId), header, rows)
+	})
+
+	w.SetContent(container.NewBorder(loader.view(), container.NewHBox(exportBtn), nil, nil, table))
+	w.Resize(fyne.NewSize(1000, 400))
+	w.Show()
+}
+
+func partnerHistoryRow(e models.AuditEntry) []string {
+	return []string{e.CreatedAt, e.User, auditActionName(e.Action), auditChanges(e)}
+}
+
+// exportAudit выгружает весь журнал изменений в CSV.
+func (a *App) exportAudit(l *loader) {
+	load(a, l, i18n.T("audit.loading_log"), a.audit.GetAuditLog,
+		func(entries []models.AuditEntry) {
+			header := []string{
+				i18n.T("col.date"), i18n.T("audit.col.user"), i18n.T("audit.col.entity"), i18n.T("audit.col.id"),
+				i18n.T("audit.col.action"), i18n.T("audit.col.changes"), i18n.T("audit.col.before"), i18n.T("audit.col.after"),
+			}
+			rows := make([][]string, 0, len(entries))
+			for _, e := range entries {
+				rows = append(rows, []string{
+					e.CreatedAt,
+					e.User,
+					auditEntityName(e.Entity),
+					strconv.Itoa(e.EntityId),
+					auditActionName(e.Action),
+					auditChanges(e),
+					e.Before,
+					e.After,
+				})
+			}
+			exportCSV(a.w, "audit_log.csv", header, rows)
+		},
+		func(err error) {
+			showError(err, a.w)
+			log.Println(err)
+		},
+	)
+}
@@ -0,0 +1,43 @@
+package application
+
+import (
+	"github.com/ttrtcixy/demo/internal/models"
+	"testing"
+)
+
+func TestAuditChanges(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry models.AuditEntry
+		want  string
+	}{
+		{
+			name:  "изменение",
+			entry: models.AuditEntry{Before: `{"Id":1,"CompanyName":"А","Rating":7,"Phone":"1"}`, After: `{"Id":1,"CompanyName":"Б","Rating":9,"Phone":"1"}`},
+			want:  "Название компании: А → Б; Рейтинг: 7 → 9",
+		},
+		{
+			name:  "создание",
+			entry: models.AuditEntry{After: `{"Id":1,"CompanyName":"А","Rating":7}`},
+			want:  "Название компании: А; Рейтинг: 7",
+		},
+		{
+			name:  "удаление",
+			entry: models.AuditEntry{Before: `{"Id":1,"Director":"Иванов"}`},
+			want:  "Директор: Иванов",
+		},
+		{
+			name:  "без изменений",
+			entry: models.AuditEntry{Before: `{"Rating":7}`, After: `{"Rating":7}`},
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := auditChanges(tt.entry); got != tt.want {
+				t.Errorf("auditChanges = %q, ожидалось %q", got, tt.want)
+			}
+		})
+	}
+}
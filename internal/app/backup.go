@@ -0,0 +1,222 @@
+package application
+
+import (
+	"context"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/backup"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/numfmt"
+	"github.com/ttrtcixy/demo/internal/storage"
+	"log"
+	"path/filepath"
+)
+
+// backupTimeLayout — формат даты копии в списке.
+const backupTimeLayout = "2006-01-02 15:04:05"
+
+// databaseMenu — действия администратора с базой. Пункты без прав пользователя не показываются.
+func (a *App) databaseMenu() *fyne.Menu {
+	menu := fyne.NewMenu(i18n.T("menu.database"))
+	if a.backups != nil && a.can(models.PermManageBackups) {
+		menu.Items = append(menu.Items, fyne.NewMenuItem(i18n.T("backup.menu"), a.showBackups))
+	}
+	if a.encryption != nil && a.can(models.PermManageEncryption) {
+		menu.Items = append(menu.Items, fyne.NewMenuItem(i18n.T("encryption.menu"), a.showRotateKey))
+	}
+	return menu
+}
+
+func backupReasonName(reason string) string {
+	return i18n.T("backup.reason." + reason)
+}
+
+// showBackups открывает окно резервных копий. Восстановление идет в два шага: выбор копии
+// из списка или файла и подтверждение после проверки выбранного файла.
+func (a *App) showBackups() {
+	w := a.app.NewWindow(i18n.T("backup.title"))
+	w.SetContent(a.backupList(w))
+	w.Resize(fyne.NewSize(700, 450))
+	w.Show()
+}
+
+// backupList — первый шаг: список копий из каталога и создание новой копии.
+func (a *App) backupList(w fyne.Window) fyne.CanvasObject {
+	header := []string{i18n.T("col.date"), i18n.T("backup.col.reason"), i18n.T("files.col.size")}
+
+	var files []backup.File
+	selected := -1
+	table := widget.NewTable(
+		func() (int, int) {
+			return len(files) + 1, len(header)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("template")
+		},
+		func(i widget.TableCellID, o fyne.CanvasObject) {
+			label := o.(*widget.Label)
+			switch {
+			case i.Row == 0:
+				label.SetText(header[i.Col])
+			case i.Row-1 >= len(files):
+				label.SetText("")
+			default:
+				f := files[i.Row-1]
+				label.SetText([]string{f.CreatedAt.Format(backupTimeLayout), backupReasonName(f.Reason), formatFileSize(f.Size)}[i.Col])
+			}
+		},
+	)
+	table.SetColumnWidth(0, 200)
+	table.SetColumnWidth(1, 260)
+	table.SetColumnWidth(2, 120)
+	table.OnSelected = func(id widget.TableCellID) {
+		if id.Row > 0 && id.Row-1 < len(files) {
+			selected = id.Row - 1
+		}
+	}
+
+	emptyLabel := widget.NewLabel(i18n.T("backup.empty"))
+	emptyLabel.Hide()
+	dirLabel := widget.NewLabel(i18n.T("backup.dir", map[string]any{"Dir": a.backups.Dir()}))
+
+	loader := newLoader()
+	reload := func() {
+		load(a, loader, i18n.T("backup.loading"),
+			func(context.Context) ([]backup.File, error) {
+				return a.backups.List()
+			},
+			func(result []backup.File) {
+				files = result
+				selected = -1
+				table.UnselectAll()
+				table.Refresh()
+				if len(files) == 0 {
+					emptyLabel.Show()
+				} else {
+					emptyLabel.Hide()
+				}
+			},
+			func(err error) {
+				showError(err, w)
+				log.Println(err)
+			},
+		)
+	}
+	reload()
+
+	createBtn := widget.NewButton(i18n.T("backup.create"), func() {
+		load(a, loader, i18n.T("backup.creating"),
+			func(ctx context.Context) (backup.File, error) {
+				return a.backups.Create(ctx, backup.ReasonManual)
+			},
+			func(f backup.File) {
+				reload()
+				dialog.ShowInformation(i18n.T("backup.title"), i18n.T("backup.created", map[string]any{"Name": f.Name}), w)
+			},
+			func(err error) {
+				showError(err, w)
+				log.Println(err)
+			},
+		)
+	})
+	restoreBtn := widget.NewButton(i18n.T("backup.restore"), func() {
+		if selected < 0 || selected >= len(files) {
+			dialog.ShowInformation(i18n.T("common.not_selected"), i18n.T("backup.select"), w)
+			return
+		}
+		w.SetContent(a.backupConfirm(w, files[selected].Path))
+	})
+	fileBtn := widget.NewButton(i18n.T("backup.from_file"), func() {
+		dialog.ShowFileOpen(func(file fyne.URIReadCloser, err error) {
+			if err != nil {
+				showError(err, w)
+				return
+			}
+			if file == nil {
+				return
+			}
+			file.Close()
+			w.SetContent(a.backupConfirm(w, file.URI().Path()))
+		}, w)
+	})
+
+	return container.NewBorder(
+		container.NewVBox(loader.view(), dirLabel, emptyLabel),
+		container.NewHBox(createBtn, restoreBtn, fileBtn),
+		nil, nil,
+		table,
+	)
+}
+
+// backupConfirm — второй шаг: проверка копии path и подтверждение восстановления. Кнопка
+// восстановления доступна, только если файл прошел проверку.
+func (a *App) backupConfirm(w fyne.Window, path string) fyne.CanvasObject {
+	title := widget.NewLabelWithStyle(i18n.T("backup.confirm_title", map[string]any{"Name": filepath.Base(path)}), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	summary := widget.NewLabel("")
+	summary.Wrapping = fyne.TextWrapWord
+	warning := widget.NewLabel(i18n.T("backup.warning"))
+	warning.Wrapping = fyne.TextWrapWord
+	warning.Hide()
+
+	loader := newLoader()
+	var restoreBtn *widget.Button
+	restoreBtn = widget.NewButton(i18n.T("backup.restore_confirm"), func() {
+		restoreBtn.Disable()
+		load(a, loader, i18n.T("backup.restoring"),
+			func(ctx context.Context) (struct{}, error) {
+				return struct{}{}, a.backups.Restore(ctx, path)
+			},
+			func(struct{}) {
+				w.Close()
+				a.unlockRestored(func() {
+					a.dataChanged()
+					dialog.ShowInformation(i18n.T("backup.title"), i18n.T("backup.restored"), a.w)
+				})
+			},
+			func(err error) {
+				restoreBtn.Enable()
+				showError(err, w)
+				log.Println(err)
+			},
+		)
+	})
+	restoreBtn.Importance = widget.DangerImportance
+	restoreBtn.Disable()
+	backBtn := widget.NewButton(i18n.T("backup.back"), func() {
+		w.SetContent(a.backupList(w))
+	})
+
+	load(a, loader, i18n.T("backup.validating"),
+		func(ctx context.Context) (storage.BackupSummary, error) {
+			return a.backups.Validate(ctx, path)
+		},
+		func(s storage.BackupSummary) {
+			lastSale := s.LastSale
+			if lastSale == "" {
+				lastSale = "—"
+			}
+			summary.SetText(i18n.T("backup.summary", map[string]any{
+				"Partners": numfmt.FormatInt(s.Partners),
+				"Sales":    numfmt.FormatInt(s.Sales),
+				"Users":    numfmt.FormatInt(s.Users),
+				"LastSale": lastSale,
+			}))
+			warning.Show()
+			restoreBtn.Enable()
+		},
+		func(err error) {
+			summary.SetText(i18n.Message(err))
+			log.Println(err)
+		},
+	)
+
+	return container.NewBorder(
+		container.NewVBox(loader.view(), title),
+		container.NewHBox(backBtn, restoreBtn),
+		nil, nil,
+		container.NewVBox(summary, warning),
+	)
+}
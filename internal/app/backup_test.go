@@ -0,0 +1,105 @@
+package application
+
+import (
+	"database/sql"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/backup"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/storage"
+	"github.com/ttrtcixy/demo/internal/storage/storagetest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withBackups подключает к App каталог копий настоящей базы SQLite с фикстурой Default.
+func withBackups(t *testing.T, a *App) {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	db, err := storage.NewDB(t.Context(), storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	seed, err := sql.Open(storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer seed.Close()
+	if err := storagetest.Seed(seed, storage.DriverSQLite, storagetest.Default); err != nil {
+		t.Fatal(err)
+	}
+	a.backups = backup.NewManager(db, backup.Config{Dir: t.TempDir()})
+}
+
+func TestDatabaseMenu(t *testing.T) {
+	for role, want := range map[string]bool{models.RoleAdmin: true, models.RoleManager: false} {
+		a, _ := newTestAppAs(t, role)
+		withBackups(t, a)
+		found := false
+		for _, menu := range a.mainMenu().Items {
+			found = found || menu.Label == "База данных"
+		}
+		if found != want {
+			t.Errorf("%s: меню базы данных %v, ожидалось %v", role, found, want)
+		}
+	}
+}
+
+func TestBackupRestoreWizard(t *testing.T) {
+	a, _ := newTestApp(t)
+	withBackups(t, a)
+	reloaded := 0
+	a.onDataChanged(func() { reloaded++ })
+
+	a.showBackups()
+	w := lastWindow(a)
+	table := find[*widget.Table](w.Content())[0]
+	rows := func() int {
+		n, _ := table.Length()
+		return n - 1
+	}
+	waitFor(t, a, "загрузка списка копий", func() bool {
+		return strings.Contains(texts(w.Content()), "Резервных копий пока нет")
+	})
+
+	test.Tap(findButton(t, w.Content(), "Создать копию"))
+	waitFor(t, a, "создание копии", func() bool { return rows() == 1 })
+	if !strings.Contains(texts(w.Content()), "Вручную") {
+		t.Errorf("причина копии не показана: %s", texts(w.Content()))
+	}
+
+	table.Select(widget.TableCellID{Row: 1, Col: 0})
+	test.Tap(findButton(t, w.Content(), "Восстановить..."))
+	restoreBtn := findButton(t, w.Content(), "Восстановить")
+	waitFor(t, a, "проверка копии", func() bool { return !restoreBtn.Disabled() })
+	if text := texts(w.Content()); !strings.Contains(text, "Партнеров: 6, продаж: 7") {
+		t.Errorf("сводка копии: %s", text)
+	}
+
+	test.Tap(restoreBtn)
+	waitDialog(t, a, "База восстановлена из резервной копии")
+	if reloaded != 1 {
+		t.Errorf("вкладки обновлены %d раз", reloaded)
+	}
+	files, err := a.backups.List()
+	if err != nil || len(files) != 2 || files[0].Reason != backup.ReasonBeforeRestore {
+		t.Errorf("копии после восстановления: %+v, %v", files, err)
+	}
+}
+
+func TestBackupWizardRejectsInvalidFile(t *testing.T) {
+	a, _ := newTestApp(t)
+	withBackups(t, a)
+
+	w := a.app.NewWindow("")
+	w.SetContent(a.backupConfirm(w, filepath.Join(t.TempDir(), "missing.db")))
+	waitFor(t, a, "проверка файла", func() bool {
+		return strings.Contains(texts(w.Content()), "Файл не является целой базой данных")
+	})
+	if !findButton(t, w.Content(), "Восстановить").Disabled() {
+		t.Error("восстановление из некорректного файла доступно")
+	}
+}
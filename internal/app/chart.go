@@ -0,0 +1,157 @@
+package application
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"math"
+)
+
+// barChart — столбчатая диаграмма: по столбцу на значение, подпись под столбцом и значение над ним.
+// Если подписи не помещаются, выводится только каждая n-я.
+type barChart struct {
+	widget.BaseWidget
+
+	labels []string
+	values []float64
+	format func(float64) string
+}
+
+func newBarChart(format func(float64) string) *barChart {
+	c := &barChart{format: format}
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+// SetData заменяет данные диаграммы. labels и values должны быть одной длины.
+func (c *barChart) SetData(labels []string, values []float64) {
+	c.labels = labels
+	c.values = values
+	c.Refresh()
+}
+
+func (c *barChart) CreateRenderer() fyne.WidgetRenderer {
+	r := &barChartRenderer{
+		chart: c,
+		axis:  canvas.NewLine(theme.Color(theme.ColorNameForeground)),
+		empty: canvas.NewText(i18n.T("chart.no_data"), theme.Color(theme.ColorNamePlaceHolder)),
+	}
+	r.Refresh()
+	return r
+}
+
+type barChartRenderer struct {
+	chart *barChart
+
+	axis   *canvas.Line
+	empty  *canvas.Text
+	bars   []*canvas.Rectangle
+	labels []*canvas.Text
+	values []*canvas.Text
+}
+
+func (r *barChartRenderer) Destroy() {}
+
+func (r *barChartRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(300, 200)
+}
+
+func (r *barChartRenderer) Objects() []fyne.CanvasObject {
+	objects := []fyne.CanvasObject{r.axis, r.empty}
+	for i := range r.bars {
+		objects = append(objects, r.bars[i], r.labels[i], r.values[i])
+	}
+	return objects
+}
+
+// Refresh пересоздает столбцы под текущие данные и обновляет цвета темы.
+func (r *barChartRenderer) Refresh() {
+	n := len(r.chart.values)
+	if len(r.bars) != n {
+		r.bars = make([]*canvas.Rectangle, n)
+		r.labels = make([]*canvas.Text, n)
+		r.values = make([]*canvas.Text, n)
+		for i := 0; i < n; i++ {
+			r.bars[i] = canvas.NewRectangle(nil)
+			r.labels[i] = canvas.NewText("", nil)
+			r.values[i] = canvas.NewText("", nil)
+		}
+	}
+
+	textSize := theme.CaptionTextSize()
+	for i, v := range r.chart.values {
+		r.bars[i].FillColor = theme.Color(theme.ColorNamePrimary)
+		r.labels[i].Text = r.chart.labels[i]
+		r.values[i].Text = r.chart.format(v)
+		for _, t := range []*canvas.Text{r.labels[i], r.values[i]} {
+			t.Color = theme.Color(theme.ColorNameForeground)
+			t.TextSize = textSize
+			t.Alignment = fyne.TextAlignCenter
+		}
+	}
+	r.axis.StrokeColor = theme.Color(theme.ColorNameForeground)
+	r.empty.Color = theme.Color(theme.ColorNamePlaceHolder)
+	r.empty.Text = i18n.T("chart.no_data")
+	r.empty.Hidden = n > 0
+
+	r.Layout(r.chart.Size())
+	canvas.Refresh(r.chart)
+}
+
+func (r *barChartRenderer) Layout(size fyne.Size) {
+	pad := theme.Padding()
+	textHeight := fyne.MeasureText("0", theme.CaptionTextSize(), fyne.TextStyle{}).Height
+
+	r.empty.Move(fyne.NewPos((size.Width-r.empty.MinSize().Width)/2, (size.Height-r.empty.MinSize().Height)/2))
+	r.empty.Resize(r.empty.MinSize())
+
+	baseline := size.Height - textHeight - pad
+	r.axis.Position1 = fyne.NewPos(0, baseline)
+	r.axis.Position2 = fyne.NewPos(size.Width, baseline)
+
+	n := len(r.bars)
+	if n == 0 {
+		return
+	}
+
+	maxValue := 0.0
+	for _, v := range r.chart.values {
+		maxValue = math.Max(maxValue, v)
+	}
+	slot := size.Width / float32(n)
+	barArea := baseline - textHeight - pad
+	labelStep := r.step(slot, r.labels)
+	valueStep := r.step(slot, r.values)
+
+	for i, bar := range r.bars {
+		height := float32(0)
+		if maxValue > 0 {
+			height = barArea * float32(r.chart.values[i]/maxValue)
+		}
+		x := slot * float32(i)
+		bar.Move(fyne.NewPos(x+slot*0.2, baseline-height))
+		bar.Resize(fyne.NewSize(slot*0.6, height))
+
+		r.labels[i].Hidden = i%labelStep != 0
+		r.labels[i].Move(fyne.NewPos(x, baseline+pad/2))
+		r.labels[i].Resize(fyne.NewSize(slot, textHeight))
+
+		r.values[i].Hidden = i%valueStep != 0
+		r.values[i].Move(fyne.NewPos(x, baseline-height-textHeight))
+		r.values[i].Resize(fyne.NewSize(slot, textHeight))
+	}
+}
+
+// step — через сколько столбцов выводить подписи, чтобы самая широкая из них не наезжала на соседнюю.
+func (r *barChartRenderer) step(slot float32, texts []*canvas.Text) int {
+	widest := float32(0)
+	for _, t := range texts {
+		widest = max(widest, t.MinSize().Width)
+	}
+	if slot <= 0 {
+		return 1
+	}
+	return max(1, int(math.Ceil(float64(widest/slot))))
+}
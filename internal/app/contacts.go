@@ -0,0 +1,203 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/mailaddr"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/phone"
+	"log"
+	"strings"
+)
+
+func contactRoleName(role string) string {
+	return i18n.T("contact.role." + role)
+}
+
+// contactSummary описывает контакт одной строкой: «Закупщик: Смирнова О. Л., +7 900 111-22-33, a@b.ru».
+func contactSummary(c models.Contact) string {
+	parts := []string{c.Name}
+	if c.Phone != "" {
+		parts = append(parts, phone.Format(c.Phone))
+	}
+	if c.Email != "" {
+		parts = append(parts, c.Email)
+	}
+	return contactRoleName(c.Role) + ": " + strings.Join(parts, ", ")
+}
+
+// contactsSection — список контактных лиц партнера для его карточки. Если editable, под списком
+// кнопки добавления, изменения и удаления контакта.
+func (a *App) contactsSection(p models.Partner, editable bool, w fyne.Window) fyne.CanvasObject {
+	header := []string{i18n.T("contacts.form.role"), i18n.T("contacts.form.name"), i18n.T("partners.form.phone"), i18n.T("partners.form.email")}
+
+	var contacts []models.Contact
+	selected := -1
+	table := widget.NewTable(
+		func() (int, int) {
+			return len(contacts) + 1, len(header)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("template")
+		},
+		func(i widget.TableCellID, o fyne.CanvasObject) {
+			label := o.(*widget.Label)
+			switch {
+			case i.Row == 0:
+				label.SetText(header[i.Col])
+			case i.Row-1 >= len(contacts):
+				label.SetText("")
+			default:
+				c := contacts[i.Row-1]
+				label.SetText([]string{contactRoleName(c.Role), c.Name, phone.Format(c.Phone), c.Email}[i.Col])
+			}
+		},
+	)
+	table.SetColumnWidth(0, 120)
+	table.SetColumnWidth(1, 220)
+	table.SetColumnWidth(2, 170)
+	table.SetColumnWidth(3, 220)
+	table.OnSelected = func(id widget.TableCellID) {
+		if id.Row > 0 && id.Row-1 < len(contacts) {
+			selected = id.Row - 1
+		}
+	}
+
+	emptyLabel := widget.NewLabel(i18n.T("contacts.empty"))
+	emptyLabel.Hide()
+
+	loader := newLoader()
+	reload := func() {
+		load(a, loader, i18n.T("contacts.loading"),
+			func(ctx context.Context) ([]models.Contact, error) {
+				return a.contacts.GetContacts(ctx, p.Id)
+			},
+			func(result []models.Contact) {
+				contacts = result
+				selected = -1
+				table.UnselectAll()
+				table.Refresh()
+				if len(contacts) == 0 {
+					emptyLabel.Show()
+				} else {
+					emptyLabel.Hide()
+				}
+			},
+			func(err error) {
+				showError(err, w)
+				log.Println(err)
+			},
+		)
+	}
+	reload()
+
+	save := func(err error) {
+		if err != nil {
+			showError(err, w)
+			log.Println(err)
+		}
+		reload()
+	}
+	withSelected := func(f func(c models.Contact)) func() {
+		return func() {
+			if selected < 0 {
+				dialog.ShowInformation(i18n.T("common.not_selected"), i18n.T("contacts.select"), w)
+				return
+			}
+			f(contacts[selected])
+		}
+	}
+	addBtn := widget.NewButton(i18n.T("contacts.add"), func() {
+		showContactForm(w, models.Contact{PartnerId: p.Id, Role: models.ContactBuyer}, func(c models.Contact) {
+			_, err := a.contacts.AddContact(a.ctx, c)
+			save(err)
+		})
+	})
+	editBtn := widget.NewButton(i18n.T("contacts.edit"), withSelected(func(c models.Contact) {
+		showContactForm(w, c, func(c models.Contact) {
+			save(a.contacts.UpdateContact(a.ctx, c))
+		})
+	}))
+	deleteBtn := widget.NewButton(i18n.T("contacts.delete"), withSelected(func(c models.Contact) {
+		save(a.contacts.DeleteContact(a.ctx, c.Id))
+	}))
+	showIf(addBtn, editable)
+	showIf(editBtn, editable)
+	showIf(deleteBtn, editable)
+
+	return container.NewBorder(container.NewVBox(loader.view(), emptyLabel), container.NewHBox(addBtn, editBtn, deleteBtn), nil, nil, table)
+}
+
+// showContactForm открывает форму контактного лица. Телефон и email сохраняются нормализованными.
+func showContactForm(w fyne.Window, c models.Contact, onSave func(models.Contact)) {
+	roleNames := make([]string, len(models.ContactRoles))
+	for i, role := range models.ContactRoles {
+		roleNames[i] = contactRoleName(role)
+	}
+	roleSelect := widget.NewSelect(roleNames, nil)
+	for i, role := range models.ContactRoles {
+		if role == c.Role {
+			roleSelect.SetSelectedIndex(i)
+		}
+	}
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(c.Name)
+	phoneEntry := widget.NewEntry()
+	phoneEntry.SetText(phone.Format(c.Phone))
+	phoneEntry.SetPlaceHolder("+7 912 888-33-33")
+	emailEntry := widget.NewEntry()
+	emailEntry.SetText(c.Email)
+
+	form := widget.NewForm(
+		widget.NewFormItem(i18n.T("contacts.form.role"), roleSelect),
+		widget.NewFormItem(i18n.T("contacts.form.name"), nameEntry),
+		widget.NewFormItem(i18n.T("partners.form.phone"), phoneEntry),
+		widget.NewFormItem(i18n.T("partners.form.email"), emailEntry),
+	)
+
+	dialog.ShowCustomConfirm(i18n.T("contacts.form.title"), i18n.T("common.save"), i18n.T("partners.form.cancel"), form, func(ok bool) {
+		if !ok {
+			return
+		}
+		if err := validateContactForm(nameEntry.Text, phoneEntry.Text, emailEntry.Text); err != nil {
+			showError(err, w)
+			return
+		}
+		if i := roleSelect.SelectedIndex(); i >= 0 {
+			c.Role = models.ContactRoles[i]
+		}
+		c.Name = strings.TrimSpace(nameEntry.Text)
+		c.Phone, _ = phone.Parse(phoneEntry.Text)
+		c.Email, _ = mailaddr.Parse(emailEntry.Text)
+		onSave(c)
+	}, w)
+}
+
+// validateContactForm проверяет форму контакта: имя обязательно, из телефона и email нужен
+// хотя бы один, и каждый указанный должен разбираться.
+func validateContactForm(name, phoneNumber, email string) error {
+	phoneNumber, email = strings.TrimSpace(phoneNumber), strings.TrimSpace(email)
+	if strings.TrimSpace(name) == "" {
+		return errors.New(i18n.T("validate.contact_name_empty"))
+	}
+	if phoneNumber == "" && email == "" {
+		return errors.New(i18n.T("validate.contact_no_channel"))
+	}
+	if phoneNumber != "" {
+		if _, err := phone.Parse(phoneNumber); err != nil {
+			return errors.New(i18n.T("validate.phone_invalid"))
+		}
+	}
+	if email != "" {
+		if _, err := mailaddr.Parse(email); err != nil {
+			return errors.New(i18n.T("validate.email_invalid"))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package application
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/models"
+	"strings"
+	"testing"
+)
+
+func TestValidateContactForm(t *testing.T) {
+	tests := []struct {
+		name, contactName, phone, email string
+		wantErr                         string
+	}{
+		{name: "телефон", contactName: "Смирнова", phone: "8 900 111-22-33"},
+		{name: "email", contactName: "Смирнова", email: "a@b.ru"},
+		{name: "пустое имя", phone: "8 900 111-22-33", wantErr: "Имя контактного лица не может быть пустым"},
+		{name: "без телефона и email", contactName: "Смирнова", wantErr: "Укажите телефон или email контактного лица"},
+		{name: "плохой телефон", contactName: "Смирнова", phone: "123", wantErr: "Некорректный номер телефона: укажите российский (+7 912 888-33-33, 8 912 888-33-33) или международный номер с +"},
+		{name: "плохой email", contactName: "Смирнова", email: "ab.ru", wantErr: "Некорректный email: укажите адрес вида name@example.ru"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateContactForm(tt.contactName, tt.phone, tt.email)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("неожиданная ошибка: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("ошибка %v, ожидалось %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// contactsTable возвращает таблицу контактных лиц карточки и функцию, считающую ее строки.
+func contactsTable(w fyne.Window) (*widget.Table, func() int) {
+	table := find[*widget.Table](w.Content())[0]
+	return table, func() int {
+		n, _ := table.Length()
+		return n - 1
+	}
+}
+
+func TestPartnerContacts(t *testing.T) {
+	a, store := newTestAppAs(t, models.RoleManager)
+	pt := showPartnersTab(t, a)
+
+	details := openPartnerDetails(t, a, pt, 2)
+	table, rows := contactsTable(details)
+	waitFor(t, a, "загрузка контактных лиц", func() bool { return rows() == 2 })
+
+	test.Tap(findButton(t, details.Content(), "Добавить контакт"))
+	form := details.Canvas().Overlays().Top()
+	find[*widget.Select](form)[0].SetSelected("Логист")
+	entries := find[*widget.Entry](form)
+	entries[0].SetText("Орлова Д. А.")
+	entries[1].SetText("8 (900) 123-45-67")
+	entries[2].SetText("Sklad@Parket29.RU")
+	test.Tap(findButton(t, form, "Сохранить"))
+	waitFor(t, a, "добавление контакта", func() bool { return rows() == 3 })
+
+	added := store.Contacts[len(store.Contacts)-1]
+	want := models.Contact{Id: added.Id, PartnerId: 301, Role: models.ContactLogistics, Name: "Орлова Д. А.", Phone: "+79001234567", Email: "Sklad@parket29.ru"}
+	if added != want {
+		t.Errorf("добавлен контакт %+v, ожидалось %+v", added, want)
+	}
+
+	table.Select(widget.TableCellID{Row: 3, Col: 1})
+	test.Tap(findButton(t, details.Content(), "Изменить контакт"))
+	form = details.Canvas().Overlays().Top()
+	find[*widget.Entry](form)[0].SetText("Орлова Д. А. (склад)")
+	test.Tap(findButton(t, form, "Сохранить"))
+	waitFor(t, a, "изменение контакта", func() bool { return store.Contacts[len(store.Contacts)-1].Name == "Орлова Д. А. (склад)" })
+
+	waitFor(t, a, "обновление таблицы контактов", func() bool {
+		return strings.Contains(texts(details.Content()), "Орлова Д. А. (склад)")
+	})
+	table.Select(widget.TableCellID{Row: 1, Col: 1})
+	test.Tap(findButton(t, details.Content(), "Удалить контакт"))
+	waitFor(t, a, "удаление контакта", func() bool { return rows() == 2 })
+	for _, c := range store.Contacts {
+		if c.Id == 100 {
+			t.Errorf("контакт 100 не удален")
+		}
+	}
+}
+
+func TestViewerContactsReadOnly(t *testing.T) {
+	a, _ := newTestAppAs(t, models.RoleViewer)
+	pt := showPartnersTab(t, a)
+
+	details := openPartnerDetails(t, a, pt, 2)
+	_, rows := contactsTable(details)
+	waitFor(t, a, "загрузка контактных лиц", func() bool { return rows() == 2 })
+	if findButton(t, details.Content(), "Добавить контакт").Visible() {
+		t.Error("наблюдателю доступно изменение контактных лиц")
+	}
+}
+
+func TestPartnerExportRows(t *testing.T) {
+	partners := models.Partners{
+		{Id: 301, CompanyName: "Паркет 29", PartnerType: "ООО", Phone: "+79871235678", Rating: 7, Discount: 5},
+		{Id: 302, CompanyName: "Стройсервис", PartnerType: "ПАО", Rating: 7},
+	}
+	contacts := []models.Contact{
+		{Id: 100, PartnerId: 301, Role: models.ContactBuyer, Name: "Смирнова О. Л.", Phone: "+79001112233", Email: "zakupki@parket29.ru"},
+		{Id: 101, PartnerId: 301, Role: models.ContactAccountant, Name: "Кузнецов П. Р.", Email: "buh@parket29.ru"},
+	}
+
+	rows := partnerExportRows(partners, contacts)
+	if len(rows) != 2 {
+		t.Fatalf("строк экспорта: %d", len(rows))
+	}
+	if rows[0][3] != "+7 987 123-56-78" || rows[0][7] != "5%" {
+		t.Errorf("строка партнера: %v", rows[0])
+	}
+	want := "Закупщик: Смирнова О. Л., +7 900 111-22-33, zakupki@parket29.ru; Бухгалтер: Кузнецов П. Р., buh@parket29.ru"
+	if rows[0][8] != want {
+		t.Errorf("контакты: %q, ожидалось %q", rows[0][8], want)
+	}
+	if rows[1][8] != "" {
+		t.Errorf("контакты партнера без контактов: %q", rows[1][8])
+	}
+}
@@ -0,0 +1,175 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/numfmt"
+	"github.com/ttrtcixy/demo/internal/storage"
+	"log"
+)
+
+// topPartnersLimit — сколько партнеров показывать в рейтинге на вкладке обзора.
+const topPartnersLimit = 10
+
+// dashboardData — все показатели вкладки обзора, загруженные за один раз.
+type dashboardData struct {
+	totals      models.SalesTotals
+	months      []models.MonthlySales
+	topPartners []models.PartnerTotal
+	tiers       []models.DiscountTierCount
+	types       []models.PartnerTypeCount
+}
+
+// dashboard — вкладка обзора: выручка, продажи по месяцам, лучшие партнеры и распределение
+// партнеров по скидкам и типам компаний. Перечитывается после каждого изменения данных.
+type dashboard struct {
+	loader *loader
+
+	revenue  *widget.Label
+	quantity *widget.Label
+	partners *widget.Label
+
+	monthly      *barChart
+	tiers        *barChart
+	types        *barChart
+	topPartners  []models.PartnerTotal
+	partnerTable *widget.Table
+}
+
+func (a *App) createDashboardTab() fyne.CanvasObject {
+	d := &dashboard{
+		loader:   newLoader(),
+		revenue:  newKPILabel(),
+		quantity: newKPILabel(),
+		partners: newKPILabel(),
+		monthly:  newBarChart(formatCount),
+		tiers:    newBarChart(formatCount),
+		types:    newBarChart(formatCount),
+	}
+
+	header := []string{i18n.T("dashboard.col.partner"), i18n.T("sales.col.quantity"), i18n.T("sales.col.sum")}
+	d.partnerTable = widget.NewTable(
+		func() (int, int) {
+			return len(d.topPartners) + 1, len(header)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("template")
+		},
+		func(i widget.TableCellID, o fyne.CanvasObject) {
+			label := o.(*widget.Label)
+			switch {
+			case i.Row == 0:
+				label.SetText(header[i.Col])
+			case i.Row-1 >= len(d.topPartners):
+				label.SetText("")
+			case i.Col == 0:
+				label.SetText(d.topPartners[i.Row-1].CompanyName)
+			case i.Col == 1:
+				label.SetText(numfmt.FormatInt(d.topPartners[i.Row-1].Quantity))
+			default:
+				label.SetText(numfmt.FormatMoney(d.topPartners[i.Row-1].TotalSum))
+			}
+		},
+	)
+	d.partnerTable.SetColumnWidth(0, 200)
+	d.partnerTable.SetColumnWidth(1, 100)
+	d.partnerTable.SetColumnWidth(2, 160)
+
+	a.onDataChanged(func() { d.reload(a) })
+	d.reload(a)
+
+	kpis := container.NewGridWithColumns(3,
+		widget.NewCard(i18n.T("dashboard.revenue"), "", d.revenue),
+		widget.NewCard(i18n.T("dashboard.quantity"), "", d.quantity),
+		widget.NewCard(i18n.T("dashboard.partners"), "", d.partners),
+	)
+	charts := container.NewGridWithColumns(2,
+		widget.NewCard(i18n.T("dashboard.monthly_sales"), "", d.monthly),
+		widget.NewCard(i18n.T("dashboard.top_partners", map[string]any{"Count": topPartnersLimit}), "", d.partnerTable),
+		widget.NewCard(i18n.T("dashboard.discount_tiers"), "", d.tiers),
+		widget.NewCard(i18n.T("dashboard.partner_types"), "", d.types),
+	)
+
+	return container.NewBorder(container.NewVBox(d.loader.view(), kpis), nil, nil, nil, container.NewVScroll(charts))
+}
+
+func newKPILabel() *widget.Label {
+	return widget.NewLabelWithStyle("—", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+}
+
+func formatCount(v float64) string {
+	return numfmt.FormatInt(int(v))
+}
+
+// reload загружает показатели в фоне. Повторный вызов отменяет незавершенную загрузку.
+func (d *dashboard) reload(a *App) {
+	load(a, d.loader, i18n.T("dashboard.loading"),
+		func(ctx context.Context) (dashboardData, error) {
+			var data dashboardData
+			var err error
+			if data.totals, err = a.dashboard.GetSalesTotals(ctx); err != nil {
+				return data, err
+			}
+			if data.months, err = a.dashboard.GetMonthlyTotals(ctx); err != nil {
+				return data, err
+			}
+			if data.topPartners, err = a.dashboard.GetTopPartners(ctx, topPartnersLimit); err != nil {
+				return data, err
+			}
+			if data.tiers, err = a.dashboard.GetDiscountDistribution(ctx); err != nil {
+				return data, err
+			}
+			data.types, err = a.dashboard.GetPartnerTypeDistribution(ctx)
+			return data, err
+		},
+		d.show,
+		func(err error) {
+			showError(err, a.w)
+			log.Println(err)
+		},
+	)
+}
+
+func (d *dashboard) show(data dashboardData) {
+	d.revenue.SetText(numfmt.FormatMoney(data.totals.Revenue))
+	d.quantity.SetText(numfmt.FormatInt(data.totals.Quantity))
+	d.partners.SetText(numfmt.FormatInt(data.totals.Partners))
+
+	months := fillMonths(data.months)
+	labels := make([]string, len(months))
+	values := make([]float64, len(months))
+	for i, m := range months {
+		labels[i] = m.Month
+		values[i] = float64(m.Quantity)
+	}
+	d.monthly.SetData(labels, values)
+
+	// Пороги без партнеров тоже показываются, чтобы ось скидок не зависела от данных.
+	byPercentage := make(map[int]int, len(data.tiers))
+	for _, t := range data.tiers {
+		byPercentage[t.Percentage] = t.Partners
+	}
+	labels = make([]string, len(storage.DiscountTiers))
+	values = make([]float64, len(storage.DiscountTiers))
+	for i, tier := range storage.DiscountTiers {
+		labels[i] = fmt.Sprintf("%d%%", tier.Percentage)
+		values[i] = float64(byPercentage[tier.Percentage])
+	}
+	d.tiers.SetData(labels, values)
+
+	labels = make([]string, len(data.types))
+	values = make([]float64, len(data.types))
+	for i, t := range data.types {
+		labels[i] = t.PartnerType
+		values[i] = float64(t.Partners)
+	}
+	d.types.SetData(labels, values)
+
+	d.topPartners = data.topPartners
+	d.partnerTable.Refresh()
+}
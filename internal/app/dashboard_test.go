@@ -0,0 +1,50 @@
+package application
+
+import (
+	"github.com/ttrtcixy/demo/internal/numfmt"
+	"strings"
+	"testing"
+)
+
+func TestDashboard(t *testing.T) {
+	a, _ := newTestApp(t)
+	a.w.SetContent(a.createDashboardTab())
+
+	charts := find[*barChart](a.w.Content())
+	if len(charts) != 3 {
+		t.Fatalf("на вкладке %d диаграмм, ожидалось 3", len(charts))
+	}
+	monthly, tiers, types := charts[0], charts[1], charts[2]
+	waitFor(t, a, "загрузка показателей", func() bool { return len(types.values) > 0 })
+
+	got := texts(a.w.Content())
+	for _, want := range []string{numfmt.FormatInt(414999), "МонтажПро"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("на вкладке нет %q:\n%s", want, got)
+		}
+	}
+	// Продажи с января 2023 по февраль 2024 — 14 месяцев, пустые месяцы дополнены нулями.
+	if len(monthly.labels) != 14 || monthly.values[12] != 399999 {
+		t.Errorf("продажи по месяцам: %v %v", monthly.labels, monthly.values)
+	}
+	wantTiers := []float64{2, 2, 1, 1}
+	for i, want := range wantTiers {
+		if tiers.values[i] != want {
+			t.Errorf("партнеры по скидкам: %v %v, ожидалось %v", tiers.labels, tiers.values, wantTiers)
+			break
+		}
+	}
+	if types.labels[0] != "ЗАО" || types.values[0] != 2 {
+		t.Errorf("партнеры по типам: %v %v", types.labels, types.values)
+	}
+
+	// После удаления партнера вкладка перечитывает данные без участия пользователя.
+	if err := a.partners.DeletePartner(a.ctx, 304); err != nil {
+		t.Fatal(err)
+	}
+	a.dataChanged()
+	waitFor(t, a, "обновление показателей", func() bool { return tiers.values[3] == 0 })
+	if types.values[0] != 1 {
+		t.Errorf("партнеры по типам после удаления: %v %v", types.labels, types.values)
+	}
+}
@@ -0,0 +1,151 @@
+package application
+
+import (
+	"errors"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/storage"
+	"log"
+)
+
+// unlock спрашивает пароль шифрования и вызывает then, когда личные данные доступны. При первом
+// запуске вместо пароля предлагается включить шифрование. Отмена закрывает приложение.
+func (a *App) unlock(then func()) {
+	if a.encryption == nil {
+		then()
+		return
+	}
+	status, err := a.encryption.EncryptionStatus(a.ctx)
+	if err != nil {
+		showError(err, a.w)
+		log.Println(err)
+		return
+	}
+
+	switch status {
+	case storage.EncryptionOff:
+		a.showEncryptionSetup(then)
+	case storage.EncryptionLocked:
+		a.showUnlock(then)
+	default:
+		then()
+	}
+}
+
+// unlockRestored спрашивает пароль после восстановления из копии, если копия зашифрована
+// другим паролем. Копия без шифрования открывается сразу.
+func (a *App) unlockRestored(then func()) {
+	if a.encryption == nil {
+		then()
+		return
+	}
+	if status, err := a.encryption.EncryptionStatus(a.ctx); err != nil || status != storage.EncryptionLocked {
+		then()
+		return
+	}
+	a.showUnlock(then)
+}
+
+func (a *App) showEncryptionSetup(then func()) {
+	passphraseEntry := widget.NewPasswordEntry()
+	repeatEntry := widget.NewPasswordEntry()
+	hint := widget.NewLabel(i18n.T("encryption.setup_hint", map[string]any{"Min": storage.MinPassphraseLength}))
+	hint.Wrapping = fyne.TextWrapWord
+	items := []*widget.FormItem{
+		widget.NewFormItem("", hint),
+		widget.NewFormItem(i18n.T("encryption.passphrase"), passphraseEntry),
+		widget.NewFormItem(i18n.T("encryption.repeat"), repeatEntry),
+	}
+
+	d := dialog.NewForm(i18n.T("encryption.setup_title"), i18n.T("encryption.enable"), i18n.T("login.exit"), items, func(ok bool) {
+		if !ok {
+			a.app.Quit()
+			return
+		}
+		if passphraseEntry.Text != repeatEntry.Text {
+			a.unlockFailed(errors.New(i18n.T("encryption.mismatch")), then)
+			return
+		}
+		if err := a.encryption.EnableEncryption(a.ctx, passphraseEntry.Text); err != nil {
+			a.unlockFailed(err, then)
+			return
+		}
+		then()
+	}, a.w)
+	d.Resize(fyne.NewSize(450, 300))
+	d.Show()
+}
+
+func (a *App) showUnlock(then func()) {
+	passphraseEntry := widget.NewPasswordEntry()
+	items := []*widget.FormItem{
+		widget.NewFormItem(i18n.T("encryption.passphrase"), passphraseEntry),
+	}
+
+	d := dialog.NewForm(i18n.T("encryption.unlock_title"), i18n.T("encryption.unlock"), i18n.T("login.exit"), items, func(ok bool) {
+		if !ok {
+			a.app.Quit()
+			return
+		}
+		if err := a.encryption.Unlock(a.ctx, passphraseEntry.Text); err != nil {
+			a.unlockFailed(err, then)
+			return
+		}
+		then()
+	}, a.w)
+	d.Resize(fyne.NewSize(400, 150))
+	d.Show()
+}
+
+// unlockFailed показывает ошибку и снова спрашивает пароль.
+func (a *App) unlockFailed(err error, then func()) {
+	log.Println(err)
+	errDialog := dialog.NewError(i18n.Error(err), a.w)
+	errDialog.SetOnClosed(func() { a.unlock(then) })
+	errDialog.Show()
+}
+
+// showWriteError показывает ошибку записи. Если другой клиент общей базы сменил пароль
+// шифрования, ключ в памяти устарел: после ошибки пароль спрашивается заново.
+func (a *App) showWriteError(err error, w fyne.Window) {
+	log.Println(err)
+	if !errors.Is(err, storage.ErrDatabaseLocked) {
+		showError(err, w)
+		return
+	}
+	errDialog := dialog.NewError(i18n.Error(err), w)
+	errDialog.SetOnClosed(func() { a.unlockRestored(a.dataChanged) })
+	errDialog.Show()
+}
+
+// showRotateKey меняет пароль шифрования. Все личные данные перешифровываются новым ключом.
+func (a *App) showRotateKey() {
+	currentEntry := widget.NewPasswordEntry()
+	nextEntry := widget.NewPasswordEntry()
+	repeatEntry := widget.NewPasswordEntry()
+	items := []*widget.FormItem{
+		widget.NewFormItem(i18n.T("encryption.current"), currentEntry),
+		widget.NewFormItem(i18n.T("encryption.new"), nextEntry),
+		widget.NewFormItem(i18n.T("encryption.repeat"), repeatEntry),
+	}
+
+	d := dialog.NewForm(i18n.T("encryption.rotate_title"), i18n.T("encryption.rotate"), i18n.T("common.cancel"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		if nextEntry.Text != repeatEntry.Text {
+			showError(errors.New(i18n.T("encryption.mismatch")), a.w)
+			return
+		}
+		if err := a.encryption.RotateKey(a.ctx, currentEntry.Text, nextEntry.Text); err != nil {
+			showError(err, a.w)
+			log.Println(err)
+			return
+		}
+		dialog.ShowInformation(i18n.T("encryption.rotate_title"), i18n.T("encryption.rotated"), a.w)
+	}, a.w)
+	d.Resize(fyne.NewSize(450, 250))
+	d.Show()
+}
@@ -0,0 +1,100 @@
+package application
+
+import (
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/storage"
+	"github.com/ttrtcixy/demo/internal/storage/storagetest"
+	"path/filepath"
+	"testing"
+)
+
+// fillPassphrases заполняет поля пароля открытого диалога title и нажимает кнопку confirm.
+func fillPassphrases(t *testing.T, a *App, title, confirm string, values ...string) {
+	t.Helper()
+
+	d := waitDialog(t, a, title)
+	entries := find[*widget.Entry](d)
+	if len(entries) != len(values) {
+		t.Fatalf("в диалоге %q %d полей, ожидалось %d", title, len(entries), len(values))
+	}
+	for i, v := range values {
+		entries[i].SetText(v)
+	}
+	test.Tap(findButton(t, d, confirm))
+}
+
+func TestEncryptionSetup(t *testing.T) {
+	a, store := newTestAppAs(t, "")
+	unlocked := 0
+	then := func() { unlocked++ }
+
+	a.unlock(then)
+	fillPassphrases(t, a, "Шифрование личных данных", "Включить", storagetest.Passphrase, "другой пароль")
+	test.Tap(findButton(t, waitDialog(t, a, "Пароли не совпадают"), "OK"))
+	fillPassphrases(t, a, "Шифрование личных данных", "Включить", "short", "short")
+	test.Tap(findButton(t, waitDialog(t, a, "не меньше 8 символов"), "OK"))
+	if unlocked != 0 {
+		t.Fatal("приложение открыто без шифрования")
+	}
+
+	fillPassphrases(t, a, "Шифрование личных данных", "Включить", storagetest.Passphrase, storagetest.Passphrase)
+	if unlocked != 1 {
+		t.Fatal("после включения шифрования приложение не открыто")
+	}
+	if status, _ := store.EncryptionStatus(a.ctx); status != storage.EncryptionUnlocked {
+		t.Errorf("EncryptionStatus = %v", status)
+	}
+}
+
+// TestEncryptionUnlock открывает зашифрованную базу SQLite повторно: без пароля данные недоступны.
+func TestEncryptionUnlock(t *testing.T) {
+	a, _ := newTestAppAs(t, "")
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	db, err := storage.NewDB(t.Context(), storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.EnableEncryption(t.Context(), storagetest.Passphrase); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	db, err = storage.NewDB(t.Context(), storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	a.encryption = db
+
+	unlocked := 0
+	a.unlock(func() { unlocked++ })
+	fillPassphrases(t, a, "База зашифрована", "Открыть", "неверный пароль")
+	test.Tap(findButton(t, waitDialog(t, a, "Неверный пароль шифрования"), "OK"))
+	if unlocked != 0 {
+		t.Fatal("база открыта с неверным паролем")
+	}
+
+	fillPassphrases(t, a, "База зашифрована", "Открыть", storagetest.Passphrase)
+	if unlocked != 1 {
+		t.Fatal("база не открыта верным паролем")
+	}
+}
+
+func TestRotateKeyDialog(t *testing.T) {
+	a, store := newTestApp(t)
+	if err := store.EnableEncryption(a.ctx, storagetest.Passphrase); err != nil {
+		t.Fatal(err)
+	}
+	const next = "новый пароль шифрования"
+
+	a.showRotateKey()
+	fillPassphrases(t, a, "Смена пароля шифрования", "Сменить", "неверный пароль", next, next)
+	test.Tap(findButton(t, waitDialog(t, a, "Неверный пароль шифрования"), "OK"))
+
+	a.showRotateKey()
+	fillPassphrases(t, a, "Смена пароля шифрования", "Сменить", storagetest.Passphrase, next, next)
+	waitDialog(t, a, "Пароль изменен")
+	if err := store.Unlock(a.ctx, next); err != nil {
+		t.Errorf("Unlock новым паролем: %v", err)
+	}
+}
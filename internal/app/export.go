@@ -0,0 +1,69 @@
+package application
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"log"
+)
+
+// exportCSV предлагает выбрать файл и сохраняет в него таблицу в формате CSV.
+func exportCSV(w fyne.Window, fileName string, header []string, rows [][]string) {
+	saveDialog := dialog.NewFileSave(func(file fyne.URIWriteCloser, err error) {
+		if err != nil {
+			showError(err, w)
+			return
+		}
+		if file == nil {
+			return
+		}
+		defer file.Close()
+
+		writer := csv.NewWriter(file)
+		writer.Comma = ';'
+		if err := writer.Write(header); err != nil {
+			showError(err, w)
+			log.Println(err)
+			return
+		}
+		if err := writer.WriteAll(rows); err != nil {
+			showError(err, w)
+			log.Println(err)
+			return
+		}
+		dialog.ShowInformation(i18n.T("common.export"), i18n.T("export.saved", map[string]any{"File": file.URI().Name()}), w)
+	}, w)
+	saveDialog.SetFileName(fileName)
+	saveDialog.Show()
+}
+
+// exportJSON предлагает выбрать файл и сохраняет в него v в формате JSON.
+func exportJSON(w fyne.Window, fileName string, v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		showError(err, w)
+		log.Println(err)
+		return
+	}
+	saveDialog := dialog.NewFileSave(func(file fyne.URIWriteCloser, err error) {
+		if err != nil {
+			showError(err, w)
+			return
+		}
+		if file == nil {
+			return
+		}
+		defer file.Close()
+
+		if _, err := file.Write(data); err != nil {
+			showError(err, w)
+			log.Println(err)
+			return
+		}
+		dialog.ShowInformation(i18n.T("common.export"), i18n.T("export.saved", map[string]any{"File": file.URI().Name()}), w)
+	}, w)
+	saveDialog.SetFileName(fileName)
+	saveDialog.Show()
+}
@@ -0,0 +1,46 @@
+package application
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/numfmt"
+)
+
+// languagePreference — ключ настроек Fyne, в котором хранится выбранный язык интерфейса.
+const languagePreference = "language"
+
+// showError показывает ошибку на языке интерфейса.
+func showError(err error, w fyne.Window) {
+	dialog.ShowError(i18n.Error(err), w)
+}
+
+func (a *App) languageMenu() *fyne.Menu {
+	items := make([]*fyne.MenuItem, 0, len(i18n.Languages))
+	for _, language := range i18n.Languages {
+		item := fyne.NewMenuItem(i18n.LanguageNames[language], func() {
+			a.switchLanguage(language)
+		})
+		item.Checked = language == i18n.Current()
+		items = append(items, item)
+	}
+	return fyne.NewMenu(i18n.T("menu.language"), items...)
+}
+
+// switchLanguage сохраняет выбранный язык и пересоздает интерфейс. Формат чисел
+// переключается вместе с языком.
+func (a *App) switchLanguage(language string) {
+	a.app.Preferences().SetString(languagePreference, language)
+	i18n.SetLanguage(language)
+	numfmt.SetLocale(numfmt.ForLanguage(language))
+
+	a.w.SetMainMenu(a.mainMenu())
+	if a.user.Login != "" {
+		a.updateTitle()
+		a.w.SetContent(a.InitTabs())
+	}
+}
+
+func (a *App) updateTitle() {
+	a.w.SetTitle(i18n.T("app.title_user", map[string]any{"Login": a.user.Login, "Role": roleName(a.user.Role)}))
+}
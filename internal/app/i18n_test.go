@@ -0,0 +1,38 @@
+package application
+
+import (
+	"fyne.io/fyne/v2/container"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/numfmt"
+	"github.com/ttrtcixy/demo/internal/storage"
+	"testing"
+)
+
+func TestSwitchLanguage(t *testing.T) {
+	a, _ := newTestApp(t)
+	t.Cleanup(func() {
+		i18n.SetLanguage(i18n.Russian)
+		numfmt.SetLocale(numfmt.Russian)
+	})
+	a.w.SetContent(a.InitTabs())
+
+	a.switchLanguage(i18n.English)
+
+	tabs, ok := a.w.Content().(*container.AppTabs)
+	if !ok {
+		t.Fatalf("содержимое окна не пересоздано: %T", a.w.Content())
+	}
+	if tabs.Items[1].Text != "Partners" {
+		t.Errorf("вкладка %q, ожидалось Partners", tabs.Items[1].Text)
+	}
+	findButton(t, tabs.Items[1].Content, "Add partner")
+	if got := a.app.Preferences().String(languagePreference); got != i18n.English {
+		t.Errorf("язык в настройках %q", got)
+	}
+	if numfmt.Current() != numfmt.English {
+		t.Error("формат чисел не переключен")
+	}
+
+	showError(storage.ErrForbidden, a.w)
+	waitDialog(t, a, "You do not have permission for this operation")
+}
@@ -0,0 +1,110 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"sync"
+)
+
+// loader показывает индикатор фоновой загрузки с кнопкой отмены. Новая загрузка
+// отменяет предыдущую, результат отмененной загрузки отбрасывается.
+type loader struct {
+	mu      sync.Mutex
+	current int
+	cancel  context.CancelFunc
+
+	label *widget.Label
+	bar   *widget.ProgressBarInfinite
+	box   *fyne.Container
+}
+
+func newLoader() *loader {
+	l := &loader{
+		label: widget.NewLabel(""),
+		bar:   widget.NewProgressBarInfinite(),
+	}
+	l.bar.Stop()
+
+	cancelBtn := widget.NewButton(i18n.T("common.cancel"), func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.cancel != nil {
+			l.cancel()
+		}
+	})
+
+	l.box = container.NewBorder(nil, nil, l.label, cancelBtn, l.bar)
+	l.box.Hide()
+	return l
+}
+
+func (l *loader) view() fyne.CanvasObject {
+	return l.box
+}
+
+func (l *loader) start(text string, cancel context.CancelFunc) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.cancel != nil {
+		l.cancel()
+	}
+	l.current++
+	l.cancel = cancel
+
+	l.label.SetText(text)
+	l.bar.Start()
+	l.box.Show()
+	return l.current
+}
+
+// finish скрывает индикатор, если загрузка id — последняя. Возвращает false, если результат
+// загрузки нужно отбросить.
+func (l *loader) finish(ctx context.Context, id int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if id != l.current {
+		return false
+	}
+	canceled := errors.Is(ctx.Err(), context.Canceled)
+	l.cancel()
+	l.cancel = nil
+	l.bar.Stop()
+	l.box.Hide()
+	return !canceled
+}
+
+// load выполняет fetch в фоновой горутине и передает результат в apply или ошибку в fail.
+// apply и fail выполняются в потоке UI и только если загрузку не отменили. Загрузка
+// считается завершенной в a.loads после apply или fail, в том числе если результат отброшен.
+func load[T any](a *App, l *loader, text string, fetch func(ctx context.Context) (T, error), apply func(T), fail func(error)) {
+	ctx, cancel := context.WithCancel(a.ctx)
+	id := l.start(text, cancel)
+
+	a.loads.Add(1)
+	go func() {
+		result, err := fetch(ctx)
+		a.runOnMain(func() {
+			defer a.loads.Done()
+			if !l.finish(ctx, id) {
+				return
+			}
+			if err != nil {
+				fail(err)
+				return
+			}
+			apply(result)
+		})
+	}()
+}
+
+// runOnMain применяет результат фоновой работы к виджетам в потоке UI. fyne.Do есть
+// начиная с Fyne 2.6, поэтому go.mod требует не ниже этой версии.
+func runOnMain(f func()) {
+	fyne.Do(f)
+}
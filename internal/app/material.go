@@ -1,96 +1,248 @@
 package application
 
 import (
+	"context"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
-	"fyne.io/fyne/v2/data/validation"
 	"fyne.io/fyne/v2/widget"
-	"strconv"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/numfmt"
+	"log"
 	"strings"
 )
 
-func (a *App) createMaterialsCalcTab() fyne.CanvasObject {
+// catalogOptions — списки продуктов и материалов для выпадающих списков.
+type catalogOptions struct {
+	products  []string
+	materials []string
+}
 
-	products, err := a.db.GetProducts()
-	if err != nil {
-		return widget.NewLabel("Ошибка загрузки продуктов: " + err.Error())
-	}
+// loadCatalog загружает списки продуктов и материалов в фоне.
+func (a *App) loadCatalog(l *loader, apply func(catalogOptions), fail func(error)) {
+	load(a, l, i18n.T("catalog.loading"),
+		func(ctx context.Context) (catalogOptions, error) {
+			var options catalogOptions
+			var err error
+			options.products, err = a.catalog.GetProducts(ctx)
+			if err != nil {
+				return options, fmt.Errorf("%s: %w", i18n.T("catalog.products_error"), err)
+			}
+			options.materials, err = a.catalog.GetMaterialTypes(ctx)
+			if err != nil {
+				return options, fmt.Errorf("%s: %w", i18n.T("catalog.materials_error"), err)
+			}
+			return options, nil
+		},
+		apply, fail,
+	)
+}
 
-	materialTypes, err := a.db.GetMaterialTypes()
-	if err != nil {
-		return widget.NewLabel("Ошибка загрузки типов материалов: " + err.Error())
-	}
+// materialInput — проверенные данные формы расчета.
+type materialInput struct {
+	productId, materialId string
+	param1, param2        float64
+}
 
-	productSelect := widget.NewSelect(products, nil)
-	materialSelect := widget.NewSelect(materialTypes, nil)
+func (a *App) createMaterialsCalcTab() fyne.CanvasObject {
+	productSelect := widget.NewSelect(nil, nil)
+	materialSelect := widget.NewSelect(nil, nil)
 	quantityEntry := widget.NewEntry()
-	param1Entry := widget.NewEntry()
-	param2Entry := widget.NewEntry()
+	params := newParamInputs(nil)
+	availableEntry := widget.NewEntry()
 
-	quantityEntry.SetPlaceHolder("Количество")
-	quantityEntry.Validator = validation.NewRegexp(`^[1-9]\d*$`, "Должно быть целое число > 0")
-	param1Entry.SetPlaceHolder("Параметр 1")
-	param1Entry.Validator = validation.NewRegexp(`^[0-9]*\.?[0-9]+$`, "Должно быть число > 0")
-	param2Entry.SetPlaceHolder("Параметр 2")
-	param2Entry.Validator = validation.NewRegexp(`^[0-9]*\.?[0-9]+$`, "Должно быть число > 0")
+	quantityEntry.SetPlaceHolder(i18n.T("material.quantity"))
+	quantityEntry.Validator = positiveIntValidator
+	availableEntry.SetPlaceHolder(i18n.T("material.available"))
+	availableEntry.Validator = positiveIntValidator
 
 	resultLabel := widget.NewLabel("")
 	resultLabel.TextStyle.Bold = true
 
-	calculateBtn := widget.NewButton("Рассчитать", func() {
+	loader := newLoader()
+	// Справочники и параметры загружаются отдельно, чтобы их не отменял расчет.
+	catalogLoader := newLoader()
+	history := &CalculationHistory{selected: -1, loader: newLoader()}
 
+	showFailure := func(err error) {
+		resultLabel.SetText(i18n.Message(err))
+	}
+
+	a.loadCatalog(catalogLoader, func(options catalogOptions) {
+		productSelect.SetOptions(options.products)
+		materialSelect.SetOptions(options.materials)
+	}, showFailure)
+
+	input := func() (materialInput, bool) {
 		if productSelect.Selected == "" || materialSelect.Selected == "" {
-			resultLabel.SetText("Выберите продукт и материал")
-			return
+			resultLabel.SetText(i18n.T("material.select_product_material"))
+			return materialInput{}, false
 		}
 
-		quantity, err := strconv.Atoi(quantityEntry.Text)
+		param1, param2, err := params.values()
 		if err != nil {
-			resultLabel.SetText("Некорректное количество")
-			return
+			resultLabel.SetText(i18n.Message(err))
+			return materialInput{}, false
 		}
 
-		param1, err := strconv.ParseFloat(param1Entry.Text, 64)
+		return materialInput{
+			productId:  strings.Split(productSelect.Selected, " - ")[0],
+			materialId: strings.Split(materialSelect.Selected, " - ")[0],
+			param1:     param1,
+			param2:     param2,
+		}, true
+	}
+
+	calculate := func() {
+		quantity, err := numfmt.ParseInt(quantityEntry.Text)
 		if err != nil {
-			resultLabel.SetText("Некорректный параметр 1")
+			resultLabel.SetText(i18n.T("material.invalid_quantity"))
 			return
 		}
 
-		param2, err := strconv.ParseFloat(param2Entry.Text, 64)
-		if err != nil {
-			resultLabel.SetText("Некорректный параметр 2")
+		in, ok := input()
+		if !ok {
 			return
 		}
 
-		productId := strings.Split(productSelect.Selected, " - ")[0]
-		materialId := strings.Split(materialSelect.Selected, " - ")[0]
+		load(a, loader, i18n.T("material.calculating"),
+			func(ctx context.Context) (models.MaterialCalculation, error) {
+				calc, err := a.materials.CalculateMaterial(ctx, in.productId, in.materialId, quantity, in.param1, in.param2)
+				if err != nil {
+					return calc, fmt.Errorf("%s: %w", i18n.T("material.calc_error"), err)
+				}
+				if err := a.materials.SaveCalculation(ctx, calc); err != nil {
+					log.Println(err)
+				}
+				return calc, nil
+			},
+			func(calc models.MaterialCalculation) {
+				resultLabel.SetText(formatCalculation(calc))
+				history.reload(a)
+			},
+			showFailure,
+		)
+	}
+	calculateBtn := widget.NewButton(i18n.T("material.calculate"), calculate)
 
-		required, err := a.db.CalculateMaterial(productId, materialId, quantity, param1, param2)
+	calculateMaxBtn := widget.NewButton(i18n.T("material.calculate_max"), func() {
+		available, err := numfmt.ParseInt(availableEntry.Text)
 		if err != nil {
-			resultLabel.SetText("Ошибка расчета: " + err.Error())
+			resultLabel.SetText(i18n.T("material.invalid_available"))
+			return
+		}
+
+		in, ok := input()
+		if !ok {
 			return
 		}
 
-		resultLabel.SetText(fmt.Sprintf("Требуется материала: %d единиц", required))
+		load(a, loader, i18n.T("material.calculating"),
+			func(ctx context.Context) (int, error) {
+				quantity, err := a.materials.CalculateMaxProducts(ctx, in.productId, in.materialId, available, in.param1, in.param2)
+				if err != nil {
+					return quantity, fmt.Errorf("%s: %w", i18n.T("material.calc_error"), err)
+				}
+				return quantity, nil
+			},
+			func(quantity int) {
+				resultLabel.SetText(i18n.T("material.max_result", map[string]any{"Quantity": numfmt.FormatInt(quantity)}))
+			},
+			showFailure,
+		)
 	})
 
-	form := &widget.Form{
-		Items: []*widget.FormItem{
-			{Text: "Продукт:", Widget: productSelect},
-			{Text: "Материал:", Widget: materialSelect},
-			{Text: "Количество:", Widget: quantityEntry},
-			{Text: "Параметр 1:", Widget: param1Entry},
-			{Text: "Параметр 2:", Widget: param2Entry},
-		},
+	formBox := container.NewVBox()
+	buildForm := func() {
+		items := []*widget.FormItem{
+			{Text: i18n.T("material.product_label"), Widget: productSelect},
+			{Text: i18n.T("material.material_label"), Widget: materialSelect},
+			{Text: i18n.T("material.quantity_label"), Widget: quantityEntry},
+		}
+		items = append(items, params.formItems()...)
+		items = append(items, &widget.FormItem{Text: i18n.T("material.available_label"), Widget: availableEntry})
+
+		formBox.Objects = []fyne.CanvasObject{&widget.Form{Items: items}}
+		formBox.Refresh()
+	}
+	buildForm()
+
+	// afterParams выполняется, когда загрузятся параметры выбранного продукта.
+	var afterParams func()
+
+	productSelect.OnChanged = func(s string) {
+		productId := strings.Split(s, " - ")[0]
+		load(a, catalogLoader, i18n.T("params.loading"),
+			func(ctx context.Context) ([]models.ProductParameter, error) {
+				return a.catalog.GetProductParameters(ctx, productId)
+			},
+			func(definition []models.ProductParameter) {
+				params = newParamInputs(definition)
+				buildForm()
+				if afterParams != nil {
+					afterParams()
+					afterParams = nil
+				}
+			},
+			func(err error) {
+				resultLabel.SetText(i18n.T("params.load_error") + ": " + i18n.Message(err))
+				params = newParamInputs(nil)
+				buildForm()
+				afterParams = nil
+			},
+		)
 	}
 
-	return container.NewVBox(
-		widget.NewLabel("Расчет необходимого материала"),
+	historyView := history.view(a, func(calc models.MaterialCalculation) {
+		product := fmt.Sprintf("%d - %s", calc.ProductId, calc.ProductName)
+		afterParams = func() {
+			params.setValues(calc.Param1, calc.Param2)
+			calculate()
+		}
+		materialSelect.SetSelected(fmt.Sprintf("%d - %s", calc.MaterialTypeId, calc.MaterialType))
+		quantityEntry.SetText(numfmt.FormatInt(calc.Quantity))
+		productSelect.SetSelected(product)
+		if productSelect.Selected != product {
+			afterParams = nil
+		}
+	})
+
+	calcPanel := container.NewVBox(
+		widget.NewLabel(i18n.T("material.title")),
+		catalogLoader.view(),
+		loader.view(),
 		widget.NewSeparator(),
-		form,
-		calculateBtn,
+		formBox,
+		container.NewHBox(calculateBtn, calculateMaxBtn),
 		widget.NewSeparator(),
 		resultLabel,
 	)
+
+	split := container.NewHSplit(container.NewVScroll(calcPanel), historyView)
+	split.Offset = 0.45
+	return split
+}
+
+func formatCalculation(calc models.MaterialCalculation) string {
+	lines := []string{
+		i18n.T("material.result.required", map[string]any{"Required": numfmt.FormatInt(calc.Required)}),
+		"",
+		i18n.T("material.result.coefficient", map[string]any{"Value": numfmt.FormatFloat(calc.ProductCoefficient, -1)}),
+		i18n.T("material.result.defect", map[string]any{"Value": numfmt.FormatFloat(calc.DefectPercentage, -1)}),
+		i18n.T("material.result.per_unit", map[string]any{
+			"Param1":      numfmt.FormatFloat(calc.Param1, -1),
+			"Param2":      numfmt.FormatFloat(calc.Param2, -1),
+			"Coefficient": numfmt.FormatFloat(calc.ProductCoefficient, -1),
+			"Value":       numfmt.FormatFloat(calc.MaterialPerUnit, 4),
+		}),
+		i18n.T("material.result.base", map[string]any{
+			"Quantity": numfmt.FormatInt(calc.Quantity),
+			"PerUnit":  numfmt.FormatFloat(calc.MaterialPerUnit, 4),
+			"Value":    numfmt.FormatFloat(calc.BaseTotal, 4),
+		}),
+		i18n.T("material.result.allowance", map[string]any{"Value": numfmt.FormatFloat(calc.DefectAllowance, 4)}),
+		i18n.T("material.result.rounding", map[string]any{"Value": numfmt.FormatFloat(calc.Rounding, 4)}),
+	}
+	return strings.Join(lines, "\n")
 }
@@ -0,0 +1,123 @@
+package application
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/numfmt"
+	"log"
+	"strconv"
+)
+
+type CalculationHistory struct {
+	history  []models.MaterialCalculation
+	selected int
+	table    *widget.Table
+	loader   *loader
+}
+
+func calculationHistoryHeader() []string {
+	return []string{
+		i18n.T("col.date"), i18n.T("history.col.product"), i18n.T("history.col.material"), i18n.T("history.col.quantity"),
+		i18n.T("history.col.param1"), i18n.T("history.col.param2"), i18n.T("history.col.required"),
+	}
+}
+
+func (h *CalculationHistory) view(a *App, onRerun func(models.MaterialCalculation)) fyne.CanvasObject {
+	header := calculationHistoryHeader()
+	h.table = widget.NewTable(
+		func() (int, int) {
+			return len(h.history) + 1, len(header)
+		},
+		func() fyne.CanvasObject {
+			return container.NewHScroll(widget.NewLabel("template"))
+		},
+		func(i widget.TableCellID, o fyne.CanvasObject) {
+			scrollContainer := o.(*container.Scroll)
+			label := scrollContainer.Content.(*widget.Label)
+			if i.Row == 0 {
+				label.SetText(header[i.Col])
+				return
+			}
+			if i.Row-1 < len(h.history) {
+				label.SetText(calculationRow(h.history[i.Row-1])[i.Col])
+			}
+		},
+	)
+	h.table.SetColumnWidth(0, 140)
+	h.table.SetColumnWidth(1, 200)
+	h.table.SetColumnWidth(2, 120)
+	h.table.SetColumnWidth(3, 90)
+	h.table.SetColumnWidth(4, 90)
+	h.table.SetColumnWidth(5, 90)
+	h.table.SetColumnWidth(6, 90)
+	h.table.OnSelected = func(id widget.TableCellID) {
+		if id.Row > 0 {
+			h.selected = id.Row - 1
+		}
+	}
+
+	h.reload(a)
+
+	rerunBtn := widget.NewButton(i18n.T("history.rerun"), func() {
+		if h.selected < 0 || h.selected >= len(h.history) {
+			dialog.ShowInformation(i18n.T("common.not_selected"), i18n.T("history.select"), a.w)
+			return
+		}
+		onRerun(h.history[h.selected])
+	})
+
+	exportBtn := widget.NewButton(i18n.T("common.export_csv"), func() {
+		header := append(calculationHistoryHeader(),
+			i18n.T("history.col.coefficient"), i18n.T("history.col.defect"), i18n.T("history.col.per_unit"),
+			i18n.T("history.col.base"), i18n.T("history.col.allowance"), i18n.T("history.col.rounding"))
+		rows := make([][]string, 0, len(h.history))
+		for _, c := range h.history {
+			rows = append(rows, append(calculationRow(c),
+				strconv.FormatFloat(c.ProductCoefficient, 'f', -1, 64),
+				strconv.FormatFloat(c.DefectPercentage, 'f', -1, 64),
+				strconv.FormatFloat(c.MaterialPerUnit, 'f', 4, 64),
+				strconv.FormatFloat(c.BaseTotal, 'f', 4, 64),
+				strconv.FormatFloat(c.DefectAllowance, 'f', 4, 64),
+				strconv.FormatFloat(c.Rounding, 'f', 4, 64),
+			))
+		}
+		exportCSV(a.w, "material_calculations.csv", header, rows)
+	})
+
+	return container.NewBorder(
+		container.NewVBox(widget.NewLabel(i18n.T("history.title")), h.loader.view()),
+		container.NewHBox(rerunBtn, exportBtn, widget.NewButton(i18n.T("common.refresh"), func() { h.reload(a) })),
+		nil, nil,
+		h.table,
+	)
+}
+
+func (h *CalculationHistory) reload(a *App) {
+	load(a, h.loader, i18n.T("history.loading"), a.materials.GetCalculationHistory,
+		func(history []models.MaterialCalculation) {
+			h.history = history
+			h.selected = -1
+			h.table.UnselectAll()
+			h.table.Refresh()
+		},
+		func(err error) {
+			log.Println(err)
+		},
+	)
+}
+
+func calculationRow(c models.MaterialCalculation) []string {
+	return []string{
+		c.CreatedAt,
+		c.ProductName,
+		c.MaterialType,
+		numfmt.FormatInt(c.Quantity),
+		numfmt.FormatFloat(c.Param1, -1),
+		numfmt.FormatFloat(c.Param2, -1),
+		numfmt.FormatInt(c.Required),
+	}
+}
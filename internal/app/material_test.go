@@ -0,0 +1,116 @@
+package application
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+	"strings"
+	"testing"
+)
+
+// materialsTab открывает вкладку расчета и ждет загрузки справочников.
+func materialsTab(t *testing.T, a *App) (tab fyne.CanvasObject, productSelect, materialSelect *widget.Select) {
+	t.Helper()
+
+	tab = a.createMaterialsCalcTab()
+	a.w.SetContent(tab)
+
+	selects := find[*widget.Select](tab)
+	productSelect, materialSelect = selects[0], selects[1]
+	waitFor(t, a, "загрузка справочников", func() bool {
+		return len(productSelect.Options) == 3 && len(materialSelect.Options) == 3
+	})
+	return tab, productSelect, materialSelect
+}
+
+// calcEntries возвращает поля формы расчета: количество, параметры и доступный материал.
+func calcEntries(tab fyne.CanvasObject) []*widget.Entry {
+	return find[*widget.Entry](tab)
+}
+
+func waitResult(t *testing.T, a *App, tab fyne.CanvasObject, text string) {
+	t.Helper()
+
+	waitFor(t, a, "результат "+text, func() bool {
+		return strings.Contains(texts(tab), text)
+	})
+}
+
+func TestCalculateMaterial(t *testing.T) {
+	a, store := newTestApp(t)
+	tab, productSelect, materialSelect := materialsTab(t, a)
+
+	productSelect.SetSelected("1 - Паркетная доска Ясень")
+	waitFor(t, a, "параметры продукта", func() bool {
+		return calcEntries(tab)[1].PlaceHolder == "от 0,01 до 100"
+	})
+	materialSelect.SetSelected("1 - Тип материала 1")
+
+	entries := calcEntries(tab)
+	entries[0].SetText("10")
+	entries[1].SetText("2")
+	entries[2].SetText("3")
+	test.Tap(findButton(t, tab, "Рассчитать"))
+
+	waitResult(t, a, tab, "Требуется материала: 261 единиц")
+	waitFor(t, a, "сохранение в историю", func() bool {
+		history, _ := store.GetCalculationHistory(a.ctx)
+		return len(history) == 1
+	})
+}
+
+func TestCalculateMaxProducts(t *testing.T) {
+	a, _ := newTestApp(t)
+	tab, productSelect, materialSelect := materialsTab(t, a)
+
+	productSelect.SetSelected("1 - Паркетная доска Ясень")
+	materialSelect.SetSelected("3 - Без брака")
+
+	waitFor(t, a, "параметры продукта", func() bool {
+		return calcEntries(tab)[1].PlaceHolder == "от 0,01 до 100"
+	})
+	entries := calcEntries(tab)
+	entries[1].SetText("2")
+	entries[2].SetText("0,5")
+	entries[3].SetText("100")
+	test.Tap(findButton(t, tab, "Рассчитать выпуск"))
+
+	waitResult(t, a, tab, "Можно произвести: 23 единиц продукции")
+}
+
+func TestCalculateMaterialErrors(t *testing.T) {
+	tests := []struct {
+		name                     string
+		product, material        string
+		quantity, param1, param2 string
+		want                     string
+	}{
+		{name: "не выбран продукт", quantity: "10", param1: "1", param2: "1", want: "Выберите продукт и материал"},
+		{name: "некорректное количество", product: "1 - Паркетная доска Ясень", material: "1 - Тип материала 1", quantity: "abc", param1: "1", param2: "1", want: "Некорректное количество"},
+		{name: "некорректный параметр", product: "1 - Паркетная доска Ясень", material: "1 - Тип материала 1", quantity: "10", param1: "x", param2: "1", want: "Некорректный параметр \"Длина\""},
+		{name: "параметр вне диапазона", product: "1 - Паркетная доска Ясень", material: "1 - Тип материала 1", quantity: "10", param1: "1", param2: "11", want: "Ширина: Должно быть не больше 10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := newTestApp(t)
+			tab, productSelect, materialSelect := materialsTab(t, a)
+
+			if tt.product != "" {
+				productSelect.SetSelected(tt.product)
+				waitFor(t, a, "параметры продукта", func() bool {
+					return calcEntries(tab)[1].PlaceHolder == "от 0,01 до 100"
+				})
+			}
+			materialSelect.SetSelected(tt.material)
+
+			entries := calcEntries(tab)
+			entries[0].SetText(tt.quantity)
+			entries[1].SetText(tt.param1)
+			entries[2].SetText(tt.param2)
+			test.Tap(findButton(t, tab, "Рассчитать"))
+
+			waitResult(t, a, tab, tt.want)
+		})
+	}
+}
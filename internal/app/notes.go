@@ -0,0 +1,312 @@
+package application
+
+import (
+	"context"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/numfmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// attachmentsRoot — где создается каталог сеанса для открываемых файлов; пусто — os.TempDir().
+var attachmentsRoot = ""
+
+// formatFileSize показывает размер файла в байтах, килобайтах или мегабайтах.
+func formatFileSize(size int64) string {
+	switch {
+	case size < 1<<10:
+		return i18n.T("files.size_b", map[string]any{"Size": numfmt.FormatInt(int(size))})
+	case size < 1<<20:
+		return i18n.T("files.size_kb", map[string]any{"Size": numfmt.FormatFloat(float64(size)/(1<<10), 1)})
+	default:
+		return i18n.T("files.size_mb", map[string]any{"Size": numfmt.FormatFloat(float64(size)/(1<<20), 1)})
+	}
+}
+
+// showPartnerNotes открывает окно с лентой заметок и файлами партнера. Добавлять и удалять
+// заметки и файлы можно, только если editable.
+func (a *App) showPartnerNotes(p models.Partner, editable bool) {
+	w := a.app.NewWindow(i18n.T("notes.title", map[string]any{"Name": p.CompanyName}))
+
+	tabs := container.NewAppTabs(
+		container.NewTabItem(i18n.T("notes.tab"), a.notesSection(p, editable, w)),
+		container.NewTabItem(i18n.T("files.tab"), a.attachmentsSection(p, editable, w)),
+	)
+
+	w.SetContent(tabs)
+	w.Resize(fyne.NewSize(800, 600))
+	w.Show()
+}
+
+// notesSection — лента заметок партнера, новые сверху.
+func (a *App) notesSection(p models.Partner, editable bool, w fyne.Window) fyne.CanvasObject {
+	var notes []models.Note
+	selected := -1
+	list := widget.NewList(
+		func() int {
+			return len(notes)
+		},
+		func() fyne.CanvasObject {
+			meta := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Italic: true})
+			text := widget.NewLabel("")
+			text.Wrapping = fyne.TextWrapWord
+			return container.NewVBox(meta, text)
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			box := o.(*fyne.Container)
+			n := notes[i]
+			box.Objects[0].(*widget.Label).SetText(n.CreatedAt + " · " + n.User)
+			box.Objects[1].(*widget.Label).SetText(n.Text)
+		},
+	)
+	list.OnSelected = func(id widget.ListItemID) {
+		selected = id
+	}
+
+	emptyLabel := widget.NewLabel(i18n.T("notes.empty"))
+	emptyLabel.Hide()
+
+	loader := newLoader()
+	reload := func() {
+		load(a, loader, i18n.T("notes.loading"),
+			func(ctx context.Context) ([]models.Note, error) {
+				return a.notes.GetNotes(ctx, p.Id)
+			},
+			func(result []models.Note) {
+				notes = result
+				selected = -1
+				list.UnselectAll()
+				list.Refresh()
+				if len(notes) == 0 {
+					emptyLabel.Show()
+				} else {
+					emptyLabel.Hide()
+				}
+			},
+			func(err error) {
+				showError(err, w)
+				log.Println(err)
+			},
+		)
+	}
+	reload()
+
+	textEntry := widget.NewMultiLineEntry()
+	textEntry.SetPlaceHolder(i18n.T("notes.placeholder"))
+	textEntry.SetMinRowsVisible(3)
+	addBtn := widget.NewButton(i18n.T("notes.add"), func() {
+		if _, err := a.notes.AddNote(a.ctx, p.Id, textEntry.Text); err != nil {
+			showError(err, w)
+			log.Println(err)
+			return
+		}
+		textEntry.SetText("")
+		reload()
+	})
+	deleteBtn := widget.NewButton(i18n.T("notes.delete"), func() {
+		if selected < 0 || selected >= len(notes) {
+			dialog.ShowInformation(i18n.T("common.not_selected"), i18n.T("notes.select"), w)
+			return
+		}
+		n := notes[selected]
+		dialog.ShowConfirm(i18n.T("notes.delete_title"), i18n.T("notes.delete_confirm"), func(ok bool) {
+			if !ok {
+				return
+			}
+			if err := a.notes.DeleteNote(a.ctx, n.Id); err != nil {
+				showError(err, w)
+				log.Println(err)
+			}
+			reload()
+		}, w)
+	})
+
+	editor := container.NewBorder(nil, nil, nil, container.NewVBox(addBtn, deleteBtn), textEntry)
+	showIf(editor, editable)
+	showIf(textEntry, editable)
+	showIf(addBtn, editable)
+	showIf(deleteBtn, editable)
+
+	return container.NewBorder(container.NewVBox(loader.view(), emptyLabel), editor, nil, nil, list)
+}
+
+// attachmentsSection — таблица файлов партнера с кнопками загрузки, открытия и удаления.
+func (a *App) attachmentsSection(p models.Partner, editable bool, w fyne.Window) fyne.CanvasObject {
+	header := []string{i18n.T("files.col.name"), i18n.T("files.col.size"), i18n.T("col.date"), i18n.T("audit.col.user")}
+
+	var attachments []models.Attachment
+	selected := -1
+	table := widget.NewTable(
+		func() (int, int) {
+			return len(attachments) + 1, len(header)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("template")
+		},
+		func(i widget.TableCellID, o fyne.CanvasObject) {
+			label := o.(*widget.Label)
+			switch {
+			case i.Row == 0:
+				label.SetText(header[i.Col])
+			case i.Row-1 >= len(attachments):
+				label.SetText("")
+			default:
+				f := attachments[i.Row-1]
+				label.SetText([]string{f.FileName, formatFileSize(f.Size), f.CreatedAt, f.User}[i.Col])
+			}
+		},
+	)
+	table.SetColumnWidth(0, 300)
+	table.SetColumnWidth(1, 100)
+	table.SetColumnWidth(2, 160)
+	table.SetColumnWidth(3, 120)
+	table.OnSelected = func(id widget.TableCellID) {
+		if id.Row > 0 && id.Row-1 < len(attachments) {
+			selected = id.Row - 1
+		}
+	}
+
+	emptyLabel := widget.NewLabel(i18n.T("files.empty"))
+	emptyLabel.Hide()
+
+	loader := newLoader()
+	reload := func() {
+		load(a, loader, i18n.T("files.loading"),
+			func(ctx context.Context) ([]models.Attachment, error) {
+				return a.notes.GetAttachments(ctx, p.Id)
+			},
+			func(result []models.Attachment) {
+				attachments = result
+				selected = -1
+				table.UnselectAll()
+				table.Refresh()
+				if len(attachments) == 0 {
+					emptyLabel.Show()
+				} else {
+					emptyLabel.Hide()
+				}
+			},
+			func(err error) {
+				showError(err, w)
+				log.Println(err)
+			},
+		)
+	}
+	reload()
+
+	withSelected := func(f func(models.Attachment)) func() {
+		return func() {
+			if selected < 0 || selected >= len(attachments) {
+				dialog.ShowInformation(i18n.T("common.not_selected"), i18n.T("files.select"), w)
+				return
+			}
+			f(attachments[selected])
+		}
+	}
+	addBtn := widget.NewButton(i18n.T("files.add"), func() {
+		dialog.ShowFileOpen(func(file fyne.URIReadCloser, err error) {
+			if err != nil {
+				showError(err, w)
+				return
+			}
+			if file == nil {
+				return
+			}
+			defer file.Close()
+
+			data, err := io.ReadAll(file)
+			if err == nil {
+				_, err = a.notes.AddAttachment(a.ctx, p.Id, file.URI().Name(), data)
+			}
+			if err != nil {
+				showError(err, w)
+				log.Println(err)
+				return
+			}
+			reload()
+		}, w)
+	})
+	openBtn := widget.NewButton(i18n.T("files.open"), withSelected(func(f models.Attachment) {
+		if err := a.openAttachment(f.Id); err != nil {
+			showError(err, w)
+			log.Println(err)
+		}
+	}))
+	deleteBtn := widget.NewButton(i18n.T("files.delete"), withSelected(func(f models.Attachment) {
+		dialog.ShowConfirm(i18n.T("files.delete_title"), i18n.T("files.delete_confirm", map[string]any{"Name": f.FileName}), func(ok bool) {
+			if !ok {
+				return
+			}
+			if err := a.notes.DeleteAttachment(a.ctx, f.Id); err != nil {
+				showError(err, w)
+				log.Println(err)
+			}
+			reload()
+		}, w)
+	}))
+	showIf(addBtn, editable)
+	showIf(deleteBtn, editable)
+
+	return container.NewBorder(container.NewVBox(loader.view(), emptyLabel), container.NewHBox(addBtn, openBtn, deleteBtn), nil, nil, table)
+}
+
+// openAttachment выгружает файл в каталог сеанса и открывает его программой по умолчанию.
+// Каталог файла назван по контрольной сумме, поэтому одноименные файлы разных партнеров не путаются.
+func (a *App) openAttachment(id int) error {
+	f, data, err := a.notes.ReadAttachment(a.ctx, id)
+	if err != nil {
+		return err
+	}
+	root, err := a.attachmentsDir()
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(root, f.Hash)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	path := filepath.Join(dir, f.FileName)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+	slashed := filepath.ToSlash(path)
+	if !strings.HasPrefix(slashed, "/") {
+		slashed = "/" + slashed
+	}
+	return a.app.OpenURL(&url.URL{Scheme: "file", Path: slashed})
+}
+
+// attachmentsDir возвращает каталог сеанса для открываемых файлов и создает его при первом
+// обращении. Файлы в нем не зашифрованы, поэтому каталог удаляется при выходе, см. removeAttachments.
+func (a *App) attachmentsDir() (string, error) {
+	if a.attachments == "" {
+		dir, err := os.MkdirTemp(attachmentsRoot, "demo-attachments-")
+		if err != nil {
+			return "", err
+		}
+		a.attachments = dir
+	}
+	return a.attachments, nil
+}
+
+// removeAttachments удаляет каталог сеанса с открытыми файлами. Файл, который еще держит
+// внешняя программа, в Windows удалить не получится; такая ошибка только попадает в журнал.
+func (a *App) removeAttachments() {
+	if a.attachments == "" {
+		return
+	}
+	if err := os.RemoveAll(a.attachments); err != nil {
+		log.Println(err)
+		return
+	}
+	a.attachments = ""
+}
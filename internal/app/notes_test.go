@@ -0,0 +1,158 @@
+package application
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/models"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatFileSize(t *testing.T) {
+	tests := map[int64]string{
+		512:             "512 Б",
+		1536:            "1,5 КБ",
+		3 << 20:         "3,0 МБ",
+		5<<20 + 512<<10: "5,5 МБ",
+	}
+	for size, want := range tests {
+		if got := formatFileSize(size); got != want {
+			t.Errorf("formatFileSize(%d) = %q, ожидалось %q", size, got, want)
+		}
+	}
+}
+
+// openPartnerNotes открывает окно заметок и файлов партнера из строки row и выбирает вкладку tab.
+func openPartnerNotes(t *testing.T, a *App, pt *PartnerTable, row int, tab string) fyne.Window {
+	t.Helper()
+
+	details := openPartnerDetails(t, a, pt, row)
+	test.Tap(findButton(t, details.Content(), "Заметки и файлы"))
+	w := lastWindow(a)
+	tabs := w.Content().(*container.AppTabs)
+	for _, item := range tabs.Items {
+		if item.Text == tab {
+			tabs.Select(item)
+		}
+	}
+	return w
+}
+
+// confirm нажимает кнопку подтверждения в верхнем диалоге окна w.
+func confirm(t *testing.T, w fyne.Window) {
+	t.Helper()
+
+	buttons := find[*widget.Button](w.Canvas().Overlays().Top())
+	for _, b := range buttons {
+		if b.Importance == widget.HighImportance {
+			test.Tap(b)
+			return
+		}
+	}
+	t.Fatal("в диалоге нет кнопки подтверждения")
+}
+
+func TestPartnerNotes(t *testing.T) {
+	a, _ := newTestAppAs(t, models.RoleManager)
+	pt := showPartnersTab(t, a)
+
+	w := openPartnerNotes(t, a, pt, 2, "Заметки")
+	list := find[*widget.List](w.Content())[0]
+	waitFor(t, a, "загрузка заметок", func() bool { return strings.Contains(texts(w.Content()), "Заметок пока нет") })
+
+	entry := find[*widget.Entry](w.Content())[0]
+	for _, text := range []string{"Позвонить в понедельник", "Договор подписан"} {
+		entry.SetText(text)
+		test.Tap(findButton(t, w.Content(), "Добавить заметку"))
+	}
+	waitFor(t, a, "добавление заметок", func() bool { return list.Length() == 2 })
+	if entry.Text != "" {
+		t.Errorf("после добавления в поле осталось %q", entry.Text)
+	}
+	if !strings.Contains(texts(w.Content()), "Договор подписан") {
+		t.Errorf("заметка не показана: %s", texts(w.Content()))
+	}
+
+	test.Tap(findButton(t, w.Content(), "Добавить заметку"))
+	waitFor(t, a, "ошибка пустой заметки", func() bool {
+		d := w.Canvas().Overlays().Top()
+		return d != nil && strings.Contains(texts(d), "Заметка не может быть пустой")
+	})
+	w.Canvas().Overlays().Remove(w.Canvas().Overlays().Top())
+
+	list.Select(0)
+	test.Tap(findButton(t, w.Content(), "Удалить заметку"))
+	confirm(t, w)
+	waitFor(t, a, "удаление заметки", func() bool { return list.Length() == 1 })
+	if !strings.Contains(texts(w.Content()), "Позвонить в понедельник") {
+		t.Errorf("удалена не та заметка: %s", texts(w.Content()))
+	}
+}
+
+func TestPartnerAttachments(t *testing.T) {
+	attachmentsRoot = t.TempDir()
+	a, store := newTestAppAs(t, models.RoleManager)
+	pt := showPartnersTab(t, a)
+	contract := []byte("%PDF-1.4 договор поставки")
+	if _, err := store.AddAttachment(a.ctx, 301, "Договор.pdf", contract); err != nil {
+		t.Fatal(err)
+	}
+
+	w := openPartnerNotes(t, a, pt, 2, "Файлы")
+	table := find[*widget.Table](w.Content())[0]
+	rows := func() int {
+		n, _ := table.Length()
+		return n - 1
+	}
+	waitFor(t, a, "загрузка файлов", func() bool { return rows() == 1 })
+
+	table.Select(widget.TableCellID{Row: 1, Col: 0})
+	test.Tap(findButton(t, w.Content(), "Открыть"))
+	files, _ := filepath.Glob(filepath.Join(a.attachments, "*", "Договор.pdf"))
+	if len(files) != 1 {
+		t.Fatalf("файл не выгружен: %v", files)
+	}
+	if data, err := os.ReadFile(files[0]); err != nil || string(data) != string(contract) {
+		t.Errorf("выгружено %q, %v", data, err)
+	}
+
+	// При выходе выгруженные файлы удаляются вместе с каталогом сеанса.
+	dir := a.attachments
+	a.removeAttachments()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("каталог сеанса %s не удален: %v", dir, err)
+	}
+
+	table.Select(widget.TableCellID{Row: 1, Col: 0})
+	test.Tap(findButton(t, w.Content(), "Удалить файл"))
+	confirm(t, w)
+	waitFor(t, a, "удаление файла", func() bool { return rows() == 0 })
+	if attachments, _ := store.GetAttachments(a.ctx, 301); len(attachments) != 0 {
+		t.Errorf("файл не удален: %+v", attachments)
+	}
+}
+
+func TestViewerNotesReadOnly(t *testing.T) {
+	a, _ := newTestAppAs(t, models.RoleViewer)
+	pt := showPartnersTab(t, a)
+
+	w := openPartnerNotes(t, a, pt, 2, "Заметки")
+	for _, text := range []string{"Добавить заметку", "Удалить заметку"} {
+		if findButton(t, w.Content(), text).Visible() {
+			t.Errorf("наблюдателю доступна кнопка %q", text)
+		}
+	}
+
+	tabs := w.Content().(*container.AppTabs)
+	tabs.SelectIndex(1)
+	if findButton(t, w.Content(), "Загрузить файл").Visible() || findButton(t, w.Content(), "Удалить файл").Visible() {
+		t.Error("наблюдателю доступно изменение файлов")
+	}
+	if !findButton(t, w.Content(), "Открыть").Visible() {
+		t.Error("наблюдатель не может открыть файл")
+	}
+}
@@ -0,0 +1,25 @@
+package application
+
+import (
+	"errors"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/numfmt"
+)
+
+// positiveIntValidator заменяет регулярное выражение `^[1-9]\d*$`: принимает разделители разрядов локали.
+func positiveIntValidator(s string) error {
+	v, err := numfmt.ParseInt(s)
+	if err != nil || v <= 0 {
+		return errors.New(i18n.T("validate.positive_int"))
+	}
+	return nil
+}
+
+// positiveFloatValidator принимает десятичный разделитель локали ("2,5") и точку.
+func positiveFloatValidator(s string) error {
+	v, err := numfmt.ParseFloat(s)
+	if err != nil || v <= 0 {
+		return errors.New(i18n.T("validate.positive_float"))
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/numfmt"
+)
+
+// defaultParams используются, пока продукт не выбран или для его типа нет описания параметров.
+func defaultParams() []models.ProductParameter {
+	return []models.ProductParameter{
+		{Position: 1, Name: i18n.T("params.default1")},
+		{Position: 2, Name: i18n.T("params.default2")},
+	}
+}
+
+// paramInputs — поля ввода параметров расчета, построенные по описанию типа продукции.
+type paramInputs struct {
+	params  []models.ProductParameter
+	entries []*widget.Entry
+}
+
+func newParamInputs(params []models.ProductParameter) *paramInputs {
+	if len(params) == 0 {
+		params = defaultParams()
+	}
+
+	p := &paramInputs{params: params}
+	for _, param := range params {
+		entry := widget.NewEntry()
+		entry.SetPlaceHolder(paramPlaceHolder(param))
+		entry.Validator = paramValidator(param)
+		p.entries = append(p.entries, entry)
+	}
+	return p
+}
+
+func (p *paramInputs) formItems() []*widget.FormItem {
+	items := make([]*widget.FormItem, 0, len(p.params))
+	for i, param := range p.params {
+		items = append(items, widget.NewFormItem(paramLabel(param)+":", p.entries[i]))
+	}
+	return items
+}
+
+// values возвращает параметры для формулы расчета. Не описанный параметр равен 1.
+func (p *paramInputs) values() (float64, float64, error) {
+	values := map[int]float64{1: 1, 2: 1}
+	for i, param := range p.params {
+		value, err := numfmt.ParseFloat(p.entries[i].Text)
+		if err != nil {
+			return 0, 0, errors.New(i18n.T("params.invalid", map[string]any{"Name": param.Name}))
+		}
+		if err := p.entries[i].Validate(); err != nil {
+			return 0, 0, fmt.Errorf("%s: %v", param.Name, err)
+		}
+		values[param.Position] = value
+	}
+	return values[1], values[2], nil
+}
+
+func (p *paramInputs) setValues(param1, param2 float64) {
+	values := map[int]float64{1: param1, 2: param2}
+	for i, param := range p.params {
+		p.entries[i].SetText(numfmt.FormatFloat(values[param.Position], -1))
+	}
+}
+
+func paramLabel(p models.ProductParameter) string {
+	if p.Unit == "" {
+		return p.Name
+	}
+	return fmt.Sprintf("%s, %s", p.Name, p.Unit)
+}
+
+func paramPlaceHolder(p models.ProductParameter) string {
+	switch {
+	case p.MinValue != nil && p.MaxValue != nil:
+		return i18n.T("params.range", map[string]any{"Min": numfmt.FormatFloat(*p.MinValue, -1), "Max": numfmt.FormatFloat(*p.MaxValue, -1)})
+	case p.MinValue != nil:
+		return i18n.T("params.at_least", map[string]any{"Min": numfmt.FormatFloat(*p.MinValue, -1)})
+	case p.MaxValue != nil:
+		return i18n.T("params.at_most", map[string]any{"Max": numfmt.FormatFloat(*p.MaxValue, -1)})
+	default:
+		return p.Name
+	}
+}
+
+func paramValidator(p models.ProductParameter) func(string) error {
+	return func(s string) error {
+		if err := positiveFloatValidator(s); err != nil {
+			return err
+		}
+		value, _ := numfmt.ParseFloat(s)
+		if p.MinValue != nil && value < *p.MinValue {
+			return errors.New(i18n.T("validate.at_least", map[string]any{"Min": numfmt.FormatFloat(*p.MinValue, -1)}))
+		}
+		if p.MaxValue != nil && value > *p.MaxValue {
+			return errors.New(i18n.T("validate.at_most", map[string]any{"Max": numfmt.FormatFloat(*p.MaxValue, -1)}))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,199 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/numfmt"
+	"github.com/ttrtcixy/demo/internal/phone"
+	"github.com/ttrtcixy/demo/internal/storage"
+	"log"
+	"time"
+)
+
+// topProductsLimit — сколько продуктов показывать в карточке партнера.
+const topProductsLimit = 5
+
+// partnerSalesSummary — продажи партнера для карточки: по месяцам и по продуктам.
+type partnerSalesSummary struct {
+	months   []models.MonthlySales
+	products []models.ProductSales
+}
+
+// showPartnerDetails открывает карточку партнера: контакты и контактные лица, прогресс до следующей
+// скидки, продажи по месяцам и самые продаваемые продукты. onEdit, если не nil, вызывается кнопкой
+// «Редактировать» после закрытия карточки; тогда же в карточке можно менять контактных лиц,
+// заметки и файлы.
+func (a *App) showPartnerDetails(p models.Partner, onEdit func()) {
+	w := a.app.NewWindow(i18n.T("details.title", map[string]any{"Name": p.CompanyName}))
+
+	title := widget.NewLabelWithStyle(fmt.Sprintf("%s %s", p.PartnerType, p.CompanyName), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	contacts := widget.NewForm(
+		widget.NewFormItem(i18n.T("partners.form.director"), widget.NewLabel(p.Director)),
+		widget.NewFormItem(i18n.T("partners.form.phone"), widget.NewLabel(phone.Format(p.Phone))),
+		widget.NewFormItem(i18n.T("partners.form.email"), widget.NewLabel(p.Email)),
+		widget.NewFormItem(i18n.T("partners.form.address"), widget.NewLabel(p.Address)),
+		widget.NewFormItem(i18n.T("partners.form.rating"), widget.NewLabel(numfmt.FormatInt(p.Rating))),
+	)
+
+	discountLabel := widget.NewLabel(i18n.T("details.discount", map[string]any{"Percentage": p.Discount}))
+	discountProgress := widget.NewProgressBar()
+	nextTierLabel := widget.NewLabel("")
+
+	chart := newBarChart(func(v float64) string { return numfmt.FormatInt(int(v)) })
+
+	var products []models.ProductSales
+	productsHeader := []string{i18n.T("sales.col.product"), i18n.T("sales.col.quantity"), i18n.T("sales.col.sum")}
+	productsTable := widget.NewTable(
+		func() (int, int) {
+			return len(products) + 1, len(productsHeader)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("template")
+		},
+		func(i widget.TableCellID, o fyne.CanvasObject) {
+			label := o.(*widget.Label)
+			switch {
+			case i.Row == 0:
+				label.SetText(productsHeader[i.Col])
+			case i.Row-1 >= len(products):
+				label.SetText("")
+			case i.Col == 0:
+				label.SetText(products[i.Row-1].ProductName)
+			case i.Col == 1:
+				label.SetText(numfmt.FormatInt(products[i.Row-1].Quantity))
+			default:
+				label.SetText(numfmt.FormatMoney(products[i.Row-1].TotalSum))
+			}
+		},
+	)
+	productsTable.SetColumnWidth(0, 200)
+	productsTable.SetColumnWidth(1, 100)
+	productsTable.SetColumnWidth(2, 140)
+
+	loader := newLoader()
+	load(a, loader, i18n.T("details.loading"),
+		func(ctx context.Context) (partnerSalesSummary, error) {
+			var summary partnerSalesSummary
+			var err error
+			if summary.months, err = a.sales.GetMonthlySales(ctx, p.Id); err != nil {
+				return summary, err
+			}
+			summary.products, err = a.sales.GetTopProducts(ctx, p.Id, topProductsLimit)
+			return summary, err
+		},
+		func(summary partnerSalesSummary) {
+			total := 0
+			for _, m := range summary.months {
+				total += m.Quantity
+			}
+			progress, text := discountTierProgress(float64(total))
+			discountProgress.SetValue(progress)
+			nextTierLabel.SetText(text)
+
+			months := fillMonths(summary.months)
+			labels := make([]string, len(months))
+			values := make([]float64, len(months))
+			for i, m := range months {
+				labels[i] = m.Month
+				values[i] = float64(m.Quantity)
+			}
+			chart.SetData(labels, values)
+
+			products = summary.products
+			productsTable.Refresh()
+		},
+		func(err error) {
+			showError(err, w)
+			log.Println(err)
+		},
+	)
+
+	historyBtn := widget.NewButton(i18n.T("partners.history"), func() {
+		a.showPartnerHistory(p.Id)
+	})
+	ratingBtn := widget.NewButton(i18n.T("details.rating_history"), func() {
+		a.showRatingHistory(p)
+	})
+	notesBtn := widget.NewButton(i18n.T("details.notes"), func() {
+		a.showPartnerNotes(p, onEdit != nil)
+	})
+	editBtn := widget.NewButton(i18n.T("details.edit"), func() {
+		w.Close()
+		onEdit()
+	})
+	showIf(editBtn, onEdit != nil)
+	closeBtn := widget.NewButton(i18n.T("details.close"), w.Close)
+	exportBtn, anonymizeBtn := a.personalDataButtons(p, w, loader)
+
+	top := container.NewVBox(
+		loader.view(),
+		title,
+		container.NewGridWithColumns(2,
+			widget.NewCard(i18n.T("details.contacts"), "", contacts),
+			widget.NewCard(i18n.T("details.discount_title"), "", container.NewVBox(discountLabel, discountProgress, nextTierLabel)),
+		),
+	)
+	center := container.NewGridWithRows(2,
+		widget.NewCard(i18n.T("contacts.title"), "", a.contactsSection(p, onEdit != nil, w)),
+		container.NewGridWithColumns(2,
+			widget.NewCard(i18n.T("details.monthly_sales"), "", chart),
+			widget.NewCard(i18n.T("details.top_products"), "", productsTable),
+		),
+	)
+
+	w.SetContent(container.NewBorder(top, container.NewHBox(editBtn, notesBtn, historyBtn, ratingBtn, exportBtn, anonymizeBtn, closeBtn), nil, nil, center))
+	w.Resize(fyne.NewSize(1000, 850))
+	w.Show()
+}
+
+// discountTierProgress возвращает долю пути от текущего порога скидки до следующего и подпись
+// вида «До скидки 10%: 12 000 шт.».
+func discountTierProgress(total float64) (float64, string) {
+	next, ok := storage.NextDiscountTier(total)
+	if !ok {
+		return 1, i18n.T("details.max_tier")
+	}
+
+	from := 0.0
+	for _, tier := range storage.DiscountTiers {
+		if total >= tier.MinQuantity {
+			from = tier.MinQuantity
+		}
+	}
+	remaining := int(next.MinQuantity - total)
+	text := i18n.T("details.next_tier", map[string]any{"Percentage": next.Percentage, "Quantity": numfmt.FormatInt(remaining)})
+	return (total - from) / (next.MinQuantity - from), text
+}
+
+// fillMonths дополняет продажи по месяцам нулевыми месяцами между первым и последним,
+// чтобы на диаграмме не было пропусков.
+func fillMonths(months []models.MonthlySales) []models.MonthlySales {
+	if len(months) == 0 {
+		return nil
+	}
+	first, err1 := time.Parse("2006-01", months[0].Month)
+	last, err2 := time.Parse("2006-01", months[len(months)-1].Month)
+	if err1 != nil || err2 != nil {
+		return months
+	}
+
+	byMonth := make(map[string]models.MonthlySales, len(months))
+	for _, m := range months {
+		byMonth[m.Month] = m
+	}
+	var filled []models.MonthlySales
+	for d := first; !d.After(last); d = d.AddDate(0, 1, 0) {
+		month := d.Format("2006-01")
+		m, ok := byMonth[month]
+		if !ok {
+			m = models.MonthlySales{Month: month}
+		}
+		filled = append(filled, m)
+	}
+	return filled
+}
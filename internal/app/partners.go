@@ -1,13 +1,18 @@
 package application
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/mailaddr"
 	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/numfmt"
+	"github.com/ttrtcixy/demo/internal/phone"
 	"github.com/ttrtcixy/demo/internal/storage"
 	"log"
 	"strconv"
@@ -20,19 +25,21 @@ type PartnerTable struct {
 	table             *widget.Table
 	addButton         *widget.Button
 	deleteButton      *widget.Button
-}
+	historyButton     *widget.Button
+	ratingButton      *widget.Button
+	auditButton       *widget.Button
+	exportButton      *widget.Button
+	loader            *loader
+	auditLoader       *loader
+	exportLoader      *loader
 
-func (a *App) partnersTable() (*PartnerTable, error) {
-	t := &PartnerTable{}
-	var err error
+	// phonesButton виден, пока у партнеров есть телефоны, которые не удалось нормализовать.
+	phonesButton *widget.Button
+	phones       []storage.PhoneProblem
+}
 
-	t.partners, err = a.db.GetPartners()
-	if err != nil && !errors.Is(err, storage.ErrPartnersNoFound) {
-		return t, err
-	}
-	if t.partners == nil {
-		t.partners = &models.Partners{}
-	}
+func (a *App) partnersTable() *PartnerTable {
+	t := &PartnerTable{partners: &models.Partners{}, loader: newLoader(), auditLoader: newLoader(), exportLoader: newLoader()}
 
 	table := widget.NewTable(
 		func() (int, int) {
@@ -50,21 +57,21 @@ func (a *App) partnersTable() (*PartnerTable, error) {
 				case 0:
 					label.SetText("")
 				case 1:
-					label.SetText("Название Компании")
+					label.SetText(i18n.T("partners.col.name"))
 				case 2:
-					label.SetText("Тип Компании")
+					label.SetText(i18n.T("partners.col.type"))
 				case 3:
-					label.SetText("Директор")
+					label.SetText(i18n.T("partners.col.director"))
 				case 4:
-					label.SetText("Телефон")
+					label.SetText(i18n.T("partners.col.phone"))
 				case 5:
-					label.SetText("Рейтинг")
+					label.SetText(i18n.T("partners.col.rating"))
 				case 6:
-					label.SetText("Почта")
+					label.SetText(i18n.T("partners.col.email"))
 				case 7:
-					label.SetText("Юр. Адрес")
+					label.SetText(i18n.T("partners.col.address"))
 				case 8:
-					label.SetText("Скидка")
+					label.SetText(i18n.T("partners.col.discount"))
 				}
 
 			} else {
@@ -80,9 +87,9 @@ func (a *App) partnersTable() (*PartnerTable, error) {
 					case 3:
 						label.SetText(p.Director)
 					case 4:
-						label.SetText(p.Phone)
+						label.SetText(phone.Format(p.Phone))
 					case 5:
-						label.SetText(fmt.Sprintf("%d", p.Rating))
+						label.SetText(numfmt.FormatInt(p.Rating))
 					case 6:
 						label.SetText(p.Email)
 					case 7:
@@ -110,24 +117,102 @@ func (a *App) partnersTable() (*PartnerTable, error) {
 	t.selectPartnerColumn(a)
 	t.addPartnerButton(a)
 	t.deletePartnerButton(a)
+	t.auditButtons(a)
+	t.phonesButton = widget.NewButton("", func() { a.showUnparsedPhones(t.phones) })
+	t.phonesButton.Importance = widget.WarningImportance
+	t.phonesButton.Hide()
+	showIf(t.addButton, a.can(models.PermEditPartners))
+	showIf(t.deleteButton, a.can(models.PermDeletePartners))
+	showIf(t.ratingButton, a.can(models.PermEditPartners))
+	showIf(t.auditButton, a.can(models.PermExportAudit))
+	a.onDataChanged(func() { t.reload(a) })
+	t.reload(a)
+
+	return t
+}
+
+// reload загружает партнеров в фоне и обновляет таблицу.
+func (t *PartnerTable) reload(a *App) {
+	// undecryptable — партнеры, чьи личные данные не расшифровались: список показывается
+	// без них, а ошибка — поверх таблицы.
+	var undecryptable error
+	var phones []storage.PhoneProblem
+	load(a, t.loader, i18n.T("partners.loading"),
+		func(ctx context.Context) (*models.Partners, error) {
+			// Без списка телефонов таблица все равно нужна, поэтому его ошибка только в журнале.
+			var err error
+			if phones, err = a.partners.UnparsedPhones(ctx); err != nil {
+				log.Println(err)
+			}
+			partners, err := a.partners.GetPartners(ctx)
+			if errors.Is(err, storage.ErrPartnersNoFound) {
+				return &models.Partners{}, nil
+			}
+			if errors.Is(err, storage.ErrUndecryptable) {
+				undecryptable = err
+				return partners, nil
+			}
+			return partners, err
+		},
+		func(partners *models.Partners) {
+			t.partners = partners
+			t.selectedPartnerID = 0
+			t.table.UnselectAll()
+			t.table.Refresh()
+			t.showPhoneProblems(a, phones)
 
-	return t, nil
+			if undecryptable != nil {
+				showError(undecryptable, a.w)
+				log.Println(undecryptable)
+			} else if len(*t.partners) == 0 {
+				dialog.ShowInformation(i18n.T("partners.no_data"), i18n.T("partners.empty"), a.w)
+			}
+		},
+		func(err error) {
+			showError(err, a.w)
+			log.Println(err)
+		},
+	)
+}
+
+// showPhoneProblems обновляет кнопку нераспознанных телефонов. Первый раз после входа список
+// открывается сам, чтобы телефоны, оставленные миграцией как есть, не потерялись.
+func (t *PartnerTable) showPhoneProblems(a *App, phones []storage.PhoneProblem) {
+	t.phones = phones
+	t.phonesButton.SetText(i18n.T("partners.unparsed_phones", map[string]any{"Count": len(phones)}))
+	if len(phones) > 0 {
+		t.phonesButton.Show()
+	} else {
+		t.phonesButton.Hide()
+	}
+	if len(phones) > 0 && !a.phonesReported {
+		a.phonesReported = true
+		a.showUnparsedPhones(phones)
+	}
+}
+
+// showUnparsedPhones показывает партнеров с нераспознанными телефонами. Исправить телефон
+// можно в форме партнера, после сохранения он пропадает из списка.
+func (a *App) showUnparsedPhones(phones []storage.PhoneProblem) {
+	lines := make([]string, len(phones))
+	for i, p := range phones {
+		lines[i] = i18n.T("partners.unparsed_phone", map[string]any{"Name": p.PartnerName, "Id": p.PartnerId, "Phone": p.Phone})
+	}
+	message := widget.NewLabel(i18n.T("partners.unparsed_phones_hint") + "\n\n" + strings.Join(lines, "\n"))
+	message.Wrapping = fyne.TextWrapWord
+	d := dialog.NewCustom(i18n.T("partners.unparsed_phones_title"), i18n.T("details.close"), container.NewVScroll(message), a.w)
+	d.Resize(fyne.NewSize(520, 360))
+	d.Show()
 }
 
 func (t *PartnerTable) addPartnerButton(a *App) {
-	addButton := widget.NewButton("Добавить Партнера", func() {
-		showPartnerForm(a.w, models.Partner{}, func(newPartner models.Partner) {
-			err := a.db.AddPartner(newPartner)
+	addButton := widget.NewButton(i18n.T("partners.add"), func() {
+		a.showPartnerForm(models.Partner{}, func(newPartner models.Partner) {
+			err := a.partners.AddPartner(a.ctx, newPartner)
 			if err != nil {
-				dialog.ShowError(err, a.w)
-				log.Println(err)
+				a.showWriteError(err, a.w)
 			} else {
-				t.partners, err = a.db.GetPartners()
-				if err != nil {
-					dialog.ShowError(err, a.w)
-					log.Println(err)
-				}
-				t.table.Refresh()
+				a.dataChanged()
 			}
 		})
 	})
@@ -135,56 +220,68 @@ func (t *PartnerTable) addPartnerButton(a *App) {
 }
 
 func (t *PartnerTable) deletePartnerButton(a *App) {
-	deleteButton := widget.NewButton("Удалить Партнера", func() {
+	deleteButton := widget.NewButton(i18n.T("partners.delete"), func() {
 		if t.selectedPartnerID != 0 {
-			err := a.db.DeletePartner(t.selectedPartnerID)
+			err := a.partners.DeletePartner(a.ctx, t.selectedPartnerID)
 			if err != nil {
-				dialog.ShowError(err, a.w)
+				showError(err, a.w)
 				log.Println(err)
 			} else {
-				t.partners, err = a.db.GetPartners()
-				if err != nil {
-					dialog.ShowError(err, a.w)
-					log.Println(err)
-				}
-				t.table.Refresh()
-				t.selectedPartnerID = 0
-
-				if len(*t.partners) == 0 {
-					dialog.ShowInformation("Нет данных", "Все партнеры удалены. Добавьте нового партнера.", a.w)
-				}
+				a.dataChanged()
 			}
 		} else {
-			dialog.ShowInformation("Не выбран", "Выберите партнера для удаления", a.w)
+			dialog.ShowInformation(i18n.T("common.not_selected"), i18n.T("partners.select_delete"), a.w)
 		}
 	})
 	t.deleteButton = deleteButton
 }
 
+func (t *PartnerTable) auditButtons(a *App) {
+	t.historyButton = widget.NewButton(i18n.T("partners.history"), func() {
+		if t.selectedPartnerID == 0 {
+			dialog.ShowInformation(i18n.T("common.not_selected"), i18n.T("partners.select"), a.w)
+			return
+		}
+		a.showPartnerHistory(t.selectedPartnerID)
+	})
+	t.ratingButton = widget.NewButton(i18n.T("partners.rating_suggestions"), a.showRatingSuggestions)
+	t.auditButton = widget.NewButton(i18n.T("partners.export_audit"), func() {
+		a.exportAudit(t.auditLoader)
+	})
+	t.exportButton = widget.NewButton(i18n.T("common.export_csv"), func() {
+		t.exportPartners(a)
+	})
+}
+
 func (t *PartnerTable) selectPartnerColumn(a *App) {
 	t.table.OnSelected = func(id widget.TableCellID) {
 		if id.Row > 0 && len(*t.partners) > 0 {
 			t.selectedPartnerID = (*t.partners)[id.Row-1].Id
 			p := (*t.partners)[id.Row-1]
-			if id.Col != 0 {
-				showPartnerForm(a.w, p, func(updatedPartner models.Partner) {
-					err := a.db.UpdatePartner(updatedPartner)
-					if err != nil {
-						dialog.ShowError(err, a.w)
-					} else {
-						t.partners, err = a.db.GetPartners()
+			if id.Col == 0 {
+				return
+			}
+			var onEdit func()
+			if a.can(models.PermEditPartners) {
+				onEdit = func() {
+					a.showPartnerForm(p, func(updatedPartner models.Partner) {
+						err := a.partners.UpdatePartner(a.ctx, updatedPartner)
 						if err != nil {
-							log.Println(err)
+							a.showWriteError(err, a.w)
+						} else {
+							a.dataChanged()
 						}
-						t.table.Refresh()
-					}
-				})
+					})
+				}
 			}
+			a.showPartnerDetails(p, onEdit)
 		}
 	}
 }
 
-func showPartnerForm(w fyne.Window, p models.Partner, onSave func(models.Partner)) {
+// showPartnerForm открывает форму партнера. Под формой предупреждение, если введенные email или телефон
+// уже есть у другого партнера, со ссылкой на его карточку. Сохранению предупреждение не мешает.
+func (a *App) showPartnerForm(p models.Partner, onSave func(models.Partner)) {
 	nameEntry := widget.NewEntry()
 	nameEntry.SetText(p.CompanyName)
 
@@ -197,7 +294,8 @@ func showPartnerForm(w fyne.Window, p models.Partner, onSave func(models.Partner
 	directorEntry.SetText(p.Director)
 
 	phoneEntry := widget.NewEntry()
-	phoneEntry.SetText(p.Phone)
+	phoneEntry.SetText(phone.Format(p.Phone))
+	phoneEntry.SetPlaceHolder("+7 912 888-33-33")
 
 	emailEntry := widget.NewEntry()
 	emailEntry.SetText(p.Email)
@@ -209,72 +307,170 @@ func showPartnerForm(w fyne.Window, p models.Partner, onSave func(models.Partner
 	ratingEntry.SetText(fmt.Sprintf("%d", p.Rating))
 
 	form := widget.NewForm(
-		widget.NewFormItem("Название Компании", nameEntry),
-		widget.NewFormItem("Тип компании", typeEntry),
-		widget.NewFormItem("Директор", directorEntry),
-		widget.NewFormItem("Телефон", phoneEntry),
-		widget.NewFormItem("Email", emailEntry),
-		widget.NewFormItem("Юр. Адрес", addressEntry),
-		widget.NewFormItem("Рейтинг", ratingEntry),
+		widget.NewFormItem(i18n.T("partners.form.name"), nameEntry),
+		widget.NewFormItem(i18n.T("partners.form.type"), typeEntry),
+		widget.NewFormItem(i18n.T("partners.form.director"), directorEntry),
+		widget.NewFormItem(i18n.T("partners.form.phone"), phoneEntry),
+		widget.NewFormItem(i18n.T("partners.form.email"), emailEntry),
+		widget.NewFormItem(i18n.T("partners.form.address"), addressEntry),
+		widget.NewFormItem(i18n.T("partners.form.rating"), ratingEntry),
 	)
 
 	form.SubmitText = ""
 	form.OnSubmit = nil
 
-	dialog.ShowCustomConfirm("Редактировать партнера", "Сохранить", "Отменить", form, func(b bool) {
+	duplicates := container.NewVBox()
+	checkDuplicates := a.contactDuplicatesChecker(p.Id, duplicates)
+	phoneEntry.OnChanged = func(string) { checkDuplicates(emailEntry.Text, phoneEntry.Text) }
+	emailEntry.OnChanged = func(string) { checkDuplicates(emailEntry.Text, phoneEntry.Text) }
+	checkDuplicates(emailEntry.Text, phoneEntry.Text)
+
+	dialog.ShowCustomConfirm(i18n.T("partners.form.title"), i18n.T("common.save"), i18n.T("partners.form.cancel"), container.NewVBox(form, duplicates), func(b bool) {
 		if b {
 			err := validateForm(nameEntry.Text, typeEntry.Selected, directorEntry.Text, phoneEntry.Text, emailEntry.Text, addressEntry.Text, ratingEntry.Text)
 			if err != nil {
-				dialog.ShowError(err, w)
+				showError(err, a.w)
 				return
 			}
-			rating, _ := strconv.Atoi(ratingEntry.Text)
+			rating, _ := numfmt.ParseInt(ratingEntry.Text)
+			phoneNumber, _ := phone.Parse(phoneEntry.Text)
+			email, _ := mailaddr.Parse(emailEntry.Text)
 			p.CompanyName = nameEntry.Text
 			p.PartnerType = typeEntry.Selected
 			p.Director = directorEntry.Text
-			p.Phone = phoneEntry.Text
-			p.Email = emailEntry.Text
+			p.Phone = phoneNumber
+			p.Email = email
 			p.Address = addressEntry.Text
 			p.Rating = rating
 
 			onSave(p)
 		}
-	}, w)
+	}, a.w)
 }
 
-func validateForm(companyName, partnerType, director, phone, email, address, rating string) error {
+func validateForm(companyName, partnerType, director, phoneNumber, email, address, rating string) error {
 	if companyName == "" {
-		return fmt.Errorf("Название компании не может быть пустым")
+		return errors.New(i18n.T("validate.company_empty"))
 	}
 	if partnerType == "" {
-		return fmt.Errorf("Тип компании не может быть пустым")
+		return errors.New(i18n.T("validate.type_empty"))
 	}
 	if director == "" {
-		return fmt.Errorf("Имя директора не может быть пустым")
+		return errors.New(i18n.T("validate.director_empty"))
 	}
-	if phone == "" {
-		return fmt.Errorf("Телефон не может быть пустым")
+	if strings.TrimSpace(phoneNumber) == "" {
+		return errors.New(i18n.T("validate.phone_empty"))
+	}
+	if _, err := phone.Parse(phoneNumber); err != nil {
+		return errors.New(i18n.T("validate.phone_invalid"))
 	}
 	if email == "" {
-		return fmt.Errorf("Email не может быть пустым")
+		return errors.New(i18n.T("validate.email_empty"))
 	}
-	if !strings.Contains(email, "@") {
-		return fmt.Errorf("Email должен содержать символ @")
+	if _, err := mailaddr.Parse(email); err != nil {
+		return errors.New(i18n.T("validate.email_invalid"))
 	}
 	if address == "" {
-		return fmt.Errorf("Юридический адрес не может быть пустым")
+		return errors.New(i18n.T("validate.address_empty"))
 	}
 	if rating == "" {
-		return fmt.Errorf("Рейтинг не может быть пустым")
+		return errors.New(i18n.T("validate.rating_empty"))
 	}
 
-	ratingValue, err := strconv.Atoi(rating)
+	ratingValue, err := numfmt.ParseInt(rating)
 	if err != nil {
-		return fmt.Errorf("Рейтинг должен быть числом")
+		return errors.New(i18n.T("validate.rating_number"))
 	}
-	if ratingValue < 0 {
-		return fmt.Errorf("Рейтинг должен быть положительным числом")
+	if ratingValue < models.MinRating || ratingValue > models.MaxRating {
+		return errors.New(i18n.T("validate.rating_range", map[string]any{"Min": models.MinRating, "Max": models.MaxRating}))
 	}
 
 	return nil
 }
+
+// contactDuplicatesChecker возвращает проверку email и телефона из формы партнера id: в фоне ищет других
+// партнеров с теми же контактами и показывает их в box. Нераспознанные значения не сравниваются —
+// о них скажет проверка формы.
+func (a *App) contactDuplicatesChecker(id int, box *fyne.Container) func(email, phoneNumber string) {
+	l := newLoader()
+	return func(email, phoneNumber string) {
+		candidate := models.Partner{Id: id}
+		candidate.Email, _ = mailaddr.Parse(email)
+		candidate.Phone, _ = phone.Parse(phoneNumber)
+
+		load(a, l, "",
+			func(ctx context.Context) ([]models.ContactDuplicate, error) {
+				return a.partners.FindContactDuplicates(ctx, candidate)
+			},
+			func(found []models.ContactDuplicate) {
+				box.RemoveAll()
+				for _, d := range found {
+					box.Add(contactDuplicateWarning(a, d))
+				}
+			},
+			func(err error) {
+				log.Println(err)
+			},
+		)
+	}
+}
+
+func contactDuplicateWarning(a *App, d models.ContactDuplicate) fyne.CanvasObject {
+	key := "partners.duplicate.phone"
+	switch {
+	case d.SameEmail && d.SamePhone:
+		key = "partners.duplicate.both"
+	case d.SameEmail:
+		key = "partners.duplicate.email"
+	}
+	warning := widget.NewLabel(i18n.T(key))
+	warning.Importance = widget.WarningImportance
+
+	link := widget.NewHyperlink(fmt.Sprintf("%s %s", d.Partner.PartnerType, d.Partner.CompanyName), nil)
+	link.OnTapped = func() { a.showPartnerDetails(d.Partner, nil) }
+	return container.NewHBox(warning, link)
+}
+
+// exportPartners выгружает партнеров из таблицы в CSV вместе с их контактными лицами.
+func (t *PartnerTable) exportPartners(a *App) {
+	partners := *t.partners
+	load(a, t.exportLoader, i18n.T("contacts.loading"), a.contacts.GetAllContacts,
+		func(contacts []models.Contact) {
+			header := []string{
+				i18n.T("partners.col.name"), i18n.T("partners.col.type"), i18n.T("partners.col.director"),
+				i18n.T("partners.col.phone"), i18n.T("partners.col.rating"), i18n.T("partners.col.email"),
+				i18n.T("partners.col.address"), i18n.T("partners.col.discount"), i18n.T("contacts.title"),
+			}
+			exportCSV(a.w, "partners.csv", header, partnerExportRows(partners, contacts))
+		},
+		func(err error) {
+			showError(err, a.w)
+			log.Println(err)
+		},
+	)
+}
+
+// partnerExportRows — строки экспорта партнеров. Контактные лица партнера собраны в последней
+// колонке через «; ».
+func partnerExportRows(partners models.Partners, contacts []models.Contact) [][]string {
+	byPartner := map[int][]string{}
+	for _, c := range contacts {
+		byPartner[c.PartnerId] = append(byPartner[c.PartnerId], contactSummary(c))
+	}
+
+	rows := make([][]string, 0, len(partners))
+	for _, p := range partners {
+		rows = append(rows, []string{
+			p.CompanyName,
+			p.PartnerType,
+			p.Director,
+			phone.Format(p.Phone),
+			strconv.Itoa(p.Rating),
+			p.Email,
+			p.Address,
+			fmt.Sprintf("%d%%", p.Discount),
+			strings.Join(byPartner[p.Id], "; "),
+		})
+	}
+	return rows
+}
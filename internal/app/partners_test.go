@@ -0,0 +1,314 @@
+package application
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/numfmt"
+	"strings"
+	"testing"
+)
+
+func TestValidateForm(t *testing.T) {
+	tests := []struct {
+		name                                                      string
+		companyName, partnerType, director, phone, email, address string
+		rating                                                    string
+		wantErr                                                   string
+	}{
+		{name: "корректная форма", companyName: "Паркет", partnerType: "ООО", director: "Иванов", phone: "912 888 33 33", email: "a@b.ru", address: "Москва", rating: "5"},
+		{name: "рейтинг с разделителем", companyName: "Паркет", partnerType: "ООО", director: "Иванов", phone: "912 888 33 33", email: "a@b.ru", address: "Москва", rating: "1 000", wantErr: "Рейтинг должен быть от 0 до 10"},
+		{name: "пустое название", partnerType: "ООО", director: "Иванов", phone: "912 888 33 33", email: "a@b.ru", address: "Москва", rating: "5", wantErr: "Название компании не может быть пустым"},
+		{name: "пустой тип", companyName: "Паркет", director: "Иванов", phone: "912 888 33 33", email: "a@b.ru", address: "Москва", rating: "5", wantErr: "Тип компании не может быть пустым"},
+		{name: "пустой директор", companyName: "Паркет", partnerType: "ООО", phone: "912 888 33 33", email: "a@b.ru", address: "Москва", rating: "5", wantErr: "Имя директора не может быть пустым"},
+		{name: "пустой телефон", companyName: "Паркет", partnerType: "ООО", director: "Иванов", email: "a@b.ru", address: "Москва", rating: "5", wantErr: "Телефон не может быть пустым"},
+		{name: "пустой email", companyName: "Паркет", partnerType: "ООО", director: "Иванов", phone: "912 888 33 33", address: "Москва", rating: "5", wantErr: "Email не может быть пустым"},
+		{name: "email без @", companyName: "Паркет", partnerType: "ООО", director: "Иванов", phone: "912 888 33 33", email: "ab.ru", address: "Москва", rating: "5", wantErr: "Некорректный email: укажите адрес вида name@example.ru"},
+		{name: "email без домена", companyName: "Паркет", partnerType: "ООО", director: "Иванов", phone: "912 888 33 33", email: "a@b", address: "Москва", rating: "5", wantErr: "Некорректный email: укажите адрес вида name@example.ru"},
+		{name: "email с именем", companyName: "Паркет", partnerType: "ООО", director: "Иванов", phone: "912 888 33 33", email: "Иванов <a@b.ru>", address: "Москва", rating: "5", wantErr: "Некорректный email: укажите адрес вида name@example.ru"},
+		{name: "пустой адрес", companyName: "Паркет", partnerType: "ООО", director: "Иванов", phone: "912 888 33 33", email: "a@b.ru", rating: "5", wantErr: "Юридический адрес не может быть пустым"},
+		{name: "пустой рейтинг", companyName: "Паркет", partnerType: "ООО", director: "Иванов", phone: "912 888 33 33", email: "a@b.ru", address: "Москва", wantErr: "Рейтинг не может быть пустым"},
+		{name: "рейтинг не число", companyName: "Паркет", partnerType: "ООО", director: "Иванов", phone: "912 888 33 33", email: "a@b.ru", address: "Москва", rating: "пять", wantErr: "Рейтинг должен быть числом"},
+		{name: "телефон 8 (xxx)", companyName: "Паркет", partnerType: "ООО", director: "Иванов", phone: "8 (912) 888-33-33", email: "a@b.ru", address: "Москва", rating: "5"},
+		{name: "международный телефон", companyName: "Паркет", partnerType: "ООО", director: "Иванов", phone: "+44 20 7946 0958", email: "a@b.ru", address: "Москва", rating: "5"},
+		{name: "короткий телефон", companyName: "Паркет", partnerType: "ООО", director: "Иванов", phone: "123", email: "a@b.ru", address: "Москва", rating: "5", wantErr: "Некорректный номер телефона: укажите российский (+7 912 888-33-33, 8 912 888-33-33) или международный номер с +"},
+		{name: "телефон с буквами", companyName: "Паркет", partnerType: "ООО", director: "Иванов", phone: "912 ААА 33 33", email: "a@b.ru", address: "Москва", rating: "5", wantErr: "Некорректный номер телефона: укажите российский (+7 912 888-33-33, 8 912 888-33-33) или международный номер с +"},
+		{name: "отрицательный рейтинг", companyName: "Паркет", partnerType: "ООО", director: "Иванов", phone: "912 888 33 33", email: "a@b.ru", address: "Москва", rating: "-1", wantErr: "Рейтинг должен быть от 0 до 10"},
+		{name: "рейтинг выше шкалы", companyName: "Паркет", partnerType: "ООО", director: "Иванов", phone: "912 888 33 33", email: "a@b.ru", address: "Москва", rating: "11", wantErr: "Рейтинг должен быть от 0 до 10"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateForm(tt.companyName, tt.partnerType, tt.director, tt.phone, tt.email, tt.address, tt.rating)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("неожиданная ошибка: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("ошибка %v, ожидалось %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// showPartnersTab загружает таблицу партнеров в тестовое окно.
+func showPartnersTab(t *testing.T, a *App) *PartnerTable {
+	t.Helper()
+
+	pt := a.partnersTable()
+	a.w.SetContent(container.NewBorder(nil, container.NewHBox(pt.addButton, pt.deleteButton), nil, nil, pt.table))
+	waitFor(t, a, "загрузка партнеров", func() bool { return len(*pt.partners) == 5 })
+	return pt
+}
+
+// fillPartnerForm заполняет открытую форму партнера. Поля идут в порядке формы:
+// название, директор, телефон, email, адрес, рейтинг.
+func fillPartnerForm(t *testing.T, a *App, partnerType string, values ...string) {
+	t.Helper()
+
+	form := topDialog(a)
+	if form == nil {
+		t.Fatal("форма партнера не открыта")
+	}
+	entries := find[*widget.Entry](form)
+	if len(entries) != len(values) {
+		t.Fatalf("в форме %d полей, передано %d значений", len(entries), len(values))
+	}
+	for i, v := range values {
+		entries[i].SetText(v)
+	}
+	if partnerType != "" {
+		find[*widget.Select](form)[0].SetSelected(partnerType)
+	}
+}
+
+// openPartnerDetails щелкает по строке row таблицы партнеров и возвращает открывшуюся карточку партнера.
+func openPartnerDetails(t *testing.T, a *App, pt *PartnerTable, row int) fyne.Window {
+	t.Helper()
+
+	pt.table.Select(widget.TableCellID{Row: row, Col: 1})
+	windows := a.app.Driver().AllWindows()
+	details := windows[len(windows)-1]
+	if details == a.w {
+		t.Fatal("карточка партнера не открыта")
+	}
+	return details
+}
+
+// editPartner открывает форму редактирования партнера из строки row через его карточку.
+func editPartner(t *testing.T, a *App, pt *PartnerTable, row int) {
+	t.Helper()
+
+	details := openPartnerDetails(t, a, pt, row)
+	test.Tap(findButton(t, details.Content(), "Редактировать"))
+}
+
+func TestAddPartner(t *testing.T) {
+	a, store := newTestApp(t)
+	pt := showPartnersTab(t, a)
+
+	test.Tap(pt.addButton)
+	fillPartnerForm(t, a, "ООО", "Новый Партнер", "Новиков Н. Н.", "111 222 33 44", "new@ml.ru", "Казань", "3")
+	test.Tap(findButton(t, topDialog(a), "Сохранить"))
+
+	id, name, err := store.FindPartner(a.ctx, "Новый Партнер")
+	if err != nil || name != "Новый Партнер" {
+		t.Fatalf("партнер не добавлен: %d %q %v", id, name, err)
+	}
+}
+
+func TestAddPartnerValidationError(t *testing.T) {
+	a, store := newTestApp(t)
+	pt := showPartnersTab(t, a)
+
+	test.Tap(pt.addButton)
+	fillPartnerForm(t, a, "ООО", "", "Новиков Н. Н.", "111", "new@ml.ru", "Казань", "3")
+	test.Tap(findButton(t, topDialog(a), "Сохранить"))
+
+	waitDialog(t, a, "Название компании не может быть пустым")
+	if _, _, err := store.FindPartner(a.ctx, "Новиков"); err == nil {
+		t.Fatal("партнер с ошибкой в форме сохранен")
+	}
+}
+
+func TestEditPartner(t *testing.T) {
+	a, _ := newTestApp(t)
+	pt := showPartnersTab(t, a)
+
+	editPartner(t, a, pt, 1)
+	fillPartnerForm(t, a, "", "База Строитель 2", "Иванова А. И.", "493 123 45 67", "ivanova@ml.ru", "Юрга", "9")
+	test.Tap(findButton(t, topDialog(a), "Сохранить"))
+
+	waitFor(t, a, "обновление партнера", func() bool {
+		p := (*pt.partners)[0]
+		return p.CompanyName == "База Строитель 2" && p.Rating == 9
+	})
+}
+
+func TestPartnerFormNormalizesContacts(t *testing.T) {
+	a, store := newTestApp(t)
+	pt := showPartnersTab(t, a)
+
+	test.Tap(pt.addButton)
+	fillPartnerForm(t, a, "ООО", "Новый Партнер", "Новиков Н. Н.", "8 (111) 222-33-44", " New@ML.Ru ", "Казань", "3")
+	test.Tap(findButton(t, topDialog(a), "Сохранить"))
+
+	id, _, err := store.FindPartner(a.ctx, "Новый Партнер")
+	if err != nil {
+		t.Fatalf("партнер не добавлен: %v", err)
+	}
+	for _, p := range store.Partners {
+		if p.Id == id && (p.Phone != "+71112223344" || p.Email != "New@ml.ru") {
+			t.Errorf("сохранены телефон %q и email %q", p.Phone, p.Email)
+		}
+	}
+}
+
+func TestPartnerFormDuplicateWarning(t *testing.T) {
+	a, _ := newTestApp(t)
+	pt := showPartnersTab(t, a)
+
+	test.Tap(pt.addButton)
+	fillPartnerForm(t, a, "ООО", "Новый Партнер", "Новиков Н. Н.", "111 222 33 44", "ivanova@ML.RU", "Казань", "3")
+
+	var link *widget.Hyperlink
+	waitFor(t, a, "предупреждение о дубликате", func() bool {
+		links := find[*widget.Hyperlink](topDialog(a))
+		if len(links) == 1 {
+			link = links[0]
+		}
+		return link != nil
+	})
+	if link.Text != "ЗАО База Строитель" {
+		t.Errorf("ссылка на партнера %q", link.Text)
+	}
+	if !strings.Contains(texts(topDialog(a)), "Этот email уже указан у партнера") {
+		t.Errorf("нет текста предупреждения: %s", texts(topDialog(a)))
+	}
+
+	link.OnTapped()
+	details := lastWindow(a)
+	if details == a.w || details.Title() != "Партнер База Строитель" {
+		t.Fatalf("карточка партнера не открыта: %q", details.Title())
+	}
+	if findButton(t, details.Content(), "Редактировать").Visible() {
+		t.Error("из предупреждения карточка должна открываться только для просмотра")
+	}
+
+	entries := find[*widget.Entry](topDialog(a))
+	entries[3].SetText("new@ml.ru")
+	waitFor(t, a, "предупреждение скрыто", func() bool { return len(find[*widget.Hyperlink](topDialog(a))) == 0 })
+}
+
+func TestEditPartnerNoSelfDuplicate(t *testing.T) {
+	a, _ := newTestApp(t)
+	pt := showPartnersTab(t, a)
+
+	editPartner(t, a, pt, 1)
+	email := find[*widget.Entry](topDialog(a))[3]
+	email.SetText("petrov@vl.ru")
+	waitFor(t, a, "предупреждение о дубликате", func() bool { return len(find[*widget.Hyperlink](topDialog(a))) == 1 })
+	email.SetText("ivanova@ml.ru")
+	waitFor(t, a, "собственный email не дубликат", func() bool { return len(find[*widget.Hyperlink](topDialog(a))) == 0 })
+}
+
+func TestDeletePartner(t *testing.T) {
+	a, _ := newTestApp(t)
+	pt := showPartnersTab(t, a)
+
+	test.Tap(pt.deleteButton)
+	waitDialog(t, a, "Выберите партнера для удаления")
+	dismissDialogs(a)
+
+	pt.table.Select(widget.TableCellID{Row: 1, Col: 0})
+	test.Tap(pt.deleteButton)
+
+	waitFor(t, a, "удаление партнера", func() bool { return len(*pt.partners) == 4 })
+	for _, p := range *pt.partners {
+		if p.Id == 300 {
+			t.Fatal("партнер 300 не удален")
+		}
+	}
+}
+
+func TestPartnerHistory(t *testing.T) {
+	a, _ := newTestApp(t)
+	pt := showPartnersTab(t, a)
+
+	editPartner(t, a, pt, 1)
+	fillPartnerForm(t, a, "", "База Строитель", "Иванова А. И.", "493 123 45 67", "ivanova@ml.ru", "Юрга", "9")
+	test.Tap(findButton(t, topDialog(a), "Сохранить"))
+	waitFor(t, a, "обновление партнера", func() bool { return (*pt.partners)[0].Rating == 9 })
+
+	pt.table.Select(widget.TableCellID{Row: 1, Col: 0})
+	test.Tap(pt.historyButton)
+
+	windows := a.app.Driver().AllWindows()
+	history := windows[len(windows)-1]
+	table := find[*widget.Table](history.Content())[0]
+	waitFor(t, a, "загрузка истории", func() bool {
+		rows, _ := table.Length()
+		return rows == 2
+	})
+}
+
+func TestPartnerDetails(t *testing.T) {
+	a, _ := newTestApp(t)
+	pt := showPartnersTab(t, a)
+
+	details := openPartnerDetails(t, a, pt, 2)
+	chart := find[*barChart](details.Content())[0]
+	waitFor(t, a, "загрузка продаж партнера", func() bool { return len(chart.values) > 0 })
+
+	// Продажи партнера 301 — январь 2023 и февраль 2024, между ними пустые месяцы.
+	if len(chart.labels) != 14 || chart.labels[0] != "2023-01" || chart.labels[13] != "2024-02" {
+		t.Errorf("месяцы диаграммы: %v", chart.labels)
+	}
+	if chart.values[0] != 4000 || chart.values[1] != 0 || chart.values[13] != 6000 {
+		t.Errorf("значения диаграммы: %v", chart.values)
+	}
+	if text := texts(details.Content()); !strings.Contains(text, "До скидки 10%: "+numfmt.FormatInt(40000)+" шт.") {
+		t.Errorf("нет прогресса до следующей скидки:\n%s", text)
+	}
+}
+
+func TestDiscountTierProgress(t *testing.T) {
+	// Подписи рассчитаны на русский язык, который выставляет newTestApp.
+	newTestApp(t)
+
+	progress, text := discountTierProgress(30000)
+	if progress != 0.5 || text != "До скидки 10%: "+numfmt.FormatInt(20000)+" шт." {
+		t.Errorf("discountTierProgress(30000) = %v, %q", progress, text)
+	}
+	progress, text = discountTierProgress(300000)
+	if progress != 1 || text != "Достигнута максимальная скидка" {
+		t.Errorf("discountTierProgress(300000) = %v, %q", progress, text)
+	}
+}
+
+func TestUnparsedPhones(t *testing.T) {
+	a, store := newTestApp(t)
+	store.Partners[3].Phone = "звонить секретарю"
+
+	pt := showPartnersTab(t, a)
+	waitDialog(t, a, "Ремонт и отделка (ID: 303): звонить секретарю")
+	dismissDialogs(a)
+	if !pt.phonesButton.Visible() || pt.phonesButton.Text != "Нераспознанные телефоны: 1" {
+		t.Errorf("кнопка телефонов: %q, видна %v", pt.phonesButton.Text, pt.phonesButton.Visible())
+	}
+
+	// После входа список показывается один раз, дальше — по кнопке.
+	partner := store.Partners[3]
+	partner.Phone = "+74442223311"
+	if err := store.UpdatePartner(a.ctx, partner); err != nil {
+		t.Fatal(err)
+	}
+	a.dataChanged()
+	waitFor(t, a, "скрытие кнопки телефонов", func() bool { return !pt.phonesButton.Visible() })
+	if d := topDialog(a); d != nil {
+		t.Errorf("список телефонов показан повторно: %q", texts(d))
+	}
+}
@@ -0,0 +1,342 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/numfmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+type PlanEditor struct {
+	plan        models.ProductionPlan
+	selectedRow int
+	table       *widget.Table
+	planSelect  *widget.Select
+	nameEntry   *widget.Entry
+	summary     *widget.Label
+	loader      *loader
+}
+
+func (a *App) createProductionPlanTab() fyne.CanvasObject {
+	e := &PlanEditor{selectedRow: -1, loader: newLoader()}
+
+	e.nameEntry = widget.NewEntry()
+	e.nameEntry.SetPlaceHolder(i18n.T("plan.name_placeholder"))
+
+	e.summary = widget.NewLabel("")
+	e.summary.TextStyle.Bold = true
+
+	e.planSelect = widget.NewSelect(nil, func(s string) {
+		if s == "" {
+			return
+		}
+		id, _ := splitSelected(s)
+		load(a, e.loader, i18n.T("plan.loading"),
+			func(ctx context.Context) (*models.ProductionPlan, error) {
+				return a.plans.GetPlan(ctx, id)
+			},
+			func(plan *models.ProductionPlan) {
+				e.setPlan(*plan)
+			},
+			func(err error) {
+				showError(err, a.w)
+			},
+		)
+	})
+	e.planSelect.PlaceHolder = i18n.T("plan.saved_plans")
+
+	e.table = widget.NewTable(
+		func() (int, int) {
+			return len(e.plan.Rows) + 1, 5
+		},
+		func() fyne.CanvasObject {
+			return container.NewHScroll(widget.NewLabel("template"))
+		},
+		func(i widget.TableCellID, o fyne.CanvasObject) {
+			scrollContainer := o.(*container.Scroll)
+			label := scrollContainer.Content.(*widget.Label)
+			if i.Row == 0 {
+				switch i.Col {
+				case 0:
+					label.SetText(i18n.T("history.col.product"))
+				case 1:
+					label.SetText(i18n.T("history.col.material"))
+				case 2:
+					label.SetText(i18n.T("history.col.quantity"))
+				case 3:
+					label.SetText(i18n.T("history.col.param1"))
+				case 4:
+					label.SetText(i18n.T("history.col.param2"))
+				}
+				return
+			}
+
+			if i.Row-1 < len(e.plan.Rows) {
+				row := e.plan.Rows[i.Row-1]
+				switch i.Col {
+				case 0:
+					label.SetText(row.ProductName)
+				case 1:
+					label.SetText(row.MaterialType)
+				case 2:
+					label.SetText(numfmt.FormatInt(row.Quantity))
+				case 3:
+					label.SetText(numfmt.FormatFloat(row.Param1, -1))
+				case 4:
+					label.SetText(numfmt.FormatFloat(row.Param2, -1))
+				}
+			}
+		},
+	)
+	e.table.SetColumnWidth(0, 300)
+	e.table.SetColumnWidth(1, 150)
+	e.table.SetColumnWidth(2, 100)
+	e.table.SetColumnWidth(3, 100)
+	e.table.SetColumnWidth(4, 100)
+	e.table.OnSelected = func(id widget.TableCellID) {
+		if id.Row > 0 {
+			e.selectedRow = id.Row - 1
+		}
+	}
+
+	productSelect := widget.NewSelect(nil, nil)
+	materialSelect := widget.NewSelect(nil, nil)
+	quantityEntry := widget.NewEntry()
+	var params *paramInputs
+	paramBox := container.NewGridWithColumns(len(defaultParams()))
+
+	quantityEntry.SetPlaceHolder(i18n.T("material.quantity"))
+	quantityEntry.Validator = positiveIntValidator
+
+	setParams := func(definition []models.ProductParameter) {
+		params = newParamInputs(definition)
+		paramBox.Objects = paramBox.Objects[:0]
+		for i, entry := range params.entries {
+			entry.SetPlaceHolder(paramPlaceHolder(params.params[i]))
+			paramBox.Add(entry)
+		}
+		paramBox.Layout = layout.NewGridLayoutWithColumns(len(params.entries))
+		paramBox.Refresh()
+	}
+	setParams(nil)
+
+	// Справочники и параметры загружаются отдельно, чтобы их не отменяла работа с планами.
+	catalogLoader := newLoader()
+	a.loadCatalog(catalogLoader, func(options catalogOptions) {
+		productSelect.SetOptions(options.products)
+		materialSelect.SetOptions(options.materials)
+		e.reloadPlans(a)
+	}, func(err error) {
+		e.summary.SetText(i18n.Message(err))
+	})
+
+	productSelect.OnChanged = func(s string) {
+		productId, _ := splitSelected(s)
+		load(a, catalogLoader, i18n.T("params.loading"),
+			func(ctx context.Context) ([]models.ProductParameter, error) {
+				return a.catalog.GetProductParameters(ctx, strconv.Itoa(productId))
+			},
+			setParams,
+			func(err error) {
+				log.Println(err)
+				setParams(nil)
+			},
+		)
+	}
+
+	addRowBtn := widget.NewButton(i18n.T("plan.add_row"), func() {
+		if productSelect.Selected == "" || materialSelect.Selected == "" {
+			dialog.ShowInformation(i18n.T("common.error"), i18n.T("material.select_product_material"), a.w)
+			return
+		}
+
+		quantity, err := numfmt.ParseInt(quantityEntry.Text)
+		if err != nil || quantity <= 0 {
+			dialog.ShowInformation(i18n.T("common.error"), i18n.T("material.invalid_quantity"), a.w)
+			return
+		}
+
+		param1, param2, err := params.values()
+		if err != nil {
+			dialog.ShowInformation(i18n.T("common.error"), i18n.Message(err), a.w)
+			return
+		}
+
+		productId, productName := splitSelected(productSelect.Selected)
+		materialId, materialName := splitSelected(materialSelect.Selected)
+
+		e.plan.Rows = append(e.plan.Rows, models.ProductionPlanRow{
+			ProductId:      productId,
+			ProductName:    productName,
+			MaterialTypeId: materialId,
+			MaterialType:   materialName,
+			Quantity:       quantity,
+			Param1:         param1,
+			Param2:         param2,
+		})
+		e.summary.SetText("")
+		e.table.Refresh()
+	})
+
+	deleteRowBtn := widget.NewButton(i18n.T("plan.delete_row"), func() {
+		if e.selectedRow < 0 || e.selectedRow >= len(e.plan.Rows) {
+			dialog.ShowInformation(i18n.T("plan.not_selected_row"), i18n.T("plan.select_row"), a.w)
+			return
+		}
+		e.plan.Rows = append(e.plan.Rows[:e.selectedRow], e.plan.Rows[e.selectedRow+1:]...)
+		e.selectedRow = -1
+		e.table.UnselectAll()
+		e.summary.SetText("")
+		e.table.Refresh()
+	})
+
+	calculateBtn := widget.NewButton(i18n.T("material.calculate"), func() {
+		if len(e.plan.Rows) == 0 {
+			e.summary.SetText(i18n.T("plan.empty"))
+			return
+		}
+
+		plan := e.plan
+		plan.Rows = append([]models.ProductionPlanRow(nil), e.plan.Rows...)
+		load(a, e.loader, i18n.T("plan.calculating"),
+			func(ctx context.Context) ([]models.MaterialRequirement, error) {
+				return a.materials.CalculatePlan(ctx, plan)
+			},
+			func(requirements []models.MaterialRequirement) {
+				lines := make([]string, 0, len(requirements)+1)
+				lines = append(lines, i18n.T("plan.required"))
+				for _, r := range requirements {
+					lines = append(lines, i18n.T("plan.required_line", map[string]any{"Material": r.MaterialType, "Quantity": numfmt.FormatInt(r.Quantity)}))
+				}
+				e.summary.SetText(strings.Join(lines, "\n"))
+			},
+			func(err error) {
+				e.summary.SetText(i18n.T("material.calc_error") + ": " + i18n.Message(err))
+			},
+		)
+	})
+	calculateBtn.Importance = widget.HighImportance
+
+	newBtn := widget.NewButton(i18n.T("plan.new"), func() {
+		e.setPlan(models.ProductionPlan{})
+		e.planSelect.ClearSelected()
+	})
+
+	saveBtn := widget.NewButton(i18n.T("plan.save"), func() {
+		e.plan.Name = strings.TrimSpace(e.nameEntry.Text)
+		if e.plan.Name == "" {
+			dialog.ShowInformation(i18n.T("common.error"), i18n.T("plan.enter_name"), a.w)
+			return
+		}
+
+		id, err := a.plans.SavePlan(a.ctx, e.plan)
+		if err != nil {
+			showError(err, a.w)
+			log.Println(err)
+			return
+		}
+		e.plan.Id = id
+		e.reloadPlans(a)
+		dialog.ShowInformation(i18n.T("common.saved"), i18n.T("plan.saved"), a.w)
+	})
+
+	deletePlanBtn := widget.NewButton(i18n.T("plan.delete"), func() {
+		if e.plan.Id == 0 {
+			dialog.ShowInformation(i18n.T("common.not_selected"), i18n.T("plan.select_saved"), a.w)
+			return
+		}
+
+		dialog.ShowConfirm(i18n.T("plan.delete_title"), i18n.T("plan.delete_confirm", map[string]any{"Name": e.plan.Name}), func(b bool) {
+			if !b {
+				return
+			}
+			if err := a.plans.DeletePlan(a.ctx, e.plan.Id); err != nil {
+				showError(err, a.w)
+				log.Println(err)
+				return
+			}
+			e.setPlan(models.ProductionPlan{})
+			e.planSelect.ClearSelected()
+			e.reloadPlans(a)
+		}, a.w)
+	})
+
+	showIf(saveBtn, a.can(models.PermEditPlans))
+	showIf(deletePlanBtn, a.can(models.PermEditPlans))
+
+	planBox := container.NewBorder(
+		nil, nil,
+		e.planSelect,
+		container.NewHBox(newBtn, saveBtn, deletePlanBtn),
+		e.nameEntry,
+	)
+
+	rowForm := container.NewGridWithColumns(5,
+		productSelect, materialSelect, quantityEntry, paramBox, addRowBtn,
+	)
+
+	topPanel := container.NewVBox(
+		widget.NewLabel(i18n.T("plan.title")),
+		catalogLoader.view(),
+		e.loader.view(),
+		planBox,
+		widget.NewSeparator(),
+		rowForm,
+	)
+
+	bottomPanel := container.NewVBox(
+		container.NewHBox(deleteRowBtn, calculateBtn),
+		widget.NewSeparator(),
+		e.summary,
+	)
+
+	return container.NewBorder(
+		topPanel,
+		bottomPanel,
+		nil, nil,
+		e.table,
+	)
+}
+
+func (e *PlanEditor) setPlan(plan models.ProductionPlan) {
+	e.plan = plan
+	e.selectedRow = -1
+	e.nameEntry.SetText(plan.Name)
+	e.summary.SetText("")
+	e.table.UnselectAll()
+	e.table.Refresh()
+}
+
+func (e *PlanEditor) reloadPlans(a *App) {
+	load(a, e.loader, i18n.T("plan.loading_list"), a.plans.GetPlans,
+		func(plans []models.ProductionPlan) {
+			options := make([]string, 0, len(plans))
+			for _, p := range plans {
+				options = append(options, fmt.Sprintf("%d - %s", p.Id, p.Name))
+			}
+			e.planSelect.SetOptions(options)
+		},
+		func(err error) {
+			log.Println(err)
+		},
+	)
+}
+
+// splitSelected разбирает значение выпадающего списка вида "id - название".
+func splitSelected(s string) (int, string) {
+	parts := strings.SplitN(s, " - ", 2)
+	id, _ := strconv.Atoi(parts[0])
+	if len(parts) < 2 {
+		return id, ""
+	}
+	return id, parts[1]
+}
@@ -0,0 +1,68 @@
+package application
+
+import (
+	"fmt"
+	"fyne.io/fyne/v2"
+	"github.com/ttrtcixy/demo/internal/app/theme"
+	"github.com/ttrtcixy/demo/internal/i18n"
+)
+
+// Ключи настроек Fyne с оформлением интерфейса.
+const (
+	themePreference     = "theme"
+	fontScalePreference = "font_scale"
+)
+
+func loadTheme(prefs fyne.Preferences) fyne.Theme {
+	mode := theme.ParseMode(prefs.String(themePreference))
+	scale := prefs.FloatWithFallback(fontScalePreference, 1)
+	return theme.NewTheme(mode, float32(scale))
+}
+
+func (a *App) mainMenu() *fyne.MainMenu {
+	menus := []*fyne.Menu{a.viewMenu(), a.languageMenu()}
+	if database := a.databaseMenu(); len(database.Items) > 0 {
+		menus = append(menus, database)
+	}
+	return fyne.NewMainMenu(menus...)
+}
+
+// viewMenu — выбор палитры и масштаба шрифта. Изменения применяются сразу, без перезапуска.
+func (a *App) viewMenu() *fyne.Menu {
+	prefs := a.app.Preferences()
+	current := theme.ParseMode(prefs.String(themePreference))
+	scale := float32(prefs.FloatWithFallback(fontScalePreference, 1))
+
+	modes := make([]*fyne.MenuItem, 0, len(theme.Modes))
+	for _, mode := range theme.Modes {
+		item := fyne.NewMenuItem(i18n.T("theme."+string(mode)), func() {
+			prefs.SetString(themePreference, string(mode))
+			a.applyTheme()
+		})
+		item.Checked = mode == current
+		modes = append(modes, item)
+	}
+
+	scales := make([]*fyne.MenuItem, 0, len(theme.Scales))
+	for _, s := range theme.Scales {
+		item := fyne.NewMenuItem(fmt.Sprintf("%d%%", int(s*100)), func() {
+			prefs.SetFloat(fontScalePreference, float64(s))
+			a.applyTheme()
+		})
+		item.Checked = s == scale
+		scales = append(scales, item)
+	}
+
+	themeItem := fyne.NewMenuItem(i18n.T("menu.theme"), nil)
+	themeItem.ChildMenu = fyne.NewMenu("", modes...)
+	fontItem := fyne.NewMenuItem(i18n.T("menu.font_size"), nil)
+	fontItem.ChildMenu = fyne.NewMenu("", scales...)
+	return fyne.NewMenu(i18n.T("menu.view"), themeItem, fontItem)
+}
+
+// applyTheme перечитывает оформление из настроек и применяет его ко всем окнам.
+func (a *App) applyTheme() {
+	a.theme = loadTheme(a.app.Preferences())
+	a.app.Settings().SetTheme(a.theme)
+	a.w.SetMainMenu(a.mainMenu())
+}
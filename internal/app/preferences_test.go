@@ -0,0 +1,34 @@
+package application
+
+import (
+	fynetheme "fyne.io/fyne/v2/theme"
+	"github.com/ttrtcixy/demo/internal/app/theme"
+	"testing"
+)
+
+func TestApplyTheme(t *testing.T) {
+	a, _ := newTestApp(t)
+	light := a.theme.Color(fynetheme.ColorNameBackground, fynetheme.VariantLight)
+
+	a.app.Preferences().SetString(themePreference, string(theme.ModeDark))
+	a.app.Preferences().SetFloat(fontScalePreference, 1.25)
+	a.applyTheme()
+
+	th := a.app.Settings().Theme()
+	if th != a.theme {
+		t.Fatal("тема приложения не заменена")
+	}
+	if got := th.Color(fynetheme.ColorNameBackground, fynetheme.VariantLight); got == light {
+		t.Errorf("темная палитра не применена: фон %v", got)
+	}
+	if got, want := th.Size(fynetheme.SizeNameText), fynetheme.DefaultTheme().Size(fynetheme.SizeNameText)*1.25; got != want {
+		t.Errorf("размер текста %v, ожидалось %v", got, want)
+	}
+
+	menu := a.viewMenu()
+	for _, item := range menu.Items[0].ChildMenu.Items {
+		if item.Checked != (item.Label == "Темная") {
+			t.Errorf("пункт %q отмечен: %v", item.Label, item.Checked)
+		}
+	}
+}
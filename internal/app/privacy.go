@@ -0,0 +1,69 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/models"
+	"log"
+)
+
+// personalDataButtons — кнопки карточки партнера для запросов субъекта персональных данных:
+// выгрузка всех данных в JSON и обезличивание. Видны только администратору.
+func (a *App) personalDataButtons(p models.Partner, w fyne.Window, l *loader) (exportBtn, anonymizeBtn *widget.Button) {
+	exportBtn = widget.NewButton(i18n.T("privacy.export"), func() {
+		load(a, l, i18n.T("privacy.exporting"),
+			func(ctx context.Context) (models.PartnerData, error) {
+				return a.privacy.ExportPartnerData(ctx, p.Id)
+			},
+			func(data models.PartnerData) {
+				exportJSON(w, fmt.Sprintf("partner_%d_data.json", p.Id), data)
+			},
+			func(err error) {
+				showError(err, w)
+				log.Println(err)
+			},
+		)
+	})
+
+	anonymizeBtn = widget.NewButton(i18n.T("privacy.anonymize"), func() {
+		// Заметки и файлы не обезличиваются, поэтому их можно только удалить вместе с личными данными.
+		deleteNotes := widget.NewCheck(i18n.T("privacy.delete_notes"), nil)
+		message := widget.NewLabel(i18n.T("privacy.anonymize_confirm", map[string]any{"Name": p.CompanyName}) + "\n\n" + a.backupsKeepNote())
+		message.Wrapping = fyne.TextWrapWord
+		d := dialog.NewCustomConfirm(i18n.T("privacy.anonymize_title"), i18n.T("privacy.anonymize"), i18n.T("common.cancel"),
+			container.NewVBox(message, deleteNotes), func(ok bool) {
+				if !ok {
+					return
+				}
+				if err := a.privacy.AnonymizePartner(a.ctx, p.Id, deleteNotes.Checked); err != nil {
+					a.showWriteError(err, w)
+					return
+				}
+				// Карточка показывает прежние данные, поэтому закрывается.
+				w.Close()
+				a.dataChanged()
+				dialog.ShowInformation(i18n.T("privacy.anonymize_title"), i18n.T("privacy.anonymized", map[string]any{"Name": p.CompanyName}), a.w)
+			}, w)
+		d.Resize(fyne.NewSize(520, 0))
+		d.Show()
+	})
+	anonymizeBtn.Importance = widget.DangerImportance
+
+	allowed := a.privacy != nil && a.can(models.PermManagePersonalData)
+	showIf(exportBtn, allowed)
+	showIf(anonymizeBtn, allowed)
+	return exportBtn, anonymizeBtn
+}
+
+// backupsKeepNote предупреждает, что резервные копии хранят прежние личные данные, пока не устареют.
+func (a *App) backupsKeepNote() string {
+	if a.backups == nil || a.backups.MaxAge() <= 0 {
+		return i18n.T("privacy.backups_keep")
+	}
+	return i18n.T("privacy.backups_keep_days", map[string]any{"Days": int(a.backups.MaxAge().Hours() / 24)})
+}
@@ -0,0 +1,68 @@
+package application
+
+import (
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/storage"
+	"testing"
+)
+
+func TestPersonalDataButtonsHidden(t *testing.T) {
+	a, _ := newTestAppAs(t, models.RoleManager)
+	pt := showPartnersTab(t, a)
+
+	w := openPartnerDetails(t, a, pt, 2)
+	for _, label := range []string{"Выгрузить личные данные", "Обезличить"} {
+		if findButton(t, w.Content(), label).Visible() {
+			t.Errorf("кнопка %q видна менеджеру", label)
+		}
+	}
+}
+
+func TestAnonymizePartner(t *testing.T) {
+	a, store := newTestApp(t)
+	pt := showPartnersTab(t, a)
+	reloaded := 0
+	a.onDataChanged(func() { reloaded++ })
+
+	w := openPartnerDetails(t, a, pt, 2)
+	test.Tap(findButton(t, w.Content(), "Обезличить"))
+	confirm(t, w)
+	waitDialog(t, a, "Личные данные партнера «Паркет 29» обезличены")
+	if reloaded != 1 {
+		t.Errorf("вкладки обновлены %d раз", reloaded)
+	}
+
+	contacts, err := store.GetContacts(a.ctx, 301)
+	if err != nil || len(contacts) != 2 {
+		t.Fatalf("GetContacts = %+v, %v", contacts, err)
+	}
+	for _, c := range contacts {
+		if c.Name != storage.Pseudonym(c.Id) || c.Email != "" {
+			t.Errorf("контакт не обезличен: %+v", c)
+		}
+	}
+}
+
+func TestAnonymizePartnerDeletesNotes(t *testing.T) {
+	a, store := newTestApp(t)
+	pt := showPartnersTab(t, a)
+	if _, err := store.AddNote(a.ctx, 301, "Звонить Иванову"); err != nil {
+		t.Fatal(err)
+	}
+
+	w := openPartnerDetails(t, a, pt, 2)
+	test.Tap(findButton(t, w.Content(), "Обезличить"))
+	checks := find[*widget.Check](w.Canvas().Overlays().Top())
+	if len(checks) != 1 {
+		t.Fatalf("флажков в диалоге: %d", len(checks))
+	}
+	test.Tap(checks[0])
+	confirm(t, w)
+	waitDialog(t, a, "Личные данные партнера «Паркет 29» обезличены")
+
+	if notes, err := store.GetNotes(a.ctx, 301); err != nil || len(notes) != 0 {
+		t.Errorf("GetNotes = %+v, %v", notes, err)
+	}
+}
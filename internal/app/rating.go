@@ -0,0 +1,187 @@
+package application
+
+import (
+	"context"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/numfmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+func ratingReasonName(reason string) string {
+	return i18n.T("rating.reason." + reason)
+}
+
+// showRatingHistory открывает окно с изменениями рейтинга партнера.
+func (a *App) showRatingHistory(p models.Partner) {
+	w := a.app.NewWindow(i18n.T("rating.history_title", map[string]any{"Name": p.CompanyName}))
+	header := []string{i18n.T("col.date"), i18n.T("audit.col.user"), i18n.T("rating.col.change"), i18n.T("rating.col.reason")}
+
+	var history []models.RatingChange
+	table := widget.NewTable(
+		func() (int, int) {
+			return len(history) + 1, len(header)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("template")
+		},
+		func(i widget.TableCellID, o fyne.CanvasObject) {
+			label := o.(*widget.Label)
+			switch {
+			case i.Row == 0:
+				label.SetText(header[i.Col])
+			case i.Row-1 >= len(history):
+				label.SetText("")
+			default:
+				c := history[i.Row-1]
+				label.SetText([]string{
+					c.CreatedAt,
+					c.User,
+					strconv.Itoa(c.OldRating) + " → " + strconv.Itoa(c.NewRating),
+					ratingReasonName(c.Reason),
+				}[i.Col])
+			}
+		},
+	)
+	table.SetColumnWidth(0, 160)
+	table.SetColumnWidth(1, 120)
+	table.SetColumnWidth(2, 100)
+	table.SetColumnWidth(3, 260)
+
+	loader := newLoader()
+	load(a, loader, i18n.T("rating.loading_history"),
+		func(ctx context.Context) ([]models.RatingChange, error) {
+			return a.ratings.GetRatingHistory(ctx, p.Id)
+		},
+		func(changes []models.RatingChange) {
+			history = changes
+			table.Refresh()
+		},
+		func(err error) {
+			showError(err, w)
+			log.Println(err)
+		},
+	)
+
+	w.SetContent(container.NewBorder(loader.view(), nil, nil, nil, table))
+	w.Resize(fyne.NewSize(700, 400))
+	w.Show()
+}
+
+// showRatingSuggestions открывает окно с предложениями изменить рейтинг. Выбранное предложение
+// можно принять или отклонить; отклоненное больше не показывается.
+func (a *App) showRatingSuggestions() {
+	w := a.app.NewWindow(i18n.T("rating.suggestions_title"))
+	header := []string{
+		i18n.T("dashboard.col.partner"), i18n.T("rating.col.current"), i18n.T("rating.col.suggested"),
+		i18n.T("rating.col.reason"), i18n.T("sales.col.quantity"), i18n.T("rating.col.last_sale"),
+	}
+
+	var suggestions []models.RatingSuggestion
+	selected := -1
+	table := widget.NewTable(
+		func() (int, int) {
+			return len(suggestions) + 1, len(header)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("template")
+		},
+		func(i widget.TableCellID, o fyne.CanvasObject) {
+			label := o.(*widget.Label)
+			switch {
+			case i.Row == 0:
+				label.SetText(header[i.Col])
+			case i.Row-1 >= len(suggestions):
+				label.SetText("")
+			default:
+				label.SetText(ratingSuggestionRow(suggestions[i.Row-1])[i.Col])
+			}
+		},
+	)
+	table.SetColumnWidth(0, 200)
+	table.SetColumnWidth(1, 80)
+	table.SetColumnWidth(2, 100)
+	table.SetColumnWidth(3, 260)
+	table.SetColumnWidth(4, 100)
+	table.SetColumnWidth(5, 120)
+
+	emptyLabel := widget.NewLabel(i18n.T("rating.no_suggestions"))
+	emptyLabel.Hide()
+
+	loader := newLoader()
+	reload := func() {
+		load(a, loader, i18n.T("rating.loading_suggestions"),
+			func(ctx context.Context) ([]models.RatingSuggestion, error) {
+				return a.ratings.GetRatingSuggestions(ctx, time.Now())
+			},
+			func(result []models.RatingSuggestion) {
+				suggestions = result
+				selected = -1
+				table.UnselectAll()
+				table.Refresh()
+				if len(suggestions) == 0 {
+					emptyLabel.Show()
+				} else {
+					emptyLabel.Hide()
+				}
+			},
+			func(err error) {
+				showError(err, w)
+				log.Println(err)
+			},
+		)
+	}
+	table.OnSelected = func(id widget.TableCellID) {
+		if id.Row > 0 && id.Row-1 < len(suggestions) {
+			selected = id.Row - 1
+		}
+	}
+
+	decide := func(apply func(ctx context.Context, s models.RatingSuggestion) error) func() {
+		return func() {
+			if selected < 0 {
+				dialog.ShowInformation(i18n.T("common.not_selected"), i18n.T("rating.select"), w)
+				return
+			}
+			if err := apply(a.ctx, suggestions[selected]); err != nil {
+				a.showWriteError(err, w)
+			}
+			reload()
+			a.dataChanged()
+		}
+	}
+	acceptBtn := widget.NewButton(i18n.T("rating.accept"), decide(a.ratings.AcceptRatingSuggestion))
+	acceptBtn.Importance = widget.HighImportance
+	rejectBtn := widget.NewButton(i18n.T("rating.reject"), decide(a.ratings.RejectRatingSuggestion))
+
+	reload()
+	w.SetContent(container.NewBorder(
+		container.NewVBox(loader.view(), emptyLabel),
+		container.NewHBox(acceptBtn, rejectBtn, widget.NewButton(i18n.T("details.close"), w.Close)),
+		nil, nil,
+		table,
+	))
+	w.Resize(fyne.NewSize(950, 450))
+	w.Show()
+}
+
+func ratingSuggestionRow(s models.RatingSuggestion) []string {
+	lastSale := s.LastSaleDate
+	if lastSale == "" {
+		lastSale = i18n.T("rating.never")
+	}
+	return []string{
+		s.CompanyName,
+		strconv.Itoa(s.CurrentRating),
+		strconv.Itoa(s.SuggestedRating),
+		ratingReasonName(s.Reason),
+		numfmt.FormatInt(s.TotalQuantity),
+		lastSale,
+	}
+}
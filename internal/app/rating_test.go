@@ -0,0 +1,60 @@
+package application
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/models"
+	"strings"
+	"testing"
+)
+
+// lastWindow возвращает последнее открытое окно приложения.
+func lastWindow(a *App) fyne.Window {
+	windows := a.app.Driver().AllWindows()
+	return windows[len(windows)-1]
+}
+
+func TestRatingSuggestions(t *testing.T) {
+	a, store := newTestApp(t)
+	pt := showPartnersTab(t, a)
+
+	test.Tap(pt.ratingButton)
+	w := lastWindow(a)
+	table := find[*widget.Table](w.Content())[0]
+	rows := func() int {
+		n, _ := table.Length()
+		return n - 1
+	}
+	// Продажи фикстуры старше года, поэтому правила предлагают снизить рейтинг почти всем.
+	waitFor(t, a, "расчет предложений", func() bool { return rows() == 4 })
+
+	table.Select(widget.TableCellID{Row: 1, Col: 0})
+	test.Tap(findButton(t, w.Content(), "Принять"))
+	waitFor(t, a, "обновление предложений", func() bool { return rows() == 3 })
+	if p := store.Partners[0]; p.Id != 300 || p.Rating != 0 {
+		t.Errorf("рейтинг после принятия предложения: %+v", p)
+	}
+
+	table.Select(widget.TableCellID{Row: 1, Col: 0})
+	test.Tap(findButton(t, w.Content(), "Отклонить"))
+	waitFor(t, a, "обновление предложений", func() bool { return rows() == 2 })
+	if p := store.Partners[1]; p.Rating != 7 {
+		t.Errorf("отклоненное предложение изменило рейтинг: %+v", p)
+	}
+
+	a.showRatingHistory(models.Partner{Id: 300, CompanyName: "База Строитель"})
+	history := lastWindow(a)
+	waitFor(t, a, "загрузка истории рейтинга", func() bool {
+		return strings.Contains(texts(history.Content()), "Нет продаж больше года")
+	})
+}
+
+func TestViewerCannotSeeRatingSuggestions(t *testing.T) {
+	a, _ := newTestAppAs(t, models.RoleViewer)
+	pt := showPartnersTab(t, a)
+
+	if pt.ratingButton.Visible() {
+		t.Error("наблюдателю доступны предложения рейтинга")
+	}
+}
@@ -1,25 +1,42 @@
 package application
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/numfmt"
+	"github.com/ttrtcixy/demo/internal/storage"
 	"strings"
 )
 
+// partnerSales — результат поиска на вкладке продаж.
+type partnerSales struct {
+	id    int
+	name  string
+	sales []models.PartnerSale
+	// discount — скидка партнера в процентах по общему количеству его продаж.
+	discount int
+}
+
 func (a *App) createSalesTab() fyne.CanvasObject {
 	searchEntry := widget.NewEntry()
-	searchEntry.SetPlaceHolder("Введите ID партнера или часть имени")
+	searchEntry.SetPlaceHolder(i18n.T("sales.search_placeholder"))
 	searchEntry.Resize(fyne.NewSize(400, searchEntry.MinSize().Height))
 
 	resultLabel := widget.NewLabel("")
 	resultLabel.Wrapping = fyne.TextWrapWord
 
+	loader := newLoader()
+
 	table := widget.NewTable(
 		func() (int, int) {
-			return 1, 6 // Начинаем с 1 строки (заголовки)
+			return 1, 7 // Начинаем с 1 строки (заголовки)
 		},
 		func() fyne.CanvasObject {
 			label := widget.NewLabel("")
@@ -33,17 +50,19 @@ func (a *App) createSalesTab() fyne.CanvasObject {
 			if i.Row == 0 {
 				switch i.Col {
 				case 0:
-					label.SetText("Продукция")
+					label.SetText(i18n.T("sales.col.product"))
 				case 1:
-					label.SetText("Количество")
+					label.SetText(i18n.T("sales.col.quantity"))
 				case 2:
-					label.SetText("Дата продажи")
+					label.SetText(i18n.T("sales.col.date"))
 				case 3:
-					label.SetText("Тип продукции")
+					label.SetText(i18n.T("sales.col.product_type"))
 				case 4:
-					label.SetText("Сумма")
+					label.SetText(i18n.T("sales.col.sum"))
 				case 5:
-					label.SetText("Прибыль")
+					label.SetText(i18n.T("sales.col.discounted"))
+				case 6:
+					label.SetText(i18n.T("sales.col.profit"))
 				}
 				label.TextStyle.Bold = true
 			} else {
@@ -58,31 +77,12 @@ func (a *App) createSalesTab() fyne.CanvasObject {
 	table.SetColumnWidth(2, 120)
 	table.SetColumnWidth(3, 150)
 	table.SetColumnWidth(4, 120)
-	table.SetColumnWidth(5, 120)
-
-	searchAndDisplay := func() {
-		searchTerm := strings.TrimSpace(searchEntry.Text)
-		if searchTerm == "" {
-			dialog.ShowInformation("Ошибка", "Введите ID или имя партнера", a.w)
-			return
-		}
-
-		partnerID, partnerName, err := a.db.FindPartner(searchTerm)
-		if err != nil {
-			dialog.ShowInformation("Не найдено", "Партнер не найден", a.w)
-			return
-		}
-
-		resultLabel.SetText(fmt.Sprintf("Продажи партнера: %s (ID: %d)", partnerName, partnerID))
-
-		sales, err := a.db.GetPartnerSales(partnerID)
-		if err != nil {
-			dialog.ShowError(fmt.Errorf("ошибка получения продаж: %v", err), a.w)
-			return
-		}
+	table.SetColumnWidth(5, 140)
+	table.SetColumnWidth(6, 120)
 
+	showSales := func(sales []models.PartnerSale, discount int) {
 		table.Length = func() (int, int) {
-			return len(sales) + 1, 6 // +1 для заголовков
+			return len(sales) + 1, 7 // +1 для заголовков
 		}
 
 		table.UpdateCell = func(i widget.TableCellID, o fyne.CanvasObject) {
@@ -99,35 +99,86 @@ func (a *App) createSalesTab() fyne.CanvasObject {
 				case 0:
 					label.SetText(sale.ProductName)
 				case 1:
-					label.SetText(fmt.Sprintf("%d", sale.Quantity))
+					label.SetText(numfmt.FormatInt(sale.Quantity))
 				case 2:
 					label.SetText(sale.SaleDate)
 				case 3:
 					label.SetText(sale.ProductType)
 				case 4:
-					label.SetText(fmt.Sprintf("%.2f ₽", sale.TotalSum))
+					label.SetText(numfmt.FormatMoney(sale.TotalSum))
 				case 5:
-					profit := sale.TotalSum * 0.2
-					label.SetText(fmt.Sprintf("%.2f ₽", profit))
+					label.SetText(numfmt.FormatMoney(sale.TotalSum.ApplyDiscount(discount)))
+				case 6:
+					profit := sale.TotalSum.Percent(20)
+					label.SetText(numfmt.FormatMoney(profit))
 				}
 			}
 		}
 		table.Refresh()
 	}
 
-	searchBtn := widget.NewButton("Поиск", searchAndDisplay)
+	searchAndDisplay := func() {
+		searchTerm := strings.TrimSpace(searchEntry.Text)
+		if searchTerm == "" {
+			dialog.ShowInformation(i18n.T("common.error"), i18n.T("sales.enter_term"), a.w)
+			return
+		}
+
+		load(a, loader, i18n.T("sales.loading"),
+			func(ctx context.Context) (partnerSales, error) {
+				var result partnerSales
+				var err error
+				result.id, result.name, err = a.partners.FindPartner(ctx, searchTerm)
+				if err != nil {
+					return result, err
+				}
+
+				result.sales, err = a.sales.GetPartnerSales(ctx, result.id)
+				if err != nil {
+					return result, fmt.Errorf("%s: %w", i18n.T("sales.load_error"), err)
+				}
+				// Скидку рассчитывает хранилище, как и в списке партнеров. Партнера без продаж
+				// в списке нет, его скидка — 0%.
+				partners, err := a.partners.GetPartners(ctx)
+				if err != nil && !errors.Is(err, storage.ErrPartnersNoFound) && !errors.Is(err, storage.ErrUndecryptable) {
+					return result, err
+				}
+				for _, p := range *partners {
+					if p.Id == result.id {
+						result.discount = p.Discount
+					}
+				}
+				return result, nil
+			},
+			func(result partnerSales) {
+				resultLabel.SetText(i18n.T("sales.title", map[string]any{"Name": result.name, "Id": result.id}) + "\n" +
+					i18n.T("details.discount", map[string]any{"Percentage": result.discount}))
+				showSales(result.sales, result.discount)
+			},
+			func(err error) {
+				if errors.Is(err, storage.ErrPartnerNotFound) {
+					dialog.ShowInformation(i18n.T("common.not_found"), i18n.T("sales.partner_not_found"), a.w)
+					return
+				}
+				showError(err, a.w)
+			},
+		)
+	}
+
+	searchBtn := widget.NewButton(i18n.T("sales.search"), searchAndDisplay)
 	searchBtn.Importance = widget.HighImportance
 	searchEntry.OnSubmitted = func(_ string) { searchAndDisplay() }
 
 	searchBox := container.NewBorder(
 		nil, nil,
-		widget.NewLabel("Поиск:"),
+		widget.NewLabel(i18n.T("sales.search_label")),
 		searchBtn,
 		searchEntry,
 	)
 
 	topPanel := container.NewVBox(
 		searchBox,
+		loader.view(),
 		resultLabel,
 		widget.NewSeparator(),
 	)
@@ -0,0 +1,72 @@
+package application
+
+import (
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/money"
+	"github.com/ttrtcixy/demo/internal/numfmt"
+	"strings"
+	"testing"
+)
+
+func TestSalesSearch(t *testing.T) {
+	tests := []struct {
+		name     string
+		term     string
+		wantRows int
+		wantText []string
+	}{
+		{name: "по id", term: "301", wantRows: 3, wantText: []string{"Продажи партнера: Паркет 29 (ID: 301)", "Текущая скидка: 5%"}},
+		{name: "по части названия", term: "монтаж", wantRows: 1, wantText: []string{
+			"Продажи партнера: МонтажПро (ID: 304)", "Текущая скидка: 15%",
+			// 300 000 × 2 000 ₽ со скидкой 15%.
+			numfmt.FormatMoney(money.FromRubles(510_000_000)),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := newTestApp(t)
+			tab := a.createSalesTab()
+			a.w.SetContent(tab)
+
+			find[*widget.Entry](tab)[0].SetText(tt.term)
+			test.Tap(findButton(t, tab, "Поиск"))
+
+			table := find[*widget.Table](tab)[0]
+			waitFor(t, a, "загрузка продаж", func() bool {
+				rows, _ := table.Length()
+				return rows == tt.wantRows+1
+			})
+			for _, want := range tt.wantText {
+				if !strings.Contains(texts(tab), want) {
+					t.Errorf("нет текста %q", want)
+				}
+			}
+		})
+	}
+}
+
+func TestSalesSearchErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		term       string
+		wantDialog string
+	}{
+		{name: "пустой запрос", term: " ", wantDialog: "Введите ID или имя партнера"},
+		{name: "партнер не найден", term: "Несуществующий", wantDialog: "Партнер не найден"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := newTestApp(t)
+			tab := a.createSalesTab()
+			a.w.SetContent(tab)
+
+			find[*widget.Entry](tab)[0].SetText(tt.term)
+			test.Tap(findButton(t, tab, "Поиск"))
+
+			waitDialog(t, a, tt.wantDialog)
+		})
+	}
+}
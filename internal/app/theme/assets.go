@@ -0,0 +1,101 @@
+package theme
+
+import (
+	"embed"
+	"errors"
+	"fyne.io/fyne/v2"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"sync"
+)
+
+// Файлы ресурсов. Те же имена ищутся в каталоге брендирования (см. SetBrandDir).
+const (
+	AssetIcon           = "icon.ico"
+	AssetLogo           = "logo.svg"
+	AssetFontRegular    = "fonts/regular.ttf"
+	AssetFontBold       = "fonts/bold.ttf"
+	AssetFontItalic     = "fonts/italic.ttf"
+	AssetFontBoldItalic = "fonts/bolditalic.ttf"
+	AssetFontMonospace  = "fonts/monospace.ttf"
+)
+
+// embedded — ресурсы, собранные в бинарник. Шрифты Go покрывают кириллицу, лицензия в assets/fonts/LICENSE.
+//
+//go:embed assets
+var embedded embed.FS
+
+var (
+	assetsMu  sync.Mutex
+	brandDir  string
+	resources = map[string]fyne.Resource{}
+)
+
+// SetBrandDir задает каталог, файлы которого заменяют встроенные ресурсы с тем же относительным
+// путем, например <dir>/logo.svg или <dir>/fonts/regular.ttf. Пустая строка — только встроенные ресурсы.
+func SetBrandDir(dir string) {
+	assetsMu.Lock()
+	defer assetsMu.Unlock()
+	brandDir = dir
+	resources = map[string]fyne.Resource{}
+}
+
+// Asset возвращает ресурс name: из каталога брендирования, если там есть такой файл, иначе встроенный.
+// Для неизвестного имени возвращает nil.
+func Asset(name string) fyne.Resource {
+	assetsMu.Lock()
+	defer assetsMu.Unlock()
+	if r, ok := resources[name]; ok {
+		return r
+	}
+
+	r := loadAsset(name)
+	resources[name] = r
+	return r
+}
+
+func loadAsset(name string) fyne.Resource {
+	if brandDir != "" {
+		data, err := fs.ReadFile(os.DirFS(brandDir), name)
+		switch {
+		case err == nil:
+			return fyne.NewStaticResource(path.Base(name), data)
+		case !errors.Is(err, fs.ErrNotExist):
+			log.Println(err)
+		}
+	}
+
+	data, err := embedded.ReadFile(path.Join("assets", name))
+	if err != nil {
+		return nil
+	}
+	return fyne.NewStaticResource(path.Base(name), data)
+}
+
+// AppIcon — иконка окна приложения.
+func AppIcon() fyne.Resource {
+	return Asset(AssetIcon)
+}
+
+// Logo — логотип для отчетов и экспортируемых документов.
+func Logo() fyne.Resource {
+	return Asset(AssetLogo)
+}
+
+// fontAsset выбирает файл шрифта по стилю текста.
+func fontAsset(style fyne.TextStyle) string {
+	switch {
+	case style.Monospace:
+		return AssetFontMonospace
+	case style.Bold && style.Italic:
+		return AssetFontBoldItalic
+	case style.Bold:
+		return AssetFontBold
+	case style.Italic:
+		return AssetFontItalic
+	default:
+		return AssetFontRegular
+	}
+}
@@ -6,28 +6,142 @@ import (
 	"image/color"
 )
 
-type CustomTheme struct{}
+// Mode — выбранная пользователем палитра. ModeSystem следует настройке темной темы ОС.
+type Mode string
 
-func NewTheme() fyne.Theme {
-	return &CustomTheme{}
+const (
+	ModeSystem       Mode = "system"
+	ModeLight        Mode = "light"
+	ModeDark         Mode = "dark"
+	ModeHighContrast Mode = "high_contrast"
+)
+
+// Modes — палитры в порядке показа в переключателе.
+var Modes = []Mode{ModeSystem, ModeLight, ModeDark, ModeHighContrast}
+
+// ParseMode возвращает палитру по сохраненному в настройках значению.
+// Неизвестное значение означает ModeSystem.
+func ParseMode(s string) Mode {
+	for _, m := range Modes {
+		if string(m) == s {
+			return m
+		}
+	}
+	return ModeSystem
 }
 
-func (m *CustomTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
-	switch name {
-	case theme.ColorNameBackground:
-		return color.White
-	case theme.ColorNameButton, theme.ColorNameInputBackground:
-		return color.RGBA{R: 0xF4, G: 0xE8, B: 0xD3, A: 0xFF}
-	case theme.ColorNamePrimary, theme.ColorNameFocus:
-		return color.RGBA{R: 0x67, G: 0xBA, B: 0x80, A: 0xFF}
-	case theme.ColorNameForeground:
-		return color.Black
+// Scales — доступные масштабы шрифта.
+var Scales = []float32{0.9, 1, 1.25, 1.5}
+
+// palette задает цвета, которые отличаются от стандартной темы Fyne. Остальные цвета
+// берутся из стандартной темы того же варианта (светлого или темного).
+type palette struct {
+	variant fyne.ThemeVariant
+	colors  map[fyne.ThemeColorName]color.Color
+}
+
+var (
+	brandBeige = color.RGBA{R: 0xF4, G: 0xE8, B: 0xD3, A: 0xFF}
+	brandGreen = color.RGBA{R: 0x67, G: 0xBA, B: 0x80, A: 0xFF}
+)
+
+var lightPalette = palette{
+	variant: theme.VariantLight,
+	colors: map[fyne.ThemeColorName]color.Color{
+		theme.ColorNameBackground:      color.White,
+		theme.ColorNameButton:          brandBeige,
+		theme.ColorNameInputBackground: brandBeige,
+		theme.ColorNamePrimary:         brandGreen,
+		theme.ColorNameFocus:           brandGreen,
+		theme.ColorNameForeground:      color.Black,
+	},
+}
+
+var darkPalette = palette{
+	variant: theme.VariantDark,
+	colors: map[fyne.ThemeColorName]color.Color{
+		theme.ColorNameBackground:        color.RGBA{R: 0x1F, G: 0x1F, B: 0x1F, A: 0xFF},
+		theme.ColorNameButton:            color.RGBA{R: 0x4A, G: 0x42, B: 0x36, A: 0xFF},
+		theme.ColorNameInputBackground:   color.RGBA{R: 0x33, G: 0x2E, B: 0x27, A: 0xFF},
+		theme.ColorNameHeaderBackground:  color.RGBA{R: 0x2A, G: 0x2A, B: 0x2A, A: 0xFF},
+		theme.ColorNameMenuBackground:    color.RGBA{R: 0x2A, G: 0x2A, B: 0x2A, A: 0xFF},
+		theme.ColorNameOverlayBackground: color.RGBA{R: 0x2A, G: 0x2A, B: 0x2A, A: 0xFF},
+		theme.ColorNamePrimary:           brandGreen,
+		theme.ColorNameFocus:             brandGreen,
+		theme.ColorNameForeground:        color.RGBA{R: 0xEE, G: 0xEE, B: 0xEE, A: 0xFF},
+		theme.ColorNamePlaceHolder:       color.RGBA{R: 0xA0, G: 0xA0, B: 0xA0, A: 0xFF},
+	},
+}
+
+// highContrastPalette — белый текст на черном фоне и желтые акценты, контраст не ниже 7:1.
+var highContrastPalette = palette{
+	variant: theme.VariantDark,
+	colors: map[fyne.ThemeColorName]color.Color{
+		theme.ColorNameBackground:          color.Black,
+		theme.ColorNameButton:              color.Black,
+		theme.ColorNameInputBackground:     color.Black,
+		theme.ColorNameHeaderBackground:    color.Black,
+		theme.ColorNameMenuBackground:      color.Black,
+		theme.ColorNameOverlayBackground:   color.Black,
+		theme.ColorNameInputBorder:         color.White,
+		theme.ColorNameSeparator:           color.White,
+		theme.ColorNamePrimary:             color.RGBA{R: 0xFF, G: 0xD7, B: 0x00, A: 0xFF},
+		theme.ColorNameFocus:               color.RGBA{R: 0xFF, G: 0xD7, B: 0x00, A: 0xFF},
+		theme.ColorNameForegroundOnPrimary: color.Black,
+		theme.ColorNameForeground:          color.White,
+		theme.ColorNamePlaceHolder:         color.RGBA{R: 0xD0, G: 0xD0, B: 0xD0, A: 0xFF},
+		theme.ColorNameDisabled:            color.RGBA{R: 0xB0, G: 0xB0, B: 0xB0, A: 0xFF},
+		theme.ColorNameHyperlink:           color.RGBA{R: 0x7F, G: 0xDB, B: 0xFF, A: 0xFF},
+		theme.ColorNameSelection:           color.RGBA{R: 0x00, G: 0x5F, B: 0xAF, A: 0xFF},
+		theme.ColorNameHover:               color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0x40},
+	},
+}
+
+type CustomTheme struct {
+	mode  Mode
+	scale float32
+}
+
+// NewTheme создает тему с палитрой mode и масштабом шрифта scale (1 — стандартный размер).
+func NewTheme(mode Mode, scale float32) fyne.Theme {
+	if scale <= 0 {
+		scale = 1
+	}
+	return &CustomTheme{mode: mode, scale: scale}
+}
+
+func (m *CustomTheme) palette(variant fyne.ThemeVariant) palette {
+	switch m.mode {
+	case ModeLight:
+		return lightPalette
+	case ModeDark:
+		return darkPalette
+	case ModeHighContrast:
+		return highContrastPalette
 	default:
-		return theme.DefaultTheme().Color(name, variant)
+		if variant == theme.VariantDark {
+			return darkPalette
+		}
+		return lightPalette
 	}
 }
 
+func (m *CustomTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
+	p := m.palette(variant)
+	if c, ok := p.colors[name]; ok {
+		return c
+	}
+	return theme.DefaultTheme().Color(name, p.variant)
+}
+
+// Font возвращает встроенные шрифты с кириллицей. Символьный шрифт берется из стандартной темы.
 func (m *CustomTheme) Font(style fyne.TextStyle) fyne.Resource {
+	if style.Symbol {
+		return theme.DefaultTheme().Font(style)
+	}
+	if font := Asset(fontAsset(style)); font != nil {
+		return font
+	}
 	return theme.DefaultTheme().Font(style)
 }
 
@@ -35,6 +149,14 @@ func (m *CustomTheme) Icon(name fyne.ThemeIconName) fyne.Resource {
 	return theme.DefaultTheme().Icon(name)
 }
 
+// Size увеличивает шрифты и встроенные иконки в scale раз, отступы не меняются.
 func (m *CustomTheme) Size(name fyne.ThemeSizeName) float32 {
-	return theme.DefaultTheme().Size(name)
+	size := theme.DefaultTheme().Size(name)
+	switch name {
+	case theme.SizeNameText, theme.SizeNameHeadingText, theme.SizeNameSubHeadingText,
+		theme.SizeNameCaptionText, theme.SizeNameInlineIcon:
+		return size * m.scale
+	default:
+		return size
+	}
 }
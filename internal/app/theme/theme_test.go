@@ -0,0 +1,108 @@
+package theme
+
+import (
+	"bytes"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/theme"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestColorFollowsMode(t *testing.T) {
+	dark := darkPalette.colors[theme.ColorNameBackground]
+	tests := []struct {
+		mode    Mode
+		variant fyne.ThemeVariant
+		want    color.Color
+	}{
+		{ModeSystem, theme.VariantLight, color.White},
+		{ModeSystem, theme.VariantDark, dark},
+		{ModeLight, theme.VariantDark, color.White},
+		{ModeDark, theme.VariantLight, dark},
+		{ModeHighContrast, theme.VariantLight, color.Black},
+	}
+	for _, tt := range tests {
+		got := NewTheme(tt.mode, 1).Color(theme.ColorNameBackground, tt.variant)
+		if got != tt.want {
+			t.Errorf("%s/%d: фон %v, ожидалось %v", tt.mode, tt.variant, got, tt.want)
+		}
+	}
+}
+
+// Цвета, которых нет в палитре, берутся из стандартной темы того же варианта, чтобы
+// темная палитра не смешивалась со светлыми цветами Fyne.
+func TestColorFallbackUsesPaletteVariant(t *testing.T) {
+	test.NewTempApp(t)
+
+	got := NewTheme(ModeDark, 1).Color(theme.ColorNameShadow, theme.VariantLight)
+	want := theme.DefaultTheme().Color(theme.ColorNameShadow, theme.VariantDark)
+	if got != want {
+		t.Errorf("тень %v, ожидалось %v", got, want)
+	}
+}
+
+func TestSizeScale(t *testing.T) {
+	th := NewTheme(ModeSystem, 1.5)
+	if got, want := th.Size(theme.SizeNameText), theme.DefaultTheme().Size(theme.SizeNameText)*1.5; got != want {
+		t.Errorf("размер текста %v, ожидалось %v", got, want)
+	}
+	if got, want := th.Size(theme.SizeNamePadding), theme.DefaultTheme().Size(theme.SizeNamePadding); got != want {
+		t.Errorf("отступ %v, ожидалось %v", got, want)
+	}
+	if got, want := NewTheme(ModeSystem, 0).Size(theme.SizeNameText), theme.DefaultTheme().Size(theme.SizeNameText); got != want {
+		t.Errorf("нулевой масштаб: размер %v, ожидалось %v", got, want)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for _, m := range Modes {
+		if got := ParseMode(string(m)); got != m {
+			t.Errorf("ParseMode(%q) = %q", m, got)
+		}
+	}
+	if got := ParseMode("sepia"); got != ModeSystem {
+		t.Errorf("ParseMode(sepia) = %q", got)
+	}
+}
+
+func TestEmbeddedAssets(t *testing.T) {
+	SetBrandDir("")
+	for _, name := range []string{AssetIcon, AssetLogo, AssetFontRegular, AssetFontBold, AssetFontItalic, AssetFontBoldItalic, AssetFontMonospace} {
+		r := Asset(name)
+		if r == nil || len(r.Content()) == 0 {
+			t.Errorf("ресурс %s не встроен", name)
+		}
+	}
+	if Asset("missing.png") != nil {
+		t.Error("для неизвестного ресурса ожидался nil")
+	}
+
+	th := NewTheme(ModeLight, 1)
+	if got := th.Font(fyne.TextStyle{Bold: true}); got != Asset(AssetFontBold) {
+		t.Errorf("жирный шрифт %s, ожидался %s", got.Name(), AssetFontBold)
+	}
+	if got := th.Font(fyne.TextStyle{Monospace: true, Bold: true}); got != Asset(AssetFontMonospace) {
+		t.Errorf("моноширинный шрифт %s, ожидался %s", got.Name(), AssetFontMonospace)
+	}
+}
+
+func TestBrandDirOverridesAssets(t *testing.T) {
+	dir := t.TempDir()
+	logo := []byte(`<svg xmlns="http://www.w3.org/2000/svg"/>`)
+	if err := os.WriteFile(filepath.Join(dir, AssetLogo), logo, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	SetBrandDir(dir)
+	t.Cleanup(func() { SetBrandDir("") })
+
+	if got := Logo().Content(); !bytes.Equal(got, logo) {
+		t.Errorf("логотип не взят из каталога брендирования: %q", got)
+	}
+	embeddedIcon, _ := embedded.ReadFile("assets/" + AssetIcon)
+	if got := AppIcon().Content(); !bytes.Equal(got, embeddedIcon) {
+		t.Error("иконка без замены должна быть встроенной")
+	}
+}
@@ -0,0 +1,195 @@
+package application
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/storage"
+	"log"
+)
+
+func roleName(role string) string {
+	return i18n.T("role." + role)
+}
+
+// setUser запоминает вошедшего пользователя. Все операции хранилища дальше выполняются от его имени.
+func (a *App) setUser(u models.User) {
+	a.user = u
+	a.ctx = storage.WithUser(a.ctx, u)
+}
+
+func (a *App) can(p models.Permission) bool {
+	return a.user.Can(p)
+}
+
+// showIf скрывает элемент, если у пользователя нет прав на действие.
+func showIf(o fyne.CanvasObject, allowed bool) {
+	if !allowed {
+		o.Hide()
+	}
+}
+
+// login показывает диалог входа и вызывает onLogin после успешной авторизации. Если пользователей
+// еще нет, вместо входа предлагается создать администратора. Отмена закрывает приложение.
+func (a *App) login(onLogin func()) {
+	hasUsers, err := a.users.HasUsers(a.ctx)
+	if err != nil {
+		showError(err, a.w)
+		log.Println(err)
+		return
+	}
+
+	loginEntry := widget.NewEntry()
+	passwordEntry := widget.NewPasswordEntry()
+	items := []*widget.FormItem{
+		widget.NewFormItem(i18n.T("login.login"), loginEntry),
+		widget.NewFormItem(i18n.T("login.password"), passwordEntry),
+	}
+	title, confirm := i18n.T("login.title"), i18n.T("login.submit")
+	if !hasUsers {
+		title, confirm = i18n.T("login.create_admin_title"), i18n.T("login.create")
+	}
+
+	d := dialog.NewForm(title, confirm, i18n.T("login.exit"), items, func(ok bool) {
+		if !ok {
+			a.app.Quit()
+			return
+		}
+
+		if !hasUsers {
+			if err := a.users.CreateUser(a.ctx, loginEntry.Text, passwordEntry.Text, models.RoleAdmin); err != nil {
+				a.loginFailed(err, onLogin)
+				return
+			}
+		}
+		u, err := a.users.Authenticate(a.ctx, loginEntry.Text, passwordEntry.Text)
+		if err != nil {
+			a.loginFailed(err, onLogin)
+			return
+		}
+
+		a.setUser(*u)
+		a.updateTitle()
+		onLogin()
+	}, a.w)
+	d.Resize(fyne.NewSize(400, 200))
+	d.Show()
+}
+
+// loginFailed показывает ошибку входа и снова открывает диалог входа.
+func (a *App) loginFailed(err error, onLogin func()) {
+	log.Println(err)
+	errDialog := dialog.NewError(i18n.Error(err), a.w)
+	errDialog.SetOnClosed(func() { a.login(onLogin) })
+	errDialog.Show()
+}
+
+// createUsersTab — вкладка управления пользователями, доступна только администратору.
+func (a *App) createUsersTab() fyne.CanvasObject {
+	var users []models.User
+	selected := -1
+	header := []string{i18n.T("users.col.login"), i18n.T("users.col.role")}
+
+	table := widget.NewTable(
+		func() (int, int) {
+			return len(users) + 1, len(header)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("template")
+		},
+		func(i widget.TableCellID, o fyne.CanvasObject) {
+			label := o.(*widget.Label)
+			switch {
+			case i.Row == 0:
+				label.SetText(header[i.Col])
+			case i.Row-1 >= len(users):
+				label.SetText("")
+			case i.Col == 0:
+				label.SetText(users[i.Row-1].Login)
+			default:
+				label.SetText(roleName(users[i.Row-1].Role))
+			}
+		},
+	)
+	table.SetColumnWidth(0, 200)
+	table.SetColumnWidth(1, 200)
+	table.OnSelected = func(id widget.TableCellID) {
+		selected = id.Row - 1
+	}
+
+	loader := newLoader()
+	reload := func() {
+		load(a, loader, i18n.T("users.loading"), a.users.GetUsers,
+			func(result []models.User) {
+				users = result
+				selected = -1
+				table.UnselectAll()
+				table.Refresh()
+			},
+			func(err error) {
+				showError(err, a.w)
+				log.Println(err)
+			},
+		)
+	}
+
+	addBtn := widget.NewButton(i18n.T("users.add"), func() {
+		a.showUserForm(reload)
+	})
+	deleteBtn := widget.NewButton(i18n.T("users.delete"), func() {
+		if selected < 0 || selected >= len(users) {
+			dialog.ShowInformation(i18n.T("common.not_selected"), i18n.T("users.select_delete"), a.w)
+			return
+		}
+		u := users[selected]
+		dialog.ShowConfirm(i18n.T("users.delete_title"), i18n.T("users.delete_confirm", map[string]any{"Login": u.Login}), func(ok bool) {
+			if !ok {
+				return
+			}
+			if err := a.users.DeleteUser(a.ctx, u.Id); err != nil {
+				showError(err, a.w)
+				log.Println(err)
+				return
+			}
+			reload()
+		}, a.w)
+	})
+
+	reload()
+	return container.NewBorder(loader.view(), container.NewHBox(addBtn, deleteBtn), nil, nil, table)
+}
+
+func (a *App) showUserForm(onSave func()) {
+	loginEntry := widget.NewEntry()
+	passwordEntry := widget.NewPasswordEntry()
+
+	roles := make([]string, 0, len(models.Roles))
+	for _, r := range models.Roles {
+		roles = append(roles, roleName(r))
+	}
+	roleSelect := widget.NewSelect(roles, nil)
+	roleSelect.SetSelected(roleName(models.RoleViewer))
+
+	items := []*widget.FormItem{
+		widget.NewFormItem(i18n.T("login.login"), loginEntry),
+		widget.NewFormItem(i18n.T("login.password"), passwordEntry),
+		widget.NewFormItem(i18n.T("users.role"), roleSelect),
+	}
+	d := dialog.NewForm(i18n.T("users.new_title"), i18n.T("login.create"), i18n.T("common.cancel"), items, func(ok bool) {
+		if !ok {
+			return
+		}
+		role := models.Roles[roleSelect.SelectedIndex()]
+		if err := a.users.CreateUser(a.ctx, loginEntry.Text, passwordEntry.Text, role); err != nil {
+			showError(err, a.w)
+			log.Println(err)
+			return
+		}
+		onSave()
+	}, a.w)
+	d.Resize(fyne.NewSize(400, 250))
+	d.Show()
+}
@@ -0,0 +1,100 @@
+package application
+
+import (
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+	"github.com/ttrtcixy/demo/internal/models"
+	"testing"
+)
+
+// submitLogin заполняет открытый диалог входа и нажимает кнопку confirm.
+func submitLogin(t *testing.T, a *App, title, confirm, login, password string) {
+	t.Helper()
+
+	d := waitDialog(t, a, title)
+	entries := find[*widget.Entry](d)
+	entries[0].SetText(login)
+	entries[1].SetText(password)
+	test.Tap(findButton(t, d, confirm))
+}
+
+func TestLogin(t *testing.T) {
+	a, store := newTestAppAs(t, "")
+
+	logins := 0
+	onLogin := func() { logins++ }
+
+	a.login(onLogin)
+	submitLogin(t, a, "Создание администратора", "Создать", "admin", "secret1")
+	if logins != 1 || a.user.Login != "admin" || a.user.Role != models.RoleAdmin {
+		t.Fatalf("после создания администратора: входов %d, пользователь %+v", logins, a.user)
+	}
+	if has, _ := store.HasUsers(a.ctx); !has {
+		t.Fatal("администратор не сохранен")
+	}
+
+	a.login(onLogin)
+	submitLogin(t, a, "Вход", "Войти", "admin", "wrong")
+	waitDialog(t, a, "Неверный логин или пароль")
+	if logins != 1 {
+		t.Fatal("вход с неверным паролем")
+	}
+	test.Tap(findButton(t, topDialog(a), "OK"))
+
+	submitLogin(t, a, "Вход", "Войти", "admin", "secret1")
+	if logins != 2 {
+		t.Fatal("повторный вход не выполнен")
+	}
+}
+
+func TestRolePermissions(t *testing.T) {
+	tests := []struct {
+		role                         string
+		canAdd, canDelete, canExport bool
+		canSavePlan, canManageUsers  bool
+	}{
+		{role: models.RoleAdmin, canAdd: true, canDelete: true, canExport: true, canSavePlan: true, canManageUsers: true},
+		{role: models.RoleManager, canAdd: true, canExport: true, canSavePlan: true},
+		{role: models.RoleViewer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			a, _ := newTestAppAs(t, tt.role)
+			tabs := a.InitTabs()
+			a.w.SetContent(tabs)
+
+			partners := tabs.Items[1].Content
+			if got := findButton(t, partners, "Добавить Партнера").Visible(); got != tt.canAdd {
+				t.Errorf("кнопка добавления видна: %v, ожидалось %v", got, tt.canAdd)
+			}
+			if got := findButton(t, partners, "Удалить Партнера").Visible(); got != tt.canDelete {
+				t.Errorf("кнопка удаления видна: %v, ожидалось %v", got, tt.canDelete)
+			}
+			if got := findButton(t, partners, "Экспорт аудита").Visible(); got != tt.canExport {
+				t.Errorf("кнопка экспорта видна: %v, ожидалось %v", got, tt.canExport)
+			}
+			if got := findButton(t, tabs.Items[4].Content, "Сохранить план").Visible(); got != tt.canSavePlan {
+				t.Errorf("кнопка сохранения плана видна: %v, ожидалось %v", got, tt.canSavePlan)
+			}
+			if got := len(tabs.Items) == 6; got != tt.canManageUsers {
+				t.Errorf("вкладка пользователей: %v, ожидалось %v", got, tt.canManageUsers)
+			}
+		})
+	}
+}
+
+func TestViewerCannotEditPartner(t *testing.T) {
+	a, _ := newTestAppAs(t, models.RoleViewer)
+	pt := showPartnersTab(t, a)
+
+	details := openPartnerDetails(t, a, pt, 1)
+	for _, b := range find[*widget.Button](details.Content()) {
+		if b.Text == "Редактировать" && b.Visible() {
+			t.Fatal("наблюдателю доступно редактирование из карточки партнера")
+		}
+	}
+	if pt.selectedPartnerID == 0 {
+		t.Error("партнер не выбран")
+	}
+}
@@ -0,0 +1,229 @@
+// Package backup ведет каталог резервных копий базы: создает копии при запуске и по расписанию,
+// удаляет лишние и восстанавливает базу из выбранной копии.
+package backup
+
+import (
+	"context"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/storage"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Причины создания копии, они же последняя часть имени файла.
+const (
+	ReasonStartup       = "startup"
+	ReasonScheduled     = "scheduled"
+	ReasonManual        = "manual"
+	ReasonBeforeRestore = "before_restore"
+)
+
+// Имя копии: "backup-20261019-104500.000-manual.db". Время в имени позволяет упорядочить
+// копии без чтения файлов, миллисекунды разводят копии, сделанные в одну секунду.
+const (
+	filePrefix = "backup-"
+	fileExt    = ".db"
+	timeLayout = "20060102-150405.000"
+)
+
+// Database — база, которую можно копировать и восстанавливать, не закрывая. *storage.DB
+// реализует ее для SQLite.
+type Database interface {
+	Backup(ctx context.Context, path string) error
+	ValidateBackup(ctx context.Context, path string) (storage.BackupSummary, error)
+	Restore(ctx context.Context, path string) error
+}
+
+type Config struct {
+	// Dir — каталог копий, создается при первой копии.
+	Dir string
+	// Interval — период автоматических копий; 0 — только копия при запуске.
+	Interval time.Duration
+	// Keep — сколько последних копий хранить; 0 — без ограничения.
+	Keep int
+	// MaxAge — копии старше удаляются; 0 — без ограничения. Последняя копия не удаляется никогда.
+	MaxAge time.Duration
+}
+
+// File — резервная копия в каталоге.
+type File struct {
+	Path      string
+	Name      string
+	Reason    string
+	CreatedAt time.Time
+	Size      int64
+}
+
+type Manager struct {
+	db  Database
+	cfg Config
+	now func() time.Time
+
+	// mu не дает автоматической копии совпасть с ручной или с восстановлением.
+	mu sync.Mutex
+}
+
+func NewManager(db Database, cfg Config) *Manager {
+	return &Manager{db: db, cfg: cfg, now: time.Now}
+}
+
+// Dir — каталог копий.
+func (m *Manager) Dir() string {
+	return m.cfg.Dir
+}
+
+// MaxAge — срок хранения копий; 0 — без ограничения.
+func (m *Manager) MaxAge() time.Duration {
+	return m.cfg.MaxAge
+}
+
+// Create делает копию базы и удаляет копии сверх Keep и старше MaxAge.
+func (m *Manager) Create(ctx context.Context, reason string) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := m.create(ctx, reason)
+	if err != nil {
+		return File{}, err
+	}
+	return f, m.prune()
+}
+
+func (m *Manager) create(ctx context.Context, reason string) (File, error) {
+	if err := os.MkdirAll(m.cfg.Dir, 0o755); err != nil {
+		return File{}, err
+	}
+	created := m.now()
+	name := filePrefix + created.Format(timeLayout) + "-" + reason + fileExt
+	path := filepath.Join(m.cfg.Dir, name)
+	if err := m.db.Backup(ctx, path); err != nil {
+		return File{}, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return File{}, err
+	}
+	return File{Path: path, Name: name, Reason: reason, CreatedAt: created, Size: info.Size()}, nil
+}
+
+// List возвращает копии из каталога, новые сначала. Посторонние файлы пропускаются.
+func (m *Manager) List() ([]File, error) {
+	entries, err := os.ReadDir(m.cfg.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var files []File
+	for _, e := range entries {
+		f, ok := parseName(e.Name())
+		if !ok || e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		f.Path = filepath.Join(m.cfg.Dir, f.Name)
+		f.Size = info.Size()
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].CreatedAt.After(files[j].CreatedAt)
+	})
+	return files, nil
+}
+
+func parseName(name string) (File, bool) {
+	rest, ok := strings.CutPrefix(name, filePrefix)
+	if !ok {
+		return File{}, false
+	}
+	rest, ok = strings.CutSuffix(rest, fileExt)
+	if !ok || len(rest) < len(timeLayout)+2 || rest[len(timeLayout)] != '-' {
+		return File{}, false
+	}
+	created, err := time.ParseInLocation(timeLayout, rest[:len(timeLayout)], time.Local)
+	if err != nil {
+		return File{}, false
+	}
+	return File{Name: name, Reason: rest[len(timeLayout)+1:], CreatedAt: created}, true
+}
+
+// prune удаляет копии сверх Keep и старше MaxAge, оставляя самую новую.
+func (m *Manager) prune() error {
+	files, err := m.List()
+	if err != nil {
+		return err
+	}
+	for i, f := range files {
+		if i == 0 {
+			continue
+		}
+		expired := m.cfg.MaxAge > 0 && m.now().Sub(f.CreatedAt) > m.cfg.MaxAge
+		if (m.cfg.Keep > 0 && i >= m.cfg.Keep) || expired {
+			if err := os.Remove(f.Path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Validate проверяет копию path, не меняя базу.
+func (m *Manager) Validate(ctx context.Context, path string) (storage.BackupSummary, error) {
+	return m.db.ValidateBackup(ctx, path)
+}
+
+// Restore заменяет базу копией path. Копия проверяется до замены, а текущая база перед заменой
+// сохраняется отдельной копией, чтобы восстановление можно было отменить. Лишние копии
+// удаляются только после замены: восстанавливаемая копия может оказаться самой старой.
+func (m *Manager) Restore(ctx context.Context, path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := storage.Authorize(ctx, models.PermManageBackups); err != nil {
+		return err
+	}
+	if _, err := m.db.ValidateBackup(ctx, path); err != nil {
+		return err
+	}
+	if _, err := m.create(ctx, ReasonBeforeRestore); err != nil {
+		return err
+	}
+	if err := m.db.Restore(ctx, path); err != nil {
+		return err
+	}
+	return m.prune()
+}
+
+// Run делает копию при запуске, а затем каждые Interval, пока не отменен ctx. Ошибки
+// передаются в onError и не прерывают расписание.
+func (m *Manager) Run(ctx context.Context, onError func(error)) {
+	if _, err := m.Create(ctx, ReasonStartup); err != nil {
+		onError(err)
+	}
+	if m.cfg.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.Create(ctx, ReasonScheduled); err != nil && ctx.Err() == nil {
+				onError(err)
+			}
+		}
+	}
+}
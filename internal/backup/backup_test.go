@@ -0,0 +1,184 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/storage"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeDB пишет в копию фиксированное содержимое и запоминает восстановления.
+type fakeDB struct {
+	restored []string
+}
+
+func (db *fakeDB) Backup(_ context.Context, path string) error {
+	return os.WriteFile(path, []byte("копия"), 0o600)
+}
+
+func (db *fakeDB) ValidateBackup(_ context.Context, path string) (storage.BackupSummary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "копия" {
+		return storage.BackupSummary{}, storage.ErrBackupInvalid(err)
+	}
+	return storage.BackupSummary{Partners: 1}, nil
+}
+
+func (db *fakeDB) Restore(_ context.Context, path string) error {
+	db.restored = append(db.restored, path)
+	return nil
+}
+
+// newTestManager возвращает менеджер, часы которого переводятся вызовом advance.
+func newTestManager(t *testing.T, cfg Config) (*Manager, *fakeDB, func(time.Duration)) {
+	t.Helper()
+
+	db := &fakeDB{}
+	cfg.Dir = t.TempDir()
+	m := NewManager(db, cfg)
+	clock := time.Date(2026, 10, 19, 10, 45, 0, 0, time.Local)
+	m.now = func() time.Time { return clock }
+	return m, db, func(d time.Duration) { clock = clock.Add(d) }
+}
+
+func reasons(t *testing.T, m *Manager) []string {
+	t.Helper()
+
+	files, err := m.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result []string
+	for _, f := range files {
+		result = append(result, f.Reason)
+	}
+	return result
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCreateKeepsLastCopies(t *testing.T) {
+	m, _, advance := newTestManager(t, Config{Keep: 3})
+	for _, reason := range []string{ReasonStartup, ReasonScheduled, ReasonScheduled, ReasonManual} {
+		f, err := m.Create(t.Context(), reason)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Reason != reason || f.Size == 0 {
+			t.Errorf("Create(%s) = %+v", reason, f)
+		}
+		advance(time.Hour)
+	}
+
+	want := []string{ReasonManual, ReasonScheduled, ReasonScheduled}
+	if got := reasons(t, m); !equal(got, want) {
+		t.Errorf("копии %v, ожидалось %v", got, want)
+	}
+}
+
+func TestCreateRemovesExpiredCopies(t *testing.T) {
+	m, _, advance := newTestManager(t, Config{MaxAge: 48 * time.Hour})
+	if _, err := m.Create(t.Context(), ReasonStartup); err != nil {
+		t.Fatal(err)
+	}
+	advance(72 * time.Hour)
+	// Последняя копия остается, даже если она старше MaxAge.
+	if err := m.prune(); err != nil {
+		t.Fatal(err)
+	}
+	if got := reasons(t, m); !equal(got, []string{ReasonStartup}) {
+		t.Errorf("копии %v", got)
+	}
+
+	if _, err := m.Create(t.Context(), ReasonScheduled); err != nil {
+		t.Fatal(err)
+	}
+	if got := reasons(t, m); !equal(got, []string{ReasonScheduled}) {
+		t.Errorf("копии %v, ожидалась только новая", got)
+	}
+}
+
+func TestListSkipsForeignFiles(t *testing.T) {
+	m, _, _ := newTestManager(t, Config{})
+	if _, err := m.Create(t.Context(), ReasonManual); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"test.db", "backup-вчера-manual.db", "backup-20261019-104500.000-manual.db.tmp"} {
+		if err := os.WriteFile(filepath.Join(m.Dir(), name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := m.List()
+	if err != nil || len(files) != 1 {
+		t.Fatalf("List() = %+v, %v", files, err)
+	}
+	want := time.Date(2026, 10, 19, 10, 45, 0, 0, time.Local)
+	if f := files[0]; f.Name != "backup-20261019-104500.000-manual.db" || !f.CreatedAt.Equal(want) || f.Reason != ReasonManual {
+		t.Errorf("List()[0] = %+v", f)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	m, db, advance := newTestManager(t, Config{Keep: 2})
+	admin := storage.WithUser(t.Context(), models.User{Login: "admin", Role: models.RoleAdmin})
+
+	oldest, err := m.Create(t.Context(), ReasonStartup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	advance(time.Hour)
+	if _, err := m.Create(t.Context(), ReasonScheduled); err != nil {
+		t.Fatal(err)
+	}
+	advance(time.Hour)
+
+	manager := storage.WithUser(t.Context(), models.User{Login: "manager", Role: models.RoleManager})
+	if err := m.Restore(manager, oldest.Path); !errors.Is(err, storage.ErrForbidden) {
+		t.Errorf("Restore от менеджера: ошибка %v", err)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.db")
+	if err := os.WriteFile(bad, []byte("мусор"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Restore(admin, bad); err == nil {
+		t.Error("Restore поврежденного файла без ошибки")
+	}
+	if len(db.restored) != 0 || len(reasons(t, m)) != 2 {
+		t.Fatalf("неудачное восстановление изменило базу или копии: %v, %v", db.restored, reasons(t, m))
+	}
+
+	// Восстанавливается самая старая копия: она не должна удалиться до замены базы.
+	if err := m.Restore(admin, oldest.Path); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.restored) != 1 || db.restored[0] != oldest.Path {
+		t.Errorf("восстановлено %v", db.restored)
+	}
+	if got := reasons(t, m); !equal(got, []string{ReasonBeforeRestore, ReasonScheduled}) {
+		t.Errorf("копии после восстановления %v", got)
+	}
+}
+
+func TestRunWithoutInterval(t *testing.T) {
+	m, _, _ := newTestManager(t, Config{})
+	m.Run(t.Context(), func(err error) { t.Error(err) })
+	if got := reasons(t, m); !equal(got, []string{ReasonStartup}) {
+		t.Errorf("копии %v", got)
+	}
+}
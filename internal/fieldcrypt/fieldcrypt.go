@@ -0,0 +1,92 @@
+// Package fieldcrypt шифрует отдельные текстовые поля базы: AES-256-GCM с ключом, полученным
+// из пароля через Argon2id. Зашифрованное значение — строка "enc1:" + base64(nonce || шифротекст),
+// поэтому его можно хранить в тех же текстовых столбцах и отличить от открытого текста.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"golang.org/x/crypto/argon2"
+	"strings"
+)
+
+const prefix = "enc1:"
+
+// Параметры Argon2id: рекомендованный RFC 9106 второй вариант, около 0,1 с на ключ.
+const (
+	saltSize      = 16
+	argonTime     = 3
+	argonMemoryKB = 64 * 1024
+	argonThreads  = 4
+	keySize       = 32
+)
+
+var (
+	ErrDecrypt = errors.New("не удалось расшифровать значение: неверный ключ или данные повреждены")
+	ErrFormat  = errors.New("некорректное зашифрованное значение")
+)
+
+type Key struct {
+	aead cipher.AEAD
+}
+
+// NewSalt возвращает случайную соль для DeriveKey.
+func NewSalt() ([]byte, error) {
+	salt := make([]byte, saltSize)
+	_, err := rand.Read(salt)
+	return salt, err
+}
+
+// DeriveKey получает ключ из пароля и соли. Одинаковые пароль и соль всегда дают тот же ключ.
+func DeriveKey(passphrase string, salt []byte) (*Key, error) {
+	raw := argon2.IDKey([]byte(passphrase), salt, argonTime, argonMemoryKB, argonThreads, keySize)
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{aead: aead}, nil
+}
+
+// IsEncrypted сообщает, что s — значение, зашифрованное Encrypt.
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, prefix)
+}
+
+// Encrypt шифрует s. Пустая строка остается пустой: пустое поле не считается личными данными.
+func (k *Key) Encrypt(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	nonce := make([]byte, k.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := k.aead.Seal(nonce, nonce, []byte(s), nil)
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt расшифровывает значение Encrypt. Незашифрованное значение возвращается как есть:
+// так читаются строки, записанные до включения шифрования.
+func (k *Key) Decrypt(s string) (string, error) {
+	encoded, ok := strings.CutPrefix(s, prefix)
+	if !ok {
+		return s, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < k.aead.NonceSize() {
+		return "", ErrFormat
+	}
+	nonce, ciphertext := sealed[:k.aead.NonceSize()], sealed[k.aead.NonceSize():]
+	plain, err := k.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,63 @@
+package fieldcrypt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	salt, err := NewSalt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := DeriveKey("секретная фраза", salt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, plain := range []string{"Иванов Иван Иванович", "+79128883333", "a@b.ru"} {
+		encrypted, err := key.Encrypt(plain)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsEncrypted(encrypted) || strings.Contains(encrypted, plain) {
+			t.Errorf("Encrypt(%q) = %q", plain, encrypted)
+		}
+		again, _ := key.Encrypt(plain)
+		if again == encrypted {
+			t.Errorf("повторное шифрование %q дало то же значение", plain)
+		}
+		if got, err := key.Decrypt(encrypted); err != nil || got != plain {
+			t.Errorf("Decrypt(Encrypt(%q)) = %q, %v", plain, got, err)
+		}
+	}
+
+	if got, err := key.Encrypt(""); err != nil || got != "" {
+		t.Errorf("Encrypt(\"\") = %q, %v", got, err)
+	}
+	if got, err := key.Decrypt("открытый текст"); err != nil || got != "открытый текст" {
+		t.Errorf("Decrypt открытого текста = %q, %v", got, err)
+	}
+	if _, err := key.Decrypt(prefix + "не base64"); !errors.Is(err, ErrFormat) {
+		t.Errorf("Decrypt мусора: ошибка %v", err)
+	}
+}
+
+func TestDecryptWithOtherKey(t *testing.T) {
+	salt, _ := NewSalt()
+	key, _ := DeriveKey("секретная фраза", salt)
+	same, _ := DeriveKey("секретная фраза", salt)
+	other, _ := DeriveKey("другая фраза", salt)
+
+	encrypted, err := key.Encrypt("Иванов Иван Иванович")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := same.Decrypt(encrypted); err != nil || got != "Иванов Иван Иванович" {
+		t.Errorf("ключ из того же пароля: %q, %v", got, err)
+	}
+	if _, err := other.Decrypt(encrypted); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("чужой ключ: ошибка %v", err)
+	}
+}
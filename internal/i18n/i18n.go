@@ -0,0 +1,129 @@
+// Package i18n переводит тексты интерфейса по каталогам сообщений locales/*.json.
+// Идентификаторы сообщений — ключи вида "partners.add"; если перевода нет, используется
+// русский каталог, а если нет и его — сам ключ.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	goi18n "github.com/nicksnyder/go-i18n/v2/i18n"
+	"golang.org/x/text/language"
+	"log"
+	"strings"
+	"sync"
+)
+
+const (
+	Russian = "ru"
+	English = "en"
+)
+
+// Languages — поддерживаемые языки в порядке показа в переключателе.
+var Languages = []string{Russian, English}
+
+// LanguageNames — названия языков на самих этих языках.
+var LanguageNames = map[string]string{
+	Russian: "Русский",
+	English: "English",
+}
+
+//go:embed locales/*.json
+var locales embed.FS
+
+var (
+	bundle *goi18n.Bundle
+
+	mu        sync.RWMutex
+	current   = Russian
+	localizer *goi18n.Localizer
+)
+
+func init() {
+	bundle = goi18n.NewBundle(language.Russian)
+	bundle.RegisterUnmarshalFunc("json", json.Unmarshal)
+	for _, lang := range Languages {
+		if _, err := bundle.LoadMessageFileFS(locales, "locales/"+lang+".json"); err != nil {
+			log.Println(err)
+		}
+	}
+	localizer = goi18n.NewLocalizer(bundle, Russian)
+}
+
+// ForLanguage подбирает язык интерфейса по языковому тегу вида "ru", "ru-RU" или "en_US".
+// Для неподдерживаемых языков используется русский.
+func ForLanguage(tag string) string {
+	lang, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	lang = strings.ToLower(lang)
+	if _, ok := LanguageNames[lang]; ok {
+		return lang
+	}
+	return Russian
+}
+
+// SetLanguage переключает язык. Уже созданные виджеты нужно пересоздать.
+func SetLanguage(lang string) {
+	lang = ForLanguage(lang)
+
+	mu.Lock()
+	defer mu.Unlock()
+	current = lang
+	localizer = goi18n.NewLocalizer(bundle, lang, Russian)
+}
+
+func Current() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// T возвращает перевод сообщения id. data — необязательные параметры шаблона,
+// например map[string]any{"Name": "Длина"} для "{{.Name}}: ...".
+func T(id string, data ...any) string {
+	mu.RLock()
+	l := localizer
+	mu.RUnlock()
+
+	config := &goi18n.LocalizeConfig{MessageID: id}
+	if len(data) > 0 {
+		config.TemplateData = data[0]
+	}
+	msg, err := l.Localize(config)
+	if err != nil {
+		log.Println(err)
+		return id
+	}
+	return msg
+}
+
+// Coded — ошибка с идентификатором сообщения, которую можно показать пользователю на его языке.
+// Такие ошибки возвращает пакет storage.
+type Coded interface {
+	error
+	MessageID() string
+	MessageData() map[string]any
+}
+
+// Message переводит ошибку. Для ошибок без идентификатора возвращается их текст.
+// Вложенная ошибка Coded тоже переводится, а пояснение, добавленное поверх через
+// fmt.Errorf("...: %w", err), сохраняется как есть.
+func Message(err error) string {
+	var coded Coded
+	if !errors.As(err, &coded) {
+		return err.Error()
+	}
+
+	msg := T(coded.MessageID(), coded.MessageData())
+	if inner := errors.Unwrap(coded); inner != nil {
+		msg += ": " + Message(inner)
+	}
+	if prefix, ok := strings.CutSuffix(err.Error(), coded.Error()); ok {
+		msg = prefix + msg
+	}
+	return msg
+}
+
+// Error возвращает ошибку с переведенным текстом для показа в диалоге.
+func Error(err error) error {
+	return errors.New(Message(err))
+}
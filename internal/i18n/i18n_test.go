@@ -0,0 +1,105 @@
+package i18n_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ttrtcixy/demo/internal/i18n"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/storage"
+	"os"
+	"testing"
+)
+
+func readCatalog(t *testing.T, lang string) map[string]string {
+	t.Helper()
+
+	data, err := os.ReadFile("locales/" + lang + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var catalog map[string]string
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		t.Fatalf("%s.json: %v", lang, err)
+	}
+	return catalog
+}
+
+// Каталоги должны содержать одинаковые ключи, иначе часть интерфейса останется непереведенной.
+func TestCatalogsComplete(t *testing.T) {
+	catalogs := map[string]map[string]string{}
+	for _, lang := range i18n.Languages {
+		catalogs[lang] = readCatalog(t, lang)
+	}
+	for _, lang := range i18n.Languages {
+		for _, other := range i18n.Languages {
+			for key := range catalogs[lang] {
+				if _, ok := catalogs[other][key]; !ok {
+					t.Errorf("ключ %q есть в %s.json, но нет в %s.json", key, lang, other)
+				}
+			}
+		}
+	}
+}
+
+func TestForLanguage(t *testing.T) {
+	tests := map[string]string{
+		"ru":    i18n.Russian,
+		"ru-RU": i18n.Russian,
+		"en_US": i18n.English,
+		"EN":    i18n.English,
+		"de-DE": i18n.Russian,
+		"":      i18n.Russian,
+	}
+	for tag, want := range tests {
+		if got := i18n.ForLanguage(tag); got != want {
+			t.Errorf("ForLanguage(%q) = %q, ожидалось %q", tag, got, want)
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	t.Cleanup(func() { i18n.SetLanguage(i18n.Russian) })
+
+	i18n.SetLanguage(i18n.English)
+	if got := i18n.T("partners.add"); got != "Add partner" {
+		t.Errorf("T(partners.add) = %q", got)
+	}
+	if got := i18n.T("sales.title", map[string]any{"Name": "Паркет 29", "Id": 301}); got != "Partner sales: Паркет 29 (ID: 301)" {
+		t.Errorf("T(sales.title) = %q", got)
+	}
+	if got := i18n.T("no.such.key"); got != "no.such.key" {
+		t.Errorf("T неизвестного ключа = %q", got)
+	}
+
+	i18n.SetLanguage(i18n.Russian)
+	if got := i18n.T("partners.add"); got != "Добавить Партнера" {
+		t.Errorf("T(partners.add) = %q", got)
+	}
+}
+
+func TestMessage(t *testing.T) {
+	t.Cleanup(func() { i18n.SetLanguage(i18n.Russian) })
+	i18n.SetLanguage(i18n.English)
+
+	params := []models.ProductParameter{{Position: 1, Name: "Длина", Unit: "м", MaxValue: new(float64)}}
+	paramErr := storage.CheckParams(params, 1, 1)
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "типизированная", err: storage.ErrForbidden, want: "You do not have permission for this operation"},
+		{name: "с параметрами", err: paramErr, want: "Длина: value must be at most 0 м"},
+		{name: "обернутая", err: fmt.Errorf("Calculation error: %w", storage.ErrPartnerNotFound), want: "Calculation error: Partner not found"},
+		{name: "обычная", err: errors.New("disk full"), want: "disk full"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := i18n.Message(tt.err); got != tt.want {
+				t.Errorf("Message = %q, ожидалось %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+// Package mailaddr разбирает адреса электронной почты по RFC 5322 и приводит их к единому виду.
+package mailaddr
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+)
+
+var ErrInvalid = errors.New("некорректный адрес электронной почты")
+
+// Parse проверяет адрес и возвращает его в нормализованном виде: без пробелов по краям
+// и с доменом в нижнем регистре. Локальная часть не меняется — по RFC 5322 она чувствительна
+// к регистру. Принимается только сам адрес, без имени ("Иванов <a@b.ru>"), а домен должен
+// содержать точку: адреса вида "a@localhost" у партнеров почти всегда опечатка.
+func Parse(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Name != "" || strings.HasPrefix(s, "<") {
+		return "", ErrInvalid
+	}
+
+	// Без имени и угловых скобок s и есть addr-spec; addr.Address терял бы кавычки локальной части.
+	at := strings.LastIndex(s, "@")
+	local, domain := s[:at], strings.ToLower(s[at+1:])
+	if !strings.Contains(domain, ".") || strings.HasPrefix(domain, ".") || strings.HasSuffix(domain, ".") {
+		return "", ErrInvalid
+	}
+	return local + "@" + domain, nil
+}
+
+// Normalize приводит адрес к виду Parse для сравнения. Адрес, который Parse не принимает,
+// возвращается без пробелов по краям: в старых данных встречаются и такие.
+func Normalize(s string) string {
+	if addr, err := Parse(s); err == nil {
+		return addr
+	}
+	return strings.TrimSpace(s)
+}
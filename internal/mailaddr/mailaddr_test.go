@@ -0,0 +1,55 @@
+package mailaddr
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "ivanova@ml.ru", want: "ivanova@ml.ru"},
+		{in: "  Ivanova@ML.Ru ", want: "Ivanova@ml.ru"},
+		{in: "first.last+tag@sub.example.com", want: "first.last+tag@sub.example.com"},
+		{in: `"john doe"@example.com`, want: `"john doe"@example.com`},
+		{in: "", wantErr: true},
+		{in: "ab.ru", wantErr: true},
+		{in: "a@", wantErr: true},
+		{in: "@b.ru", wantErr: true},
+		{in: "a@@b.ru", wantErr: true},
+		{in: "a b@c.ru", wantErr: true},
+		{in: "a@localhost", wantErr: true},
+		{in: "a@b.ru.", wantErr: true},
+		{in: "Иванова <ivanova@ml.ru>", wantErr: true},
+		{in: "<ivanova@ml.ru>", wantErr: true},
+		{in: "a@b.ru, c@d.ru", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q) = %q, ожидалась ошибка", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %q, %v, ожидалось %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{in: " Ivanova@ML.Ru ", want: "Ivanova@ml.ru"},
+		{in: "ivanova@ml.ru", want: "ivanova@ml.ru"},
+		{in: " Ivanova@LOCALHOST ", want: "Ivanova@LOCALHOST"},
+		{in: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, ожидалось %q", tt.in, got, tt.want)
+		}
+	}
+}
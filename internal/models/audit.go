@@ -0,0 +1,34 @@
+package models
+
+// Действия в журнале аудита.
+const (
+	AuditCreate    = "create"
+	AuditUpdate    = "update"
+	AuditDelete    = "delete"
+	AuditAnonymize = "anonymize"
+)
+
+// Сущности, изменения которых попадают в журнал аудита.
+const (
+	EntityPartner     = "partner"
+	EntityContact     = "contact"
+	EntityNote        = "note"
+	EntityAttachment  = "attachment"
+	EntitySale        = "sale"
+	EntityPlan        = "plan"
+	EntityCalculation = "calculation"
+	EntityUser        = "user"
+)
+
+// AuditEntry — запись журнала изменений. Before и After — состояние сущности в JSON
+// до и после изменения; при создании пуст Before, при удалении — After.
+type AuditEntry struct {
+	Id        int
+	Entity    string
+	EntityId  int
+	Action    string
+	User      string
+	Before    string
+	After     string
+	CreatedAt string
+}
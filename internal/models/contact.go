@@ -0,0 +1,23 @@
+package models
+
+// Роли контактных лиц партнера.
+const (
+	ContactBuyer      = "buyer"
+	ContactAccountant = "accountant"
+	ContactLogistics  = "logistics"
+	ContactOther      = "other"
+)
+
+// ContactRoles — роли в порядке показа в форме.
+var ContactRoles = []string{ContactBuyer, ContactAccountant, ContactLogistics, ContactOther}
+
+// Contact — контактное лицо партнера: закупщик, бухгалтер, логист. Телефон хранится в E.164,
+// email — нормализованным; оба необязательны.
+type Contact struct {
+	Id        int
+	PartnerId int
+	Role      string
+	Name      string
+	Phone     string
+	Email     string
+}
@@ -0,0 +1,31 @@
+package models
+
+import "github.com/ttrtcixy/demo/internal/money"
+
+// SalesTotals — итоги продаж по всем партнерам.
+type SalesTotals struct {
+	Revenue  money.Money
+	Quantity int
+	// Partners — число партнеров, у которых есть продажи.
+	Partners int
+}
+
+// PartnerTotal — продажи одного партнера за все время.
+type PartnerTotal struct {
+	PartnerId   int
+	CompanyName string
+	Quantity    int
+	TotalSum    money.Money
+}
+
+// DiscountTierCount — сколько партнеров получают скидку Percentage.
+type DiscountTierCount struct {
+	Percentage int
+	Partners   int
+}
+
+// PartnerTypeCount — сколько партнеров с типом компании PartnerType.
+type PartnerTypeCount struct {
+	PartnerType string
+	Partners    int
+}
@@ -0,0 +1,24 @@
+package models
+
+// MaterialCalculation — подробный результат расчета материала.
+type MaterialCalculation struct {
+	Id             int
+	ProductId      int
+	ProductName    string
+	MaterialTypeId int
+	MaterialType   string
+	Quantity       int
+	Param1         float64
+	Param2         float64
+
+	ProductCoefficient float64
+	DefectPercentage   float64
+
+	MaterialPerUnit float64 // Param1 * Param2 * ProductCoefficient
+	BaseTotal       float64 // Quantity * MaterialPerUnit
+	DefectAllowance float64 // Надбавка на брак
+	Rounding        float64 // Округление до целого вверх
+	Required        int
+
+	CreatedAt string
+}
@@ -0,0 +1,22 @@
+package models
+
+// Note — заметка в ленте партнера: договоренности, звонки, переписка.
+type Note struct {
+	Id        int
+	PartnerId int
+	Text      string
+	User      string
+	CreatedAt string
+}
+
+// Attachment — файл партнера, например договор. Содержимое хранится в базе отдельно от описания,
+// Hash — SHA-256 содержимого в hex, по нему проверяется целостность и ищутся повторные загрузки.
+type Attachment struct {
+	Id        int
+	PartnerId int
+	FileName  string
+	Size      int64
+	Hash      string
+	User      string
+	CreatedAt string
+}
@@ -0,0 +1,12 @@
+package models
+
+// ProductParameter описывает параметр расчета материала для типа продукции.
+// Position — номер параметра в формуле расчета (1 или 2).
+type ProductParameter struct {
+	ProductTypeId int
+	Position      int
+	Name          string
+	Unit          string
+	MinValue      *float64
+	MaxValue      *float64
+}
@@ -16,3 +16,10 @@ type Partner struct {
 }
 
 type Partners []Partner
+
+// ContactDuplicate — другой партнер, у которого тот же email или телефон.
+type ContactDuplicate struct {
+	Partner   Partner
+	SameEmail bool
+	SamePhone bool
+}
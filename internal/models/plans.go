@@ -0,0 +1,25 @@
+package models
+
+type ProductionPlanRow struct {
+	Id             int
+	ProductId      int
+	ProductName    string
+	MaterialTypeId int
+	MaterialType   string
+	Quantity       int
+	Param1         float64
+	Param2         float64
+}
+
+type ProductionPlan struct {
+	Id        int
+	Name      string
+	CreatedAt string
+	Rows      []ProductionPlanRow
+}
+
+type MaterialRequirement struct {
+	MaterialTypeId int
+	MaterialType   string
+	Quantity       int
+}
@@ -0,0 +1,21 @@
+package models
+
+// PartnerData — все данные, связанные с партнером, для ответа на запрос субъекта персональных
+// данных. Выгружается в JSON целиком, содержимое файлов — в base64.
+type PartnerData struct {
+	ExportedAt  string
+	Partner     Partner
+	Contacts    []Contact
+	Notes       []Note
+	Attachments []AttachmentData
+	Sales       []PartnerSale
+	Ratings     []RatingChange
+	// History — изменения партнера, его контактных лиц, заметок и файлов, новые первыми.
+	History []AuditEntry
+}
+
+// AttachmentData — файл партнера вместе с содержимым.
+type AttachmentData struct {
+	Attachment
+	Data []byte
+}
@@ -0,0 +1,37 @@
+package models
+
+// Шкала рейтинга партнера.
+const (
+	MinRating = 0
+	MaxRating = 10
+)
+
+// Причины изменения рейтинга.
+const (
+	RatingReasonManual   = "manual"   // изменен вручную в форме партнера
+	RatingReasonVolume   = "volume"   // принято предложение по объему продаж
+	RatingReasonInactive = "inactive" // принято предложение из-за давней последней продажи
+)
+
+// RatingChange — запись истории рейтинга партнера.
+type RatingChange struct {
+	Id        int
+	PartnerId int
+	OldRating int
+	NewRating int
+	Reason    string
+	User      string
+	CreatedAt string
+}
+
+// RatingSuggestion — изменение рейтинга, предложенное правилами. Менеджер принимает или отклоняет его.
+type RatingSuggestion struct {
+	PartnerId       int
+	CompanyName     string
+	CurrentRating   int
+	SuggestedRating int
+	Reason          string
+	TotalQuantity   int
+	// LastSaleDate — дата последней продажи в формате "ГГГГ-ММ-ДД", пусто, если продаж не было.
+	LastSaleDate string
+}
@@ -1,9 +1,34 @@
 package models
 
+import "github.com/ttrtcixy/demo/internal/money"
+
 type PartnerSale struct {
-	ProductName string  `db:"Продукция"`
-	Quantity    int     `db:"Количество"`
-	SaleDate    string  `db:"Дата продажи"`
-	ProductType string  `db:"Тип продукции"`
-	TotalSum    float64 `db:"Общая сумма"`
+	ProductName string      `db:"Продукция"`
+	Quantity    int         `db:"Количество"`
+	SaleDate    string      `db:"Дата продажи"`
+	ProductType string      `db:"Тип продукции"`
+	TotalSum    money.Money `db:"Общая сумма"`
+}
+
+// Sale — продажа в журнале изменений: снимок строки, удаленной вместе с партнером.
+type Sale struct {
+	Id          int
+	PartnerId   int
+	ProductName string
+	Quantity    int
+	SaleDate    string
+}
+
+// MonthlySales — продажи партнера за месяц. Month в формате "ГГГГ-ММ".
+type MonthlySales struct {
+	Month    string
+	Quantity int
+	TotalSum money.Money
+}
+
+// ProductSales — продажи партнера по одному продукту за все время.
+type ProductSales struct {
+	ProductName string
+	Quantity    int
+	TotalSum    money.Money
 }
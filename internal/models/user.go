@@ -0,0 +1,47 @@
+package models
+
+// Роли пользователей.
+const (
+	RoleAdmin   = "admin"
+	RoleManager = "manager"
+	RoleViewer  = "viewer"
+)
+
+var Roles = []string{RoleAdmin, RoleManager, RoleViewer}
+
+// Permission — действие, доступ к которому зависит от роли.
+type Permission string
+
+const (
+	PermEditPartners       Permission = "edit_partners"
+	PermDeletePartners     Permission = "delete_partners"
+	PermEditPlans          Permission = "edit_plans"
+	PermSaveCalculations   Permission = "save_calculations"
+	PermExportAudit        Permission = "export_audit"
+	PermManageUsers        Permission = "manage_users"
+	PermManageBackups      Permission = "manage_backups"
+	PermManageEncryption   Permission = "manage_encryption"
+	PermManagePersonalData Permission = "manage_personal_data"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleAdmin:   {PermEditPartners, PermDeletePartners, PermEditPlans, PermSaveCalculations, PermExportAudit, PermManageUsers, PermManageBackups, PermManageEncryption, PermManagePersonalData},
+	RoleManager: {PermEditPartners, PermEditPlans, PermSaveCalculations, PermExportAudit},
+	RoleViewer:  {PermSaveCalculations},
+}
+
+type User struct {
+	Id    int
+	Login string
+	Role  string
+}
+
+// Can сообщает, разрешено ли пользователю действие p.
+func (u User) Can(p Permission) bool {
+	for _, allowed := range rolePermissions[u.Role] {
+		if allowed == p {
+			return true
+		}
+	}
+	return false
+}
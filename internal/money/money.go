@@ -0,0 +1,161 @@
+// Package money хранит денежные суммы в целых копейках. Сложение и умножение на количество
+// точные, округление нужно только при взятии процента и при переводе из дробного числа.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money — сумма в копейках.
+type Money int64
+
+var ErrInvalid = errors.New("некорректная денежная сумма")
+
+// FromKopecks возвращает сумму k копеек.
+func FromKopecks(k int64) Money {
+	return Money(k)
+}
+
+// FromRubles возвращает сумму в целых рублях.
+func FromRubles(r int64) Money {
+	return Money(r * 100)
+}
+
+// Parse разбирает сумму в рублях с точкой в качестве разделителя: "1799.33", "-5", "0.5".
+// Доли копейки округляются до ближайшей копейки, половина — от нуля.
+func Parse(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	if negative || strings.HasPrefix(s, "+") {
+		s = s[1:]
+	}
+	intPart, fracPart, _ := strings.Cut(s, ".")
+	if intPart == "" && fracPart == "" || !digits(intPart) || !digits(fracPart) {
+		return 0, ErrInvalid
+	}
+
+	// Рубли и две цифры копеек склеиваются в целое число копеек, третья цифра решает округление.
+	fracPart += "000"
+	kopecks, err := strconv.ParseInt(intPart+fracPart[:2], 10, 64)
+	if err != nil {
+		return 0, ErrInvalid
+	}
+	if fracPart[2] >= '5' {
+		kopecks++
+	}
+	if negative {
+		kopecks = -kopecks
+	}
+	return Money(kopecks), nil
+}
+
+func digits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// FromFloat переводит сумму в рублях из float64, например из столбца REAL. Число берется
+// в кратчайшей десятичной записи, поэтому 19.99 дает ровно 1999 копеек.
+func FromFloat(f float64) (Money, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, ErrInvalid
+	}
+	return Parse(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+func (m Money) Kopecks() int64 {
+	return int64(m)
+}
+
+// Mul возвращает сумму за n единиц по цене m.
+func (m Money) Mul(n int) Money {
+	return m * Money(n)
+}
+
+// Percent возвращает percentage процентов от суммы, округленные до копейки, половина — от нуля.
+func (m Money) Percent(percentage int) Money {
+	product := int64(m) * int64(percentage)
+	result := product / 100
+	if remainder := product % 100; remainder >= 50 {
+		result++
+	} else if remainder <= -50 {
+		result--
+	}
+	return Money(result)
+}
+
+// ApplyDiscount возвращает сумму со скидкой percentage процентов.
+func (m Money) ApplyDiscount(percentage int) Money {
+	return m - m.Percent(percentage)
+}
+
+// String возвращает сумму в рублях без разделителей разрядов: "1799.33", "-0.05".
+func (m Money) String() string {
+	sign := ""
+	kopecks := uint64(m)
+	if m < 0 {
+		sign = "-"
+		kopecks = uint64(-m)
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, kopecks/100, kopecks%100)
+}
+
+// Scan читает сумму в копейках. Количество в продажах хранится в REAL, поэтому произведение
+// приходит дробным числом, а PostgreSQL отдает NUMERIC и DOUBLE PRECISION текстом. Дробное
+// число копеек — ошибка: значит, в запросе сумма считается не в копейках.
+func (m *Money) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*m = 0
+	case int64:
+		*m = Money(v)
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) > 1<<53 {
+			return fmt.Errorf("%w: %v копеек", ErrInvalid, v)
+		}
+		*m = Money(v)
+	case []byte:
+		return m.Scan(string(v))
+	case string:
+		if k, err := strconv.ParseInt(v, 10, 64); err == nil {
+			*m = Money(k)
+			return nil
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("%w: %q", ErrInvalid, v)
+		}
+		return m.Scan(f)
+	default:
+		return fmt.Errorf("%w: тип %T", ErrInvalid, src)
+	}
+	return nil
+}
+
+// Value записывает сумму в базу в копейках.
+func (m Money) Value() (driver.Value, error) {
+	return int64(m), nil
+}
+
+// MarshalJSON записывает сумму числом в рублях: 1799.33, а не 179933.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	v, err := Parse(string(data))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
@@ -0,0 +1,145 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Money
+		wantErr bool
+	}{
+		{in: "1799.33", want: 179933},
+		{in: "4456.9", want: 445690},
+		{in: "2000", want: 200000},
+		{in: "0.5", want: 50},
+		{in: ".05", want: 5},
+		{in: "-12.34", want: -1234},
+		{in: "+1", want: 100},
+		{in: "0.005", want: 1},
+		{in: "0.0049999", want: 0},
+		{in: "-0.005", want: -1},
+		{in: " 10.10 ", want: 1010},
+		{in: "", wantErr: true},
+		{in: ".", wantErr: true},
+		{in: "1,5", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "--1", wantErr: true},
+		{in: "1e3", wantErr: true},
+		{in: "99999999999999999999", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q) = %v, ожидалась ошибка", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %v, %v, ожидалось %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+// TestFromFloat проверяет значения, которые умножение на 100 с отбрасыванием или округлением
+// переводит в копейки с ошибкой.
+func TestFromFloat(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want Money
+	}{
+		{in: 1799.33, want: 179933},
+		{in: 4456.9, want: 445690},
+		{in: 0.1 + 0.2, want: 30},
+		{in: 1.005, want: 101},
+		{in: 19.99, want: 1999},
+		{in: -0.07, want: -7},
+		{in: 1e6, want: 100000000},
+	}
+
+	for _, tt := range tests {
+		if got, err := FromFloat(tt.in); err != nil || got != tt.want {
+			t.Errorf("FromFloat(%v) = %v, %v, ожидалось %v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		m          Money
+		percentage int
+		percent    Money
+		discounted Money
+	}{
+		{m: 179933, percentage: 20, percent: 35987, discounted: 143946},
+		{m: 179933, percentage: 5, percent: 8997, discounted: 170936},
+		{m: 10, percentage: 15, percent: 2, discounted: 8},
+		{m: 1, percentage: 50, percent: 1, discounted: 0},
+		{m: -10, percentage: 15, percent: -2, discounted: -8},
+		{m: 200000, percentage: 0, percent: 0, discounted: 200000},
+	}
+
+	for _, tt := range tests {
+		if got := tt.m.Percent(tt.percentage); got != tt.percent {
+			t.Errorf("%v.Percent(%d) = %v, ожидалось %v", tt.m, tt.percentage, got, tt.percent)
+		}
+		if got := tt.m.ApplyDiscount(tt.percentage); got != tt.discounted {
+			t.Errorf("%v.ApplyDiscount(%d) = %v, ожидалось %v", tt.m, tt.percentage, got, tt.discounted)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := map[Money]string{0: "0.00", 5: "0.05", -5: "-0.05", 179933: "1799.33", 20 * 179933: "35986.60", -123456: "-1234.56"}
+	for m, want := range tests {
+		if got := m.String(); got != want {
+			t.Errorf("Money(%d).String() = %q, ожидалось %q", int64(m), got, want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		src     any
+		want    Money
+		wantErr bool
+	}{
+		{src: int64(179933), want: 179933},
+		{src: float64(1079598000), want: 1079598000},
+		{src: []byte("1079598000"), want: 1079598000},
+		{src: "1.079598e+09", want: 1079598000},
+		{src: nil, want: 0},
+		{src: 1799.33, wantErr: true},
+		{src: "abc", wantErr: true},
+		{src: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		var got Money = 1
+		err := got.Scan(tt.src)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Scan(%v) = %v, ожидалась ошибка", tt.src, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Scan(%v) = %v, %v, ожидалось %v", tt.src, got, err, tt.want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(struct{ Sum Money }{179933})
+	if err != nil || string(data) != `{"Sum":1799.33}` {
+		t.Fatalf("json.Marshal = %s, %v", data, err)
+	}
+	var v struct{ Sum Money }
+	if err := json.Unmarshal(data, &v); err != nil || v.Sum != 179933 {
+		t.Errorf("json.Unmarshal = %+v, %v", v, err)
+	}
+}
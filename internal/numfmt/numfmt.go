@@ -0,0 +1,211 @@
+// Package numfmt разбирает и форматирует числа с учетом десятичного разделителя
+// и разделителя разрядов текущей локали.
+package numfmt
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ttrtcixy/demo/internal/money"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type Locale struct {
+	Decimal  rune
+	Group    rune
+	Currency string
+}
+
+var (
+	Russian = Locale{Decimal: ',', Group: ' ', Currency: "₽"}
+	English = Locale{Decimal: '.', Group: ',', Currency: "₽"}
+)
+
+var current = Russian
+
+// ForLanguage подбирает локаль по языковому тегу вида "ru", "ru-RU" или "en_US".
+func ForLanguage(tag string) Locale {
+	language, _, _ := strings.Cut(strings.ReplaceAll(tag, "_", "-"), "-")
+	switch strings.ToLower(language) {
+	case "en", "zh", "ja", "ko", "he", "th":
+		return English
+	default:
+		return Russian
+	}
+}
+
+// SetLocale задает локаль для функций пакета. Вызывается один раз при запуске приложения.
+func SetLocale(l Locale) {
+	current = l
+}
+
+func Current() Locale {
+	return current
+}
+
+var ErrInvalidNumber = errors.New("некорректное число")
+
+func ParseFloat(s string) (float64, error) {
+	return current.ParseFloat(s)
+}
+
+func ParseInt(s string) (int, error) {
+	return current.ParseInt(s)
+}
+
+func FormatFloat(v float64, prec int) string {
+	return current.FormatFloat(v, prec)
+}
+
+func FormatInt(v int) string {
+	return current.FormatInt(v)
+}
+
+func FormatMoney(v money.Money) string {
+	return current.FormatMoney(v)
+}
+
+// ParseFloat принимает десятичный разделитель локали, а для локалей с запятой — и точку.
+// Разделитель разрядов допускается только между группами по три цифры в целой части.
+func (l Locale) ParseFloat(s string) (float64, error) {
+	normalized, err := l.normalize(s, true)
+	if err != nil {
+		return 0, err
+	}
+
+	v, err := strconv.ParseFloat(normalized, 64)
+	if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0, ErrInvalidNumber
+	}
+	return v, nil
+}
+
+func (l Locale) ParseInt(s string) (int, error) {
+	normalized, err := l.normalize(s, false)
+	if err != nil {
+		return 0, err
+	}
+
+	v, err := strconv.Atoi(normalized)
+	if err != nil {
+		return 0, ErrInvalidNumber
+	}
+	return v, nil
+}
+
+// normalize приводит число к виду, понятному strconv. Разделитель разрядов — разделитель локали,
+// апостроф или пробел — должен стоять между группами по три цифры, и перед первым не больше
+// трех цифр: иначе "2,5" в английской локали превратилось бы в 25.
+func (l Locale) normalize(s string, allowDecimal bool) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", ErrInvalidNumber
+	}
+
+	var b strings.Builder
+	if s[0] == '-' || s[0] == '+' {
+		b.WriteByte(s[0])
+		s = s[1:]
+	}
+
+	// groups — длины групп цифр целой части, separator — разделитель разрядов, если он встретился.
+	groups := []int{0}
+	var separator rune
+	fracDigits, decimalSeen := 0, false
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+			if decimalSeen {
+				fracDigits++
+			} else {
+				groups[len(groups)-1]++
+			}
+		case r == l.Decimal || (r == '.' && l.Decimal != '.' && l.Group != '.'):
+			if !allowDecimal || decimalSeen {
+				return "", ErrInvalidNumber
+			}
+			decimalSeen = true
+			b.WriteRune('.')
+		case r == l.Group || r == '\'' || unicode.IsSpace(r):
+			if decimalSeen || groups[len(groups)-1] == 0 || separator != 0 && r != separator {
+				return "", ErrInvalidNumber
+			}
+			separator = r
+			groups = append(groups, 0)
+		default:
+			return "", ErrInvalidNumber
+		}
+	}
+
+	if groups[0] == 0 && fracDigits == 0 {
+		return "", ErrInvalidNumber
+	}
+	if len(groups) > 1 {
+		if groups[0] > 3 {
+			return "", ErrInvalidNumber
+		}
+		for _, n := range groups[1:] {
+			if n != 3 {
+				return "", ErrInvalidNumber
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// FormatFloat форматирует число с разделителями локали. prec < 0 — минимально необходимое
+// количество знаков после запятой.
+func (l Locale) FormatFloat(v float64, prec int) string {
+	formatted := strconv.FormatFloat(math.Abs(v), 'f', prec, 64)
+	intPart, fracPart, hasFrac := strings.Cut(formatted, ".")
+
+	var b strings.Builder
+	if v < 0 && strings.Trim(formatted, "0.") != "" {
+		b.WriteRune('-')
+	}
+	b.WriteString(l.group(intPart))
+	if hasFrac {
+		b.WriteRune(l.Decimal)
+		b.WriteString(fracPart)
+	}
+	return b.String()
+}
+
+func (l Locale) FormatInt(v int) string {
+	if v < 0 {
+		return "-" + l.group(strconv.FormatUint(uint64(-int64(v)), 10))
+	}
+	return l.group(strconv.Itoa(v))
+}
+
+// FormatMoney форматирует сумму в рублях с копейками. Сумма не проходит через float64,
+// поэтому копейки выводятся точно при любой величине.
+func (l Locale) FormatMoney(v money.Money) string {
+	sign, kopecks := "", uint64(v.Kopecks())
+	if v < 0 {
+		sign, kopecks = "-", uint64(-v.Kopecks())
+	}
+	return fmt.Sprintf("%s%s%c%02d %s", sign, l.group(strconv.FormatUint(kopecks/100, 10)), l.Decimal, kopecks%100, l.Currency)
+}
+
+func (l Locale) group(digits string) string {
+	if len(digits) <= 3 {
+		return digits
+	}
+
+	var b strings.Builder
+	head := len(digits) % 3
+	if head > 0 {
+		b.WriteString(digits[:head])
+	}
+	for i := head; i < len(digits); i += 3 {
+		if b.Len() > 0 {
+			b.WriteRune(l.Group)
+		}
+		b.WriteString(digits[i : i+3])
+	}
+	return b.String()
+}
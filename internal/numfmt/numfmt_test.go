@@ -0,0 +1,139 @@
+package numfmt
+
+import (
+	"errors"
+	"github.com/ttrtcixy/demo/internal/money"
+	"testing"
+)
+
+func TestParseFloat(t *testing.T) {
+	tests := []struct {
+		locale  Locale
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{locale: Russian, in: "2,5", want: 2.5},
+		{locale: Russian, in: "2.5", want: 2.5},
+		{locale: Russian, in: "1 000,5", want: 1000.5},
+		{locale: Russian, in: "1 000 000", want: 1000000},
+		{locale: Russian, in: "-12 345,67", want: -12345.67},
+		{locale: Russian, in: "0,125", want: 0.125},
+		{locale: Russian, in: "1,0000", want: 1},
+		{locale: Russian, in: ",5", want: 0.5},
+		{locale: Russian, in: "1'000", want: 1000},
+		{locale: Russian, in: "1,000", want: 1},
+		{locale: Russian, in: "1,125", want: 1.125},
+		{locale: Russian, in: "12,345", want: 12.345},
+		{locale: Russian, in: "1 2 3", wantErr: true},
+		{locale: Russian, in: "1000 000", wantErr: true},
+		{locale: Russian, in: "1 00", wantErr: true},
+		{locale: Russian, in: "1  000", wantErr: true},
+		{locale: Russian, in: "1 000'000", wantErr: true},
+		{locale: Russian, in: "1 000,000 5", wantErr: true},
+		{locale: Russian, in: "1,5,5", wantErr: true},
+		{locale: English, in: "2.5", want: 2.5},
+		{locale: English, in: "1,000.5", want: 1000.5},
+		{locale: English, in: "1,234,567", want: 1234567},
+		{locale: English, in: "1.000", want: 1},
+		{locale: English, in: "2,5", wantErr: true},
+		{locale: English, in: "12,34", wantErr: true},
+		{locale: English, in: ",000", wantErr: true},
+		{locale: English, in: "1,000,", wantErr: true},
+		{locale: English, in: "1 2 3", wantErr: true},
+		{locale: English, in: "1,5.5", wantErr: true},
+		{locale: English, in: "1.5,000", wantErr: true},
+		{locale: English, in: "", wantErr: true},
+		{locale: English, in: "-", wantErr: true},
+		{locale: English, in: "abc", wantErr: true},
+		{locale: English, in: "1e5", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.locale.ParseFloat(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidNumber) {
+				t.Errorf("%c ParseFloat(%q) = %v, %v, ожидалась ошибка", tt.locale.Decimal, tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%c ParseFloat(%q) = %v, %v, ожидалось %v", tt.locale.Decimal, tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseInt(t *testing.T) {
+	tests := []struct {
+		locale  Locale
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{locale: Russian, in: "15 000", want: 15000},
+		{locale: Russian, in: "+7", want: 7},
+		{locale: Russian, in: "-300", want: -300},
+		{locale: Russian, in: "2,5", wantErr: true},
+		{locale: Russian, in: "1 2 3", wantErr: true},
+		{locale: English, in: "15,000", want: 15000},
+		{locale: English, in: "2,5", wantErr: true},
+		{locale: English, in: "12,34", wantErr: true},
+		{locale: English, in: "1.0", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.locale.ParseInt(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidNumber) {
+				t.Errorf("%c ParseInt(%q) = %v, %v, ожидалась ошибка", tt.locale.Decimal, tt.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%c ParseInt(%q) = %v, %v, ожидалось %v", tt.locale.Decimal, tt.in, got, err, tt.want)
+		}
+	}
+}
+
+// TestFormatParse проверяет, что отформатированное число разбирается обратно в той же локали.
+func TestFormatParse(t *testing.T) {
+	for _, l := range []Locale{Russian, English} {
+		for _, v := range []float64{0, 1.5, -999.25, 1000, 1234567.89} {
+			formatted := l.FormatFloat(v, 2)
+			if got, err := l.ParseFloat(formatted); err != nil || got != v {
+				t.Errorf("%c ParseFloat(FormatFloat(%v)) = %v, %v (%q)", l.Decimal, v, got, err, formatted)
+			}
+		}
+		if got := l.FormatInt(-1234567); got != "-1"+string(l.Group)+"234"+string(l.Group)+"567" {
+			t.Errorf("%c FormatInt(-1234567) = %q", l.Decimal, got)
+		}
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		locale Locale
+		in     int64
+		want   string
+	}{
+		{locale: Russian, in: 179933, want: "1 799,33 ₽"},
+		{locale: Russian, in: -5, want: "-0,05 ₽"},
+		{locale: English, in: 123456789, want: "1,234,567.89 ₽"},
+		{locale: English, in: 0, want: "0.00 ₽"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.locale.FormatMoney(money.FromKopecks(tt.in)); got != tt.want {
+			t.Errorf("%c FormatMoney(%d) = %q, ожидалось %q", tt.locale.Decimal, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestForLanguage(t *testing.T) {
+	tests := map[string]Locale{"ru": Russian, "ru-RU": Russian, "en_US": English, "EN": English, "": Russian, "de": Russian}
+	for tag, want := range tests {
+		if got := ForLanguage(tag); got != want {
+			t.Errorf("ForLanguage(%q) = %+v, ожидалось %+v", tag, got, want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Package phone разбирает телефонные номера в российском и международном формате
+// и приводит их к E.164 ("+79128883333").
+package phone
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalid = errors.New("некорректный номер телефона")
+
+// Длина номера E.164 без "+": не больше 15 цифр. Короче 8 цифр номеров с кодом страны не бывает.
+const (
+	minDigits = 8
+	maxDigits = 15
+)
+
+// Parse приводит номер к E.164. Пробелы, дефисы, точки и скобки пропускаются.
+// Номера без "+" считаются российскими: "8 912 888-33-33", "7 912 888 33 33"
+// и "912 888 33 33" дают "+79128883333". "00" в начале заменяется на "+".
+func Parse(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	international := false
+	switch {
+	case strings.HasPrefix(s, "+"):
+		international = true
+		s = s[1:]
+	case strings.HasPrefix(s, "00"):
+		international = true
+		s = s[2:]
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r == ' ' || r == '-' || r == '(' || r == ')' || r == '.':
+		default:
+			return "", ErrInvalid
+		}
+	}
+	digits := b.String()
+
+	if international {
+		if len(digits) < minDigits || len(digits) > maxDigits || digits[0] == '0' {
+			return "", ErrInvalid
+		}
+		if digits[0] == '7' && len(digits) != 11 {
+			return "", ErrInvalid
+		}
+		return "+" + digits, nil
+	}
+
+	switch {
+	case len(digits) == 10:
+		return "+7" + digits, nil
+	case len(digits) == 11 && (digits[0] == '8' || digits[0] == '7'):
+		return "+7" + digits[1:], nil
+	default:
+		return "", ErrInvalid
+	}
+}
+
+// Format показывает номер E.164 в привычном виде: российские — "+7 912 888-33-33",
+// остальные — как есть. Номер, который не удается разобрать, возвращается без изменений.
+func Format(s string) string {
+	e164, err := Parse(s)
+	if err != nil {
+		return s
+	}
+	if !strings.HasPrefix(e164, "+7") {
+		return e164
+	}
+	d := e164[2:]
+	return "+7 " + d[:3] + " " + d[3:6] + "-" + d[6:8] + "-" + d[8:]
+}
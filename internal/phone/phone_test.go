@@ -0,0 +1,55 @@
+package phone
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "912 888 33 33", want: "+79128883333"},
+		{in: "8 (912) 888-33-33", want: "+79128883333"},
+		{in: "7 912 888 33 33", want: "+79128883333"},
+		{in: "+7 912 888-33-33", want: "+79128883333"},
+		{in: " +79128883333 ", want: "+79128883333"},
+		{in: "+44 20 7946 0958", want: "+442079460958"},
+		{in: "00 49 30 123456", want: "+4930123456"},
+		{in: "+1.202.555.0143", want: "+12025550143"},
+		{in: "", wantErr: true},
+		{in: "123", wantErr: true},
+		{in: "912 888 33 3", wantErr: true},
+		{in: "9 912 888 33 33", wantErr: true},
+		{in: "+7 912 888 33", wantErr: true},
+		{in: "+0 123 456 789", wantErr: true},
+		{in: "+1234567890123456", wantErr: true},
+		{in: "912 888 33 33 доб. 5", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q) = %q, ожидалась ошибка", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Parse(%q) = %q, %v, ожидалось %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := map[string]string{
+		"+79128883333":  "+7 912 888-33-33",
+		"912 888 33 33": "+7 912 888-33-33",
+		"+442079460958": "+442079460958",
+		"не номер":      "не номер",
+	}
+	for in, want := range tests {
+		if got := Format(in); got != want {
+			t.Errorf("Format(%q) = %q, ожидалось %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/ttrtcixy/demo/internal/models"
+)
+
+// AuditUser — имя пользователя для журнала: логин из контекста или "system".
+func AuditUser(ctx context.Context) string {
+	if user, ok := UserFromContext(ctx); ok {
+		return user.Login
+	}
+	return "system"
+}
+
+// AuditJSON сериализует состояние сущности для журнала. nil, в том числе нулевой указатель,
+// дает пустую строку.
+func AuditJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("ошибка сериализации для аудита: %v", err)
+	}
+	if string(data) == "null" {
+		return "", nil
+	}
+	return string(data), nil
+}
+
+var addAudit = `insert into AuditLog(Entity, EntityId, PartnerId, Operation, UserName, BeforeData, AfterData) values(?, ?, ?, ?, ?, ?, ?)`
+
+// audit записывает изменение в журнал в той же транзакции, что и само изменение.
+func (t tx) audit(ctx context.Context, entity string, id int, action string, before, after any) error {
+	beforeJSON, err := AuditJSON(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := AuditJSON(after)
+	if err != nil {
+		return err
+	}
+	// PartnerId позволяет выбрать историю партнера запросом, не разбирая снимки.
+	var partnerId sql.NullInt64
+	if linked, ok := linkedPartner(entity, id, beforeJSON, afterJSON); ok {
+		partnerId = sql.NullInt64{Int64: int64(linked), Valid: true}
+	}
+	if entity == models.EntityPartner {
+		// Снимки партнера содержат личные данные и шифруются так же, как сама строка.
+		if beforeJSON, err = t.sealAuditJSON(ctx, beforeJSON); err != nil {
+			return err
+		}
+		if afterJSON, err = t.sealAuditJSON(ctx, afterJSON); err != nil {
+			return err
+		}
+	}
+
+	_, err = t.ExecContext(ctx, addAudit, entity, id, partnerId, action, AuditUser(ctx), beforeJSON, afterJSON)
+	return err
+}
+
+var setAuditData = `update AuditLog set BeforeData = ?, AfterData = ? where AuditId = ?`
+
+var getAuditLog = `
+    SELECT AuditId, Entity, EntityId, Operation, UserName, BeforeData, AfterData, CreatedAt
+    FROM AuditLog`
+
+// GetAuditLog возвращает весь журнал, новые записи первыми.
+func (db *DB) GetAuditLog(ctx context.Context) ([]models.AuditEntry, error) {
+	return queryAudit(ctx, db.connect, getAuditLog+` ORDER BY AuditId DESC`)
+}
+
+// GetEntityHistory возвращает изменения одной сущности, новые записи первыми.
+func (db *DB) GetEntityHistory(ctx context.Context, entity string, id int) ([]models.AuditEntry, error) {
+	return queryAudit(ctx, db.connect, getAuditLog+` WHERE Entity = ? AND EntityId = ? ORDER BY AuditId DESC`, entity, id)
+}
+
+// queryAudit читает записи журнала; снимки партнеров расшифровываются.
+func queryAudit(ctx context.Context, q querier, query string, args ...any) ([]models.AuditEntry, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения журнала изменений: %v", err)
+	}
+	defer rows.Close()
+
+	var entries []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.Id, &e.Entity, &e.EntityId, &e.Action, &e.User, &e.Before, &e.After, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки: %v", err)
+		}
+		if e.Entity == models.EntityPartner {
+			if e.Before, err = q.keyring().openAuditJSON(e.Before); err != nil {
+				return nil, err
+			}
+			if e.After, err = q.keyring().openAuditJSON(e.After); err != nil {
+				return nil, err
+			}
+		}
+		entries = append(entries, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при обработке результатов: %v", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"github.com/ttrtcixy/demo/internal/models"
+	"os"
+	"strings"
+	"time"
+)
+
+// backupStepPages — сколько страниц копируется за шаг. Между шагами база доступна другим
+// соединениям, поэтому копирование не останавливает работу приложения.
+const backupStepPages = 256
+
+// backupRequiredTables — таблицы, без которых файл не считается копией базы. Остальные
+// таблицы создаются при восстановлении, если копия сделана старой версией приложения.
+var backupRequiredTables = []string{"ProductTypes", "Partners", "Products", "PartnerProducts", "MaterialTypes"}
+
+// BackupSummary — содержимое копии, которое показывается перед восстановлением.
+type BackupSummary struct {
+	Partners int
+	Sales    int
+	Users    int
+	// LastSale — дата последней продажи в копии, пусто, если продаж нет.
+	LastSale string
+}
+
+// Backup сохраняет копию открытой базы в файл path через backup API SQLite. Копия сначала
+// пишется во временный файл рядом, поэтому прерванное копирование не оставляет неполный path.
+func (db *DB) Backup(ctx context.Context, path string) error {
+	if db.connect.dialect.name != DriverSQLite {
+		return ErrBackupUnsupported
+	}
+
+	tmp := path + ".tmp"
+	os.Remove(tmp)
+	dst, err := sql.Open(DriverSQLite, fileDSN(tmp))
+	if err != nil {
+		return err
+	}
+	err = copyDatabase(ctx, dst, db.connect.DB)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ValidateBackup проверяет, что path — целая база SQLite с таблицами приложения, и описывает
+// ее содержимое. Файл открывается только для чтения.
+func (db *DB) ValidateBackup(ctx context.Context, path string) (BackupSummary, error) {
+	if db.connect.dialect.name != DriverSQLite {
+		return BackupSummary{}, ErrBackupUnsupported
+	}
+	if _, err := os.Stat(path); err != nil {
+		return BackupSummary{}, ErrBackupInvalid(err)
+	}
+
+	src, err := sql.Open(DriverSQLite, readOnlyDSN(path))
+	if err != nil {
+		return BackupSummary{}, ErrBackupInvalid(err)
+	}
+	defer src.Close()
+
+	var check string
+	if err := src.QueryRowContext(ctx, "PRAGMA integrity_check").Scan(&check); err != nil {
+		return BackupSummary{}, ErrBackupInvalid(err)
+	}
+	if check != "ok" {
+		return BackupSummary{}, ErrBackupInvalid(fmt.Errorf("integrity_check: %s", check))
+	}
+
+	for _, table := range backupRequiredTables {
+		exists, err := tableExists(ctx, src, table)
+		if err != nil {
+			return BackupSummary{}, ErrBackupInvalid(err)
+		}
+		if !exists {
+			return BackupSummary{}, ErrBackupMissingTable(table)
+		}
+	}
+
+	var s BackupSummary
+	var lastSale sql.NullString
+	if err := src.QueryRowContext(ctx, "SELECT COUNT(*) FROM Partners").Scan(&s.Partners); err != nil {
+		return BackupSummary{}, ErrBackupInvalid(err)
+	}
+	if err := src.QueryRowContext(ctx, "SELECT COUNT(*), MAX(substr(SaleDate, 1, 10)) FROM PartnerProducts").Scan(&s.Sales, &lastSale); err != nil {
+		return BackupSummary{}, ErrBackupInvalid(err)
+	}
+	s.LastSale = lastSale.String
+	// Таблицы пользователей нет в копиях, сделанных до появления ролей.
+	hasUsers, err := tableExists(ctx, src, "Users")
+	if err != nil {
+		return BackupSummary{}, ErrBackupInvalid(err)
+	}
+	if hasUsers {
+		if err := src.QueryRowContext(ctx, "SELECT COUNT(*) FROM Users").Scan(&s.Users); err != nil {
+			return BackupSummary{}, ErrBackupInvalid(err)
+		}
+	}
+	return s, nil
+}
+
+func tableExists(ctx context.Context, q *sql.DB, table string) (bool, error) {
+	var n int
+	err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&n)
+	return n > 0, err
+}
+
+// Restore заменяет содержимое открытой базы копией из path. Копия проверяется заранее,
+// после замены недостающие таблицы и миграции данных применяются как при открытии базы.
+func (db *DB) Restore(ctx context.Context, path string) error {
+	if err := Authorize(ctx, models.PermManageBackups); err != nil {
+		return err
+	}
+	if _, err := db.ValidateBackup(ctx, path); err != nil {
+		return err
+	}
+
+	src, err := sql.Open(DriverSQLite, readOnlyDSN(path))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if err := copyDatabase(ctx, db.connect.DB, src); err != nil {
+		return err
+	}
+
+	return db.migrate(ctx)
+}
+
+// fileDSN — DSN файла SQLite по пути path. Символы, которые драйвер читает как начало
+// параметров, экранируются, поэтому "?" и "#" в имени каталога не обрезают путь.
+func fileDSN(path string) string {
+	path = strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23").Replace(path)
+	return "file:" + path
+}
+
+// readOnlyDSN открывает файл SQLite только для чтения: проверка копии не должна ее менять.
+func readOnlyDSN(path string) string {
+	return fileDSN(path) + "?mode=ro"
+}
+
+// copyDatabase копирует базу src в dst по шагам. Занятость базы другим соединением
+// не ошибка: шаг повторяется, пока не отменен ctx.
+func copyDatabase(ctx context.Context, dst, src *sql.DB) error {
+	dstConn, err := dst.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	return dstConn.Raw(func(d any) error {
+		return srcConn.Raw(func(s any) error {
+			backup, err := d.(*sqlite3.SQLiteConn).Backup("main", s.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+			remaining := -1
+			for {
+				done, err := backup.Step(backupStepPages)
+				if err != nil {
+					backup.Finish()
+					return err
+				}
+				if done {
+					return backup.Finish()
+				}
+				if err := ctx.Err(); err != nil {
+					backup.Finish()
+					return err
+				}
+				// Шаг без прогресса — база занята записью, ждем ее завершения.
+				if backup.Remaining() == remaining {
+					time.Sleep(10 * time.Millisecond)
+				}
+				remaining = backup.Remaining()
+			}
+		})
+	})
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/ttrtcixy/demo/internal/models"
+	"slices"
+)
+
+// ValidateContact проверяет роль и имя контактного лица. Телефон и email нормализует форма.
+func ValidateContact(c models.Contact) error {
+	if !slices.Contains(models.ContactRoles, c.Role) {
+		return ErrContactRole
+	}
+	if c.Name == "" {
+		return ErrContactNameEmpty
+	}
+	return nil
+}
+
+var getContacts = `SELECT ContactId, PartnerId, Role, Name, Phone, Email FROM PartnerContacts`
+
+func (db *DB) GetContacts(ctx context.Context, partnerId int) ([]models.Contact, error) {
+	return queryContacts(ctx, db.connect, getContacts+` WHERE PartnerId = ? ORDER BY ContactId`, partnerId)
+}
+
+func (db *DB) GetAllContacts(ctx context.Context) ([]models.Contact, error) {
+	return queryContacts(ctx, db.connect, getContacts+` ORDER BY PartnerId, ContactId`)
+}
+
+func queryContacts(ctx context.Context, q querier, query string, args ...any) ([]models.Contact, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contacts []models.Contact
+	for rows.Next() {
+		var c models.Contact
+		if err := rows.Scan(&c.Id, &c.PartnerId, &c.Role, &c.Name, &c.Phone, &c.Email); err != nil {
+			return nil, err
+		}
+		contacts = append(contacts, c)
+	}
+	return contacts, rows.Err()
+}
+
+func readContact(ctx context.Context, q querier, id int) (*models.Contact, error) {
+	var c models.Contact
+	err := q.QueryRowContext(ctx, getContacts+` WHERE ContactId = ?`, id).Scan(&c.Id, &c.PartnerId, &c.Role, &c.Name, &c.Phone, &c.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+var addContact = `insert into PartnerContacts(PartnerId, Role, Name, Phone, Email) values(?, ?, ?, ?, ?) returning ContactId`
+
+func (db *DB) AddContact(ctx context.Context, contact models.Contact) (int, error) {
+	if err := Authorize(ctx, models.PermEditPartners); err != nil {
+		return 0, err
+	}
+	if err := ValidateContact(contact); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.connect.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	partner, err := readPartner(ctx, tx, contact.PartnerId)
+	if err != nil {
+		return 0, err
+	}
+	if partner == nil {
+		return 0, ErrPartnerNotFound
+	}
+
+	var id int
+	args := []any{contact.PartnerId, contact.Role, contact.Name, contact.Phone, contact.Email}
+	if err := tx.QueryRowContext(ctx, addContact, args...).Scan(&id); err != nil {
+		return 0, err
+	}
+	contact.Id = id
+	if err := tx.audit(ctx, models.EntityContact, id, models.AuditCreate, nil, contact); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+var updateContact = `update PartnerContacts set Role = ?, Name = ?, Phone = ?, Email = ? where ContactId = ?`
+
+func (db *DB) UpdateContact(ctx context.Context, contact models.Contact) error {
+	if err := Authorize(ctx, models.PermEditPartners); err != nil {
+		return err
+	}
+	if err := ValidateContact(contact); err != nil {
+		return err
+	}
+
+	tx, err := db.connect.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := readContact(ctx, tx, contact.Id)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return ErrContactNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, updateContact, contact.Role, contact.Name, contact.Phone, contact.Email, contact.Id); err != nil {
+		return err
+	}
+	contact.PartnerId = before.PartnerId
+	if err := tx.audit(ctx, models.EntityContact, contact.Id, models.AuditUpdate, before, contact); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *DB) DeleteContact(ctx context.Context, id int) error {
+	if err := Authorize(ctx, models.PermEditPartners); err != nil {
+		return err
+	}
+
+	tx, err := db.connect.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := readContact(ctx, tx, id)
+	if err != nil || before == nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM PartnerContacts WHERE ContactId = ?", id); err != nil {
+		return err
+	}
+	if err := tx.audit(ctx, models.EntityContact, id, models.AuditDelete, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
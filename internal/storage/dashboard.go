@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"context"
+	"github.com/ttrtcixy/demo/internal/models"
+)
+
+var getSalesTotals = `
+    SELECT
+        COALESCE(SUM(pp.Quantity * p.MinCostKopecks), 0),
+        CAST(COALESCE(SUM(pp.Quantity), 0) AS INTEGER),
+        COUNT(DISTINCT pp.PartnerId)
+    FROM
+        PartnerProducts pp
+    JOIN
+        Products p ON pp.ProductId = p.ProductId`
+
+func (db *DB) GetSalesTotals(ctx context.Context) (models.SalesTotals, error) {
+	var totals models.SalesTotals
+	err := db.connect.QueryRowContext(ctx, getSalesTotals).Scan(&totals.Revenue, &totals.Quantity, &totals.Partners)
+	return totals, err
+}
+
+var getMonthlyTotals = `
+    SELECT
+        substr(pp.SaleDate, 1, 7) AS Month,
+        CAST(SUM(pp.Quantity) AS INTEGER),
+        SUM(pp.Quantity * p.MinCostKopecks)
+    FROM
+        PartnerProducts pp
+    JOIN
+        Products p ON pp.ProductId = p.ProductId
+    GROUP BY
+        substr(pp.SaleDate, 1, 7)
+    ORDER BY
+        Month`
+
+func (db *DB) GetMonthlyTotals(ctx context.Context) ([]models.MonthlySales, error) {
+	rows, err := db.connect.QueryContext(ctx, getMonthlyTotals)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var months []models.MonthlySales
+	for rows.Next() {
+		var m models.MonthlySales
+		if err := rows.Scan(&m.Month, &m.Quantity, &m.TotalSum); err != nil {
+			return nil, err
+		}
+		months = append(months, m)
+	}
+	return months, rows.Err()
+}
+
+var getTopPartners = `
+    SELECT
+        pa.PartnerId,
+        pa.PartnerName,
+        CAST(SUM(pp.Quantity) AS INTEGER) AS Quantity,
+        SUM(pp.Quantity * p.MinCostKopecks)
+    FROM
+        PartnerProducts pp
+    JOIN
+        Partners pa ON pp.PartnerId = pa.PartnerId
+    JOIN
+        Products p ON pp.ProductId = p.ProductId
+    GROUP BY
+        pa.PartnerId, pa.PartnerName
+    ORDER BY
+        Quantity DESC, pa.PartnerName
+    LIMIT ?`
+
+func (db *DB) GetTopPartners(ctx context.Context, limit int) ([]models.PartnerTotal, error) {
+	rows, err := db.connect.QueryContext(ctx, getTopPartners, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var partners []models.PartnerTotal
+	for rows.Next() {
+		var p models.PartnerTotal
+		if err := rows.Scan(&p.PartnerId, &p.CompanyName, &p.Quantity, &p.TotalSum); err != nil {
+			return nil, err
+		}
+		partners = append(partners, p)
+	}
+	return partners, rows.Err()
+}
+
+// Пороги скидок берутся из DiscountTiers, как в getPartners. Партнеры без продаж попадают
+// в первый порог.
+var getDiscountDistribution = `
+    SELECT
+        t.DiscountPercentage,
+        COUNT(*)
+    FROM (
+        SELECT
+            ` + discountCase("COALESCE(SUM(pp.Quantity), 0)") + ` AS DiscountPercentage
+        FROM
+            Partners p
+        LEFT JOIN
+            PartnerProducts pp ON p.PartnerId = pp.PartnerId
+        GROUP BY
+            p.PartnerId
+    ) t
+    GROUP BY
+        t.DiscountPercentage
+    ORDER BY
+        t.DiscountPercentage`
+
+func (db *DB) GetDiscountDistribution(ctx context.Context) ([]models.DiscountTierCount, error) {
+	rows, err := db.connect.QueryContext(ctx, getDiscountDistribution)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tiers []models.DiscountTierCount
+	for rows.Next() {
+		var t models.DiscountTierCount
+		if err := rows.Scan(&t.Percentage, &t.Partners); err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, t)
+	}
+	return tiers, rows.Err()
+}
+
+var getPartnerTypeDistribution = `
+    SELECT
+        COALESCE(PartnerType, '') AS PartnerType,
+        COUNT(*) AS Partners
+    FROM
+        Partners
+    GROUP BY
+        COALESCE(PartnerType, '')
+    ORDER BY
+        Partners DESC, PartnerType`
+
+func (db *DB) GetPartnerTypeDistribution(ctx context.Context) ([]models.PartnerTypeCount, error) {
+	rows, err := db.connect.QueryContext(ctx, getPartnerTypeDistribution)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var types []models.PartnerTypeCount
+	for rows.Next() {
+		var t models.PartnerTypeCount
+		if err := rows.Scan(&t.PartnerType, &t.Partners); err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+	return types, rows.Err()
+}
@@ -1,120 +1,309 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/ttrtcixy/demo/internal/models"
-	"log"
-	"math"
+	"github.com/ttrtcixy/demo/internal/phone"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type DB struct {
-	connect *sql.DB
+	connect conn
 }
 type Query struct {
 	query string
 	args  []any
 }
 
-func NewDB() *DB {
-	d, err := sql.Open("sqlite3", "./test.db")
+// NewDB открывает базу driver ("sqlite3" или "postgres") и создает недостающие таблицы.
+func NewDB(ctx context.Context, driver, dsn string) (*DB, error) {
+	var d dialect
+	switch driver {
+	case DriverSQLite:
+		d = sqliteDialect
+		// Без этого SQLite не выполняет ON DELETE CASCADE, а PostgreSQL выполняет.
+		if !strings.Contains(dsn, "_foreign_keys") {
+			if strings.Contains(dsn, "?") {
+				dsn += "&_foreign_keys=on"
+			} else {
+				dsn += "?_foreign_keys=on"
+			}
+		}
+	case DriverPostgres:
+		d = postgresDialect
+	default:
+		return nil, fmt.Errorf("неизвестный драйвер базы данных: %s", driver)
+	}
+
+	c, err := sql.Open(driver, dsn)
 	if err != nil {
-		log.Fatalln(err)
+		return nil, err
+	}
+
+	db := &DB{connect: conn{DB: c, dialect: d, keys: &keyring{}}}
+	if err := db.migrate(ctx); err != nil {
+		c.Close()
+		return nil, err
 	}
 
-	return &DB{connect: d}
+	return db, nil
+}
+
+func (db *DB) Close() error {
+	return db.connect.Close()
+}
+
+// discountCase строит выражение SQL со скидкой партнера по общему количеству total
+// из порогов DiscountTiers.
+func discountCase(total string) string {
+	var b strings.Builder
+	b.WriteString("CASE")
+	for i := len(DiscountTiers) - 1; i > 0; i-- {
+		fmt.Fprintf(&b, " WHEN %s >= %s THEN %d", total, strconv.FormatFloat(DiscountTiers[i].MinQuantity, 'f', -1, 64), DiscountTiers[i].Percentage)
+	}
+	fmt.Fprintf(&b, " ELSE %d END", DiscountTiers[0].Percentage)
+	return b.String()
 }
 
 var getPartners = `SELECT 
    p.PartnerId, p.PartnerType, p.PartnerName, p.Director, p.Phone, p.Rating, p.Email, p.LegalAddress,
-    CASE
-        WHEN SUM(pp.Quantity) < 10000 THEN 0
-        WHEN SUM(pp.Quantity) BETWEEN 10000 AND 49999 THEN 5
-        WHEN SUM(pp.Quantity) BETWEEN 50000 AND 299999 THEN 10
-        WHEN SUM(pp.Quantity) >= 300000 THEN 15
-    END AS DiscountPercentage
+    ` + discountCase("SUM(pp.Quantity)") + ` AS DiscountPercentage
 FROM 
     Partners p
 JOIN 
     PartnerProducts pp ON p.PartnerId = pp.PartnerId
 GROUP BY 
-    p.PartnerId, p.PartnerName;`
-
-var ErrPartnersNoFound = errors.New("партнеры не найдены")
+    p.PartnerId, p.PartnerName
+ORDER BY 
+    p.PartnerId;`
 
-func (db *DB) GetPartners() (*models.Partners, error) {
+func (db *DB) GetPartners(ctx context.Context) (*models.Partners, error) {
 	query := Query{query: getPartners}
-	rows, err := db.connect.Query(query.query)
+	rows, err := db.connect.QueryContext(ctx, query.query)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
 	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
 		return &models.Partners{}, ErrPartnersNoFound
 	}
 
 	partners := models.Partners{}
+	var undecryptable []string
 	for {
 		var partner models.Partner
 		err := rows.Scan(&partner.Id, &partner.PartnerType, &partner.CompanyName, &partner.Director, &partner.Phone, &partner.Rating, &partner.Email, &partner.Address, &partner.Discount)
 		if err != nil {
 			return nil, err
 		}
+		if err := db.connect.keys.openPartner(&partner); errors.Is(err, ErrUndecryptable) {
+			// Строку записали другим ключом. Партнер остается в списке без личных данных,
+			// чтобы одна такая строка не закрывала таблицу для всех.
+			for _, field := range personalFields(&partner) {
+				*field = ""
+			}
+			undecryptable = append(undecryptable, partner.CompanyName)
+		} else if err != nil {
+			return nil, err
+		}
 		partners = append(partners, partner)
 
 		if !rows.Next() {
 			break
 		}
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(undecryptable) > 0 {
+		return &partners, ErrPartnersUndecryptable(undecryptable)
+	}
 
 	return &partners, nil
 }
 
-var addPartner = `insert into Partners(PartnerType, PartnerName, Director, Phone, Rating, Email, LegalAddress) values(?, ?, ?, ?, ?, ?, ?)`
+var getPartner = `SELECT PartnerId, PartnerType, PartnerName, Director, Phone, Rating, Email, LegalAddress FROM Partners WHERE PartnerId = ?`
+
+// readPartner возвращает партнера без скидки или nil, если его нет.
+func readPartner(ctx context.Context, q querier, id int) (*models.Partner, error) {
+	var p models.Partner
+	err := q.QueryRowContext(ctx, getPartner, id).Scan(&p.Id, &p.PartnerType, &p.CompanyName, &p.Director, &p.Phone, &p.Rating, &p.Email, &p.Address)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := q.keyring().openPartner(&p); err != nil {
+		// В транзакции записи это обычно значит, что другой клиент сменил пароль шифрования.
+		if keyErr := q.checkKey(ctx); keyErr != nil {
+			return nil, keyErr
+		}
+		return nil, err
+	}
+	return &p, nil
+}
+
+var addPartner = `insert into Partners(PartnerType, PartnerName, Director, Phone, Rating, Email, LegalAddress) values(?, ?, ?, ?, ?, ?, ?) returning PartnerId`
+
+func (db *DB) AddPartner(ctx context.Context, partner models.Partner) error {
+	if err := Authorize(ctx, models.PermEditPartners); err != nil {
+		return err
+	}
+	if err := ValidateRating(partner.Rating); err != nil {
+		return err
+	}
+
+	tx, err := db.connect.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-func (db *DB) AddPartner(partner models.Partner) error {
-	args := []any{partner.PartnerType, partner.CompanyName, partner.Director, partner.Phone, partner.Rating, partner.Email, partner.Address}
-	query := Query{query: addPartner, args: args}
-	_, err := db.connect.Exec(query.query, query.args...)
+	sealed, err := tx.sealPartner(ctx, partner)
 	if err != nil {
 		return err
 	}
-	return nil
+	args := []any{sealed.PartnerType, sealed.CompanyName, sealed.Director, sealed.Phone, sealed.Rating, sealed.Email, sealed.Address}
+	var id int
+	if err := tx.QueryRowContext(ctx, addPartner, args...).Scan(&id); err != nil {
+		return err
+	}
+
+	after, err := readPartner(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+	if err := tx.audit(ctx, models.EntityPartner, id, models.AuditCreate, nil, after); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 var deletePartner = `delete from Partners where PartnerId = ?`
 
-func (db *DB) DeletePartner(id int) error {
-	query := Query{query: deletePartner, args: []any{id}}
-	_, err := db.connect.Exec(query.query, query.args...)
+func (db *DB) DeletePartner(ctx context.Context, id int) error {
+	if err := Authorize(ctx, models.PermDeletePartners); err != nil {
+		return err
+	}
+
+	tx, err := db.connect.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := readPartner(ctx, tx, id)
+	if err != nil || before == nil {
+		return err
+	}
+
+	// Продажи удаляются каскадом (ON DELETE CASCADE), поэтому в журнал их нужно записать заранее.
+	sales, err := partnerSaleRows(ctx, tx, id)
 	if err != nil {
 		return err
 	}
-	return nil
+	if _, err := tx.ExecContext(ctx, deletePartner, id); err != nil {
+		return err
+	}
+	for _, sale := range sales {
+		if err := tx.audit(ctx, models.EntitySale, sale.Id, models.AuditDelete, sale, nil); err != nil {
+			return err
+		}
+	}
+	if err := tx.audit(ctx, models.EntityPartner, id, models.AuditDelete, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-var updatePartner = `update Partners set PartnerType = ?, PartnerName = ?, Director = ?, Phone = ?, Rating = ? where PartnerId = ?;`
+var getPartnerSaleRows = `
+    SELECT pp.PartnerProductId, p.ProductName, pp.Quantity, COALESCE(pp.SaleDate, '')
+    FROM PartnerProducts pp
+    JOIN Products p ON pp.ProductId = p.ProductId
+    WHERE pp.PartnerId = ?
+    ORDER BY pp.PartnerProductId`
+
+// partnerSaleRows возвращает продажи партнера для журнала.
+func partnerSaleRows(ctx context.Context, q querier, partnerId int) ([]models.Sale, error) {
+	rows, err := q.QueryContext(ctx, getPartnerSaleRows, partnerId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sales []models.Sale
+	for rows.Next() {
+		sale := models.Sale{PartnerId: partnerId}
+		if err := rows.Scan(&sale.Id, &sale.ProductName, &sale.Quantity, &sale.SaleDate); err != nil {
+			return nil, err
+		}
+		sales = append(sales, sale)
+	}
+	return sales, rows.Err()
+}
+
+var updatePartner = `update Partners set PartnerType = ?, PartnerName = ?, Director = ?, Phone = ?, Rating = ?, Email = ?, LegalAddress = ? where PartnerId = ?;`
+
+func (db *DB) UpdatePartner(ctx context.Context, partner models.Partner) error {
+	if err := Authorize(ctx, models.PermEditPartners); err != nil {
+		return err
+	}
+	if err := ValidateRating(partner.Rating); err != nil {
+		return err
+	}
+
+	tx, err := db.connect.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := readPartner(ctx, tx, partner.Id)
+	if err != nil || before == nil {
+		return err
+	}
+
+	sealed, err := tx.sealPartner(ctx, partner)
+	if err != nil {
+		return err
+	}
+	args := []any{sealed.PartnerType, sealed.CompanyName, sealed.Director, sealed.Phone, sealed.Rating, sealed.Email, sealed.Address, sealed.Id}
+	if _, err := tx.ExecContext(ctx, updatePartner, args...); err != nil {
+		return err
+	}
+	if err := tx.recordRating(ctx, partner.Id, before.Rating, partner.Rating, models.RatingReasonManual); err != nil {
+		return err
+	}
 
-func (db *DB) UpdatePartner(partner models.Partner) error {
-	args := []any{partner.PartnerType, partner.CompanyName, partner.Director, partner.Phone, partner.Rating, partner.Id}
-	query := Query{query: updatePartner, args: args}
-	_, err := db.connect.Exec(query.query, query.args...)
+	after, err := readPartner(ctx, tx, partner.Id)
 	if err != nil {
 		return err
 	}
-	return nil
+	if err := tx.audit(ctx, models.EntityPartner, partner.Id, models.AuditUpdate, before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 var getPartnerSales = `
     SELECT 
-        p.ProductName AS 'Продукция',
-        pp.Quantity AS 'Количество',
-        pp.SaleDate AS 'Дата продажи',
-        pt.ProductType AS 'Тип продукции',
-        (pp.Quantity * p.MinCost) AS 'Общая сумма'
+        p.ProductName AS "Продукция",
+        pp.Quantity AS "Количество",
+        pp.SaleDate AS "Дата продажи",
+        pt.ProductType AS "Тип продукции",
+        (pp.Quantity * p.MinCostKopecks) AS "Общая сумма"
     FROM 
         PartnerProducts pp
     JOIN 
@@ -126,9 +315,9 @@ var getPartnerSales = `
     ORDER BY 
         pp.SaleDate DESC`
 
-func (db *DB) GetPartnerSales(id int) ([]models.PartnerSale, error) {
+func (db *DB) GetPartnerSales(ctx context.Context, id int) ([]models.PartnerSale, error) {
 	var sales []models.PartnerSale
-	rows, err := db.connect.Query(getPartnerSales, id)
+	rows, err := db.connect.QueryContext(ctx, getPartnerSales, id)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка выполнения запроса продаж: %v", err)
 	}
@@ -169,25 +358,127 @@ func (db *DB) GetPartnerSales(id int) ([]models.PartnerSale, error) {
 	return sales, nil
 }
 
-func (db *DB) FindPartner(searchTerm string) (int, string, error) {
+// SaleDate хранится строкой "ГГГГ-ММ-ДД ...", поэтому месяц — первые 7 символов в обеих СУБД.
+var getMonthlySales = `
+    SELECT 
+        substr(pp.SaleDate, 1, 7) AS Month,
+        CAST(SUM(pp.Quantity) AS INTEGER),
+        SUM(pp.Quantity * p.MinCostKopecks)
+    FROM 
+        PartnerProducts pp
+    JOIN 
+        Products p ON pp.ProductId = p.ProductId
+    WHERE 
+        pp.PartnerId = ?
+    GROUP BY 
+        substr(pp.SaleDate, 1, 7)
+    ORDER BY 
+        Month`
+
+func (db *DB) GetMonthlySales(ctx context.Context, id int) ([]models.MonthlySales, error) {
+	rows, err := db.connect.QueryContext(ctx, getMonthlySales, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var months []models.MonthlySales
+	for rows.Next() {
+		var m models.MonthlySales
+		if err := rows.Scan(&m.Month, &m.Quantity, &m.TotalSum); err != nil {
+			return nil, err
+		}
+		months = append(months, m)
+	}
+	return months, rows.Err()
+}
+
+var getTopProducts = `
+    SELECT 
+        p.ProductName,
+        CAST(SUM(pp.Quantity) AS INTEGER) AS Quantity,
+        SUM(pp.Quantity * p.MinCostKopecks)
+    FROM 
+        PartnerProducts pp
+    JOIN 
+        Products p ON pp.ProductId = p.ProductId
+    WHERE 
+        pp.PartnerId = ?
+    GROUP BY 
+        p.ProductId, p.ProductName
+    ORDER BY 
+        Quantity DESC, p.ProductName
+    LIMIT ?`
+
+func (db *DB) GetTopProducts(ctx context.Context, id int, limit int) ([]models.ProductSales, error) {
+	rows, err := db.connect.QueryContext(ctx, getTopProducts, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []models.ProductSales
+	for rows.Next() {
+		var p models.ProductSales
+		if err := rows.Scan(&p.ProductName, &p.Quantity, &p.TotalSum); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+func (db *DB) FindPartner(ctx context.Context, searchTerm string) (int, string, error) {
 	var partnerID int
 	var partnerName string
 
-	err := db.connect.QueryRow("SELECT PartnerId, PartnerName FROM Partners WHERE PartnerId = ?", searchTerm).Scan(&partnerID, &partnerName)
+	err := db.connect.QueryRowContext(ctx, "SELECT PartnerId, PartnerName FROM Partners WHERE PartnerId = ?", searchTerm).Scan(&partnerID, &partnerName)
 	if err == nil {
 		return partnerID, partnerName, nil
 	}
 
-	err = db.connect.QueryRow("SELECT PartnerId, PartnerName FROM Partners WHERE PartnerName LIKE ? LIMIT 1", "%"+searchTerm+"%").Scan(&partnerID, &partnerName)
+	err = db.connect.QueryRowContext(ctx, "SELECT PartnerId, PartnerName FROM Partners WHERE PartnerName "+db.connect.dialect.like+" ? ORDER BY PartnerId LIMIT 1", "%"+searchTerm+"%").Scan(&partnerID, &partnerName)
+	if err != nil && ctx.Err() == nil {
+		partnerID, partnerName, err = db.findPartnerByContact(ctx, searchTerm)
+	}
+	if ctx.Err() != nil {
+		return 0, "", ctx.Err()
+	}
 	if err != nil {
-		return 0, "", fmt.Errorf("партнер не найден")
+		return 0, "", ErrPartnerNotFound
 	}
 
 	return partnerID, partnerName, nil
 }
 
-func (db *DB) GetProducts() ([]string, error) {
-	rows, err := db.connect.Query("SELECT ProductId, ProductName FROM Products")
+var findPartnerByContact = `
+    SELECT p.PartnerId, p.PartnerName
+    FROM PartnerContacts c
+    JOIN Partners p ON p.PartnerId = c.PartnerId
+    WHERE %s
+    ORDER BY p.PartnerId, c.ContactId
+    LIMIT 1`
+
+// findPartnerByContact ищет партнера по имени или email контактного лица, а если searchTerm —
+// телефон, то и по телефону контакта.
+func (db *DB) findPartnerByContact(ctx context.Context, searchTerm string) (int, string, error) {
+	like := db.connect.dialect.like
+	conditions := []string{"c.Name " + like + " ?", "c.Email " + like + " ?"}
+	args := []any{"%" + searchTerm + "%", "%" + searchTerm + "%"}
+	if number, err := phone.Parse(searchTerm); err == nil {
+		conditions = append(conditions, "c.Phone = ?")
+		args = append(args, number)
+	}
+
+	var partnerID int
+	var partnerName string
+	query := fmt.Sprintf(findPartnerByContact, strings.Join(conditions, " OR "))
+	err := db.connect.QueryRowContext(ctx, query, args...).Scan(&partnerID, &partnerName)
+	return partnerID, partnerName, err
+}
+
+func (db *DB) GetProducts(ctx context.Context) ([]string, error) {
+	rows, err := db.connect.QueryContext(ctx, "SELECT ProductId, ProductName FROM Products ORDER BY ProductId")
 	if err != nil {
 		return nil, err
 	}
@@ -204,8 +495,8 @@ func (db *DB) GetProducts() ([]string, error) {
 	return products, nil
 }
 
-func (db *DB) GetMaterialTypes() ([]string, error) {
-	rows, err := db.connect.Query("SELECT MaterialTypeId, MaterialType FROM MaterialTypes")
+func (db *DB) GetMaterialTypes(ctx context.Context) ([]string, error) {
+	rows, err := db.connect.QueryContext(ctx, "SELECT MaterialTypeId, MaterialType FROM MaterialTypes ORDER BY MaterialTypeId")
 	if err != nil {
 		return nil, err
 	}
@@ -221,33 +512,3 @@ func (db *DB) GetMaterialTypes() ([]string, error) {
 	}
 	return materials, nil
 }
-
-func (db *DB) CalculateMaterial(productId, materialId string, quantity int, param1, param2 float64) (int, error) {
-
-	var productCoef float64
-	err := db.connect.QueryRow(`
-        SELECT pt.Coefficient 
-        FROM ProductTypes pt
-        JOIN Products p ON pt.ProductTypeId = p.ProductTypeId
-        WHERE p.ProductId = ?`, productId).Scan(&productCoef)
-	if err != nil {
-		return -1, fmt.Errorf("не найден коэффициент для продукта")
-	}
-
-	var defectPercentage float64
-	err = db.connect.QueryRow(`
-        SELECT DefectPercentage 
-        FROM MaterialTypes 
-        WHERE MaterialTypeId = ?`, materialId).Scan(&defectPercentage)
-	if err != nil {
-		return -1, fmt.Errorf("не найден процент брака для материала")
-	}
-
-	materialPerUnit := param1 * param2 * productCoef
-	totalMaterial := float64(quantity) * materialPerUnit
-	if defectPercentage > 0 {
-		totalMaterial = totalMaterial * (1 + defectPercentage/100)
-	}
-
-	return int(math.Ceil(totalMaterial)), nil
-}
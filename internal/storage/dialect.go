@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+const (
+	DriverSQLite   = "sqlite3"
+	DriverPostgres = "postgres"
+)
+
+// dialect описывает различия SQL между поддерживаемыми СУБД. Запросы в пакете пишутся
+// с плейсхолдерами "?", для PostgreSQL они заменяются на $1, $2, ...
+type dialect struct {
+	name   string
+	like   string // регистронезависимый LIKE
+	schema []string
+	// forShare и forUpdate блокируют прочитанные строки до конца транзакции. SQLite
+	// блокирует базу целиком при записи, поэтому там они пустые.
+	forShare, forUpdate string
+}
+
+var (
+	sqliteDialect   = dialect{name: DriverSQLite, like: "LIKE", schema: sqliteSchema}
+	postgresDialect = dialect{name: DriverPostgres, like: "ILIKE", schema: postgresSchema, forShare: " FOR SHARE", forUpdate: " FOR UPDATE"}
+)
+
+func (d dialect) rebind(query string) string {
+	if d.name != DriverPostgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	n, quoted := 0, false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// conn — *sql.DB, который переписывает плейсхолдеры под диалект.
+type conn struct {
+	*sql.DB
+	dialect dialect
+	keys    *keyring
+}
+
+func (c conn) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return c.DB.QueryContext(ctx, c.dialect.rebind(query), args...)
+}
+
+func (c conn) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return c.DB.QueryRowContext(ctx, c.dialect.rebind(query), args...)
+}
+
+func (c conn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return c.DB.ExecContext(ctx, c.dialect.rebind(query), args...)
+}
+
+func (c conn) BeginTx(ctx context.Context) (tx, error) {
+	t, err := c.DB.BeginTx(ctx, nil)
+	return tx{Tx: t, dialect: c.dialect, keys: c.keys}, err
+}
+
+// querier — методы чтения, общие для conn и tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	keyring() *keyring
+	// checkKey сверяет ключ в памяти с базой перед записью. Вне транзакции ничего не пишется,
+	// поэтому conn ключ не проверяет.
+	checkKey(ctx context.Context) error
+}
+
+func (c conn) keyring() *keyring {
+	return c.keys
+}
+
+func (c conn) checkKey(ctx context.Context) error {
+	return nil
+}
+
+type tx struct {
+	*sql.Tx
+	dialect dialect
+	keys    *keyring
+}
+
+func (t tx) keyring() *keyring {
+	return t.keys
+}
+
+func (t tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return t.Tx.QueryContext(ctx, t.dialect.rebind(query), args...)
+}
+
+func (t tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return t.Tx.QueryRowContext(ctx, t.dialect.rebind(query), args...)
+}
+
+func (t tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return t.Tx.ExecContext(ctx, t.dialect.rebind(query), args...)
+}
+
+// lockTable не дает другим транзакциям писать в table до конца t, а в PostgreSQL и другим
+// lockTable той же таблицы. Так проверка "строк еще нет" и следующая за ней вставка
+// выполняются без гонки. SQLite блокирует базу целиком: пустой UPDATE сразу берет блокировку
+// записи, и вторая транзакция ждет первую.
+func (t tx) lockTable(ctx context.Context, table string) error {
+	query := `UPDATE ` + table + ` SET rowid = rowid WHERE 0`
+	if t.dialect.name == DriverPostgres {
+		query = `LOCK TABLE ` + table + ` IN SHARE ROW EXCLUSIVE MODE`
+	}
+	_, err := t.ExecContext(ctx, query)
+	return err
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"github.com/ttrtcixy/demo/internal/mailaddr"
+	"github.com/ttrtcixy/demo/internal/models"
+)
+
+// getContactDuplicates выбирает всех остальных партнеров: email и телефон могут быть
+// зашифрованы, поэтому сравниваются после расшифровки, а не в SQL.
+var getContactDuplicates = `
+    SELECT
+        p.PartnerId, p.PartnerType, p.PartnerName, p.Director, p.Phone, p.Rating, p.Email, p.LegalAddress,
+        COALESCE(SUM(pp.Quantity), 0)
+    FROM
+        Partners p
+    LEFT JOIN
+        PartnerProducts pp ON p.PartnerId = pp.PartnerId
+    WHERE
+        p.PartnerId <> ?
+    GROUP BY
+        p.PartnerId, p.PartnerType, p.PartnerName, p.Director, p.Phone, p.Rating, p.Email, p.LegalAddress
+    ORDER BY
+        p.PartnerId`
+
+func (db *DB) FindContactDuplicates(ctx context.Context, partner models.Partner) ([]models.ContactDuplicate, error) {
+	email := mailaddr.Normalize(partner.Email)
+	if email == "" && partner.Phone == "" {
+		return nil, nil
+	}
+
+	rows, err := db.connect.QueryContext(ctx, getContactDuplicates, partner.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var duplicates []models.ContactDuplicate
+	for rows.Next() {
+		var p models.Partner
+		var total float64
+		if err := rows.Scan(&p.Id, &p.PartnerType, &p.CompanyName, &p.Director, &p.Phone, &p.Rating, &p.Email, &p.Address, &total); err != nil {
+			return nil, err
+		}
+		// Партнер, записанный другим ключом, не сравнивается, как и в GetPartners.
+		if err := db.connect.keys.openPartner(&p); errors.Is(err, ErrUndecryptable) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		sameEmail := email != "" && mailaddr.Normalize(p.Email) == email
+		samePhone := partner.Phone != "" && p.Phone == partner.Phone
+		if !sameEmail && !samePhone {
+			continue
+		}
+		p.Discount = DiscountPercentage(total)
+		duplicates = append(duplicates, models.ContactDuplicate{Partner: p, SameEmail: sameEmail, SamePhone: samePhone})
+	}
+	return duplicates, rows.Err()
+}
@@ -0,0 +1,455 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/ttrtcixy/demo/internal/fieldcrypt"
+	"github.com/ttrtcixy/demo/internal/models"
+	"sync"
+)
+
+// MinPassphraseLength — наименьшая длина пароля шифрования.
+const MinPassphraseLength = 8
+
+// keyCheck шифруется ключом и хранится в Encryption.KeyCheck: по нему проверяется пароль.
+const keyCheck = "demo-key-check"
+
+// EncryptionStatus — состояние шифрования личных данных партнеров.
+type EncryptionStatus string
+
+const (
+	// EncryptionOff — шифрование не включено, данные хранятся открыто.
+	EncryptionOff EncryptionStatus = "off"
+	// EncryptionLocked — данные зашифрованы, пароль еще не введен.
+	EncryptionLocked EncryptionStatus = "locked"
+	// EncryptionUnlocked — данные зашифрованы и доступны.
+	EncryptionUnlocked EncryptionStatus = "unlocked"
+)
+
+// keyring — ключ шифрования личных данных. Один на базу: conn и транзакции ссылаются на него,
+// поэтому разблокировка и смена ключа сразу видны всем запросам.
+type keyring struct {
+	mu      sync.RWMutex
+	enabled bool
+	key     *fieldcrypt.Key
+}
+
+func (k *keyring) status() EncryptionStatus {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	switch {
+	case !k.enabled:
+		return EncryptionOff
+	case k.key == nil:
+		return EncryptionLocked
+	default:
+		return EncryptionUnlocked
+	}
+}
+
+// writeKey возвращает ключ для записи: nil, если шифрование выключено.
+func (k *keyring) writeKey() (*fieldcrypt.Key, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	switch {
+	case !k.enabled:
+		return nil, nil
+	case k.key == nil:
+		return nil, ErrDatabaseLocked
+	default:
+		return k.key, nil
+	}
+}
+
+// personalFields — поля партнера с личными данными (152-ФЗ), которые хранятся зашифрованными.
+func personalFields(p *models.Partner) []*string {
+	return []*string{&p.Director, &p.Phone, &p.Email, &p.Address}
+}
+
+// sealPartner возвращает партнера с зашифрованными личными данными для записи в базу.
+func (k *keyring) sealPartner(p models.Partner) (models.Partner, error) {
+	key, err := k.writeKey()
+	if err != nil || key == nil {
+		return p, err
+	}
+	for _, field := range personalFields(&p) {
+		if *field, err = key.Encrypt(*field); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// checkKey сверяет ключ в памяти с таблицей Encryption перед записью личных данных. Другой
+// клиент общей базы мог включить шифрование или сменить пароль: строку, записанную старым
+// ключом, потом никто не расшифрует. Тогда ключ забывается, и база снова требует пароль.
+func (t tx) checkKey(ctx context.Context) error {
+	_, check, enabled, err := readEncryption(ctx, t, t.dialect.forShare)
+	if err != nil {
+		return err
+	}
+
+	k := t.keys
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if !enabled {
+		k.enabled, k.key = false, nil
+		return nil
+	}
+	k.enabled = true
+	if k.key == nil || !verifyKey(k.key, check) {
+		k.key = nil
+		return ErrDatabaseLocked
+	}
+	return nil
+}
+
+// sealPartner шифрует личные данные партнера для записи в транзакции t, проверив ключ.
+func (t tx) sealPartner(ctx context.Context, p models.Partner) (models.Partner, error) {
+	if err := t.checkKey(ctx); err != nil {
+		return p, err
+	}
+	return t.keys.sealPartner(p)
+}
+
+// sealAuditJSON шифрует снимок партнера для журнала в транзакции t, проверив ключ.
+func (t tx) sealAuditJSON(ctx context.Context, data string) (string, error) {
+	if data == "" {
+		return "", nil
+	}
+	if err := t.checkKey(ctx); err != nil {
+		return "", err
+	}
+	return t.keys.sealAuditJSON(data)
+}
+
+// openPartner расшифровывает личные данные прочитанного партнера. Открытые значения,
+// записанные до включения шифрования, не меняются.
+func (k *keyring) openPartner(p *models.Partner) error {
+	k.mu.RLock()
+	key := k.key
+	k.mu.RUnlock()
+
+	for _, field := range personalFields(p) {
+		if !fieldcrypt.IsEncrypted(*field) {
+			continue
+		}
+		if key == nil {
+			return ErrDatabaseLocked
+		}
+		plain, err := key.Decrypt(*field)
+		if err != nil {
+			return ErrUndecryptable
+		}
+		*field = plain
+	}
+	return nil
+}
+
+// sealAuditJSON шифрует личные данные в снимке партнера для журнала.
+func (k *keyring) sealAuditJSON(data string) (string, error) {
+	if data == "" {
+		return "", nil
+	}
+	var p models.Partner
+	if err := json.Unmarshal([]byte(data), &p); err != nil {
+		return "", err
+	}
+	sealed, err := k.sealPartner(p)
+	if err != nil {
+		return "", err
+	}
+	return AuditJSON(sealed)
+}
+
+// openAuditJSON расшифровывает снимок партнера из журнала. Снимок без зашифрованных полей
+// возвращается без изменений.
+func (k *keyring) openAuditJSON(data string) (string, error) {
+	if data == "" {
+		return "", nil
+	}
+	var p models.Partner
+	if err := json.Unmarshal([]byte(data), &p); err != nil {
+		return "", err
+	}
+	encrypted := false
+	for _, field := range personalFields(&p) {
+		encrypted = encrypted || fieldcrypt.IsEncrypted(*field)
+	}
+	if !encrypted {
+		return data, nil
+	}
+	if err := k.openPartner(&p); err != nil {
+		return "", err
+	}
+	return AuditJSON(p)
+}
+
+var (
+	getEncryption    = `SELECT Salt, KeyCheck FROM Encryption WHERE Id = 1`
+	addEncryption    = `insert into Encryption(Id, Salt, KeyCheck) values(1, ?, ?)`
+	updateEncryption = `update Encryption set Salt = ?, KeyCheck = ? where Id = 1`
+)
+
+// readEncryption возвращает соль и проверочное значение ключа; ok = false, если шифрование
+// не включено. lock — блокировка строки из dialect для чтения внутри транзакции.
+func readEncryption(ctx context.Context, q querier, lock string) (salt []byte, check string, ok bool, err error) {
+	var encodedSalt string
+	err = q.QueryRowContext(ctx, getEncryption+lock).Scan(&encodedSalt, &check)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, "", false, nil
+	}
+	if err != nil {
+		return nil, "", false, err
+	}
+	salt, err = base64.StdEncoding.DecodeString(encodedSalt)
+	return salt, check, true, err
+}
+
+// verifyKey сообщает, что check зашифрован ключом key.
+func verifyKey(key *fieldcrypt.Key, check string) bool {
+	plain, err := key.Decrypt(check)
+	return err == nil && plain == keyCheck && fieldcrypt.IsEncrypted(check)
+}
+
+// loadEncryption читает состояние шифрования при открытии базы и после восстановления из копии.
+// Ключ сохраняется, только если подходит к базе: у копии может быть другой пароль.
+func (db *DB) loadEncryption(ctx context.Context) error {
+	_, check, enabled, err := readEncryption(ctx, db.connect, "")
+	if err != nil {
+		return err
+	}
+
+	keys := db.connect.keys
+	keys.mu.Lock()
+	defer keys.mu.Unlock()
+	keys.enabled = enabled
+	if !enabled || (keys.key != nil && !verifyKey(keys.key, check)) {
+		keys.key = nil
+	}
+	return nil
+}
+
+func (db *DB) EncryptionStatus(ctx context.Context) (EncryptionStatus, error) {
+	return db.connect.keys.status(), nil
+}
+
+// Unlock проверяет пароль и открывает доступ к зашифрованным данным.
+func (db *DB) Unlock(ctx context.Context, passphrase string) error {
+	salt, check, enabled, err := readEncryption(ctx, db.connect, "")
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return ErrEncryptionOff
+	}
+	key, err := unlockKey(passphrase, salt, check)
+	if err != nil {
+		return err
+	}
+
+	keys := db.connect.keys
+	keys.mu.Lock()
+	defer keys.mu.Unlock()
+	keys.enabled = true
+	keys.key = key
+	return nil
+}
+
+func unlockKey(passphrase string, salt []byte, check string) (*fieldcrypt.Key, error) {
+	key, err := fieldcrypt.DeriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	if !verifyKey(key, check) {
+		return nil, ErrWrongPassphrase
+	}
+	return key, nil
+}
+
+// newKey создает ключ из пароля со случайной солью и возвращает соль и проверочное значение
+// для таблицы Encryption.
+func newKey(passphrase string) (key *fieldcrypt.Key, salt string, check string, err error) {
+	if len([]rune(passphrase)) < MinPassphraseLength {
+		return nil, "", "", ErrShortPassphrase
+	}
+	rawSalt, err := fieldcrypt.NewSalt()
+	if err != nil {
+		return nil, "", "", err
+	}
+	if key, err = fieldcrypt.DeriveKey(passphrase, rawSalt); err != nil {
+		return nil, "", "", err
+	}
+	if check, err = key.Encrypt(keyCheck); err != nil {
+		return nil, "", "", err
+	}
+	return key, base64.StdEncoding.EncodeToString(rawSalt), check, nil
+}
+
+// EnableEncryption включает шифрование: личные данные партнеров, в том числе в журнале,
+// шифруются ключом из passphrase. Вызывается при первом запуске, до входа пользователя.
+func (db *DB) EnableEncryption(ctx context.Context, passphrase string) error {
+	key, salt, check, err := newKey(passphrase)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.connect.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, _, enabled, err := readEncryption(ctx, tx, tx.dialect.forUpdate); err != nil || enabled {
+		if err == nil {
+			err = ErrEncryptionEnabled
+		}
+		return err
+	}
+	if err := reencrypt(ctx, tx, nil, key); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, addEncryption, salt, check); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	keys := db.connect.keys
+	keys.mu.Lock()
+	defer keys.mu.Unlock()
+	keys.enabled = true
+	keys.key = key
+	return nil
+}
+
+// RotateKey заменяет пароль шифрования и перешифровывает все личные данные новым ключом
+// в одной транзакции. Текущий пароль нужно ввести заново, даже если база разблокирована.
+func (db *DB) RotateKey(ctx context.Context, current, next string) error {
+	if err := Authorize(ctx, models.PermManageEncryption); err != nil {
+		return err
+	}
+	key, salt, check, err := newKey(next)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.connect.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Строка блокируется до конца транзакции: другие клиенты общей базы проверяют по ней
+	// ключ перед записью и дождутся нового проверочного значения.
+	oldSalt, oldCheck, enabled, err := readEncryption(ctx, tx, tx.dialect.forUpdate)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return ErrEncryptionOff
+	}
+	oldKey, err := unlockKey(current, oldSalt, oldCheck)
+	if err != nil {
+		return err
+	}
+	if err := reencrypt(ctx, tx, oldKey, key); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, updateEncryption, salt, check); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	keys := db.connect.keys
+	keys.mu.Lock()
+	defer keys.mu.Unlock()
+	keys.key = key
+	return nil
+}
+
+var (
+	getPartnerPersonal = `SELECT PartnerId, COALESCE(Director, ''), COALESCE(Phone, ''), COALESCE(Email, ''), COALESCE(LegalAddress, '') FROM Partners`
+	setPartnerPersonal = `update Partners set Director = ?, Phone = ?, Email = ?, LegalAddress = ? where PartnerId = ?`
+	getPartnerAudit    = `SELECT AuditId, COALESCE(BeforeData, ''), COALESCE(AfterData, '') FROM AuditLog WHERE Entity = ?`
+)
+
+// reencrypt перешифровывает личные данные партнеров и их снимки в журнале с ключа from
+// на ключ to. from = nil — данные еще не зашифрованы.
+func reencrypt(ctx context.Context, t tx, from, to *fieldcrypt.Key) error {
+	old := &keyring{enabled: from != nil, key: from}
+	next := &keyring{enabled: true, key: to}
+
+	var partners []models.Partner
+	rows, err := t.QueryContext(ctx, getPartnerPersonal)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var p models.Partner
+		if err := rows.Scan(&p.Id, &p.Director, &p.Phone, &p.Email, &p.Address); err != nil {
+			rows.Close()
+			return err
+		}
+		partners = append(partners, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, p := range partners {
+		if err := old.openPartner(&p); err != nil {
+			return err
+		}
+		sealed, err := next.sealPartner(p)
+		if err != nil {
+			return err
+		}
+		if _, err := t.ExecContext(ctx, setPartnerPersonal, sealed.Director, sealed.Phone, sealed.Email, sealed.Address, p.Id); err != nil {
+			return err
+		}
+	}
+
+	type auditData struct {
+		id            int
+		before, after string
+	}
+	var entries []auditData
+	rows, err = t.QueryContext(ctx, getPartnerAudit, models.EntityPartner)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var e auditData
+		if err := rows.Scan(&e.id, &e.before, &e.after); err != nil {
+			rows.Close()
+			return err
+		}
+		entries = append(entries, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		for _, data := range []*string{&e.before, &e.after} {
+			plain, err := old.openAuditJSON(*data)
+			if err != nil {
+				return err
+			}
+			if *data, err = next.sealAuditJSON(plain); err != nil {
+				return err
+			}
+		}
+		if _, err := t.ExecContext(ctx, setAuditData, e.before, e.after, e.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/ttrtcixy/demo/internal/models"
+	"strconv"
+	"strings"
+)
+
+// Error — ошибка хранилища, которую можно показать пользователю. Code — идентификатор
+// сообщения в каталогах перевода интерфейса, Data — параметры сообщения. Error возвращает
+// текст на русском для журнала приложения.
+type Error struct {
+	Code string
+	Data map[string]any
+	Err  error // уточняющая ошибка, например ошибка расчета строки плана
+
+	msg string
+}
+
+func newError(code, msg string) *Error {
+	return &Error{Code: code, msg: msg}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.msg + ": " + e.Err.Error()
+	}
+	return e.msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) MessageID() string {
+	return e.Code
+}
+
+func (e *Error) MessageData() map[string]any {
+	return e.Data
+}
+
+var (
+	ErrPartnersNoFound    = newError("error.partners_not_found", "партнеры не найдены")
+	ErrPartnerNotFound    = newError("error.partner_not_found", "партнер не найден")
+	ErrPlanNotFound       = newError("error.plan_not_found", "план не найден")
+	ErrContactNotFound    = newError("error.contact_not_found", "контактное лицо не найдено")
+	ErrContactNameEmpty   = newError("error.contact_name_empty", "имя контактного лица не может быть пустым")
+	ErrContactRole        = newError("error.contact_role", "неизвестная роль контактного лица")
+	ErrEmptyNote          = newError("error.empty_note", "заметка не может быть пустой")
+	ErrEmptyAttachment    = newError("error.empty_attachment", "файл пуст")
+	ErrAttachmentNotFound = newError("error.attachment_not_found", "файл не найден")
+	ErrAttachmentCorrupt  = newError("error.attachment_corrupt", "содержимое файла не совпадает с сохраненной контрольной суммой")
+	ErrSuggestionOutdated = newError("error.suggestion_outdated", "рейтинг партнера изменился после расчета предложения")
+	ErrNoCoefficient      = newError("error.no_product_coefficient", "не найден коэффициент для продукта")
+	ErrNoDefectPercentage = newError("error.no_defect_percentage", "не найден процент брака для материала")
+	ErrZeroConsumption    = newError("error.zero_consumption", "расход материала на единицу продукции должен быть больше нуля")
+
+	ErrNotAuthenticated   = newError("error.not_authenticated", "пользователь не авторизован")
+	ErrForbidden          = newError("error.forbidden", "недостаточно прав для операции")
+	ErrInvalidCredentials = newError("error.invalid_credentials", "неверный логин или пароль")
+	ErrUserExists         = newError("error.user_exists", "пользователь с таким логином уже существует")
+	ErrEmptyLogin         = newError("error.empty_login", "логин не может быть пустым")
+	ErrShortPassword      = newError("error.short_password", "пароль должен содержать не меньше 6 символов")
+	ErrDeleteSelf         = newError("error.delete_self", "нельзя удалить собственную учетную запись")
+	ErrBackupUnsupported  = newError("error.backup_unsupported", "резервное копирование поддерживается только для SQLite")
+
+	ErrDatabaseLocked    = newError("error.database_locked", "личные данные зашифрованы: база не разблокирована паролем")
+	ErrWrongPassphrase   = newError("error.wrong_passphrase", "неверный пароль шифрования")
+	ErrEncryptionEnabled = newError("error.encryption_enabled", "шифрование уже включено")
+	ErrEncryptionOff     = newError("error.encryption_off", "шифрование не включено")
+)
+
+// ErrRatingOutOfRange — рейтинг вне шкалы от models.MinRating до models.MaxRating.
+var ErrRatingOutOfRange = &Error{
+	Code: "error.rating_out_of_range",
+	Data: map[string]any{"Min": models.MinRating, "Max": models.MaxRating},
+	msg:  fmt.Sprintf("рейтинг должен быть от %d до %d", models.MinRating, models.MaxRating),
+}
+
+// ErrShortPassphrase — пароль шифрования короче MinPassphraseLength.
+var ErrShortPassphrase = &Error{
+	Code: "error.short_passphrase",
+	Data: map[string]any{"Min": MinPassphraseLength},
+	msg:  fmt.Sprintf("пароль шифрования должен содержать не меньше %d символов", MinPassphraseLength),
+}
+
+// MaxAttachmentSize — наибольший размер файла партнера.
+const MaxAttachmentSize = 20 << 20
+
+// ErrAttachmentTooLarge — файл больше MaxAttachmentSize.
+var ErrAttachmentTooLarge = &Error{
+	Code: "error.attachment_too_large",
+	Data: map[string]any{"MaxMB": MaxAttachmentSize >> 20},
+	msg:  fmt.Sprintf("файл больше %d МБ", MaxAttachmentSize>>20),
+}
+
+// ErrAttachmentExists — у партнера уже есть файл с тем же содержимым.
+func ErrAttachmentExists(fileName string) error {
+	return &Error{Code: "error.attachment_exists", Data: map[string]any{"Name": fileName}, msg: "такой файл уже загружен: " + fileName}
+}
+
+// ErrBackupInvalid — файл нельзя открыть как базу SQLite или он поврежден.
+func ErrBackupInvalid(err error) error {
+	return &Error{Code: "error.backup_invalid", Err: err, msg: "файл не является целой базой данных"}
+}
+
+// ErrBackupMissingTable — в файле нет таблицы приложения, значит это не его копия.
+func ErrBackupMissingTable(table string) error {
+	return &Error{Code: "error.backup_missing_table", Data: map[string]any{"Table": table}, msg: "в файле нет таблицы " + table}
+}
+
+// ErrUndecryptable — личные данные не расшифровываются ключом базы: их записали ключом,
+// который уже заменен.
+var ErrUndecryptable = newError("error.undecryptable", "личные данные не расшифровываются текущим ключом")
+
+// ErrPartnersUndecryptable перечисляет партнеров, чьи личные данные не расшифровываются.
+// GetPartners возвращает ее вместе со списком, в котором у этих партнеров личные данные пустые.
+func ErrPartnersUndecryptable(names []string) error {
+	list := strings.Join(names, ", ")
+	return &Error{Code: "error.partners_undecryptable", Data: map[string]any{"Names": list}, Err: ErrUndecryptable, msg: "партнеры " + list}
+}
+
+// ErrUnknownRole — роль пользователя не из models.Roles.
+func ErrUnknownRole(role string) error {
+	return &Error{Code: "error.unknown_role", Data: map[string]any{"Role": role}, msg: "неизвестная роль: " + role}
+}
+
+// paramError — значение параметра расчета вне допустимого диапазона.
+func paramError(code, msg string, data map[string]any) error {
+	return &Error{Code: code, Data: data, msg: msg}
+}
+
+// planRowError уточняет, в какой строке плана не удался расчет.
+func planRowError(row int, err error) error {
+	return &Error{Code: "error.plan_row", Data: map[string]any{"Row": row}, Err: err, msg: "строка " + strconv.Itoa(row)}
+}
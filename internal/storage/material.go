@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"github.com/ttrtcixy/demo/internal/models"
+	"math"
+)
+
+var getProductCoefficient = `
+        SELECT p.ProductId, p.ProductName, pt.Coefficient
+        FROM ProductTypes pt
+        JOIN Products p ON pt.ProductTypeId = p.ProductTypeId
+        WHERE p.ProductId = ?`
+
+var getMaterialDefect = `
+        SELECT MaterialTypeId, MaterialType, DefectPercentage
+        FROM MaterialTypes
+        WHERE MaterialTypeId = ?`
+
+// newCalculation заполняет продукт, материал и коэффициенты, на которых строится расчет.
+func (db *DB) newCalculation(ctx context.Context, productId, materialId string) (models.MaterialCalculation, error) {
+	var calc models.MaterialCalculation
+
+	err := db.connect.QueryRowContext(ctx, getProductCoefficient, productId).Scan(&calc.ProductId, &calc.ProductName, &calc.ProductCoefficient)
+	if ctx.Err() != nil {
+		return calc, ctx.Err()
+	}
+	if err != nil {
+		return calc, ErrNoCoefficient
+	}
+
+	err = db.connect.QueryRowContext(ctx, getMaterialDefect, materialId).Scan(&calc.MaterialTypeId, &calc.MaterialType, &calc.DefectPercentage)
+	if ctx.Err() != nil {
+		return calc, ctx.Err()
+	}
+	if err != nil {
+		return calc, ErrNoDefectPercentage
+	}
+
+	return calc, nil
+}
+
+func (db *DB) CalculateMaterial(ctx context.Context, productId, materialId string, quantity int, param1, param2 float64) (models.MaterialCalculation, error) {
+	calc, err := db.newCalculation(ctx, productId, materialId)
+	if err != nil {
+		return calc, err
+	}
+	if err := db.validateParams(ctx, productId, param1, param2); err != nil {
+		return calc, err
+	}
+
+	return ComputeMaterial(calc, quantity, param1, param2), nil
+}
+
+// ComputeMaterial выполняет расчет по формуле. В calc должны быть заполнены продукт, материал
+// и их коэффициенты; общая часть для всех реализаций MaterialCalculator.
+func ComputeMaterial(calc models.MaterialCalculation, quantity int, param1, param2 float64) models.MaterialCalculation {
+	calc.Quantity = quantity
+	calc.Param1 = param1
+	calc.Param2 = param2
+
+	calc.MaterialPerUnit = param1 * param2 * calc.ProductCoefficient
+	calc.BaseTotal = float64(quantity) * calc.MaterialPerUnit
+	totalMaterial := calc.BaseTotal
+	if calc.DefectPercentage > 0 {
+		totalMaterial = totalMaterial * (1 + calc.DefectPercentage/100)
+	}
+	calc.DefectAllowance = totalMaterial - calc.BaseTotal
+	calc.Required = int(math.Ceil(totalMaterial))
+	calc.Rounding = float64(calc.Required) - totalMaterial
+
+	return calc
+}
+
+// CalculateMaxProducts решает обратную задачу к CalculateMaterial: сколько целых единиц продукции
+// можно произвести из available единиц материала с учетом процента брака.
+func (db *DB) CalculateMaxProducts(ctx context.Context, productId, materialId string, available int, param1, param2 float64) (int, error) {
+	calc, err := db.newCalculation(ctx, productId, materialId)
+	if err != nil {
+		return -1, err
+	}
+	if err := db.validateParams(ctx, productId, param1, param2); err != nil {
+		return -1, err
+	}
+
+	return ComputeMaxProducts(calc, available, param1, param2)
+}
+
+// ComputeMaxProducts — обратная формула к ComputeMaterial.
+func ComputeMaxProducts(calc models.MaterialCalculation, available int, param1, param2 float64) (int, error) {
+	materialPerUnit := param1 * param2 * calc.ProductCoefficient
+	if calc.DefectPercentage > 0 {
+		materialPerUnit = materialPerUnit * (1 + calc.DefectPercentage/100)
+	}
+	if materialPerUnit <= 0 {
+		return -1, ErrZeroConsumption
+	}
+	if available <= 0 {
+		return 0, nil
+	}
+
+	quantity := int(math.Floor(float64(available) / materialPerUnit))
+	// Поправка на погрешность float: результат должен совпадать с прямым расчетом.
+	for quantity > 0 && int(math.Ceil(float64(quantity)*materialPerUnit)) > available {
+		quantity--
+	}
+	for int(math.Ceil(float64(quantity+1)*materialPerUnit)) <= available {
+		quantity++
+	}
+
+	return quantity, nil
+}
+
+var addCalculation = `insert into MaterialCalculations(ProductId, ProductName, MaterialTypeId, MaterialType, Quantity, Param1, Param2,
+    ProductCoefficient, DefectPercentage, MaterialPerUnit, BaseTotal, DefectAllowance, Rounding, Required)
+    values(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) returning CalculationId`
+
+func (db *DB) SaveCalculation(ctx context.Context, calc models.MaterialCalculation) error {
+	if err := Authorize(ctx, models.PermSaveCalculations); err != nil {
+		return err
+	}
+
+	args := []any{calc.ProductId, calc.ProductName, calc.MaterialTypeId, calc.MaterialType, calc.Quantity, calc.Param1, calc.Param2,
+		calc.ProductCoefficient, calc.DefectPercentage, calc.MaterialPerUnit, calc.BaseTotal, calc.DefectAllowance, calc.Rounding, calc.Required}
+	query := Query{query: addCalculation, args: args}
+
+	tx, err := db.connect.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, query.query, query.args...).Scan(&calc.Id); err != nil {
+		return err
+	}
+	if err := tx.audit(ctx, models.EntityCalculation, calc.Id, models.AuditCreate, nil, calc); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+var getCalculations = `
+    SELECT
+        CalculationId, ProductId, ProductName, MaterialTypeId, MaterialType, Quantity, Param1, Param2,
+        ProductCoefficient, DefectPercentage, MaterialPerUnit, BaseTotal, DefectAllowance, Rounding, Required, CreatedAt
+    FROM
+        MaterialCalculations
+    ORDER BY
+        CalculationId DESC`
+
+func (db *DB) GetCalculationHistory(ctx context.Context) ([]models.MaterialCalculation, error) {
+	rows, err := db.connect.QueryContext(ctx, getCalculations)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения истории расчетов: %v", err)
+	}
+	defer rows.Close()
+
+	var history []models.MaterialCalculation
+	for rows.Next() {
+		var c models.MaterialCalculation
+		err := rows.Scan(&c.Id, &c.ProductId, &c.ProductName, &c.MaterialTypeId, &c.MaterialType, &c.Quantity, &c.Param1, &c.Param2,
+			&c.ProductCoefficient, &c.DefectPercentage, &c.MaterialPerUnit, &c.BaseTotal, &c.DefectAllowance, &c.Rounding, &c.Required, &c.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки: %v", err)
+		}
+		history = append(history, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при обработке результатов: %v", err)
+	}
+
+	return history, nil
+}
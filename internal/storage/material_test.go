@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"github.com/ttrtcixy/demo/internal/models"
+	"testing"
+)
+
+func TestComputeMaterial(t *testing.T) {
+	tests := []struct {
+		name                string
+		coefficient, defect float64
+		quantity            int
+		param1, param2      float64
+		wantRequired        int
+	}{
+		{name: "без брака", coefficient: 2.35, quantity: 4, param1: 0.5, param2: 2, wantRequired: 10},
+		{name: "с браком", coefficient: 4.34, defect: 0.1, quantity: 10, param1: 2, param2: 3, wantRequired: 261},
+		{name: "целый результат", coefficient: 1, quantity: 5, param1: 2, param2: 1, wantRequired: 10},
+		{name: "нулевое количество", coefficient: 5.15, defect: 0.95, quantity: 0, param1: 1, param2: 1, wantRequired: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calc := models.MaterialCalculation{ProductCoefficient: tt.coefficient, DefectPercentage: tt.defect}
+			got := ComputeMaterial(calc, tt.quantity, tt.param1, tt.param2)
+			if got.Required != tt.wantRequired {
+				t.Fatalf("Required = %d, ожидалось %d", got.Required, tt.wantRequired)
+			}
+			if got.Rounding < 0 || got.Rounding >= 1 {
+				t.Errorf("Rounding = %v вне [0, 1)", got.Rounding)
+			}
+		})
+	}
+}
+
+// TestComputeMaxProductsInverse проверяет, что обратный расчет согласован с прямым
+// на сетке значений, где ошибка округления float наиболее вероятна.
+func TestComputeMaxProductsInverse(t *testing.T) {
+	for _, coefficient := range []float64{1, 2.35, 4.34, 5.15, 5.45} {
+		for _, defect := range []float64{0, 0.1, 0.95, 0.55} {
+			calc := models.MaterialCalculation{ProductCoefficient: coefficient, DefectPercentage: defect}
+			for available := 0; available <= 500; available += 7 {
+				quantity, err := ComputeMaxProducts(calc, available, 0.5, 2)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ComputeMaterial(calc, quantity, 0.5, 2).Required > available ||
+					ComputeMaterial(calc, quantity+1, 0.5, 2).Required <= available {
+					t.Fatalf("коэффициент %v, брак %v, доступно %d: результат %d не максимален", coefficient, defect, available, quantity)
+				}
+			}
+		}
+	}
+}
+
+func TestComputeMaxProductsZeroConsumption(t *testing.T) {
+	calc := models.MaterialCalculation{ProductCoefficient: 0}
+	if _, err := ComputeMaxProducts(calc, 100, 1, 1); err == nil {
+		t.Fatal("ожидалась ошибка при нулевом расходе материала")
+	}
+}
+
+// rowCalculator возвращает для каждой строки плана потребность amount без брака.
+type rowCalculator struct {
+	MaterialCalculator
+	amount float64
+}
+
+func (c rowCalculator) CalculateMaterial(ctx context.Context, productId, materialId string, quantity int, param1, param2 float64) (models.MaterialCalculation, error) {
+	return models.MaterialCalculation{BaseTotal: c.amount}, nil
+}
+
+func TestCalculatePlanRounding(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount float64
+		rows   int
+		want   int
+	}{
+		// Сумма 30 раз по 0.1 во float64 — 3.0000000000000013.
+		{name: "погрешность float64", amount: 0.1, rows: 30, want: 3},
+		{name: "доля единицы сверх целого", amount: 1.000001, rows: 2, want: 3},
+		{name: "меньше единицы", amount: 0.000001, rows: 1, want: 1},
+	}
+
+	for _, tt := range tests {
+		plan := models.ProductionPlan{Rows: make([]models.ProductionPlanRow, tt.rows)}
+		for i := range plan.Rows {
+			plan.Rows[i].MaterialTypeId = 1
+		}
+		got, err := CalculatePlan(t.Context(), rowCalculator{amount: tt.amount}, plan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].Quantity != tt.want {
+			t.Errorf("%s: CalculatePlan = %+v, ожидалось %d", tt.name, got, tt.want)
+		}
+	}
+}
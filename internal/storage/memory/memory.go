@@ -0,0 +1,1523 @@
+// Package memory — реализация интерфейсов хранилища в памяти для тестов.
+// Повторяет поведение storage.DB, включая расчет скидок и материалов.
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ttrtcixy/demo/internal/mailaddr"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/money"
+	"github.com/ttrtcixy/demo/internal/phone"
+	"github.com/ttrtcixy/demo/internal/storage"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type ProductType struct {
+	Id          int
+	Name        string
+	Coefficient float64
+}
+
+type Product struct {
+	Id            int
+	ProductTypeId int
+	Name          string
+	MinCost       money.Money
+}
+
+type MaterialType struct {
+	Id               int
+	Name             string
+	DefectPercentage float64
+}
+
+type Sale struct {
+	PartnerId int
+	ProductId int
+	Quantity  int
+	SaleDate  string
+}
+
+// Store хранит данные в памяти. Справочники, партнеры и продажи можно заполнить напрямую через
+// экспортируемые поля до начала работы.
+type Store struct {
+	mu sync.Mutex
+
+	ProductTypes  []ProductType
+	Products      []Product
+	MaterialTypes []MaterialType
+	Parameters    []models.ProductParameter
+	Partners      []models.Partner
+	Sales         []Sale
+	Contacts      []models.Contact
+
+	plans        []models.ProductionPlan
+	calculations []models.MaterialCalculation
+	audit        []models.AuditEntry
+	ratings      []models.RatingChange
+	rejections   []rejection
+	users        []user
+	notes        []models.Note
+	attachments  []attachment
+	passphrase   string
+	lastId       int
+}
+
+// attachment — файл партнера вместе с содержимым.
+type attachment struct {
+	models.Attachment
+	data []byte
+}
+
+// rejection — отклоненное предложение рейтинга.
+type rejection struct {
+	partnerId, current, suggested int
+}
+
+type user struct {
+	models.User
+	passwordHash string
+}
+
+func NewStore() *Store {
+	return &Store{}
+}
+
+var (
+	_ storage.PartnerRepository    = (*Store)(nil)
+	_ storage.SalesRepository      = (*Store)(nil)
+	_ storage.DashboardRepository  = (*Store)(nil)
+	_ storage.RatingRepository     = (*Store)(nil)
+	_ storage.ContactRepository    = (*Store)(nil)
+	_ storage.NoteRepository       = (*Store)(nil)
+	_ storage.CatalogRepository    = (*Store)(nil)
+	_ storage.MaterialCalculator   = (*Store)(nil)
+	_ storage.PlanRepository       = (*Store)(nil)
+	_ storage.AuditRepository      = (*Store)(nil)
+	_ storage.UserRepository       = (*Store)(nil)
+	_ storage.EncryptionRepository = (*Store)(nil)
+	_ storage.PrivacyRepository    = (*Store)(nil)
+)
+
+// lock захватывает хранилище, если операция еще не отменена.
+func (s *Store) lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	return nil
+}
+
+func (s *Store) nextId() int {
+	s.lastId++
+	return s.lastId
+}
+
+func now() string {
+	return time.Now().UTC().Format("2006-01-02 15:04:05")
+}
+
+// GetPartners, как и запрос в storage.DB, возвращает только партнеров с продажами.
+func (s *Store) GetPartners(ctx context.Context) (*models.Partners, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	totals := map[int]float64{}
+	for _, sale := range s.Sales {
+		totals[sale.PartnerId] += float64(sale.Quantity)
+	}
+
+	partners := models.Partners{}
+	for _, p := range s.Partners {
+		total, ok := totals[p.Id]
+		if !ok {
+			continue
+		}
+		p.Discount = storage.DiscountPercentage(total)
+		partners = append(partners, p)
+	}
+
+	if len(partners) == 0 {
+		return &models.Partners{}, storage.ErrPartnersNoFound
+	}
+	return &partners, nil
+}
+
+func (s *Store) AddPartner(ctx context.Context, partner models.Partner) error {
+	if err := storage.Authorize(ctx, models.PermEditPartners); err != nil {
+		return err
+	}
+	if err := storage.ValidateRating(partner.Rating); err != nil {
+		return err
+	}
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	if partner.CompanyName == "" {
+		return errors.New("NOT NULL constraint failed: Partners.PartnerName")
+	}
+	// Как AUTOINCREMENT: новый идентификатор больше всех существующих.
+	partner.Id = 1
+	for _, p := range s.Partners {
+		if p.Id >= partner.Id {
+			partner.Id = p.Id + 1
+		}
+	}
+	partner.Discount = 0
+	s.Partners = append(s.Partners, partner)
+	return s.record(ctx, models.EntityPartner, partner.Id, models.AuditCreate, nil, partner)
+}
+
+func (s *Store) UpdatePartner(ctx context.Context, partner models.Partner) error {
+	if err := storage.Authorize(ctx, models.PermEditPartners); err != nil {
+		return err
+	}
+	if err := storage.ValidateRating(partner.Rating); err != nil {
+		return err
+	}
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	for i := range s.Partners {
+		if s.Partners[i].Id == partner.Id {
+			before := s.Partners[i]
+			partner.Discount = 0
+			s.Partners[i] = partner
+			s.recordRating(ctx, partner.Id, before.Rating, partner.Rating, models.RatingReasonManual)
+			return s.record(ctx, models.EntityPartner, partner.Id, models.AuditUpdate, before, partner)
+		}
+	}
+	return nil
+}
+
+func (s *Store) DeletePartner(ctx context.Context, id int) error {
+	if err := storage.Authorize(ctx, models.PermDeletePartners); err != nil {
+		return err
+	}
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	var before *models.Partner
+	for i := range s.Partners {
+		if s.Partners[i].Id == id {
+			p := s.Partners[i]
+			before = &p
+			s.Partners = append(s.Partners[:i], s.Partners[i+1:]...)
+			break
+		}
+	}
+	if before == nil {
+		return nil
+	}
+
+	// У продаж в памяти нет идентификатора, в журнал попадает номер строки в Sales.
+	var removed []models.Sale
+	sales := s.Sales[:0]
+	for i, sale := range s.Sales {
+		if sale.PartnerId != id {
+			sales = append(sales, sale)
+			continue
+		}
+		var productName string
+		if product, ok := s.product(sale.ProductId); ok {
+			productName = product.Name
+		}
+		removed = append(removed, models.Sale{Id: i + 1, PartnerId: id, ProductName: productName, Quantity: sale.Quantity, SaleDate: sale.SaleDate})
+	}
+	s.Sales = sales
+
+	contacts := s.Contacts[:0]
+	for _, c := range s.Contacts {
+		if c.PartnerId != id {
+			contacts = append(contacts, c)
+		}
+	}
+	s.Contacts = contacts
+	s.notes = slices.DeleteFunc(s.notes, func(n models.Note) bool { return n.PartnerId == id })
+	s.attachments = slices.DeleteFunc(s.attachments, func(f attachment) bool { return f.PartnerId == id })
+
+	ratings := s.ratings[:0]
+	for _, c := range s.ratings {
+		if c.PartnerId != id {
+			ratings = append(ratings, c)
+		}
+	}
+	s.ratings = ratings
+	rejections := s.rejections[:0]
+	for _, r := range s.rejections {
+		if r.partnerId != id {
+			rejections = append(rejections, r)
+		}
+	}
+	s.rejections = rejections
+	for _, sale := range removed {
+		if err := s.record(ctx, models.EntitySale, sale.Id, models.AuditDelete, sale, nil); err != nil {
+			return err
+		}
+	}
+	return s.record(ctx, models.EntityPartner, id, models.AuditDelete, before, nil)
+}
+
+func (s *Store) UnparsedPhones(ctx context.Context) ([]storage.PhoneProblem, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	var problems []storage.PhoneProblem
+	for _, p := range s.Partners {
+		if p.Phone == "" {
+			continue
+		}
+		if _, err := phone.Parse(p.Phone); err != nil {
+			problems = append(problems, storage.PhoneProblem{PartnerId: p.Id, PartnerName: p.CompanyName, Phone: p.Phone})
+		}
+	}
+	return problems, nil
+}
+
+func (s *Store) FindPartner(ctx context.Context, searchTerm string) (int, string, error) {
+	if err := s.lock(ctx); err != nil {
+		return 0, "", err
+	}
+	defer s.mu.Unlock()
+
+	if id, err := strconv.Atoi(searchTerm); err == nil {
+		for _, p := range s.Partners {
+			if p.Id == id {
+				return p.Id, p.CompanyName, nil
+			}
+		}
+	}
+
+	term := strings.ToLower(searchTerm)
+	for _, p := range s.Partners {
+		if strings.Contains(strings.ToLower(p.CompanyName), term) {
+			return p.Id, p.CompanyName, nil
+		}
+	}
+
+	number, err := phone.Parse(searchTerm)
+	found := 0
+	for _, c := range s.Contacts {
+		match := strings.Contains(strings.ToLower(c.Name), term) || strings.Contains(strings.ToLower(c.Email), term) ||
+			err == nil && c.Phone == number
+		if match && (found == 0 || c.PartnerId < found) {
+			found = c.PartnerId
+		}
+	}
+	for _, p := range s.Partners {
+		if p.Id == found {
+			return p.Id, p.CompanyName, nil
+		}
+	}
+	return 0, "", storage.ErrPartnerNotFound
+}
+
+func (s *Store) GetContacts(ctx context.Context, partnerId int) ([]models.Contact, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	var contacts []models.Contact
+	for _, c := range s.Contacts {
+		if c.PartnerId == partnerId {
+			contacts = append(contacts, c)
+		}
+	}
+	sort.Slice(contacts, func(i, j int) bool { return contacts[i].Id < contacts[j].Id })
+	return contacts, nil
+}
+
+func (s *Store) GetAllContacts(ctx context.Context) ([]models.Contact, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	contacts := slices.Clone(s.Contacts)
+	sort.Slice(contacts, func(i, j int) bool {
+		if contacts[i].PartnerId != contacts[j].PartnerId {
+			return contacts[i].PartnerId < contacts[j].PartnerId
+		}
+		return contacts[i].Id < contacts[j].Id
+	})
+	return contacts, nil
+}
+
+func (s *Store) AddContact(ctx context.Context, contact models.Contact) (int, error) {
+	if err := storage.Authorize(ctx, models.PermEditPartners); err != nil {
+		return 0, err
+	}
+	if err := storage.ValidateContact(contact); err != nil {
+		return 0, err
+	}
+	if err := s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+
+	if !s.hasPartner(contact.PartnerId) {
+		return 0, storage.ErrPartnerNotFound
+	}
+	contact.Id = 1
+	for _, c := range s.Contacts {
+		if c.Id >= contact.Id {
+			contact.Id = c.Id + 1
+		}
+	}
+	s.Contacts = append(s.Contacts, contact)
+	return contact.Id, s.record(ctx, models.EntityContact, contact.Id, models.AuditCreate, nil, contact)
+}
+
+func (s *Store) UpdateContact(ctx context.Context, contact models.Contact) error {
+	if err := storage.Authorize(ctx, models.PermEditPartners); err != nil {
+		return err
+	}
+	if err := storage.ValidateContact(contact); err != nil {
+		return err
+	}
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	for i, before := range s.Contacts {
+		if before.Id == contact.Id {
+			contact.PartnerId = before.PartnerId
+			s.Contacts[i] = contact
+			return s.record(ctx, models.EntityContact, contact.Id, models.AuditUpdate, before, contact)
+		}
+	}
+	return storage.ErrContactNotFound
+}
+
+func (s *Store) DeleteContact(ctx context.Context, id int) error {
+	if err := storage.Authorize(ctx, models.PermEditPartners); err != nil {
+		return err
+	}
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	for i, before := range s.Contacts {
+		if before.Id == id {
+			s.Contacts = append(s.Contacts[:i], s.Contacts[i+1:]...)
+			return s.record(ctx, models.EntityContact, id, models.AuditDelete, before, nil)
+		}
+	}
+	return nil
+}
+
+func (s *Store) FindContactDuplicates(ctx context.Context, partner models.Partner) ([]models.ContactDuplicate, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	email := mailaddr.Normalize(partner.Email)
+	totals := map[int]float64{}
+	for _, sale := range s.Sales {
+		totals[sale.PartnerId] += float64(sale.Quantity)
+	}
+
+	var duplicates []models.ContactDuplicate
+	for _, p := range s.Partners {
+		if p.Id == partner.Id {
+			continue
+		}
+		d := models.ContactDuplicate{
+			SameEmail: email != "" && mailaddr.Normalize(p.Email) == email,
+			SamePhone: partner.Phone != "" && p.Phone == partner.Phone,
+		}
+		if !d.SameEmail && !d.SamePhone {
+			continue
+		}
+		p.Discount = storage.DiscountPercentage(totals[p.Id])
+		d.Partner = p
+		duplicates = append(duplicates, d)
+	}
+	sort.Slice(duplicates, func(i, j int) bool { return duplicates[i].Partner.Id < duplicates[j].Partner.Id })
+	return duplicates, nil
+}
+
+func (s *Store) hasPartner(id int) bool {
+	return slices.ContainsFunc(s.Partners, func(p models.Partner) bool { return p.Id == id })
+}
+
+func (s *Store) GetNotes(ctx context.Context, partnerId int) ([]models.Note, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	var notes []models.Note
+	for i := len(s.notes) - 1; i >= 0; i-- {
+		if s.notes[i].PartnerId == partnerId {
+			notes = append(notes, s.notes[i])
+		}
+	}
+	return notes, nil
+}
+
+func (s *Store) AddNote(ctx context.Context, partnerId int, text string) (int, error) {
+	if err := storage.Authorize(ctx, models.PermEditPartners); err != nil {
+		return 0, err
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, storage.ErrEmptyNote
+	}
+	if err := s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+
+	if !s.hasPartner(partnerId) {
+		return 0, storage.ErrPartnerNotFound
+	}
+	note := models.Note{Id: s.nextId(), PartnerId: partnerId, Text: text, User: storage.AuditUser(ctx), CreatedAt: now()}
+	s.notes = append(s.notes, note)
+	return note.Id, s.record(ctx, models.EntityNote, note.Id, models.AuditCreate, nil, note)
+}
+
+func (s *Store) DeleteNote(ctx context.Context, id int) error {
+	if err := storage.Authorize(ctx, models.PermEditPartners); err != nil {
+		return err
+	}
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	for i, before := range s.notes {
+		if before.Id == id {
+			s.notes = append(s.notes[:i], s.notes[i+1:]...)
+			return s.record(ctx, models.EntityNote, id, models.AuditDelete, before, nil)
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetAttachments(ctx context.Context, partnerId int) ([]models.Attachment, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	var attachments []models.Attachment
+	for i := len(s.attachments) - 1; i >= 0; i-- {
+		if s.attachments[i].PartnerId == partnerId {
+			attachments = append(attachments, s.attachments[i].Attachment)
+		}
+	}
+	return attachments, nil
+}
+
+func (s *Store) AddAttachment(ctx context.Context, partnerId int, fileName string, data []byte) (int, error) {
+	if err := storage.Authorize(ctx, models.PermEditPartners); err != nil {
+		return 0, err
+	}
+	fileName, err := storage.ValidateAttachment(fileName, data)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+
+	if !s.hasPartner(partnerId) {
+		return 0, storage.ErrPartnerNotFound
+	}
+	hash := storage.AttachmentHash(data)
+	for _, f := range s.attachments {
+		if f.PartnerId == partnerId && f.Hash == hash {
+			return 0, storage.ErrAttachmentExists(f.FileName)
+		}
+	}
+	f := models.Attachment{
+		Id:        s.nextId(),
+		PartnerId: partnerId,
+		FileName:  fileName,
+		Size:      int64(len(data)),
+		Hash:      hash,
+		User:      storage.AuditUser(ctx),
+		CreatedAt: now(),
+	}
+	s.attachments = append(s.attachments, attachment{Attachment: f, data: slices.Clone(data)})
+	return f.Id, s.record(ctx, models.EntityAttachment, f.Id, models.AuditCreate, nil, f)
+}
+
+func (s *Store) ReadAttachment(ctx context.Context, id int) (models.Attachment, []byte, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.Attachment{}, nil, err
+	}
+	defer s.mu.Unlock()
+
+	for _, f := range s.attachments {
+		if f.Id == id {
+			return f.Attachment, slices.Clone(f.data), nil
+		}
+	}
+	return models.Attachment{}, nil, storage.ErrAttachmentNotFound
+}
+
+func (s *Store) DeleteAttachment(ctx context.Context, id int) error {
+	if err := storage.Authorize(ctx, models.PermEditPartners); err != nil {
+		return err
+	}
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	for i, before := range s.attachments {
+		if before.Id == id {
+			s.attachments = append(s.attachments[:i], s.attachments[i+1:]...)
+			return s.record(ctx, models.EntityAttachment, id, models.AuditDelete, before.Attachment, nil)
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetPartnerSales(ctx context.Context, id int) ([]models.PartnerSale, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	var sales []models.PartnerSale
+	for _, sale := range s.Sales {
+		if sale.PartnerId != id {
+			continue
+		}
+		product, ok := s.product(sale.ProductId)
+		if !ok {
+			continue
+		}
+		productType, ok := s.productType(product.ProductTypeId)
+		if !ok {
+			continue
+		}
+		sales = append(sales, models.PartnerSale{
+			ProductName: product.Name,
+			Quantity:    sale.Quantity,
+			SaleDate:    sale.SaleDate,
+			ProductType: productType.Name,
+			TotalSum:    product.MinCost.Mul(sale.Quantity),
+		})
+	}
+
+	sort.SliceStable(sales, func(i, j int) bool {
+		return sales[i].SaleDate > sales[j].SaleDate
+	})
+	return sales, nil
+}
+
+func (s *Store) GetMonthlySales(ctx context.Context, id int) ([]models.MonthlySales, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	return s.monthlySales(func(sale Sale) bool { return sale.PartnerId == id }), nil
+}
+
+// monthlySales суммирует по месяцам продажи, для которых include возвращает true.
+func (s *Store) monthlySales(include func(Sale) bool) []models.MonthlySales {
+	byMonth := map[string]*models.MonthlySales{}
+	var months []models.MonthlySales
+	for _, sale := range s.Sales {
+		product, ok := s.product(sale.ProductId)
+		if !include(sale) || !ok {
+			continue
+		}
+		month := sale.SaleDate[:7]
+		m, ok := byMonth[month]
+		if !ok {
+			m = &models.MonthlySales{Month: month}
+			byMonth[month] = m
+		}
+		m.Quantity += sale.Quantity
+		m.TotalSum += product.MinCost.Mul(sale.Quantity)
+	}
+	for _, m := range byMonth {
+		months = append(months, *m)
+	}
+
+	sort.Slice(months, func(i, j int) bool {
+		return months[i].Month < months[j].Month
+	})
+	return months
+}
+
+func (s *Store) GetTopProducts(ctx context.Context, id int, limit int) ([]models.ProductSales, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	byProduct := map[int]*models.ProductSales{}
+	var products []models.ProductSales
+	for _, sale := range s.Sales {
+		product, ok := s.product(sale.ProductId)
+		if sale.PartnerId != id || !ok {
+			continue
+		}
+		p, ok := byProduct[product.Id]
+		if !ok {
+			p = &models.ProductSales{ProductName: product.Name}
+			byProduct[product.Id] = p
+		}
+		p.Quantity += sale.Quantity
+		p.TotalSum += product.MinCost.Mul(sale.Quantity)
+	}
+	for _, p := range byProduct {
+		products = append(products, *p)
+	}
+
+	sort.Slice(products, func(i, j int) bool {
+		if products[i].Quantity != products[j].Quantity {
+			return products[i].Quantity > products[j].Quantity
+		}
+		return products[i].ProductName < products[j].ProductName
+	})
+	if len(products) > limit {
+		products = products[:limit]
+	}
+	return products, nil
+}
+
+func (s *Store) GetSalesTotals(ctx context.Context) (models.SalesTotals, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.SalesTotals{}, err
+	}
+	defer s.mu.Unlock()
+
+	var totals models.SalesTotals
+	partners := map[int]bool{}
+	for _, sale := range s.Sales {
+		product, ok := s.product(sale.ProductId)
+		if !ok {
+			continue
+		}
+		totals.Revenue += product.MinCost.Mul(sale.Quantity)
+		totals.Quantity += sale.Quantity
+		partners[sale.PartnerId] = true
+	}
+	totals.Partners = len(partners)
+	return totals, nil
+}
+
+func (s *Store) GetMonthlyTotals(ctx context.Context) ([]models.MonthlySales, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	return s.monthlySales(func(Sale) bool { return true }), nil
+}
+
+func (s *Store) GetTopPartners(ctx context.Context, limit int) ([]models.PartnerTotal, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	byPartner := map[int]*models.PartnerTotal{}
+	for _, p := range s.Partners {
+		byPartner[p.Id] = &models.PartnerTotal{PartnerId: p.Id, CompanyName: p.CompanyName}
+	}
+	sold := map[int]bool{}
+	for _, sale := range s.Sales {
+		product, ok := s.product(sale.ProductId)
+		p, found := byPartner[sale.PartnerId]
+		if !ok || !found {
+			continue
+		}
+		p.Quantity += sale.Quantity
+		p.TotalSum += product.MinCost.Mul(sale.Quantity)
+		sold[p.PartnerId] = true
+	}
+
+	var partners []models.PartnerTotal
+	for _, p := range s.Partners {
+		if sold[p.Id] {
+			partners = append(partners, *byPartner[p.Id])
+		}
+	}
+	sort.SliceStable(partners, func(i, j int) bool {
+		if partners[i].Quantity != partners[j].Quantity {
+			return partners[i].Quantity > partners[j].Quantity
+		}
+		return partners[i].CompanyName < partners[j].CompanyName
+	})
+	if len(partners) > limit {
+		partners = partners[:limit]
+	}
+	return partners, nil
+}
+
+// GetDiscountDistribution, как и запрос в storage.DB, относит партнеров без продаж к порогу 0%.
+func (s *Store) GetDiscountDistribution(ctx context.Context) ([]models.DiscountTierCount, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	totals := map[int]float64{}
+	for _, sale := range s.Sales {
+		totals[sale.PartnerId] += float64(sale.Quantity)
+	}
+	byPercentage := map[int]int{}
+	for _, p := range s.Partners {
+		byPercentage[storage.DiscountPercentage(totals[p.Id])]++
+	}
+
+	var tiers []models.DiscountTierCount
+	for _, tier := range storage.DiscountTiers {
+		if n := byPercentage[tier.Percentage]; n > 0 {
+			tiers = append(tiers, models.DiscountTierCount{Percentage: tier.Percentage, Partners: n})
+		}
+	}
+	return tiers, nil
+}
+
+func (s *Store) GetPartnerTypeDistribution(ctx context.Context) ([]models.PartnerTypeCount, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	byType := map[string]int{}
+	for _, p := range s.Partners {
+		byType[p.PartnerType]++
+	}
+
+	var types []models.PartnerTypeCount
+	for partnerType, n := range byType {
+		types = append(types, models.PartnerTypeCount{PartnerType: partnerType, Partners: n})
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if types[i].Partners != types[j].Partners {
+			return types[i].Partners > types[j].Partners
+		}
+		return types[i].PartnerType < types[j].PartnerType
+	})
+	return types, nil
+}
+
+// recordRating, как и storage.DB, пишет в историю только действительные изменения рейтинга.
+func (s *Store) recordRating(ctx context.Context, partnerId, oldRating, newRating int, reason string) {
+	if oldRating == newRating {
+		return
+	}
+	s.ratings = append(s.ratings, models.RatingChange{
+		Id:        len(s.ratings) + 1,
+		PartnerId: partnerId,
+		OldRating: oldRating,
+		NewRating: newRating,
+		Reason:    reason,
+		User:      storage.AuditUser(ctx),
+		CreatedAt: now(),
+	})
+}
+
+func (s *Store) GetRatingHistory(ctx context.Context, partnerId int) ([]models.RatingChange, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	var history []models.RatingChange
+	for i := len(s.ratings) - 1; i >= 0; i-- {
+		if s.ratings[i].PartnerId == partnerId {
+			history = append(history, s.ratings[i])
+		}
+	}
+	return history, nil
+}
+
+func (s *Store) GetRatingSuggestions(ctx context.Context, at time.Time) ([]models.RatingSuggestion, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	rejected := map[rejection]bool{}
+	for _, r := range s.rejections {
+		rejected[r] = true
+	}
+
+	var suggestions []models.RatingSuggestion
+	for _, p := range s.Partners {
+		activity := storage.PartnerActivity{PartnerId: p.Id, CompanyName: p.CompanyName, Rating: p.Rating}
+		for _, sale := range s.Sales {
+			if sale.PartnerId != p.Id {
+				continue
+			}
+			activity.TotalQuantity += float64(sale.Quantity)
+			activity.LastSaleDate = max(activity.LastSaleDate, sale.SaleDate)
+		}
+		suggestion, ok := storage.SuggestRating(activity, at)
+		if ok && !rejected[rejection{suggestion.PartnerId, suggestion.CurrentRating, suggestion.SuggestedRating}] {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		return suggestions[i].PartnerId < suggestions[j].PartnerId
+	})
+	return suggestions, nil
+}
+
+func (s *Store) AcceptRatingSuggestion(ctx context.Context, suggestion models.RatingSuggestion) error {
+	if err := storage.Authorize(ctx, models.PermEditPartners); err != nil {
+		return err
+	}
+	if err := storage.ValidateRating(suggestion.SuggestedRating); err != nil {
+		return err
+	}
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	for i := range s.Partners {
+		if s.Partners[i].Id != suggestion.PartnerId {
+			continue
+		}
+		before := s.Partners[i]
+		if before.Rating != suggestion.CurrentRating {
+			return storage.ErrSuggestionOutdated
+		}
+		s.Partners[i].Rating = suggestion.SuggestedRating
+		s.recordRating(ctx, before.Id, before.Rating, suggestion.SuggestedRating, suggestion.Reason)
+		return s.record(ctx, models.EntityPartner, before.Id, models.AuditUpdate, before, s.Partners[i])
+	}
+	return storage.ErrPartnerNotFound
+}
+
+func (s *Store) RejectRatingSuggestion(ctx context.Context, suggestion models.RatingSuggestion) error {
+	if err := storage.Authorize(ctx, models.PermEditPartners); err != nil {
+		return err
+	}
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	s.rejections = append(s.rejections, rejection{suggestion.PartnerId, suggestion.CurrentRating, suggestion.SuggestedRating})
+	return nil
+}
+
+func (s *Store) GetProducts(ctx context.Context) ([]string, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	var products []string
+	for _, p := range s.Products {
+		products = append(products, fmt.Sprintf("%d - %s", p.Id, p.Name))
+	}
+	return products, nil
+}
+
+func (s *Store) GetMaterialTypes(ctx context.Context) ([]string, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	var materials []string
+	for _, m := range s.MaterialTypes {
+		materials = append(materials, fmt.Sprintf("%d - %s", m.Id, m.Name))
+	}
+	return materials, nil
+}
+
+func (s *Store) GetProductParameters(ctx context.Context, productId string) ([]models.ProductParameter, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	return s.productParameters(productId), nil
+}
+
+func (s *Store) productParameters(productId string) []models.ProductParameter {
+	id, _ := strconv.Atoi(productId)
+	product, ok := s.product(id)
+	if !ok {
+		return nil
+	}
+
+	var params []models.ProductParameter
+	for _, p := range s.Parameters {
+		if p.ProductTypeId == product.ProductTypeId {
+			params = append(params, p)
+		}
+	}
+	sort.Slice(params, func(i, j int) bool {
+		return params[i].Position < params[j].Position
+	})
+	return params
+}
+
+func (s *Store) newCalculation(productId, materialId string) (models.MaterialCalculation, error) {
+	var calc models.MaterialCalculation
+
+	id, _ := strconv.Atoi(productId)
+	product, ok := s.product(id)
+	if !ok {
+		return calc, storage.ErrNoCoefficient
+	}
+	productType, ok := s.productType(product.ProductTypeId)
+	if !ok {
+		return calc, storage.ErrNoCoefficient
+	}
+
+	id, _ = strconv.Atoi(materialId)
+	material, ok := s.materialType(id)
+	if !ok {
+		return calc, storage.ErrNoDefectPercentage
+	}
+
+	calc.ProductId = product.Id
+	calc.ProductName = product.Name
+	calc.ProductCoefficient = productType.Coefficient
+	calc.MaterialTypeId = material.Id
+	calc.MaterialType = material.Name
+	calc.DefectPercentage = material.DefectPercentage
+	return calc, nil
+}
+
+func (s *Store) CalculateMaterial(ctx context.Context, productId, materialId string, quantity int, param1, param2 float64) (models.MaterialCalculation, error) {
+	if err := s.lock(ctx); err != nil {
+		return models.MaterialCalculation{}, err
+	}
+	defer s.mu.Unlock()
+
+	calc, err := s.newCalculation(productId, materialId)
+	if err != nil {
+		return calc, err
+	}
+	if err := storage.CheckParams(s.productParameters(productId), param1, param2); err != nil {
+		return calc, err
+	}
+
+	return storage.ComputeMaterial(calc, quantity, param1, param2), nil
+}
+
+func (s *Store) CalculateMaxProducts(ctx context.Context, productId, materialId string, available int, param1, param2 float64) (int, error) {
+	if err := s.lock(ctx); err != nil {
+		return -1, err
+	}
+	defer s.mu.Unlock()
+
+	calc, err := s.newCalculation(productId, materialId)
+	if err != nil {
+		return -1, err
+	}
+	if err := storage.CheckParams(s.productParameters(productId), param1, param2); err != nil {
+		return -1, err
+	}
+
+	return storage.ComputeMaxProducts(calc, available, param1, param2)
+}
+
+func (s *Store) CalculatePlan(ctx context.Context, plan models.ProductionPlan) ([]models.MaterialRequirement, error) {
+	return storage.CalculatePlan(ctx, s, plan)
+}
+
+func (s *Store) SaveCalculation(ctx context.Context, calc models.MaterialCalculation) error {
+	if err := storage.Authorize(ctx, models.PermSaveCalculations); err != nil {
+		return err
+	}
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	calc.Id = s.nextId()
+	if err := s.record(ctx, models.EntityCalculation, calc.Id, models.AuditCreate, nil, calc); err != nil {
+		return err
+	}
+	calc.CreatedAt = now()
+	s.calculations = append(s.calculations, calc)
+	return nil
+}
+
+func (s *Store) GetCalculationHistory(ctx context.Context) ([]models.MaterialCalculation, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	history := make([]models.MaterialCalculation, 0, len(s.calculations))
+	for i := len(s.calculations) - 1; i >= 0; i-- {
+		history = append(history, s.calculations[i])
+	}
+	return history, nil
+}
+
+func (s *Store) GetPlans(ctx context.Context) ([]models.ProductionPlan, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	plans := make([]models.ProductionPlan, 0, len(s.plans))
+	for i := len(s.plans) - 1; i >= 0; i-- {
+		plan := s.plans[i]
+		plan.Rows = nil
+		plans = append(plans, plan)
+	}
+	return plans, nil
+}
+
+func (s *Store) GetPlan(ctx context.Context, id int) (*models.ProductionPlan, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	if plan := s.plan(id); plan != nil {
+		return plan, nil
+	}
+	return nil, storage.ErrPlanNotFound
+}
+
+// plan возвращает копию плана с названиями продуктов и материалов или nil.
+func (s *Store) plan(id int) *models.ProductionPlan {
+	for _, plan := range s.plans {
+		if plan.Id != id {
+			continue
+		}
+		plan.Rows = append([]models.ProductionPlanRow(nil), plan.Rows...)
+		for i := range plan.Rows {
+			if product, ok := s.product(plan.Rows[i].ProductId); ok {
+				plan.Rows[i].ProductName = product.Name
+			}
+			if material, ok := s.materialType(plan.Rows[i].MaterialTypeId); ok {
+				plan.Rows[i].MaterialType = material.Name
+			}
+		}
+		return &plan
+	}
+	return nil
+}
+
+func (s *Store) SavePlan(ctx context.Context, plan models.ProductionPlan) (int, error) {
+	if err := storage.Authorize(ctx, models.PermEditPlans); err != nil {
+		return 0, err
+	}
+	if err := s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+
+	plan.Rows = append([]models.ProductionPlanRow(nil), plan.Rows...)
+	for i := range plan.Rows {
+		plan.Rows[i].Id = s.nextId()
+	}
+
+	for i := range s.plans {
+		if s.plans[i].Id == plan.Id {
+			before := s.plan(plan.Id)
+			plan.CreatedAt = s.plans[i].CreatedAt
+			s.plans[i] = plan
+			return plan.Id, s.record(ctx, models.EntityPlan, plan.Id, models.AuditUpdate, before, s.plan(plan.Id))
+		}
+	}
+
+	plan.Id = s.nextId()
+	plan.CreatedAt = now()
+	s.plans = append(s.plans, plan)
+	return plan.Id, s.record(ctx, models.EntityPlan, plan.Id, models.AuditCreate, nil, s.plan(plan.Id))
+}
+
+func (s *Store) DeletePlan(ctx context.Context, id int) error {
+	if err := storage.Authorize(ctx, models.PermEditPlans); err != nil {
+		return err
+	}
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	for i := range s.plans {
+		if s.plans[i].Id == id {
+			before := s.plan(id)
+			s.plans = append(s.plans[:i], s.plans[i+1:]...)
+			return s.record(ctx, models.EntityPlan, id, models.AuditDelete, before, nil)
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetAuditLog(ctx context.Context) ([]models.AuditEntry, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	return s.auditEntries(func(models.AuditEntry) bool { return true }), nil
+}
+
+func (s *Store) GetEntityHistory(ctx context.Context, entity string, id int) ([]models.AuditEntry, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	return s.auditEntries(func(e models.AuditEntry) bool { return e.Entity == entity && e.EntityId == id }), nil
+}
+
+// auditEntries возвращает подходящие записи журнала, новые первыми.
+func (s *Store) auditEntries(match func(models.AuditEntry) bool) []models.AuditEntry {
+	var entries []models.AuditEntry
+	for i := len(s.audit) - 1; i >= 0; i-- {
+		if match(s.audit[i]) {
+			entries = append(entries, s.audit[i])
+		}
+	}
+	return entries
+}
+
+// record добавляет запись в журнал. Вызывается под блокировкой.
+func (s *Store) record(ctx context.Context, entity string, id int, action string, before, after any) error {
+	beforeJSON, err := storage.AuditJSON(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := storage.AuditJSON(after)
+	if err != nil {
+		return err
+	}
+
+	s.audit = append(s.audit, models.AuditEntry{
+		Id:        len(s.audit) + 1,
+		Entity:    entity,
+		EntityId:  id,
+		Action:    action,
+		User:      storage.AuditUser(ctx),
+		Before:    beforeJSON,
+		After:     afterJSON,
+		CreatedAt: now(),
+	})
+	return nil
+}
+
+func (s *Store) HasUsers(ctx context.Context) (bool, error) {
+	if err := s.lock(ctx); err != nil {
+		return false, err
+	}
+	defer s.mu.Unlock()
+
+	return len(s.users) > 0, nil
+}
+
+func (s *Store) Authenticate(ctx context.Context, login, password string) (*models.User, error) {
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Login == login && storage.CheckPassword(u.passwordHash, password) {
+			found := u.User
+			return &found, nil
+		}
+	}
+	return nil, storage.ErrInvalidCredentials
+}
+
+func (s *Store) GetUsers(ctx context.Context) ([]models.User, error) {
+	if err := storage.Authorize(ctx, models.PermManageUsers); err != nil {
+		return nil, err
+	}
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	users := make([]models.User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u.User)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].Login < users[j].Login })
+	return users, nil
+}
+
+func (s *Store) CreateUser(ctx context.Context, login, password, role string) error {
+	if err := storage.ValidateNewUser(login, password, role); err != nil {
+		return err
+	}
+	hash, err := storage.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	if len(s.users) > 0 || role != models.RoleAdmin {
+		if err := storage.Authorize(ctx, models.PermManageUsers); err != nil {
+			return err
+		}
+	}
+	for _, u := range s.users {
+		if u.Login == login {
+			return storage.ErrUserExists
+		}
+	}
+
+	u := user{User: models.User{Id: s.nextId(), Login: login, Role: role}, passwordHash: hash}
+	s.users = append(s.users, u)
+	return s.record(ctx, models.EntityUser, u.Id, models.AuditCreate, nil, u.User)
+}
+
+func (s *Store) DeleteUser(ctx context.Context, id int) error {
+	if err := storage.Authorize(ctx, models.PermManageUsers); err != nil {
+		return err
+	}
+	if current, _ := storage.UserFromContext(ctx); current.Id == id {
+		return storage.ErrDeleteSelf
+	}
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	for i := range s.users {
+		if s.users[i].Id == id {
+			before := s.users[i].User
+			s.users = append(s.users[:i], s.users[i+1:]...)
+			return s.record(ctx, models.EntityUser, id, models.AuditDelete, before, nil)
+		}
+	}
+	return nil
+}
+
+func (s *Store) ExportPartnerData(ctx context.Context, partnerId int) (models.PartnerData, error) {
+	if err := storage.Authorize(ctx, models.PermManagePersonalData); err != nil {
+		return models.PartnerData{}, err
+	}
+	if err := s.lock(ctx); err != nil {
+		return models.PartnerData{}, err
+	}
+	i := slices.IndexFunc(s.Partners, func(p models.Partner) bool { return p.Id == partnerId })
+	if i < 0 {
+		s.mu.Unlock()
+		return models.PartnerData{}, storage.ErrPartnerNotFound
+	}
+	data := models.PartnerData{ExportedAt: time.Now().UTC().Format(time.RFC3339), Partner: s.Partners[i]}
+	data.History = s.auditEntries(func(e models.AuditEntry) bool {
+		return (e.Entity == models.EntityPartner && e.EntityId == partnerId) || storage.PartnerLinked(e, partnerId)
+	})
+	for i := len(s.attachments) - 1; i >= 0; i-- {
+		if f := s.attachments[i]; f.PartnerId == partnerId {
+			data.Attachments = append(data.Attachments, models.AttachmentData{Attachment: f.Attachment, Data: slices.Clone(f.data)})
+		}
+	}
+	s.mu.Unlock()
+
+	var err error
+	if data.Contacts, err = s.GetContacts(ctx, partnerId); err != nil {
+		return models.PartnerData{}, err
+	}
+	if data.Notes, err = s.GetNotes(ctx, partnerId); err != nil {
+		return models.PartnerData{}, err
+	}
+	if data.Sales, err = s.GetPartnerSales(ctx, partnerId); err != nil {
+		return models.PartnerData{}, err
+	}
+	if data.Ratings, err = s.GetRatingHistory(ctx, partnerId); err != nil {
+		return models.PartnerData{}, err
+	}
+	return data, nil
+}
+
+func (s *Store) AnonymizePartner(ctx context.Context, partnerId int, deleteNotes bool) error {
+	if err := storage.Authorize(ctx, models.PermManagePersonalData); err != nil {
+		return err
+	}
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.Partners, func(p models.Partner) bool { return p.Id == partnerId })
+	if i < 0 {
+		return storage.ErrPartnerNotFound
+	}
+	s.Partners[i] = storage.AnonymizePartner(s.Partners[i])
+	for j, c := range s.Contacts {
+		if c.PartnerId == partnerId {
+			s.Contacts[j] = storage.AnonymizeContact(c)
+		}
+	}
+	for j, e := range s.audit {
+		if (e.Entity == models.EntityPartner && e.EntityId == partnerId) || storage.PartnerLinked(e, partnerId) {
+			anonymized, err := storage.AnonymizeAuditEntry(e)
+			if err != nil {
+				return err
+			}
+			if deleteNotes {
+				if anonymized, err = storage.RedactAuditEntry(anonymized); err != nil {
+					return err
+				}
+			}
+			s.audit[j] = anonymized
+		}
+	}
+	if deleteNotes {
+		if err := s.deletePartnerNotes(ctx, partnerId); err != nil {
+			return err
+		}
+	}
+	return s.record(ctx, models.EntityPartner, partnerId, models.AuditAnonymize, nil, s.Partners[i])
+}
+
+// deletePartnerNotes удаляет заметки и файлы партнера; в журнал попадают только их идентификаторы.
+func (s *Store) deletePartnerNotes(ctx context.Context, partnerId int) error {
+	var removed []models.AuditEntry
+	for _, n := range s.notes {
+		if n.PartnerId == partnerId {
+			removed = append(removed, models.AuditEntry{Entity: models.EntityNote, EntityId: n.Id})
+		}
+	}
+	for _, f := range s.attachments {
+		if f.PartnerId == partnerId {
+			removed = append(removed, models.AuditEntry{Entity: models.EntityAttachment, EntityId: f.Id})
+		}
+	}
+	s.notes = slices.DeleteFunc(s.notes, func(n models.Note) bool { return n.PartnerId == partnerId })
+	s.attachments = slices.DeleteFunc(s.attachments, func(f attachment) bool { return f.PartnerId == partnerId })
+
+	for _, e := range removed {
+		var before any = models.Note{Id: e.EntityId, PartnerId: partnerId}
+		if e.Entity == models.EntityAttachment {
+			before = models.Attachment{Id: e.EntityId, PartnerId: partnerId}
+		}
+		if err := s.record(ctx, e.Entity, e.EntityId, models.AuditDelete, before, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncryptionStatus: в памяти данные не хранятся на диске, поэтому шифрование только
+// запоминает пароль, а включенная база всегда разблокирована.
+func (s *Store) EncryptionStatus(ctx context.Context) (storage.EncryptionStatus, error) {
+	if err := s.lock(ctx); err != nil {
+		return "", err
+	}
+	defer s.mu.Unlock()
+
+	if s.passphrase == "" {
+		return storage.EncryptionOff, nil
+	}
+	return storage.EncryptionUnlocked, nil
+}
+
+func (s *Store) EnableEncryption(ctx context.Context, passphrase string) error {
+	if len([]rune(passphrase)) < storage.MinPassphraseLength {
+		return storage.ErrShortPassphrase
+	}
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	if s.passphrase != "" {
+		return storage.ErrEncryptionEnabled
+	}
+	s.passphrase = passphrase
+	return nil
+}
+
+func (s *Store) Unlock(ctx context.Context, passphrase string) error {
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	return s.checkPassphrase(passphrase)
+}
+
+func (s *Store) RotateKey(ctx context.Context, current, next string) error {
+	if err := storage.Authorize(ctx, models.PermManageEncryption); err != nil {
+		return err
+	}
+	if len([]rune(next)) < storage.MinPassphraseLength {
+		return storage.ErrShortPassphrase
+	}
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	if err := s.checkPassphrase(current); err != nil {
+		return err
+	}
+	s.passphrase = next
+	return nil
+}
+
+func (s *Store) checkPassphrase(passphrase string) error {
+	switch {
+	case s.passphrase == "":
+		return storage.ErrEncryptionOff
+	case s.passphrase != passphrase:
+		return storage.ErrWrongPassphrase
+	}
+	return nil
+}
+
+func (s *Store) product(id int) (Product, bool) {
+	for _, p := range s.Products {
+		if p.Id == id {
+			return p, true
+		}
+	}
+	return Product{}, false
+}
+
+func (s *Store) productType(id int) (ProductType, bool) {
+	for _, t := range s.ProductTypes {
+		if t.Id == id {
+			return t, true
+		}
+	}
+	return ProductType{}, false
+}
+
+func (s *Store) materialType(id int) (MaterialType, bool) {
+	for _, m := range s.MaterialTypes {
+		if m.Id == id {
+			return m, true
+		}
+	}
+	return MaterialType{}, false
+}
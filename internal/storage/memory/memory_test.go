@@ -0,0 +1,12 @@
+package memory_test
+
+import (
+	"github.com/ttrtcixy/demo/internal/storage/storagetest"
+	"testing"
+)
+
+func TestConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, f storagetest.Fixture) storagetest.Repository {
+		return storagetest.NewMemory(f)
+	})
+}
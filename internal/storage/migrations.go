@@ -0,0 +1,308 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"github.com/ttrtcixy/demo/internal/fieldcrypt"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/money"
+	"github.com/ttrtcixy/demo/internal/phone"
+	"strings"
+)
+
+// dataMigration — разовое преобразование данных. В отличие от схемы выполняется один раз:
+// примененные миграции записываются в таблицу Migrations.
+type dataMigration struct {
+	name  string
+	apply func(ctx context.Context, db *DB, t tx) error
+}
+
+var dataMigrations = []dataMigration{
+	{name: "normalize_partner_phones", apply: normalizePhones},
+	{name: "min_cost_kopecks", apply: minCostToKopecks},
+	{name: "product_type_parameters", apply: seedParameters},
+	{name: "audit_partner_id", apply: auditPartnerIds},
+}
+
+var (
+	isMigrationApplied = `SELECT COUNT(*) FROM Migrations WHERE Name = ?`
+	addMigration       = `insert into Migrations(Name) values(?)`
+)
+
+func (db *DB) migrateData(ctx context.Context) error {
+	for _, m := range dataMigrations {
+		tx, err := db.connect.BeginTx(ctx)
+		if err != nil {
+			return err
+		}
+
+		var applied int
+		if err := tx.QueryRowContext(ctx, isMigrationApplied, m.name).Scan(&applied); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if applied > 0 {
+			tx.Rollback()
+			continue
+		}
+
+		if err := m.apply(ctx, db, tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, addMigration, m.name); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PhoneProblem — телефон партнера, который не удалось привести к E.164: миграция оставила
+// его как есть, и исправить его можно только вручную.
+type PhoneProblem struct {
+	PartnerId   int
+	PartnerName string
+	Phone       string
+}
+
+// UnparsedPhones возвращает телефоны партнеров, которые не разбираются как номер. Список
+// строится по текущим данным, поэтому исправленный в карточке телефон из него пропадает.
+// Партнеры, чьи данные не расшифровываются, пропускаются.
+func (db *DB) UnparsedPhones(ctx context.Context) ([]PhoneProblem, error) {
+	rows, err := db.connect.QueryContext(ctx, getPartnerPhones)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var problems []PhoneProblem
+	for rows.Next() {
+		var p models.Partner
+		if err := rows.Scan(&p.Id, &p.CompanyName, &p.Phone); err != nil {
+			return nil, err
+		}
+		if err := db.connect.keys.openPartner(&p); errors.Is(err, ErrUndecryptable) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if p.Phone == "" {
+			continue
+		}
+		if _, err := phone.Parse(p.Phone); err != nil {
+			problems = append(problems, PhoneProblem{PartnerId: p.Id, PartnerName: p.CompanyName, Phone: p.Phone})
+		}
+	}
+	return problems, rows.Err()
+}
+
+var (
+	getPartnerPhones = `SELECT PartnerId, PartnerName, COALESCE(Phone, '') FROM Partners ORDER BY PartnerId`
+	setPartnerPhone  = `update Partners set Phone = ? where PartnerId = ?`
+)
+
+// normalizePhones приводит Partners.Phone к E.164. Каждое изменение попадает в журнал
+// от имени "system"; пустые и нераспознанные номера не меняются.
+func normalizePhones(ctx context.Context, db *DB, t tx) error {
+	type partnerPhone struct {
+		id          int
+		name, phone string
+	}
+
+	rows, err := t.QueryContext(ctx, getPartnerPhones)
+	if err != nil {
+		return err
+	}
+	var phones []partnerPhone
+	for rows.Next() {
+		var p partnerPhone
+		if err := rows.Scan(&p.id, &p.name, &p.phone); err != nil {
+			rows.Close()
+			return err
+		}
+		phones = append(phones, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, p := range phones {
+		if p.phone == "" || fieldcrypt.IsEncrypted(p.phone) {
+			continue
+		}
+		normalized, err := phone.Parse(p.phone)
+		if err != nil {
+			continue
+		}
+		if normalized == p.phone {
+			continue
+		}
+
+		before, err := readPartner(ctx, t, p.id)
+		if err != nil {
+			return err
+		}
+		if _, err := t.ExecContext(ctx, setPartnerPhone, normalized, p.id); err != nil {
+			return err
+		}
+		after := *before
+		after.Phone = normalized
+		if err := t.audit(ctx, models.EntityPartner, p.id, models.AuditUpdate, before, after); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	getProductCosts = `SELECT ProductId, MinCost FROM Products WHERE MinCost IS NOT NULL`
+	setProductCost  = `update Products set MinCostKopecks = ? where ProductId = ?`
+)
+
+// minCostToKopecks переносит Products.MinCost из REAL в целые копейки MinCostKopecks и удаляет
+// старый столбец. В новых базах столбца MinCost нет, и миграция ничего не делает.
+func minCostToKopecks(ctx context.Context, db *DB, t tx) error {
+	hasMinCost, err := columnExists(ctx, t, "Products", "MinCost")
+	if err != nil || !hasMinCost {
+		return err
+	}
+	hasKopecks, err := columnExists(ctx, t, "Products", "MinCostKopecks")
+	if err != nil {
+		return err
+	}
+	if !hasKopecks {
+		kopecksType := "INTEGER"
+		if t.dialect.name == DriverPostgres {
+			kopecksType = "BIGINT"
+		}
+		if _, err := t.ExecContext(ctx, `ALTER TABLE Products ADD COLUMN MinCostKopecks `+kopecksType); err != nil {
+			return err
+		}
+	}
+
+	type productCost struct {
+		id   int
+		cost float64
+	}
+	rows, err := t.QueryContext(ctx, getProductCosts)
+	if err != nil {
+		return err
+	}
+	var costs []productCost
+	for rows.Next() {
+		var c productCost
+		if err := rows.Scan(&c.id, &c.cost); err != nil {
+			rows.Close()
+			return err
+		}
+		costs = append(costs, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range costs {
+		// REAL хранит 19.99 как ближайшее двоичное число, и умножение на 100 дает 1998.99...
+		// Перевод через кратчайшую десятичную запись возвращает ровно 1999 копеек.
+		kopecks, err := money.FromFloat(c.cost)
+		if err != nil {
+			return err
+		}
+		if _, err := t.ExecContext(ctx, setProductCost, kopecks, c.id); err != nil {
+			return err
+		}
+	}
+	_, err = t.ExecContext(ctx, `ALTER TABLE Products DROP COLUMN MinCost`)
+	return err
+}
+
+// columnExists сообщает, есть ли в таблице столбец. PostgreSQL хранит имена без кавычек
+// в нижнем регистре.
+func columnExists(ctx context.Context, t tx, table, column string) (bool, error) {
+	query := `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`
+	if t.dialect.name == DriverPostgres {
+		query = `SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?`
+		table, column = strings.ToLower(table), strings.ToLower(column)
+	}
+	var n int
+	err := t.QueryRowContext(ctx, query, table, column).Scan(&n)
+	return n > 0, err
+}
+
+// seedProductTypeParameters описывает параметры 1 и 2 как длину и ширину изделия в метрах.
+// Названия для типов из исходной test.db уточнены; допустимые диапазоны не задаются,
+// их вводит администратор.
+var seedProductTypeParameters = `INSERT INTO ProductTypeParameters(ProductTypeId, Position, Name, Unit)
+    WITH known(ProductType, Position, Name) AS (VALUES
+        ('Паркетная доска', 1, 'Длина доски'), ('Паркетная доска', 2, 'Ширина доски'),
+        ('Ламинат', 1, 'Длина панели'), ('Ламинат', 2, 'Ширина панели'),
+        ('Массивная доска', 1, 'Длина доски'), ('Массивная доска', 2, 'Ширина доски'),
+        ('Пробковое покрытие', 1, 'Длина плитки'), ('Пробковое покрытие', 2, 'Ширина плитки')
+    ), positions(Position, Name) AS (VALUES (1, 'Длина'), (2, 'Ширина'))
+    SELECT pt.ProductTypeId, pos.Position, COALESCE(k.Name, pos.Name), 'м'
+    FROM ProductTypes pt
+    CROSS JOIN positions pos
+    LEFT JOIN known k ON k.ProductType = pt.ProductType AND k.Position = pos.Position
+    WHERE NOT EXISTS (SELECT 1 FROM ProductTypeParameters p WHERE p.ProductTypeId = pt.ProductTypeId)`
+
+// seedParameters описывает параметры существующих типов продукции. Миграция выполняется
+// один раз, поэтому описания, удаленные администратором, не появляются снова.
+func seedParameters(ctx context.Context, db *DB, t tx) error {
+	_, err := t.ExecContext(ctx, seedProductTypeParameters)
+	return err
+}
+
+var (
+	getLinkedAudit          = `SELECT AuditId, Entity, EntityId, BeforeData, AfterData FROM AuditLog WHERE PartnerId IS NULL`
+	setAuditPartner         = `update AuditLog set PartnerId = ? where AuditId = ?`
+	createAuditPartnerIndex = `CREATE INDEX IF NOT EXISTS AuditLogPartner ON AuditLog(PartnerId)`
+)
+
+// auditPartnerIds заполняет AuditLog.PartnerId у записей, сделанных до появления столбца.
+// Снимки связанных сущностей разбираются здесь один раз, дальше PartnerId пишет audit.
+func auditPartnerIds(ctx context.Context, db *DB, t tx) error {
+	hasPartnerId, err := columnExists(ctx, t, "AuditLog", "PartnerId")
+	if err != nil {
+		return err
+	}
+	if !hasPartnerId {
+		if _, err := t.ExecContext(ctx, `ALTER TABLE AuditLog ADD COLUMN PartnerId INTEGER`); err != nil {
+			return err
+		}
+	}
+
+	type link struct{ auditId, partnerId int }
+	rows, err := t.QueryContext(ctx, getLinkedAudit)
+	if err != nil {
+		return err
+	}
+	var links []link
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.Id, &e.Entity, &e.EntityId, &e.Before, &e.After); err != nil {
+			rows.Close()
+			return err
+		}
+		if partnerId, ok := linkedPartner(e.Entity, e.EntityId, e.Before, e.After); ok {
+			links = append(links, link{e.Id, partnerId})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, l := range links {
+		if _, err := t.ExecContext(ctx, setAuditPartner, l.partnerId, l.auditId); err != nil {
+			return err
+		}
+	}
+	_, err = t.ExecContext(ctx, createAuditPartnerIndex)
+	return err
+}
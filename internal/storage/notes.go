@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"github.com/ttrtcixy/demo/internal/models"
+	"path/filepath"
+	"strings"
+)
+
+// AttachmentHash — контрольная сумма содержимого файла: SHA-256 в hex.
+func AttachmentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidateAttachment проверяет файл перед сохранением и возвращает его имя без каталогов.
+func ValidateAttachment(fileName string, data []byte) (string, error) {
+	if len(data) == 0 {
+		return "", ErrEmptyAttachment
+	}
+	if len(data) > MaxAttachmentSize {
+		return "", ErrAttachmentTooLarge
+	}
+	return filepath.Base(filepath.FromSlash(fileName)), nil
+}
+
+var getNotes = `SELECT NoteId, PartnerId, Text, UserName, CreatedAt FROM PartnerNotes`
+
+func (db *DB) GetNotes(ctx context.Context, partnerId int) ([]models.Note, error) {
+	rows, err := db.connect.QueryContext(ctx, getNotes+` WHERE PartnerId = ? ORDER BY CreatedAt DESC, NoteId DESC`, partnerId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []models.Note
+	for rows.Next() {
+		var n models.Note
+		if err := rows.Scan(&n.Id, &n.PartnerId, &n.Text, &n.User, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+var addNote = `insert into PartnerNotes(PartnerId, Text, UserName) values(?, ?, ?) returning NoteId`
+
+func (db *DB) AddNote(ctx context.Context, partnerId int, text string) (int, error) {
+	if err := Authorize(ctx, models.PermEditPartners); err != nil {
+		return 0, err
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, ErrEmptyNote
+	}
+
+	tx, err := db.connect.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := partnerExists(ctx, tx, partnerId); err != nil {
+		return 0, err
+	}
+	var id int
+	if err := tx.QueryRowContext(ctx, addNote, partnerId, text, AuditUser(ctx)).Scan(&id); err != nil {
+		return 0, err
+	}
+	after, err := readNote(ctx, tx, id)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.audit(ctx, models.EntityNote, id, models.AuditCreate, nil, after); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func readNote(ctx context.Context, q querier, id int) (*models.Note, error) {
+	var n models.Note
+	err := q.QueryRowContext(ctx, getNotes+` WHERE NoteId = ?`, id).Scan(&n.Id, &n.PartnerId, &n.Text, &n.User, &n.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+func (db *DB) DeleteNote(ctx context.Context, id int) error {
+	if err := Authorize(ctx, models.PermEditPartners); err != nil {
+		return err
+	}
+
+	tx, err := db.connect.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := readNote(ctx, tx, id)
+	if err != nil || before == nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM PartnerNotes WHERE NoteId = ?", id); err != nil {
+		return err
+	}
+	if err := tx.audit(ctx, models.EntityNote, id, models.AuditDelete, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// partnerExists возвращает ErrPartnerNotFound, если партнера нет.
+func partnerExists(ctx context.Context, q querier, partnerId int) error {
+	partner, err := readPartner(ctx, q, partnerId)
+	if err != nil {
+		return err
+	}
+	if partner == nil {
+		return ErrPartnerNotFound
+	}
+	return nil
+}
+
+var getAttachments = `SELECT AttachmentId, PartnerId, FileName, Size, Hash, UserName, CreatedAt FROM PartnerAttachments`
+
+func (db *DB) GetAttachments(ctx context.Context, partnerId int) ([]models.Attachment, error) {
+	rows, err := db.connect.QueryContext(ctx, getAttachments+` WHERE PartnerId = ? ORDER BY CreatedAt DESC, AttachmentId DESC`, partnerId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []models.Attachment
+	for rows.Next() {
+		var f models.Attachment
+		if err := rows.Scan(&f.Id, &f.PartnerId, &f.FileName, &f.Size, &f.Hash, &f.User, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, f)
+	}
+	return attachments, rows.Err()
+}
+
+func readAttachment(ctx context.Context, q querier, query string, args ...any) (*models.Attachment, error) {
+	var f models.Attachment
+	err := q.QueryRowContext(ctx, getAttachments+query, args...).Scan(&f.Id, &f.PartnerId, &f.FileName, &f.Size, &f.Hash, &f.User, &f.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+var addAttachment = `insert into PartnerAttachments(PartnerId, FileName, Size, Hash, Data, UserName) values(?, ?, ?, ?, ?, ?) returning AttachmentId`
+
+func (db *DB) AddAttachment(ctx context.Context, partnerId int, fileName string, data []byte) (int, error) {
+	if err := Authorize(ctx, models.PermEditPartners); err != nil {
+		return 0, err
+	}
+	fileName, err := ValidateAttachment(fileName, data)
+	if err != nil {
+		return 0, err
+	}
+	hash := AttachmentHash(data)
+
+	tx, err := db.connect.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err := partnerExists(ctx, tx, partnerId); err != nil {
+		return 0, err
+	}
+	existing, err := readAttachment(ctx, tx, ` WHERE PartnerId = ? AND Hash = ?`, partnerId, hash)
+	if err != nil {
+		return 0, err
+	}
+	if existing != nil {
+		return 0, ErrAttachmentExists(existing.FileName)
+	}
+
+	var id int
+	args := []any{partnerId, fileName, len(data), hash, data, AuditUser(ctx)}
+	if err := tx.QueryRowContext(ctx, addAttachment, args...).Scan(&id); err != nil {
+		return 0, err
+	}
+	after, err := readAttachment(ctx, tx, ` WHERE AttachmentId = ?`, id)
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.audit(ctx, models.EntityAttachment, id, models.AuditCreate, nil, after); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (db *DB) ReadAttachment(ctx context.Context, id int) (models.Attachment, []byte, error) {
+	f, err := readAttachment(ctx, db.connect, ` WHERE AttachmentId = ?`, id)
+	if err != nil {
+		return models.Attachment{}, nil, err
+	}
+	if f == nil {
+		return models.Attachment{}, nil, ErrAttachmentNotFound
+	}
+
+	var data []byte
+	if err := db.connect.QueryRowContext(ctx, "SELECT Data FROM PartnerAttachments WHERE AttachmentId = ?", id).Scan(&data); err != nil {
+		return models.Attachment{}, nil, err
+	}
+	if AttachmentHash(data) != f.Hash {
+		return *f, nil, ErrAttachmentCorrupt
+	}
+	return *f, data, nil
+}
+
+func (db *DB) DeleteAttachment(ctx context.Context, id int) error {
+	if err := Authorize(ctx, models.PermEditPartners); err != nil {
+		return err
+	}
+
+	tx, err := db.connect.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := readAttachment(ctx, tx, ` WHERE AttachmentId = ?`, id)
+	if err != nil || before == nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM PartnerAttachments WHERE AttachmentId = ?", id); err != nil {
+		return err
+	}
+	if err := tx.audit(ctx, models.EntityAttachment, id, models.AuditDelete, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
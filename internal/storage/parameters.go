@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"github.com/ttrtcixy/demo/internal/models"
+	"strconv"
+)
+
+var getProductParameters = `
+    SELECT
+        tp.ProductTypeId, tp.Position, tp.Name, tp.Unit, tp.MinValue, tp.MaxValue
+    FROM
+        ProductTypeParameters tp
+    JOIN
+        Products p ON p.ProductTypeId = tp.ProductTypeId
+    WHERE
+        p.ProductId = ?
+    ORDER BY
+        tp.Position`
+
+// GetProductParameters возвращает описание параметров расчета для типа указанного продукта.
+func (db *DB) GetProductParameters(ctx context.Context, productId string) ([]models.ProductParameter, error) {
+	rows, err := db.connect.QueryContext(ctx, getProductParameters, productId)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения параметров продукта: %v", err)
+	}
+	defer rows.Close()
+
+	var params []models.ProductParameter
+	for rows.Next() {
+		var p models.ProductParameter
+		if err := rows.Scan(&p.ProductTypeId, &p.Position, &p.Name, &p.Unit, &p.MinValue, &p.MaxValue); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки: %v", err)
+		}
+		params = append(params, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при обработке результатов: %v", err)
+	}
+
+	return params, nil
+}
+
+func (db *DB) validateParams(ctx context.Context, productId string, param1, param2 float64) error {
+	params, err := db.GetProductParameters(ctx, productId)
+	if err != nil {
+		return err
+	}
+	return CheckParams(params, param1, param2)
+}
+
+// CheckParams проверяет значения параметров по описанию типа продукции.
+// Параметры без описания не проверяются.
+func CheckParams(params []models.ProductParameter, param1, param2 float64) error {
+	values := map[int]float64{1: param1, 2: param2}
+	for _, p := range params {
+		if err := checkParam(p, values[p.Position]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkParam(p models.ProductParameter, value float64) error {
+	if value <= 0 {
+		return paramError("error.param_positive", p.Name+": значение должно быть больше нуля", map[string]any{"Name": p.Name})
+	}
+	if p.MinValue != nil && value < *p.MinValue {
+		limit := strconv.FormatFloat(*p.MinValue, 'f', -1, 64)
+		return paramError("error.param_min", fmt.Sprintf("%s: значение должно быть не меньше %s %s", p.Name, limit, p.Unit),
+			map[string]any{"Name": p.Name, "Limit": limit, "Unit": p.Unit})
+	}
+	if p.MaxValue != nil && value > *p.MaxValue {
+		limit := strconv.FormatFloat(*p.MaxValue, 'f', -1, 64)
+		return paramError("error.param_max", fmt.Sprintf("%s: значение должно быть не больше %s %s", p.Name, limit, p.Unit),
+			map[string]any{"Name": p.Name, "Limit": limit, "Unit": p.Unit})
+	}
+	return nil
+}
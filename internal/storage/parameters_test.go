@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"github.com/ttrtcixy/demo/internal/models"
+	"testing"
+)
+
+func TestCheckParams(t *testing.T) {
+	min, max := 0.01, 10.0
+	params := []models.ProductParameter{
+		{Position: 1, Name: "Длина", Unit: "м", MinValue: &min},
+		{Position: 2, Name: "Ширина", Unit: "м", MinValue: &min, MaxValue: &max},
+	}
+
+	tests := []struct {
+		name           string
+		params         []models.ProductParameter
+		param1, param2 float64
+		wantErr        string
+	}{
+		{name: "в диапазоне", params: params, param1: 5, param2: 10},
+		{name: "на нижней границе", params: params, param1: 0.01, param2: 0.01},
+		{name: "без верхней границы", params: params, param1: 1000, param2: 1},
+		{name: "ноль", params: params, param1: 0, param2: 1, wantErr: "Длина: значение должно быть больше нуля"},
+		{name: "меньше минимума", params: params, param1: 0.001, param2: 1, wantErr: "Длина: значение должно быть не меньше 0.01 м"},
+		{name: "больше максимума", params: params, param1: 1, param2: 10.5, wantErr: "Ширина: значение должно быть не больше 10 м"},
+		{name: "без описания", params: nil, param1: -1, param2: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckParams(tt.params, tt.param1, tt.param2)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("неожиданная ошибка: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("ошибка %v, ожидалось %q", err, tt.wantErr)
+			}
+		})
+	}
+}
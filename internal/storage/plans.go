@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/ttrtcixy/demo/internal/models"
+	"math"
+	"sort"
+	"strconv"
+)
+
+var getPlans = `SELECT PlanId, PlanName, CreatedAt FROM ProductionPlans ORDER BY CreatedAt DESC, PlanId DESC`
+
+func (db *DB) GetPlans(ctx context.Context) ([]models.ProductionPlan, error) {
+	rows, err := db.connect.QueryContext(ctx, getPlans)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения планов: %v", err)
+	}
+	defer rows.Close()
+
+	var plans []models.ProductionPlan
+	for rows.Next() {
+		var plan models.ProductionPlan
+		if err := rows.Scan(&plan.Id, &plan.Name, &plan.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки: %v", err)
+		}
+		plans = append(plans, plan)
+	}
+	return plans, rows.Err()
+}
+
+var getPlanRows = `
+    SELECT
+        r.PlanRowId, r.ProductId, p.ProductName, r.MaterialTypeId, m.MaterialType, r.Quantity, r.Param1, r.Param2
+    FROM
+        ProductionPlanRows r
+    JOIN
+        Products p ON r.ProductId = p.ProductId
+    JOIN
+        MaterialTypes m ON r.MaterialTypeId = m.MaterialTypeId
+    WHERE
+        r.PlanId = ?
+    ORDER BY
+        r.PlanRowId`
+
+func (db *DB) GetPlan(ctx context.Context, id int) (*models.ProductionPlan, error) {
+	plan, err := readPlan(ctx, db.connect, id)
+	if err != nil {
+		return nil, err
+	}
+	if plan == nil {
+		return nil, ErrPlanNotFound
+	}
+	return plan, nil
+}
+
+// readPlan возвращает план со строками или nil, если его нет.
+func readPlan(ctx context.Context, q querier, id int) (*models.ProductionPlan, error) {
+	plan := &models.ProductionPlan{}
+	err := q.QueryRowContext(ctx, "SELECT PlanId, PlanName, CreatedAt FROM ProductionPlans WHERE PlanId = ?", id).Scan(&plan.Id, &plan.Name, &plan.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.QueryContext(ctx, getPlanRows, id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения строк плана: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.ProductionPlanRow
+		err := rows.Scan(&row.Id, &row.ProductId, &row.ProductName, &row.MaterialTypeId, &row.MaterialType, &row.Quantity, &row.Param1, &row.Param2)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки: %v", err)
+		}
+		plan.Rows = append(plan.Rows, row)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка при обработке результатов: %v", err)
+	}
+
+	return plan, nil
+}
+
+var (
+	addPlan       = `insert into ProductionPlans(PlanName) values(?) returning PlanId`
+	updatePlan    = `update ProductionPlans set PlanName = ? where PlanId = ?`
+	deletePlan    = `delete from ProductionPlans where PlanId = ?`
+	deletePlanRow = `delete from ProductionPlanRows where PlanId = ?`
+	addPlanRow    = `insert into ProductionPlanRows(PlanId, ProductId, MaterialTypeId, Quantity, Param1, Param2) values(?, ?, ?, ?, ?, ?)`
+)
+
+// SavePlan сохраняет план вместе со строками. Если plan.Id == 0, создается новый план,
+// иначе строки существующего плана заменяются целиком. Возвращает id плана.
+func (db *DB) SavePlan(ctx context.Context, plan models.ProductionPlan) (int, error) {
+	if err := Authorize(ctx, models.PermEditPlans); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.connect.BeginTx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var before *models.ProductionPlan
+	if plan.Id == 0 {
+		if err := tx.QueryRowContext(ctx, addPlan, plan.Name).Scan(&plan.Id); err != nil {
+			return 0, err
+		}
+	} else {
+		if before, err = readPlan(ctx, tx, plan.Id); err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, updatePlan, plan.Name, plan.Id); err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, deletePlanRow, plan.Id); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, row := range plan.Rows {
+		_, err := tx.ExecContext(ctx, addPlanRow, plan.Id, row.ProductId, row.MaterialTypeId, row.Quantity, row.Param1, row.Param2)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	after, err := readPlan(ctx, tx, plan.Id)
+	if err != nil {
+		return 0, err
+	}
+	action := models.AuditUpdate
+	if before == nil {
+		action = models.AuditCreate
+	}
+	if err := tx.audit(ctx, models.EntityPlan, plan.Id, action, before, after); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return plan.Id, nil
+}
+
+func (db *DB) DeletePlan(ctx context.Context, id int) error {
+	if err := Authorize(ctx, models.PermEditPlans); err != nil {
+		return err
+	}
+
+	tx, err := db.connect.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := readPlan(ctx, tx, id)
+	if err != nil || before == nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, deletePlanRow, id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, deletePlan, id); err != nil {
+		return err
+	}
+	if err := tx.audit(ctx, models.EntityPlan, id, models.AuditDelete, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *DB) CalculatePlan(ctx context.Context, plan models.ProductionPlan) ([]models.MaterialRequirement, error) {
+	return CalculatePlan(ctx, db, plan)
+}
+
+// planAmountScale — доли единицы материала, в которых CalculatePlan суммирует потребность строк.
+const planAmountScale = 1_000_000
+
+// CalculatePlan считает потребность в материалах для каждой строки плана через CalculateMaterial
+// и суммирует результат по типам материалов. Суммируется потребность до округления, а вверх
+// округляется итог по материалу: иначе каждая строка добавляла бы свою долю единицы.
+func CalculatePlan(ctx context.Context, calculator MaterialCalculator, plan models.ProductionPlan) ([]models.MaterialRequirement, error) {
+	totals := map[int]*models.MaterialRequirement{}
+	// Потребность строки округляется до миллионной доли единицы и копится целым числом.
+	// Так погрешность float64 (1.9999999999 или 2.0000000001 вместо 2) не меняет итог
+	// на целую единицу, а сумма строк не зависит от их порядка.
+	amounts := map[int]int64{}
+	for i, row := range plan.Rows {
+		calc, err := calculator.CalculateMaterial(ctx, strconv.Itoa(row.ProductId), strconv.Itoa(row.MaterialTypeId), row.Quantity, row.Param1, row.Param2)
+		if err != nil {
+			return nil, planRowError(i+1, err)
+		}
+
+		if _, ok := totals[row.MaterialTypeId]; !ok {
+			totals[row.MaterialTypeId] = &models.MaterialRequirement{MaterialTypeId: row.MaterialTypeId, MaterialType: row.MaterialType}
+		}
+		amounts[row.MaterialTypeId] += int64(math.Round((calc.BaseTotal + calc.DefectAllowance) * planAmountScale))
+	}
+	for id, total := range totals {
+		total.Quantity = int((amounts[id] + planAmountScale - 1) / planAmountScale)
+	}
+
+	requirements := make([]models.MaterialRequirement, 0, len(totals))
+	for _, total := range totals {
+		requirements = append(requirements, *total)
+	}
+	sort.Slice(requirements, func(i, j int) bool {
+		return requirements[i].MaterialTypeId < requirements[j].MaterialTypeId
+	})
+
+	return requirements, nil
+}
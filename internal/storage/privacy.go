@@ -0,0 +1,299 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ttrtcixy/demo/internal/models"
+	"time"
+)
+
+// Pseudonym — имя, которым при обезличивании заменяются ФИО директора партнера и контактных лиц.
+// Номер — идентификатор партнера или контакта: записи в отчетах остаются различимыми.
+func Pseudonym(id int) string {
+	return fmt.Sprintf("Обезличено #%d", id)
+}
+
+// AnonymizePartner заменяет ФИО директора псевдонимом и очищает телефон, email и адрес. Название,
+// ИНН, продажи и рейтинг не меняются.
+func AnonymizePartner(p models.Partner) models.Partner {
+	p.Director = Pseudonym(p.Id)
+	p.Phone, p.Email, p.Address = "", "", ""
+	return p
+}
+
+// AnonymizeContact заменяет имя контактного лица псевдонимом и очищает телефон и email.
+func AnonymizeContact(c models.Contact) models.Contact {
+	c.Name = Pseudonym(c.Id)
+	c.Phone, c.Email = "", ""
+	return c
+}
+
+// PartnerLinked сообщает, что запись журнала — контакт, заметка, файл или продажа партнера partnerId.
+func PartnerLinked(e models.AuditEntry, partnerId int) bool {
+	linked, ok := linkedPartner(e.Entity, e.EntityId, e.Before, e.After)
+	return ok && e.Entity != models.EntityPartner && linked == partnerId
+}
+
+// linkedPartner возвращает партнера, к которому относится запись журнала: сам партнер или
+// владелец контакта, заметки, файла или продажи по снимку. ok = false для остальных записей.
+func linkedPartner(entity string, id int, before, after string) (partnerId int, ok bool) {
+	switch entity {
+	case models.EntityPartner:
+		return id, true
+	case models.EntityContact, models.EntityNote, models.EntityAttachment, models.EntitySale:
+	default:
+		return 0, false
+	}
+	for _, data := range []string{before, after} {
+		var linked struct{ PartnerId int }
+		if data != "" && json.Unmarshal([]byte(data), &linked) == nil && linked.PartnerId != 0 {
+			return linked.PartnerId, true
+		}
+	}
+	return 0, false
+}
+
+var getPartnerAuditLog = getAuditLog + ` WHERE PartnerId = ? ORDER BY AuditId DESC`
+
+// partnerHistory возвращает изменения партнера и связанных с ним сущностей, в том числе уже удаленных.
+func partnerHistory(ctx context.Context, q querier, partnerId int) ([]models.AuditEntry, error) {
+	return queryAudit(ctx, q, getPartnerAuditLog, partnerId)
+}
+
+// ExportPartnerData собирает все данные партнера для ответа на запрос субъекта персональных данных.
+func (db *DB) ExportPartnerData(ctx context.Context, partnerId int) (models.PartnerData, error) {
+	if err := Authorize(ctx, models.PermManagePersonalData); err != nil {
+		return models.PartnerData{}, err
+	}
+	partner, err := readPartner(ctx, db.connect, partnerId)
+	if err != nil {
+		return models.PartnerData{}, err
+	}
+	if partner == nil {
+		return models.PartnerData{}, ErrPartnerNotFound
+	}
+
+	data := models.PartnerData{ExportedAt: time.Now().UTC().Format(time.RFC3339), Partner: *partner}
+	if data.Contacts, err = db.GetContacts(ctx, partnerId); err != nil {
+		return models.PartnerData{}, err
+	}
+	if data.Notes, err = db.GetNotes(ctx, partnerId); err != nil {
+		return models.PartnerData{}, err
+	}
+	attachments, err := db.GetAttachments(ctx, partnerId)
+	if err != nil {
+		return models.PartnerData{}, err
+	}
+	for _, f := range attachments {
+		_, content, err := db.ReadAttachment(ctx, f.Id)
+		if err != nil {
+			return models.PartnerData{}, err
+		}
+		data.Attachments = append(data.Attachments, models.AttachmentData{Attachment: f, Data: content})
+	}
+	if data.Sales, err = db.GetPartnerSales(ctx, partnerId); err != nil {
+		return models.PartnerData{}, err
+	}
+	if data.Ratings, err = db.GetRatingHistory(ctx, partnerId); err != nil {
+		return models.PartnerData{}, err
+	}
+	if data.History, err = partnerHistory(ctx, db.connect, partnerId); err != nil {
+		return models.PartnerData{}, err
+	}
+	return data, nil
+}
+
+var (
+	anonymizePartner = `update Partners set Director = ?, Phone = ?, Email = ?, LegalAddress = ? where PartnerId = ?`
+	anonymizeContact = `update PartnerContacts set Name = ?, Phone = ?, Email = ? where ContactId = ?`
+)
+
+// AnonymizePartner обезличивает партнера по запросу субъекта: личные данные партнера и его
+// контактных лиц заменяются псевдонимами и в таблицах, и в снимках журнала. Продажи и рейтинг
+// остаются, чтобы отчеты не менялись. Текст заметок и файлы не разбираются: если deleteNotes
+// не задан, упомянутые в них ФИО и контакты сохраняются. Резервные копии базы этот вызов
+// не меняет, прежние данные остаются в них до удаления копий.
+func (db *DB) AnonymizePartner(ctx context.Context, partnerId int, deleteNotes bool) error {
+	if err := Authorize(ctx, models.PermManagePersonalData); err != nil {
+		return err
+	}
+
+	tx, err := db.connect.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	partner, err := readPartner(ctx, tx, partnerId)
+	if err != nil {
+		return err
+	}
+	if partner == nil {
+		return ErrPartnerNotFound
+	}
+	anonymized, err := tx.sealPartner(ctx, AnonymizePartner(*partner))
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, anonymizePartner, anonymized.Director, anonymized.Phone, anonymized.Email, anonymized.Address, partnerId); err != nil {
+		return err
+	}
+
+	history, err := partnerHistory(ctx, tx, partnerId)
+	if err != nil {
+		return err
+	}
+	for _, e := range history {
+		if err := anonymizeAudit(ctx, tx, e, deleteNotes); err != nil {
+			return err
+		}
+	}
+	if deleteNotes {
+		if err := deletePartnerNotes(ctx, tx, partnerId); err != nil {
+			return err
+		}
+	}
+
+	contacts, err := queryContacts(ctx, tx, getContacts+` WHERE PartnerId = ?`, partnerId)
+	if err != nil {
+		return err
+	}
+	for _, c := range contacts {
+		c = AnonymizeContact(c)
+		if _, err := tx.ExecContext(ctx, anonymizeContact, c.Name, c.Phone, c.Email, c.Id); err != nil {
+			return err
+		}
+	}
+
+	// В журнал попадает только обезличенный снимок: прежние данные записывать уже нельзя.
+	if err := tx.audit(ctx, models.EntityPartner, partnerId, models.AuditAnonymize, nil, AnonymizePartner(*partner)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// AnonymizeAuditEntry заменяет личные данные в снимках записи журнала партнера или контакта.
+// Записи других сущностей возвращаются без изменений.
+func AnonymizeAuditEntry(e models.AuditEntry) (models.AuditEntry, error) {
+	for _, data := range []*string{&e.Before, &e.After} {
+		if *data == "" {
+			continue
+		}
+		var anonymized any
+		switch e.Entity {
+		case models.EntityPartner:
+			var p models.Partner
+			if err := json.Unmarshal([]byte(*data), &p); err != nil {
+				return e, err
+			}
+			p.Id = e.EntityId
+			anonymized = AnonymizePartner(p)
+		case models.EntityContact:
+			var c models.Contact
+			if err := json.Unmarshal([]byte(*data), &c); err != nil {
+				return e, err
+			}
+			c.Id = e.EntityId
+			anonymized = AnonymizeContact(c)
+		default:
+			return e, nil
+		}
+		var err error
+		if *data, err = AuditJSON(anonymized); err != nil {
+			return e, err
+		}
+	}
+	return e, nil
+}
+
+// RedactAuditEntry оставляет в снимках заметки или файла только идентификаторы: текст заметки
+// и имя файла тоже могут содержать личные данные. Записи других сущностей не меняются.
+func RedactAuditEntry(e models.AuditEntry) (models.AuditEntry, error) {
+	if e.Entity != models.EntityNote && e.Entity != models.EntityAttachment {
+		return e, nil
+	}
+	for _, data := range []*string{&e.Before, &e.After} {
+		if *data == "" {
+			continue
+		}
+		var ids struct{ Id, PartnerId int }
+		if err := json.Unmarshal([]byte(*data), &ids); err != nil {
+			return e, err
+		}
+		var err error
+		if *data, err = AuditJSON(redactedNote(e.Entity, ids.Id, ids.PartnerId)); err != nil {
+			return e, err
+		}
+	}
+	return e, nil
+}
+
+// redactedNote — снимок заметки или файла для журнала без текста и имени.
+func redactedNote(entity string, id, partnerId int) any {
+	if entity == models.EntityNote {
+		return models.Note{Id: id, PartnerId: partnerId}
+	}
+	return models.Attachment{Id: id, PartnerId: partnerId}
+}
+
+// deletePartnerNotes удаляет заметки и файлы партнера при обезличивании. В журнал удаление
+// попадает без текста и имени файла.
+func deletePartnerNotes(ctx context.Context, t tx, partnerId int) error {
+	for _, table := range []struct{ entity, ids, remove string }{
+		{models.EntityNote, `SELECT NoteId FROM PartnerNotes WHERE PartnerId = ?`, `DELETE FROM PartnerNotes WHERE NoteId = ?`},
+		{models.EntityAttachment, `SELECT AttachmentId FROM PartnerAttachments WHERE PartnerId = ?`, `DELETE FROM PartnerAttachments WHERE AttachmentId = ?`},
+	} {
+		rows, err := t.QueryContext(ctx, table.ids, partnerId)
+		if err != nil {
+			return err
+		}
+		var ids []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, id := range ids {
+			if _, err := t.ExecContext(ctx, table.remove, id); err != nil {
+				return err
+			}
+			if err := t.audit(ctx, table.entity, id, models.AuditDelete, redactedNote(table.entity, id, partnerId), nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// anonymizeAudit перезаписывает снимки записи журнала обезличенными. redact — заметки и файлы
+// удаляются, и их снимки тоже очищаются.
+func anonymizeAudit(ctx context.Context, t tx, e models.AuditEntry, redact bool) error {
+	e, err := AnonymizeAuditEntry(e)
+	if err != nil {
+		return err
+	}
+	if redact {
+		if e, err = RedactAuditEntry(e); err != nil {
+			return err
+		}
+	}
+	if e.Entity == models.EntityPartner {
+		if e.Before, err = t.sealAuditJSON(ctx, e.Before); err != nil {
+			return err
+		}
+		if e.After, err = t.sealAuditJSON(ctx, e.After); err != nil {
+			return err
+		}
+	}
+	_, err = t.ExecContext(ctx, setAuditData, e.Before, e.After, e.Id)
+	return err
+}
@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"context"
+	"github.com/ttrtcixy/demo/internal/models"
+	"time"
+)
+
+// RatingRule — рейтинг, который правила предлагают партнеру, продавшему не меньше MinQuantity продукции.
+type RatingRule struct {
+	MinQuantity float64
+	Rating      int
+}
+
+// RatingRules — пороги рейтинга по объему продаж по возрастанию.
+var RatingRules = []RatingRule{
+	{MinQuantity: 0, Rating: 3},
+	{MinQuantity: 10000, Rating: 5},
+	{MinQuantity: 50000, Rating: 7},
+	{MinQuantity: 300000, Rating: 9},
+}
+
+const (
+	// RatingInactivityPeriod — через сколько после последней продажи партнер считается неактивным.
+	RatingInactivityPeriod = 365 * 24 * time.Hour
+	// RatingInactivityPenalty — на сколько снижается рейтинг неактивного партнера.
+	RatingInactivityPenalty = 3
+	// RatingSuggestionThreshold — на сколько предложенный рейтинг должен отличаться от текущего,
+	// чтобы правила предложили изменение.
+	RatingSuggestionThreshold = 2
+)
+
+// ValidateRating проверяет, что рейтинг на шкале от models.MinRating до models.MaxRating.
+func ValidateRating(rating int) error {
+	if rating < models.MinRating || rating > models.MaxRating {
+		return ErrRatingOutOfRange
+	}
+	return nil
+}
+
+// PartnerActivity — продажи партнера, по которым правила предлагают рейтинг.
+type PartnerActivity struct {
+	PartnerId     int
+	CompanyName   string
+	Rating        int
+	TotalQuantity float64
+	// LastSaleDate — дата последней продажи, как она хранится в PartnerProducts. Пусто, если продаж не было.
+	LastSaleDate string
+}
+
+// SuggestRating применяет правила к продажам партнера: рейтинг по объему продаж из RatingRules,
+// сниженный на RatingInactivityPenalty, если продаж не было дольше RatingInactivityPeriod до now.
+// ok = false, если рейтинг отличается от текущего меньше чем на RatingSuggestionThreshold.
+func SuggestRating(a PartnerActivity, now time.Time) (s models.RatingSuggestion, ok bool) {
+	rating := models.MinRating
+	for _, rule := range RatingRules {
+		if a.TotalQuantity >= rule.MinQuantity {
+			rating = rule.Rating
+		}
+	}
+
+	reason := models.RatingReasonVolume
+	lastSale, err := time.Parse("2006-01-02", firstN(a.LastSaleDate, 10))
+	if err != nil || now.Sub(lastSale) > RatingInactivityPeriod {
+		reason = models.RatingReasonInactive
+		rating = max(models.MinRating, rating-RatingInactivityPenalty)
+	}
+	rating = min(rating, models.MaxRating)
+
+	s = models.RatingSuggestion{
+		PartnerId:       a.PartnerId,
+		CompanyName:     a.CompanyName,
+		CurrentRating:   a.Rating,
+		SuggestedRating: rating,
+		Reason:          reason,
+		TotalQuantity:   int(a.TotalQuantity),
+		LastSaleDate:    firstN(a.LastSaleDate, 10),
+	}
+	diff := rating - a.Rating
+	return s, diff >= RatingSuggestionThreshold || -diff >= RatingSuggestionThreshold
+}
+
+func firstN(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+var addRatingChange = `insert into RatingHistory(PartnerId, OldRating, NewRating, Reason, UserName) values(?, ?, ?, ?, ?)`
+
+// recordRating записывает изменение рейтинга в историю, если рейтинг действительно изменился.
+func (t tx) recordRating(ctx context.Context, partnerId, oldRating, newRating int, reason string) error {
+	if oldRating == newRating {
+		return nil
+	}
+	_, err := t.ExecContext(ctx, addRatingChange, partnerId, oldRating, newRating, reason, AuditUser(ctx))
+	return err
+}
+
+var getRatingHistory = `
+    SELECT RatingHistoryId, PartnerId, OldRating, NewRating, Reason, UserName, CreatedAt
+    FROM RatingHistory
+    WHERE PartnerId = ?
+    ORDER BY CreatedAt DESC, RatingHistoryId DESC`
+
+func (db *DB) GetRatingHistory(ctx context.Context, partnerId int) ([]models.RatingChange, error) {
+	rows, err := db.connect.QueryContext(ctx, getRatingHistory, partnerId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []models.RatingChange
+	for rows.Next() {
+		var c models.RatingChange
+		if err := rows.Scan(&c.Id, &c.PartnerId, &c.OldRating, &c.NewRating, &c.Reason, &c.User, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		history = append(history, c)
+	}
+	return history, rows.Err()
+}
+
+var getPartnerActivity = `
+    SELECT
+        p.PartnerId,
+        p.PartnerName,
+        COALESCE(p.Rating, 0),
+        COALESCE(SUM(pp.Quantity), 0),
+        COALESCE(MAX(pp.SaleDate), '')
+    FROM
+        Partners p
+    LEFT JOIN
+        PartnerProducts pp ON p.PartnerId = pp.PartnerId
+    GROUP BY
+        p.PartnerId, p.PartnerName, p.Rating
+    ORDER BY
+        p.PartnerId`
+
+var getRatingRejections = `SELECT PartnerId, CurrentRating, SuggestedRating FROM RatingRejections`
+
+// ratingRejection — ключ отклоненного предложения: такое же предложение больше не показывается.
+type ratingRejection struct {
+	partnerId, current, suggested int
+}
+
+func (db *DB) GetRatingSuggestions(ctx context.Context, now time.Time) ([]models.RatingSuggestion, error) {
+	rejected := map[ratingRejection]bool{}
+	rows, err := db.connect.QueryContext(ctx, getRatingRejections)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var r ratingRejection
+		if err := rows.Scan(&r.partnerId, &r.current, &r.suggested); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rejected[r] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = db.connect.QueryContext(ctx, getPartnerActivity)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suggestions []models.RatingSuggestion
+	for rows.Next() {
+		var a PartnerActivity
+		if err := rows.Scan(&a.PartnerId, &a.CompanyName, &a.Rating, &a.TotalQuantity, &a.LastSaleDate); err != nil {
+			return nil, err
+		}
+		s, ok := SuggestRating(a, now)
+		if ok && !rejected[ratingRejection{s.PartnerId, s.CurrentRating, s.SuggestedRating}] {
+			suggestions = append(suggestions, s)
+		}
+	}
+	return suggestions, rows.Err()
+}
+
+var setPartnerRating = `update Partners set Rating = ? where PartnerId = ?`
+
+func (db *DB) AcceptRatingSuggestion(ctx context.Context, s models.RatingSuggestion) error {
+	if err := Authorize(ctx, models.PermEditPartners); err != nil {
+		return err
+	}
+	if err := ValidateRating(s.SuggestedRating); err != nil {
+		return err
+	}
+
+	tx, err := db.connect.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := readPartner(ctx, tx, s.PartnerId)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return ErrPartnerNotFound
+	}
+	if before.Rating != s.CurrentRating {
+		return ErrSuggestionOutdated
+	}
+
+	if _, err := tx.ExecContext(ctx, setPartnerRating, s.SuggestedRating, s.PartnerId); err != nil {
+		return err
+	}
+	if err := tx.recordRating(ctx, s.PartnerId, before.Rating, s.SuggestedRating, s.Reason); err != nil {
+		return err
+	}
+	after, err := readPartner(ctx, tx, s.PartnerId)
+	if err != nil {
+		return err
+	}
+	if err := tx.audit(ctx, models.EntityPartner, s.PartnerId, models.AuditUpdate, before, after); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+var addRatingRejection = `insert into RatingRejections(PartnerId, CurrentRating, SuggestedRating, UserName) values(?, ?, ?, ?)`
+
+func (db *DB) RejectRatingSuggestion(ctx context.Context, s models.RatingSuggestion) error {
+	if err := Authorize(ctx, models.PermEditPartners); err != nil {
+		return err
+	}
+
+	_, err := db.connect.ExecContext(ctx, addRatingRejection, s.PartnerId, s.CurrentRating, s.SuggestedRating, AuditUser(ctx))
+	return err
+}
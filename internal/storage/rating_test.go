@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"github.com/ttrtcixy/demo/internal/models"
+	"testing"
+	"time"
+)
+
+func TestSuggestRating(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		activity   PartnerActivity
+		wantRating int
+		wantReason string
+		wantOk     bool
+	}{
+		{name: "объем выше рейтинга", activity: PartnerActivity{Rating: 5, TotalQuantity: 50000, LastSaleDate: "2024-01-01"}, wantRating: 7, wantReason: models.RatingReasonVolume, wantOk: true},
+		{name: "объем ниже рейтинга", activity: PartnerActivity{Rating: 7, TotalQuantity: 10000, LastSaleDate: "2024-02-01 10:00:00"}, wantRating: 5, wantReason: models.RatingReasonVolume, wantOk: true},
+		{name: "разница меньше порога", activity: PartnerActivity{Rating: 10, TotalQuantity: 300000, LastSaleDate: "2024-01-01"}, wantRating: 9, wantReason: models.RatingReasonVolume},
+		{name: "давняя продажа", activity: PartnerActivity{Rating: 7, TotalQuantity: 300000, LastSaleDate: "2023-05-01"}, wantRating: 6, wantReason: models.RatingReasonInactive},
+		{name: "давняя продажа и малый объем", activity: PartnerActivity{Rating: 7, TotalQuantity: 5000, LastSaleDate: "2023-03-23"}, wantRating: 0, wantReason: models.RatingReasonInactive, wantOk: true},
+		{name: "без продаж", activity: PartnerActivity{Rating: 5}, wantRating: 0, wantReason: models.RatingReasonInactive, wantOk: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ok := SuggestRating(tt.activity, now)
+			if s.SuggestedRating != tt.wantRating || s.Reason != tt.wantReason || ok != tt.wantOk {
+				t.Errorf("SuggestRating = %d %q %v, ожидалось %d %q %v", s.SuggestedRating, s.Reason, ok, tt.wantRating, tt.wantReason, tt.wantOk)
+			}
+		})
+	}
+}
+
+func TestValidateRating(t *testing.T) {
+	for _, rating := range []int{models.MinRating, 5, models.MaxRating} {
+		if err := ValidateRating(rating); err != nil {
+			t.Errorf("ValidateRating(%d): %v", rating, err)
+		}
+	}
+	for _, rating := range []int{models.MinRating - 1, models.MaxRating + 1} {
+		if err := ValidateRating(rating); err != ErrRatingOutOfRange {
+			t.Errorf("ValidateRating(%d) = %v, ожидалась ErrRatingOutOfRange", rating, err)
+		}
+	}
+}
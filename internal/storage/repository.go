@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"context"
+	"github.com/ttrtcixy/demo/internal/models"
+	"time"
+)
+
+// Интерфейсы, через которые UI работает с хранилищем. DB реализует их все;
+// memory.Store — реализация в памяти для тестов. Все методы принимают context.Context:
+// UI отменяет загрузку, если она больше не нужна.
+
+type PartnerRepository interface {
+	GetPartners(ctx context.Context) (*models.Partners, error)
+	AddPartner(ctx context.Context, partner models.Partner) error
+	UpdatePartner(ctx context.Context, partner models.Partner) error
+	// DeletePartner удаляет партнера вместе с его продажами, контактами и заметками. Удаленные
+	// продажи записываются в журнал отдельными записями.
+	DeletePartner(ctx context.Context, id int) error
+	FindPartner(ctx context.Context, searchTerm string) (int, string, error)
+	// UnparsedPhones возвращает партнеров, чей телефон не удалось привести к E.164.
+	UnparsedPhones(ctx context.Context) ([]PhoneProblem, error)
+	// FindContactDuplicates возвращает других партнеров (кроме partner.Id) с тем же email или
+	// телефоном. Email сравниваются после mailaddr.Normalize: регистр домена не важен, регистр
+	// локальной части важен. Пустые email и телефон не сравниваются, партнеры с нерасшифровываемыми
+	// данными пропускаются.
+	FindContactDuplicates(ctx context.Context, partner models.Partner) ([]models.ContactDuplicate, error)
+}
+
+type SalesRepository interface {
+	GetPartnerSales(ctx context.Context, id int) ([]models.PartnerSale, error)
+	// GetMonthlySales возвращает продажи партнера по месяцам в порядке возрастания. Месяцы без продаж пропускаются.
+	GetMonthlySales(ctx context.Context, id int) ([]models.MonthlySales, error)
+	// GetTopProducts возвращает не больше limit продуктов партнера по убыванию проданного количества.
+	GetTopProducts(ctx context.Context, id int, limit int) ([]models.ProductSales, error)
+}
+
+// RatingRepository — история рейтинга партнеров и предложения его изменить по правилам SuggestRating.
+// Рейтинг, измененный через UpdatePartner, тоже попадает в историю.
+type RatingRepository interface {
+	// GetRatingHistory возвращает изменения рейтинга партнера, новые первыми.
+	GetRatingHistory(ctx context.Context, partnerId int) ([]models.RatingChange, error)
+	// GetRatingSuggestions возвращает предложения на момент now, кроме отклоненных.
+	GetRatingSuggestions(ctx context.Context, now time.Time) ([]models.RatingSuggestion, error)
+	AcceptRatingSuggestion(ctx context.Context, s models.RatingSuggestion) error
+	RejectRatingSuggestion(ctx context.Context, s models.RatingSuggestion) error
+}
+
+// DashboardRepository — сводные показатели по всем партнерам для вкладки обзора.
+type DashboardRepository interface {
+	GetSalesTotals(ctx context.Context) (models.SalesTotals, error)
+	// GetMonthlyTotals возвращает продажи всех партнеров по месяцам в порядке возрастания.
+	GetMonthlyTotals(ctx context.Context) ([]models.MonthlySales, error)
+	// GetTopPartners возвращает не больше limit партнеров по убыванию проданного количества.
+	GetTopPartners(ctx context.Context, limit int) ([]models.PartnerTotal, error)
+	// GetDiscountDistribution возвращает число партнеров по порогам скидок. Пороги без партнеров пропускаются.
+	GetDiscountDistribution(ctx context.Context) ([]models.DiscountTierCount, error)
+	GetPartnerTypeDistribution(ctx context.Context) ([]models.PartnerTypeCount, error)
+}
+
+type CatalogRepository interface {
+	GetProducts(ctx context.Context) ([]string, error)
+	GetMaterialTypes(ctx context.Context) ([]string, error)
+	GetProductParameters(ctx context.Context, productId string) ([]models.ProductParameter, error)
+}
+
+type MaterialCalculator interface {
+	CalculateMaterial(ctx context.Context, productId, materialId string, quantity int, param1, param2 float64) (models.MaterialCalculation, error)
+	CalculateMaxProducts(ctx context.Context, productId, materialId string, available int, param1, param2 float64) (int, error)
+	CalculatePlan(ctx context.Context, plan models.ProductionPlan) ([]models.MaterialRequirement, error)
+	SaveCalculation(ctx context.Context, calc models.MaterialCalculation) error
+	GetCalculationHistory(ctx context.Context) ([]models.MaterialCalculation, error)
+}
+
+type PlanRepository interface {
+	GetPlans(ctx context.Context) ([]models.ProductionPlan, error)
+	GetPlan(ctx context.Context, id int) (*models.ProductionPlan, error)
+	SavePlan(ctx context.Context, plan models.ProductionPlan) (int, error)
+	DeletePlan(ctx context.Context, id int) error
+}
+
+// AuditRepository — журнал изменений. Записи добавляют сами методы, изменяющие данные.
+type AuditRepository interface {
+	GetAuditLog(ctx context.Context) ([]models.AuditEntry, error)
+	GetEntityHistory(ctx context.Context, entity string, id int) ([]models.AuditEntry, error)
+}
+
+// ContactRepository — контактные лица партнеров. Изменять их может тот, кто может изменять партнеров.
+type ContactRepository interface {
+	GetContacts(ctx context.Context, partnerId int) ([]models.Contact, error)
+	// GetAllContacts возвращает контакты всех партнеров по возрастанию PartnerId, для экспорта.
+	GetAllContacts(ctx context.Context) ([]models.Contact, error)
+	AddContact(ctx context.Context, contact models.Contact) (int, error)
+	// UpdateContact меняет роль, имя, телефон и email. Партнер контакта не меняется.
+	UpdateContact(ctx context.Context, contact models.Contact) error
+	DeleteContact(ctx context.Context, id int) error
+}
+
+// NoteRepository — заметки и файлы партнеров. Добавлять и удалять их может тот, кто может изменять партнеров.
+type NoteRepository interface {
+	// GetNotes возвращает заметки партнера, новые первыми.
+	GetNotes(ctx context.Context, partnerId int) ([]models.Note, error)
+	AddNote(ctx context.Context, partnerId int, text string) (int, error)
+	DeleteNote(ctx context.Context, id int) error
+	// GetAttachments возвращает описания файлов партнера без содержимого, новые первыми.
+	GetAttachments(ctx context.Context, partnerId int) ([]models.Attachment, error)
+	// AddAttachment сохраняет файл. Файл с тем же содержимым у партнера дает ErrAttachmentExists.
+	AddAttachment(ctx context.Context, partnerId int, fileName string, data []byte) (int, error)
+	// ReadAttachment возвращает содержимое файла, проверив его по сохраненной контрольной сумме.
+	ReadAttachment(ctx context.Context, id int) (models.Attachment, []byte, error)
+	DeleteAttachment(ctx context.Context, id int) error
+}
+
+// UserRepository — учетные записи. Управлять пользователями может только администратор.
+type UserRepository interface {
+	HasUsers(ctx context.Context) (bool, error)
+	Authenticate(ctx context.Context, login, password string) (*models.User, error)
+	GetUsers(ctx context.Context) ([]models.User, error)
+	CreateUser(ctx context.Context, login, password, role string) error
+	DeleteUser(ctx context.Context, id int) error
+}
+
+// PrivacyRepository — запросы субъектов персональных данных: выгрузка всего, что связано
+// с партнером, и обезличивание. Доступно администратору.
+type PrivacyRepository interface {
+	ExportPartnerData(ctx context.Context, partnerId int) (models.PartnerData, error)
+	// AnonymizePartner заменяет личные данные партнера и его контактных лиц псевдонимами,
+	// в том числе в журнале. Продажи и рейтинг сохраняются. deleteNotes удаляет и заметки
+	// с файлами партнера: их содержимое не обезличивается.
+	AnonymizePartner(ctx context.Context, partnerId int, deleteNotes bool) error
+}
+
+// EncryptionRepository — шифрование личных данных партнеров ключом из пароля. EnableEncryption
+// и Unlock вызываются при запуске до входа пользователя, смена ключа доступна администратору.
+type EncryptionRepository interface {
+	EncryptionStatus(ctx context.Context) (EncryptionStatus, error)
+	EnableEncryption(ctx context.Context, passphrase string) error
+	// Unlock проверяет пароль; неверный пароль дает ErrWrongPassphrase.
+	Unlock(ctx context.Context, passphrase string) error
+	// RotateKey перешифровывает данные ключом из next, если current — текущий пароль.
+	RotateKey(ctx context.Context, current, next string) error
+}
+
+var (
+	_ PartnerRepository    = (*DB)(nil)
+	_ SalesRepository      = (*DB)(nil)
+	_ DashboardRepository  = (*DB)(nil)
+	_ RatingRepository     = (*DB)(nil)
+	_ ContactRepository    = (*DB)(nil)
+	_ NoteRepository       = (*DB)(nil)
+	_ CatalogRepository    = (*DB)(nil)
+	_ MaterialCalculator   = (*DB)(nil)
+	_ PlanRepository       = (*DB)(nil)
+	_ AuditRepository      = (*DB)(nil)
+	_ UserRepository       = (*DB)(nil)
+	_ EncryptionRepository = (*DB)(nil)
+	_ PrivacyRepository    = (*DB)(nil)
+)
+
+// DiscountTier — порог скидки: партнер, продавший не меньше MinQuantity продукции, получает Percentage.
+type DiscountTier struct {
+	MinQuantity float64
+	Percentage  int
+}
+
+// DiscountTiers — пороги скидок по возрастанию. По ним же строятся запросы SQL (discountCase).
+var DiscountTiers = []DiscountTier{
+	{MinQuantity: 0, Percentage: 0},
+	{MinQuantity: 10000, Percentage: 5},
+	{MinQuantity: 50000, Percentage: 10},
+	{MinQuantity: 300000, Percentage: 15},
+}
+
+// DiscountPercentage — скидка партнера по общему количеству проданной продукции.
+func DiscountPercentage(totalQuantity float64) int {
+	percentage := 0
+	for _, tier := range DiscountTiers {
+		if totalQuantity >= tier.MinQuantity {
+			percentage = tier.Percentage
+		}
+	}
+	return percentage
+}
+
+// NextDiscountTier возвращает ближайший порог выше текущей скидки. ok = false, если скидка уже максимальная.
+func NextDiscountTier(totalQuantity float64) (tier DiscountTier, ok bool) {
+	for _, tier := range DiscountTiers {
+		if totalQuantity < tier.MinQuantity {
+			return tier, true
+		}
+	}
+	return DiscountTier{}, false
+}
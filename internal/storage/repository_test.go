@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestDiscountPercentage(t *testing.T) {
+	tests := []struct {
+		quantity float64
+		want     int
+	}{
+		{0, 0},
+		{9999, 0},
+		{9999.5, 0},
+		{10000, 5},
+		{49999, 5},
+		{49999.5, 5},
+		{50000, 10},
+		{299999, 10},
+		{300000, 15},
+		{1000000, 15},
+	}
+
+	for _, tt := range tests {
+		if got := DiscountPercentage(tt.quantity); got != tt.want {
+			t.Errorf("DiscountPercentage(%v) = %d, ожидалось %d", tt.quantity, got, tt.want)
+		}
+	}
+}
+
+// TestDiscountCase сверяет скидку, которую считает SQL из discountCase, с DiscountPercentage.
+func TestDiscountCase(t *testing.T) {
+	d, err := sql.Open(DriverSQLite, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	query := `WITH q(Total) AS (SELECT ?) SELECT ` + discountCase("Total") + ` FROM q`
+	for _, quantity := range []float64{0, 9999, 10000, 49999, 50000, 299999, 300000, 1000000} {
+		var got int
+		if err := d.QueryRow(query, quantity).Scan(&got); err != nil {
+			t.Fatal(err)
+		}
+		if want := DiscountPercentage(quantity); got != want {
+			t.Errorf("discountCase для %v = %d, ожидалось %d", quantity, got, want)
+		}
+	}
+}
+
+func TestNextDiscountTier(t *testing.T) {
+	tests := []struct {
+		quantity float64
+		want     DiscountTier
+		ok       bool
+	}{
+		{0, DiscountTier{MinQuantity: 10000, Percentage: 5}, true},
+		{9999.5, DiscountTier{MinQuantity: 10000, Percentage: 5}, true},
+		{10000, DiscountTier{MinQuantity: 50000, Percentage: 10}, true},
+		{299999, DiscountTier{MinQuantity: 300000, Percentage: 15}, true},
+		{300000, DiscountTier{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := NextDiscountTier(tt.quantity)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NextDiscountTier(%v) = %+v, %v, ожидалось %+v, %v", tt.quantity, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package storage
+
+import "context"
+
+// sqliteSchema создает таблицы и их начальные данные. Выполняется при каждом запуске,
+// поэтому все запросы должны быть идемпотентными. Первые таблицы повторяют исходную test.db.
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS ProductTypes (
+    ProductTypeId INTEGER PRIMARY KEY AUTOINCREMENT,
+    ProductType TEXT NOT NULL,
+    Coefficient REAL
+)`,
+	`CREATE TABLE IF NOT EXISTS Partners (
+    PartnerId INTEGER PRIMARY KEY AUTOINCREMENT,
+    PartnerType TEXT,
+    PartnerName TEXT NOT NULL,
+    Director TEXT,
+    Email TEXT,
+    Phone TEXT,
+    LegalAddress TEXT,
+    INN TEXT UNIQUE,
+    Rating INTEGER
+)`,
+	`CREATE TABLE IF NOT EXISTS Products (
+    ProductId INTEGER PRIMARY KEY AUTOINCREMENT,
+    ProductTypeId INTEGER NOT NULL,
+    ProductName TEXT NOT NULL,
+    Article TEXT,
+    MinCostKopecks INTEGER,
+    FOREIGN KEY (ProductTypeId) REFERENCES ProductTypes(ProductTypeId)
+)`,
+	`CREATE TABLE IF NOT EXISTS PartnerProducts (
+    PartnerProductId INTEGER PRIMARY KEY AUTOINCREMENT,
+    ProductId INTEGER NOT NULL,
+    PartnerId INTEGER NOT NULL,
+    Quantity REAL NOT NULL,
+    SaleDate TEXT DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (PartnerId) REFERENCES Partners(PartnerId) ON DELETE CASCADE,
+    FOREIGN KEY (ProductId) REFERENCES Products(ProductId) ON DELETE CASCADE
+)`,
+	`CREATE TABLE IF NOT EXISTS MaterialTypes (
+    MaterialTypeId INTEGER PRIMARY KEY AUTOINCREMENT,
+    MaterialType TEXT NOT NULL,
+    DefectPercentage REAL
+)`,
+	`CREATE TABLE IF NOT EXISTS ProductionPlans (
+    PlanId INTEGER PRIMARY KEY AUTOINCREMENT,
+    PlanName TEXT NOT NULL,
+    CreatedAt TEXT DEFAULT CURRENT_TIMESTAMP
+)`,
+	`CREATE TABLE IF NOT EXISTS ProductionPlanRows (
+    PlanRowId INTEGER PRIMARY KEY AUTOINCREMENT,
+    PlanId INTEGER NOT NULL,
+    ProductId INTEGER NOT NULL,
+    MaterialTypeId INTEGER NOT NULL,
+    Quantity INTEGER NOT NULL,
+    Param1 REAL NOT NULL,
+    Param2 REAL NOT NULL,
+    FOREIGN KEY (PlanId) REFERENCES ProductionPlans(PlanId) ON DELETE CASCADE,
+    FOREIGN KEY (ProductId) REFERENCES Products(ProductId),
+    FOREIGN KEY (MaterialTypeId) REFERENCES MaterialTypes(MaterialTypeId)
+)`,
+	`CREATE TABLE IF NOT EXISTS MaterialCalculations (
+    CalculationId INTEGER PRIMARY KEY AUTOINCREMENT,
+    ProductId INTEGER NOT NULL,
+    ProductName TEXT NOT NULL,
+    MaterialTypeId INTEGER NOT NULL,
+    MaterialType TEXT NOT NULL,
+    Quantity INTEGER NOT NULL,
+    Param1 REAL NOT NULL,
+    Param2 REAL NOT NULL,
+    ProductCoefficient REAL NOT NULL,
+    DefectPercentage REAL NOT NULL,
+    MaterialPerUnit REAL NOT NULL,
+    BaseTotal REAL NOT NULL,
+    DefectAllowance REAL NOT NULL,
+    Rounding REAL NOT NULL,
+    Required INTEGER NOT NULL,
+    CreatedAt TEXT DEFAULT CURRENT_TIMESTAMP
+)`,
+	`CREATE TABLE IF NOT EXISTS ProductTypeParameters (
+    ParameterId INTEGER PRIMARY KEY AUTOINCREMENT,
+    ProductTypeId INTEGER NOT NULL,
+    Position INTEGER NOT NULL CHECK (Position IN (1, 2)),
+    Name TEXT NOT NULL,
+    Unit TEXT NOT NULL DEFAULT '',
+    MinValue REAL,
+    MaxValue REAL,
+    UNIQUE (ProductTypeId, Position),
+    FOREIGN KEY (ProductTypeId) REFERENCES ProductTypes(ProductTypeId) ON DELETE CASCADE
+)`,
+	`CREATE TABLE IF NOT EXISTS AuditLog (
+    AuditId INTEGER PRIMARY KEY AUTOINCREMENT,
+    Entity TEXT NOT NULL,
+    EntityId INTEGER NOT NULL,
+    PartnerId INTEGER,
+    Operation TEXT NOT NULL,
+    UserName TEXT NOT NULL,
+    BeforeData TEXT NOT NULL DEFAULT '',
+    AfterData TEXT NOT NULL DEFAULT '',
+    CreatedAt TEXT DEFAULT CURRENT_TIMESTAMP
+)`,
+	`CREATE INDEX IF NOT EXISTS AuditLogEntity ON AuditLog(Entity, EntityId)`,
+	`CREATE TABLE IF NOT EXISTS Users (
+    UserId INTEGER PRIMARY KEY AUTOINCREMENT,
+    Login TEXT NOT NULL UNIQUE,
+    PasswordHash TEXT NOT NULL,
+    Role TEXT NOT NULL CHECK (Role IN ('admin', 'manager', 'viewer'))
+)`,
+	`CREATE TABLE IF NOT EXISTS RatingHistory (
+    RatingHistoryId INTEGER PRIMARY KEY AUTOINCREMENT,
+    PartnerId INTEGER NOT NULL,
+    OldRating INTEGER NOT NULL,
+    NewRating INTEGER NOT NULL,
+    Reason TEXT NOT NULL,
+    UserName TEXT NOT NULL,
+    CreatedAt TEXT DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (PartnerId) REFERENCES Partners(PartnerId) ON DELETE CASCADE
+)`,
+	`CREATE INDEX IF NOT EXISTS RatingHistoryPartner ON RatingHistory(PartnerId)`,
+	// Отклоненные предложения рейтинга больше не показываются, пока у партнера не изменится
+	// текущий или предлагаемый рейтинг.
+	`CREATE TABLE IF NOT EXISTS RatingRejections (
+    RejectionId INTEGER PRIMARY KEY AUTOINCREMENT,
+    PartnerId INTEGER NOT NULL,
+    CurrentRating INTEGER NOT NULL,
+    SuggestedRating INTEGER NOT NULL,
+    UserName TEXT NOT NULL,
+    CreatedAt TEXT DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (PartnerId) REFERENCES Partners(PartnerId) ON DELETE CASCADE
+)`,
+	`CREATE TABLE IF NOT EXISTS PartnerContacts (
+    ContactId INTEGER PRIMARY KEY AUTOINCREMENT,
+    PartnerId INTEGER NOT NULL,
+    Role TEXT NOT NULL,
+    Name TEXT NOT NULL,
+    Phone TEXT NOT NULL DEFAULT '',
+    Email TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (PartnerId) REFERENCES Partners(PartnerId) ON DELETE CASCADE
+)`,
+	`CREATE INDEX IF NOT EXISTS PartnerContactsPartner ON PartnerContacts(PartnerId)`,
+	`CREATE TABLE IF NOT EXISTS PartnerNotes (
+    NoteId INTEGER PRIMARY KEY AUTOINCREMENT,
+    PartnerId INTEGER NOT NULL,
+    Text TEXT NOT NULL,
+    UserName TEXT NOT NULL,
+    CreatedAt TEXT DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (PartnerId) REFERENCES Partners(PartnerId) ON DELETE CASCADE
+)`,
+	`CREATE INDEX IF NOT EXISTS PartnerNotesPartner ON PartnerNotes(PartnerId)`,
+	// Файлы хранятся в самой базе, поэтому попадают в ее резервные копии.
+	`CREATE TABLE IF NOT EXISTS PartnerAttachments (
+    AttachmentId INTEGER PRIMARY KEY AUTOINCREMENT,
+    PartnerId INTEGER NOT NULL,
+    FileName TEXT NOT NULL,
+    Size INTEGER NOT NULL,
+    Hash TEXT NOT NULL,
+    Data BLOB NOT NULL,
+    UserName TEXT NOT NULL,
+    CreatedAt TEXT DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (PartnerId, Hash),
+    FOREIGN KEY (PartnerId) REFERENCES Partners(PartnerId) ON DELETE CASCADE
+)`,
+	// Разовые преобразования данных, см. dataMigrations.
+	`CREATE TABLE IF NOT EXISTS Migrations (
+    Name TEXT PRIMARY KEY,
+    AppliedAt TEXT DEFAULT CURRENT_TIMESTAMP
+)`,
+	// Параметры шифрования личных данных, не больше одной строки; см. EnableEncryption.
+	`CREATE TABLE IF NOT EXISTS Encryption (
+    Id INTEGER PRIMARY KEY CHECK (Id = 1),
+    Salt TEXT NOT NULL,
+    KeyCheck TEXT NOT NULL
+)`,
+}
+
+func (db *DB) migrate(ctx context.Context) error {
+	for _, query := range db.connect.dialect.schema {
+		if _, err := db.connect.ExecContext(ctx, query); err != nil {
+			return err
+		}
+	}
+	if err := db.loadEncryption(ctx); err != nil {
+		return err
+	}
+	return db.migrateData(ctx)
+}
@@ -0,0 +1,156 @@
+package storage
+
+// postgresSchema — та же схема, что sqliteSchema, в синтаксисе PostgreSQL.
+// Даты хранятся текстом в формате CURRENT_TIMESTAMP SQLite, чтобы оба хранилища возвращали одинаковые строки.
+var postgresSchema = []string{
+	`CREATE TABLE IF NOT EXISTS ProductTypes (
+    ProductTypeId INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    ProductType TEXT NOT NULL,
+    Coefficient DOUBLE PRECISION
+)`,
+	`CREATE TABLE IF NOT EXISTS Partners (
+    PartnerId INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    PartnerType TEXT,
+    PartnerName TEXT NOT NULL,
+    Director TEXT,
+    Email TEXT,
+    Phone TEXT,
+    LegalAddress TEXT,
+    INN TEXT UNIQUE,
+    Rating INTEGER
+)`,
+	`CREATE TABLE IF NOT EXISTS Products (
+    ProductId INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    ProductTypeId INTEGER NOT NULL REFERENCES ProductTypes(ProductTypeId),
+    ProductName TEXT NOT NULL,
+    Article TEXT,
+    MinCostKopecks BIGINT
+)`,
+	`CREATE TABLE IF NOT EXISTS PartnerProducts (
+    PartnerProductId INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    ProductId INTEGER NOT NULL REFERENCES Products(ProductId) ON DELETE CASCADE,
+    PartnerId INTEGER NOT NULL REFERENCES Partners(PartnerId) ON DELETE CASCADE,
+    Quantity DOUBLE PRECISION NOT NULL,
+    SaleDate TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS')
+)`,
+	`CREATE TABLE IF NOT EXISTS MaterialTypes (
+    MaterialTypeId INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    MaterialType TEXT NOT NULL,
+    DefectPercentage DOUBLE PRECISION
+)`,
+	`CREATE TABLE IF NOT EXISTS ProductionPlans (
+    PlanId INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    PlanName TEXT NOT NULL,
+    CreatedAt TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS')
+)`,
+	`CREATE TABLE IF NOT EXISTS ProductionPlanRows (
+    PlanRowId INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    PlanId INTEGER NOT NULL REFERENCES ProductionPlans(PlanId) ON DELETE CASCADE,
+    ProductId INTEGER NOT NULL REFERENCES Products(ProductId),
+    MaterialTypeId INTEGER NOT NULL REFERENCES MaterialTypes(MaterialTypeId),
+    Quantity INTEGER NOT NULL,
+    Param1 DOUBLE PRECISION NOT NULL,
+    Param2 DOUBLE PRECISION NOT NULL
+)`,
+	`CREATE TABLE IF NOT EXISTS MaterialCalculations (
+    CalculationId INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    ProductId INTEGER NOT NULL,
+    ProductName TEXT NOT NULL,
+    MaterialTypeId INTEGER NOT NULL,
+    MaterialType TEXT NOT NULL,
+    Quantity INTEGER NOT NULL,
+    Param1 DOUBLE PRECISION NOT NULL,
+    Param2 DOUBLE PRECISION NOT NULL,
+    ProductCoefficient DOUBLE PRECISION NOT NULL,
+    DefectPercentage DOUBLE PRECISION NOT NULL,
+    MaterialPerUnit DOUBLE PRECISION NOT NULL,
+    BaseTotal DOUBLE PRECISION NOT NULL,
+    DefectAllowance DOUBLE PRECISION NOT NULL,
+    Rounding DOUBLE PRECISION NOT NULL,
+    Required INTEGER NOT NULL,
+    CreatedAt TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS')
+)`,
+	`CREATE TABLE IF NOT EXISTS ProductTypeParameters (
+    ParameterId INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    ProductTypeId INTEGER NOT NULL REFERENCES ProductTypes(ProductTypeId) ON DELETE CASCADE,
+    Position INTEGER NOT NULL CHECK (Position IN (1, 2)),
+    Name TEXT NOT NULL,
+    Unit TEXT NOT NULL DEFAULT '',
+    MinValue DOUBLE PRECISION,
+    MaxValue DOUBLE PRECISION,
+    UNIQUE (ProductTypeId, Position)
+)`,
+	`CREATE TABLE IF NOT EXISTS AuditLog (
+    AuditId INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    Entity TEXT NOT NULL,
+    EntityId INTEGER NOT NULL,
+    PartnerId INTEGER,
+    Operation TEXT NOT NULL,
+    UserName TEXT NOT NULL,
+    BeforeData TEXT NOT NULL DEFAULT '',
+    AfterData TEXT NOT NULL DEFAULT '',
+    CreatedAt TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS')
+)`,
+	`CREATE INDEX IF NOT EXISTS AuditLogEntity ON AuditLog(Entity, EntityId)`,
+	`CREATE TABLE IF NOT EXISTS Users (
+    UserId INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    Login TEXT NOT NULL UNIQUE,
+    PasswordHash TEXT NOT NULL,
+    Role TEXT NOT NULL CHECK (Role IN ('admin', 'manager', 'viewer'))
+)`,
+	`CREATE TABLE IF NOT EXISTS RatingHistory (
+    RatingHistoryId INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    PartnerId INTEGER NOT NULL REFERENCES Partners(PartnerId) ON DELETE CASCADE,
+    OldRating INTEGER NOT NULL,
+    NewRating INTEGER NOT NULL,
+    Reason TEXT NOT NULL,
+    UserName TEXT NOT NULL,
+    CreatedAt TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS')
+)`,
+	`CREATE INDEX IF NOT EXISTS RatingHistoryPartner ON RatingHistory(PartnerId)`,
+	`CREATE TABLE IF NOT EXISTS RatingRejections (
+    RejectionId INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    PartnerId INTEGER NOT NULL REFERENCES Partners(PartnerId) ON DELETE CASCADE,
+    CurrentRating INTEGER NOT NULL,
+    SuggestedRating INTEGER NOT NULL,
+    UserName TEXT NOT NULL,
+    CreatedAt TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS')
+)`,
+	`CREATE TABLE IF NOT EXISTS PartnerContacts (
+    ContactId INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    PartnerId INTEGER NOT NULL REFERENCES Partners(PartnerId) ON DELETE CASCADE,
+    Role TEXT NOT NULL,
+    Name TEXT NOT NULL,
+    Phone TEXT NOT NULL DEFAULT '',
+    Email TEXT NOT NULL DEFAULT ''
+)`,
+	`CREATE INDEX IF NOT EXISTS PartnerContactsPartner ON PartnerContacts(PartnerId)`,
+	`CREATE TABLE IF NOT EXISTS PartnerNotes (
+    NoteId INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    PartnerId INTEGER NOT NULL REFERENCES Partners(PartnerId) ON DELETE CASCADE,
+    Text TEXT NOT NULL,
+    UserName TEXT NOT NULL,
+    CreatedAt TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS')
+)`,
+	`CREATE INDEX IF NOT EXISTS PartnerNotesPartner ON PartnerNotes(PartnerId)`,
+	`CREATE TABLE IF NOT EXISTS PartnerAttachments (
+    AttachmentId INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+    PartnerId INTEGER NOT NULL REFERENCES Partners(PartnerId) ON DELETE CASCADE,
+    FileName TEXT NOT NULL,
+    Size INTEGER NOT NULL,
+    Hash TEXT NOT NULL,
+    Data BYTEA NOT NULL,
+    UserName TEXT NOT NULL,
+    CreatedAt TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS'),
+    UNIQUE (PartnerId, Hash)
+)`,
+	`CREATE TABLE IF NOT EXISTS Migrations (
+    Name TEXT PRIMARY KEY,
+    AppliedAt TEXT DEFAULT to_char(now() AT TIME ZONE 'UTC', 'YYYY-MM-DD HH24:MI:SS')
+)`,
+	`CREATE TABLE IF NOT EXISTS Encryption (
+    Id INTEGER PRIMARY KEY CHECK (Id = 1),
+    Salt TEXT NOT NULL,
+    KeyCheck TEXT NOT NULL
+)`,
+}
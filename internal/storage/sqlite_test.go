@@ -0,0 +1,728 @@
+package storage_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/storage"
+	"github.com/ttrtcixy/demo/internal/storage/storagetest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// openSQLite создает пустую базу dsn и заполняет ее фикстурой.
+func openSQLite(t *testing.T, dsn string, f storagetest.Fixture) *storage.DB {
+	t.Helper()
+
+	db, err := storage.NewDB(t.Context(), storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	seed, err := sql.Open(storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer seed.Close()
+	if err := storagetest.Seed(seed, storage.DriverSQLite, f); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSQLiteConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, f storagetest.Fixture) storagetest.Repository {
+		return openSQLite(t, filepath.Join(t.TempDir(), "test.db"), f)
+	})
+}
+
+// TestSQLiteInMemoryConformance повторяет набор на базе в памяти. Общий кэш нужен, чтобы
+// все соединения пула и соединение для фикстуры видели одну базу.
+func TestSQLiteInMemoryConformance(t *testing.T) {
+	n := 0
+	storagetest.Run(t, func(t *testing.T, f storagetest.Fixture) storagetest.Repository {
+		n++
+		return openSQLite(t, fmt.Sprintf("file:conformance%d?mode=memory&cache=shared", n), f)
+	})
+}
+
+// TestSQLitePhoneMigration открывает базу со старыми телефонами: распознанные приводятся к E.164,
+// остальные попадают в отчет и не меняются. Повторное открытие миграцию не выполняет.
+func TestSQLitePhoneMigration(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	raw, err := sql.Open(storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	for _, query := range []string{
+		`CREATE TABLE Partners (
+    PartnerId INTEGER PRIMARY KEY AUTOINCREMENT,
+    PartnerType TEXT,
+    PartnerName TEXT NOT NULL,
+    Director TEXT,
+    Email TEXT,
+    Phone TEXT,
+    LegalAddress TEXT,
+    INN TEXT UNIQUE,
+    Rating INTEGER
+)`,
+		`INSERT INTO Partners(PartnerId, PartnerType, PartnerName, Director, Email, Phone, LegalAddress, Rating) VALUES
+    (1, 'ЗАО', 'МонтажПро', 'Степанов С. С.', 'stepanov@stepan.ru', '912 888 33 33', 'Старый Оскол', 10),
+    (2, 'ООО', 'Паркет 29', 'Петров В. П.', 'petrov@vl.ru', '8 (987) 123-56-78', 'Северодвинск', 7),
+    (3, 'ПАО', 'Стройсервис', 'Соловьев А. Н.', 'solovev@st.ru', '+79122233200', 'Приморск', 7),
+    (4, 'ОАО', 'Ремонт и отделка', 'Воробьева Е. В.', 'vorobeva@ml.ru', 'звонить секретарю', 'Реутов', 5),
+    (5, 'ИП', 'Без телефона', 'Сидоров С. С.', 'none@ml.ru', NULL, 'Москва', 1)`,
+	} {
+		if _, err := raw.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err := storage.NewDB(t.Context(), storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems, err := db.UnparsedPhones(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	want := []storage.PhoneProblem{{PartnerId: 4, PartnerName: "Ремонт и отделка", Phone: "звонить секретарю"}}
+	if len(problems) != 1 || problems[0] != want[0] {
+		t.Errorf("UnparsedPhones() = %v, ожидалось %v", problems, want)
+	}
+
+	phones := map[int]string{}
+	rows, err := raw.Query(`SELECT PartnerId, COALESCE(Phone, '') FROM Partners`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var id int
+		var phone string
+		if err := rows.Scan(&id, &phone); err != nil {
+			t.Fatal(err)
+		}
+		phones[id] = phone
+	}
+	rows.Close()
+	wantPhones := map[int]string{1: "+79128883333", 2: "+79871235678", 3: "+79122233200", 4: "звонить секретарю", 5: ""}
+	for id, phone := range wantPhones {
+		if phones[id] != phone {
+			t.Errorf("телефон партнера %d = %q, ожидалось %q", id, phones[id], phone)
+		}
+	}
+
+	var audited int
+	if err := raw.QueryRow(`SELECT COUNT(*) FROM AuditLog WHERE Entity = 'partner' AND UserName = 'system'`).Scan(&audited); err != nil {
+		t.Fatal(err)
+	}
+	if audited != 2 {
+		t.Errorf("записей в журнале: %d, ожидалось 2", audited)
+	}
+
+	if _, err := raw.Exec(`UPDATE Partners SET Phone = '912 888 33 33' WHERE PartnerId = 1`); err != nil {
+		t.Fatal(err)
+	}
+	db, err = storage.NewDB(t.Context(), storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// Миграция уже применена, но нераспознанный телефон по-прежнему в списке.
+	if problems, err := db.UnparsedPhones(t.Context()); err != nil || len(problems) != 1 || problems[0] != want[0] {
+		t.Errorf("после повторного открытия: UnparsedPhones() = %v, %v", problems, err)
+	}
+	var phone string
+	if err := raw.QueryRow(`SELECT Phone FROM Partners WHERE PartnerId = 1`).Scan(&phone); err != nil {
+		t.Fatal(err)
+	}
+	if phone != "912 888 33 33" {
+		t.Errorf("миграция выполнена повторно: телефон %q", phone)
+	}
+}
+
+// TestSQLiteAuditPartnerMigration открывает базу с журналом без PartnerId: миграция связывает
+// старые записи с партнерами по снимкам, и история партнера выбирается по столбцу.
+func TestSQLiteAuditPartnerMigration(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	raw, err := sql.Open(storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	for _, query := range []string{
+		`CREATE TABLE Partners (
+    PartnerId INTEGER PRIMARY KEY AUTOINCREMENT,
+    PartnerType TEXT,
+    PartnerName TEXT NOT NULL,
+    Director TEXT,
+    Email TEXT,
+    Phone TEXT,
+    LegalAddress TEXT,
+    INN TEXT UNIQUE,
+    Rating INTEGER
+)`,
+		`INSERT INTO Partners(PartnerId, PartnerType, PartnerName, Director, Email, Phone, LegalAddress, Rating) VALUES
+    (1, 'ЗАО', 'МонтажПро', 'Степанов С. С.', 'stepanov@stepan.ru', '+79128883333', 'Старый Оскол', 10)`,
+		`CREATE TABLE AuditLog (
+    AuditId INTEGER PRIMARY KEY AUTOINCREMENT,
+    Entity TEXT NOT NULL,
+    EntityId INTEGER NOT NULL,
+    Operation TEXT NOT NULL,
+    UserName TEXT NOT NULL,
+    BeforeData TEXT NOT NULL DEFAULT '',
+    AfterData TEXT NOT NULL DEFAULT '',
+    CreatedAt TEXT DEFAULT CURRENT_TIMESTAMP
+)`,
+		`INSERT INTO AuditLog(AuditId, Entity, EntityId, Operation, UserName, BeforeData, AfterData) VALUES
+    (1, 'partner', 1, 'update', 'admin', '{"Rating":9}', '{"Rating":10}'),
+    (2, 'note', 7, 'create', 'admin', '', '{"Id":7,"PartnerId":1,"Text":"Перезвонить"}'),
+    (3, 'contact', 8, 'delete', 'admin', '{"Id":8,"PartnerId":2,"Name":"Орлова Д. А."}', ''),
+    (4, 'plan', 1, 'create', 'admin', '', '{"Id":1}')`,
+	} {
+		if _, err := raw.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err := storage.NewDB(t.Context(), storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := raw.Query(`SELECT AuditId, COALESCE(PartnerId, 0) FROM AuditLog WHERE AuditId <= 4 ORDER BY AuditId`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for rows.Next() {
+		var auditId, partnerId int
+		if err := rows.Scan(&auditId, &partnerId); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%d:%d", auditId, partnerId))
+	}
+	rows.Close()
+	if fmt.Sprint(got) != "[1:1 2:1 3:2 4:0]" {
+		t.Errorf("PartnerId записей журнала = %v, ожидалось [1:1 2:1 3:2 4:0]", got)
+	}
+
+	data, err := db.ExportPartnerData(storagetest.As(t, models.RoleAdmin), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, e := range data.History {
+		ids = append(ids, e.Id)
+	}
+	if fmt.Sprint(ids) != "[2 1]" {
+		t.Errorf("история партнера = %v, ожидались записи [2 1]", ids)
+	}
+}
+
+// TestSQLiteMinCostMigration открывает базу со стоимостью в REAL: цены переносятся в копейки
+// без потери точности, суммы продаж считаются точно, а столбец MinCost удаляется.
+func TestSQLiteMinCostMigration(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	raw, err := sql.Open(storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	for _, query := range []string{
+		`CREATE TABLE ProductTypes (
+    ProductTypeId INTEGER PRIMARY KEY AUTOINCREMENT,
+    ProductType TEXT NOT NULL,
+    Coefficient REAL
+)`,
+		`CREATE TABLE Products (
+    ProductId INTEGER PRIMARY KEY AUTOINCREMENT,
+    ProductTypeId INTEGER NOT NULL,
+    ProductName TEXT NOT NULL,
+    Article TEXT,
+    MinCost REAL,
+    FOREIGN KEY (ProductTypeId) REFERENCES ProductTypes(ProductTypeId)
+)`,
+		`INSERT INTO ProductTypes(ProductTypeId, ProductType, Coefficient) VALUES (1, 'Ламинат', 2.35)`,
+		`INSERT INTO Products(ProductId, ProductTypeId, ProductName, MinCost) VALUES
+    (1, 1, 'Ламинат Дуб', 1799.33),
+    (2, 1, 'Ламинат Орех', 19.99),
+    (3, 1, 'Ламинат Бук', 4456.9),
+    (4, 1, 'Ламинат Клен', NULL)`,
+	} {
+		if _, err := raw.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err := storage.NewDB(t.Context(), storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	costs := map[int]sql.NullInt64{}
+	rows, err := raw.Query(`SELECT ProductId, MinCostKopecks FROM Products`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+		var id int
+		var cost sql.NullInt64
+		if err := rows.Scan(&id, &cost); err != nil {
+			t.Fatal(err)
+		}
+		costs[id] = cost
+	}
+	rows.Close()
+	wantCosts := map[int]sql.NullInt64{1: {Int64: 179933, Valid: true}, 2: {Int64: 1999, Valid: true}, 3: {Int64: 445690, Valid: true}, 4: {}}
+	for id, cost := range wantCosts {
+		if costs[id] != cost {
+			t.Errorf("стоимость продукта %d = %+v, ожидалось %+v", id, costs[id], cost)
+		}
+	}
+
+	var oldColumns int
+	if err := raw.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('Products') WHERE name = 'MinCost'`).Scan(&oldColumns); err != nil {
+		t.Fatal(err)
+	}
+	if oldColumns != 0 {
+		t.Error("столбец MinCost не удален")
+	}
+
+	if _, err := raw.Exec(`INSERT INTO Partners(PartnerId, PartnerType, PartnerName) VALUES (1, 'ООО', 'Паркет 29')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := raw.Exec(`INSERT INTO PartnerProducts(ProductId, PartnerId, Quantity, SaleDate) VALUES (1, 1, 3, '2024-01-01')`); err != nil {
+		t.Fatal(err)
+	}
+	sales, err := db.GetPartnerSales(t.Context(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sales) != 1 || sales[0].TotalSum != 3*179933 {
+		t.Errorf("GetPartnerSales = %+v, ожидалась сумма 5397.99", sales)
+	}
+}
+
+// TestSQLiteParameterSeed проверяет параметры, которые получают типы продукции из старой базы:
+// известные типы — свои названия, остальные — длину и ширину, все без диапазона. Описания,
+// удаленные администратором, при следующем открытии базы не появляются снова.
+func TestSQLiteParameterSeed(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	raw, err := sql.Open(storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	for _, query := range []string{
+		`CREATE TABLE ProductTypes (
+    ProductTypeId INTEGER PRIMARY KEY AUTOINCREMENT,
+    ProductType TEXT NOT NULL,
+    Coefficient REAL
+)`,
+		`INSERT INTO ProductTypes(ProductTypeId, ProductType, Coefficient) VALUES (1, 'Ламинат', 2.35), (2, 'Винил', 1.2)`,
+	} {
+		if _, err := raw.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	parameters := func() []string {
+		t.Helper()
+
+		rows, err := raw.Query(`SELECT ProductTypeId, Position, Name, Unit, MinValue, MaxValue FROM ProductTypeParameters ORDER BY ProductTypeId, Position`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var got []string
+		for rows.Next() {
+			var typeId, position int
+			var name, unit string
+			var min, max sql.NullFloat64
+			if err := rows.Scan(&typeId, &position, &name, &unit, &min, &max); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, fmt.Sprintf("%d/%d %s, %s", typeId, position, name, unit))
+			if min.Valid || max.Valid {
+				t.Errorf("у параметра %s задан диапазон %v-%v", got[len(got)-1], min, max)
+			}
+		}
+		return got
+	}
+
+	db, err := storage.NewDB(t.Context(), storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := parameters()
+	want := []string{"1/1 Длина панели, м", "1/2 Ширина панели, м", "2/1 Длина, м", "2/2 Ширина, м"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("параметры = %q, ожидалось %q", got, want)
+	}
+	if _, err := raw.Exec(`DELETE FROM ProductTypeParameters WHERE ProductTypeId = 2`); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	db, err = storage.NewDB(t.Context(), storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if got := parameters(); len(got) != 2 {
+		t.Errorf("после повторного открытия параметры = %q, ожидались только параметры типа 1", got)
+	}
+}
+
+// TestSQLiteDeletePartnerCascade проверяет по самой базе, что продажи, контакты и заметки
+// партнера удаляет внешний ключ, а удаленные продажи остаются в журнале.
+func TestSQLiteDeletePartnerCascade(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	db := openSQLite(t, dsn, storagetest.Default)
+	admin := storagetest.As(t, models.RoleAdmin)
+	if _, err := db.AddNote(admin, 301, "Перезвонить"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeletePartner(admin, 301); err != nil {
+		t.Fatalf("DeletePartner: %v", err)
+	}
+
+	raw, err := sql.Open(storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	for _, table := range []string{"PartnerProducts", "PartnerContacts", "PartnerNotes"} {
+		var n int
+		if err := raw.QueryRow(`SELECT COUNT(*) FROM ` + table + ` WHERE PartnerId = 301`).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("в %s осталось %d строк удаленного партнера", table, n)
+		}
+	}
+
+	rows, err := raw.Query(`SELECT EntityId FROM AuditLog WHERE Entity = ? AND Operation = ? ORDER BY EntityId`, models.EntitySale, models.AuditDelete)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if fmt.Sprint(ids) != "[2 3 4]" {
+		t.Errorf("в журнале удалены продажи %v, ожидались [2 3 4]", ids)
+	}
+}
+
+// TestSQLiteFirstAdminConcurrent создает первого администратора одновременно с двух клиентов.
+func TestSQLiteFirstAdminConcurrent(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	var clients []storagetest.Repository
+	for range 2 {
+		db, err := storage.NewDB(t.Context(), storage.DriverSQLite, dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		clients = append(clients, db)
+	}
+	storagetest.FirstAdminConcurrent(t, clients...)
+}
+
+// TestSQLiteAttachmentCorrupt портит содержимое файла в базе: ReadAttachment должен заметить
+// несовпадение контрольной суммы.
+func TestSQLiteAttachmentCorrupt(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	db := openSQLite(t, dsn, storagetest.Default)
+	id, err := db.AddAttachment(storagetest.As(t, models.RoleManager), 300, "договор.pdf", []byte("договор поставки"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := sql.Open(storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	if _, err := raw.Exec(`UPDATE PartnerAttachments SET Data = ? WHERE AttachmentId = ?`, []byte("договор аренды"), id); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := db.ReadAttachment(t.Context(), id); !errors.Is(err, storage.ErrAttachmentCorrupt) {
+		t.Errorf("ReadAttachment: ошибка %v, ожидалась %v", err, storage.ErrAttachmentCorrupt)
+	}
+}
+
+// TestSQLiteBackupRestore делает копию, удаляет партнера и восстанавливает базу из копии.
+func TestSQLiteBackupRestore(t *testing.T) {
+	dir := t.TempDir()
+	db := openSQLite(t, filepath.Join(dir, "test.db"), storagetest.Default)
+	// "?" и "#" в пути не должны читаться драйвером как параметры DSN.
+	copyDir := filepath.Join(dir, "копии?#1")
+	if err := os.Mkdir(copyDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	copyPath := filepath.Join(copyDir, "copy.db")
+	if err := db.Backup(t.Context(), copyPath); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+
+	summary, err := db.ValidateBackup(t.Context(), copyPath)
+	if err != nil {
+		t.Fatalf("ValidateBackup: %v", err)
+	}
+	if summary.Partners != len(storagetest.Default.Partners) || summary.Sales != len(storagetest.Default.Sales) || summary.LastSale == "" {
+		t.Errorf("ValidateBackup = %+v", summary)
+	}
+
+	if err := db.DeletePartner(storagetest.As(t, models.RoleAdmin), 300); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Restore(storagetest.As(t, models.RoleManager), copyPath); !errors.Is(err, storage.ErrForbidden) {
+		t.Errorf("Restore от менеджера: ошибка %v", err)
+	}
+	if err := db.Restore(storagetest.As(t, models.RoleAdmin), copyPath); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	partners, err := db.GetPartners(t.Context())
+	if err != nil || len(*partners) == 0 || (*partners)[0].Id != 300 {
+		t.Errorf("после восстановления GetPartners = %v, %v", partners, err)
+	}
+}
+
+func TestSQLiteValidateBackup(t *testing.T) {
+	dir := t.TempDir()
+	db := openSQLite(t, filepath.Join(dir, "test.db"), storagetest.Default)
+
+	garbage := filepath.Join(dir, "garbage.db")
+	if err := os.WriteFile(garbage, []byte("это не база данных, а текстовый файл достаточной длины"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	foreign := filepath.Join(dir, "foreign.db")
+	raw, err := sql.Open(storage.DriverSQLite, foreign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := raw.Exec(`CREATE TABLE Notes (Id INTEGER PRIMARY KEY, Text TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	raw.Close()
+
+	tests := []struct {
+		name, path, code string
+	}{
+		{name: "нет файла", path: filepath.Join(dir, "missing.db"), code: "error.backup_invalid"},
+		{name: "не база", path: garbage, code: "error.backup_invalid"},
+		{name: "чужая база", path: foreign, code: "error.backup_missing_table"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := db.ValidateBackup(t.Context(), tt.path)
+			var e *storage.Error
+			if !errors.As(err, &e) || e.Code != tt.code {
+				t.Errorf("ValidateBackup: ошибка %v, ожидался код %s", err, tt.code)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing.db")); !os.IsNotExist(err) {
+		t.Errorf("проверка создала файл: %v", err)
+	}
+}
+
+// TestSQLiteEncryptedConformance повторяет набор на базе, в которой личные данные зашифрованы.
+func TestSQLiteEncryptedConformance(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T, f storagetest.Fixture) storagetest.Repository {
+		db := openSQLite(t, filepath.Join(t.TempDir(), "test.db"), f)
+		if err := db.EnableEncryption(t.Context(), storagetest.Passphrase); err != nil {
+			t.Fatal(err)
+		}
+		return db
+	})
+}
+
+// TestSQLiteEncryption проверяет, что личные данные лежат в файле зашифрованными, а после
+// повторного открытия база заблокирована до ввода пароля.
+func TestSQLiteEncryption(t *testing.T) {
+	dir := t.TempDir()
+	dsn := filepath.Join(dir, "test.db")
+	db := openSQLite(t, dsn, storagetest.Default)
+	admin := storagetest.As(t, models.RoleAdmin)
+
+	if err := db.EnableEncryption(t.Context(), storagetest.Passphrase); err != nil {
+		t.Fatal(err)
+	}
+	partner := storagetest.Default.Partners[1]
+	partner.Rating = 8
+	if err := db.UpdatePartner(admin, partner); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := sql.Open(storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	var director, email, audit string
+	if err := raw.QueryRow(`SELECT Director, Email FROM Partners WHERE PartnerId = 301`).Scan(&director, &email); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(director, "enc1:") || !strings.HasPrefix(email, "enc1:") {
+		t.Errorf("личные данные в файле открыты: %q, %q", director, email)
+	}
+	if err := raw.QueryRow(`SELECT BeforeData || AfterData FROM AuditLog WHERE Entity = 'partner'`).Scan(&audit); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(audit, partner.Director) || strings.Contains(audit, partner.Email) {
+		t.Errorf("личные данные в журнале открыты: %s", audit)
+	}
+
+	copyPath := filepath.Join(dir, "copy.db")
+	if err := db.Backup(t.Context(), copyPath); err != nil {
+		t.Fatal(err)
+	}
+	const next = "новый пароль шифрования"
+	if err := db.RotateKey(admin, storagetest.Passphrase, next); err != nil {
+		t.Fatalf("RotateKey: %v", err)
+	}
+	db.Close()
+
+	db, err = storage.NewDB(t.Context(), storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if status, _ := db.EncryptionStatus(t.Context()); status != storage.EncryptionLocked {
+		t.Errorf("после открытия EncryptionStatus = %v", status)
+	}
+	if _, err := db.GetPartners(admin); !errors.Is(err, storage.ErrDatabaseLocked) {
+		t.Errorf("GetPartners без пароля: ошибка %v", err)
+	}
+	if err := db.AddPartner(admin, models.Partner{CompanyName: "Новый", Director: "Новиков Н. Н.", Rating: 1}); !errors.Is(err, storage.ErrDatabaseLocked) {
+		t.Errorf("AddPartner без пароля: ошибка %v", err)
+	}
+	if err := db.Unlock(t.Context(), storagetest.Passphrase); !errors.Is(err, storage.ErrWrongPassphrase) {
+		t.Errorf("Unlock старым паролем: ошибка %v", err)
+	}
+	if err := db.Unlock(t.Context(), next); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	history, err := db.GetEntityHistory(admin, models.EntityPartner, 301)
+	if err != nil || len(history) != 1 || !strings.Contains(history[0].Before, partner.Director) {
+		t.Errorf("история партнера = %+v, %v", history, err)
+	}
+
+	// Копия зашифрована прежним паролем: после восстановления база снова заблокирована.
+	if err := db.Restore(admin, copyPath); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if status, _ := db.EncryptionStatus(t.Context()); status != storage.EncryptionLocked {
+		t.Errorf("после восстановления EncryptionStatus = %v", status)
+	}
+	if err := db.Unlock(t.Context(), storagetest.Passphrase); err != nil {
+		t.Fatalf("Unlock паролем копии: %v", err)
+	}
+	partners, err := db.GetPartners(admin)
+	if err != nil || (*partners)[1].Director != partner.Director {
+		t.Errorf("после восстановления GetPartners = %v, %v", partners, err)
+	}
+}
+
+// TestSQLiteSharedKeyRotation открывает одну базу двумя клиентами. После смены пароля первым
+// второй не может писать старым ключом, пока не введет новый пароль. Строка, записанная
+// старым ключом, не закрывает список партнеров.
+func TestSQLiteSharedKeyRotation(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	first := openSQLite(t, dsn, storagetest.Default)
+	admin := storagetest.As(t, models.RoleAdmin)
+	if err := first.EnableEncryption(t.Context(), storagetest.Passphrase); err != nil {
+		t.Fatal(err)
+	}
+	second, err := storage.NewDB(t.Context(), storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	if err := second.Unlock(t.Context(), storagetest.Passphrase); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := sql.Open(storage.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+	var staleDirector string
+	if err := raw.QueryRow(`SELECT Director FROM Partners WHERE PartnerId = 302`).Scan(&staleDirector); err != nil {
+		t.Fatal(err)
+	}
+
+	const next = "новый пароль шифрования"
+	if err := first.RotateKey(admin, storagetest.Passphrase, next); err != nil {
+		t.Fatal(err)
+	}
+	partner := storagetest.Default.Partners[1]
+	partner.Rating = 9
+	if err := second.UpdatePartner(admin, partner); !errors.Is(err, storage.ErrDatabaseLocked) {
+		t.Fatalf("UpdatePartner старым ключом: ошибка %v, ожидалась ErrDatabaseLocked", err)
+	}
+	if status, _ := second.EncryptionStatus(t.Context()); status != storage.EncryptionLocked {
+		t.Errorf("после отказа EncryptionStatus = %v", status)
+	}
+	if err := second.Unlock(t.Context(), next); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.UpdatePartner(admin, partner); err != nil {
+		t.Fatalf("UpdatePartner после Unlock: %v", err)
+	}
+
+	// Так выглядит строка, которую клиент без проверки ключа записал бы после смены пароля.
+	if _, err := raw.Exec(`UPDATE Partners SET Director = ? WHERE PartnerId = 302`, staleDirector); err != nil {
+		t.Fatal(err)
+	}
+	partners, err := first.GetPartners(admin)
+	if !errors.Is(err, storage.ErrUndecryptable) || partners == nil {
+		t.Fatalf("GetPartners = %v, ошибка %v, ожидался список и ErrUndecryptable", partners, err)
+	}
+	found := 0
+	for _, p := range *partners {
+		switch p.Id {
+		case 302:
+			found++
+			if p.Director != "" || p.Email != "" {
+				t.Errorf("у партнера со старым ключом личные данные: %+v", p)
+			}
+		case 301:
+			found++
+			if p.Director != partner.Director {
+				t.Errorf("партнер 301 = %+v", p)
+			}
+		}
+	}
+	if found != 2 {
+		t.Errorf("в списке нет партнеров 301 и 302: %+v", *partners)
+	}
+	if !strings.Contains(err.Error(), "партнеры "+storagetest.Default.Partners[2].CompanyName) {
+		t.Errorf("ошибка не называет партнера: %v", err)
+	}
+
+	duplicates, err := first.FindContactDuplicates(admin, models.Partner{Email: partner.Email})
+	if err != nil || len(duplicates) != 1 || duplicates[0].Partner.Id != 301 {
+		t.Errorf("FindContactDuplicates рядом со строкой старого ключа = %+v, %v", duplicates, err)
+	}
+}
@@ -0,0 +1,92 @@
+package storagetest
+
+import (
+	"database/sql"
+	"github.com/ttrtcixy/demo/internal/storage"
+	"github.com/ttrtcixy/demo/internal/storage/memory"
+	"strconv"
+	"strings"
+)
+
+// NewMemory создает хранилище в памяти с данными f.
+func NewMemory(f Fixture) *memory.Store {
+	s := memory.NewStore()
+	s.ProductTypes = append(s.ProductTypes, f.ProductTypes...)
+	s.Products = append(s.Products, f.Products...)
+	s.MaterialTypes = append(s.MaterialTypes, f.MaterialTypes...)
+	s.Parameters = append(s.Parameters, f.Parameters...)
+	s.Partners = append(s.Partners, f.Partners...)
+	s.Sales = append(s.Sales, f.Sales...)
+	s.Contacts = append(s.Contacts, f.Contacts...)
+	return s
+}
+
+// Seed записывает фикстуру в базу, в которой уже созданы таблицы. Используется отдельное
+// подключение, поэтому Seed не зависит от внутренностей storage.DB.
+func Seed(db *sql.DB, driver string, f Fixture) error {
+	exec := func(query string, args ...any) error {
+		if driver == storage.DriverPostgres {
+			query = numberPlaceholders(query)
+		}
+		_, err := db.Exec(query, args...)
+		return err
+	}
+
+	for _, t := range f.ProductTypes {
+		if err := exec(`INSERT INTO ProductTypes(ProductTypeId, ProductType, Coefficient) VALUES (?, ?, ?)`,
+			t.Id, t.Name, t.Coefficient); err != nil {
+			return err
+		}
+	}
+	for _, p := range f.Products {
+		if err := exec(`INSERT INTO Products(ProductId, ProductTypeId, ProductName, MinCostKopecks) VALUES (?, ?, ?, ?)`,
+			p.Id, p.ProductTypeId, p.Name, p.MinCost); err != nil {
+			return err
+		}
+	}
+	for _, m := range f.MaterialTypes {
+		if err := exec(`INSERT INTO MaterialTypes(MaterialTypeId, MaterialType, DefectPercentage) VALUES (?, ?, ?)`,
+			m.Id, m.Name, m.DefectPercentage); err != nil {
+			return err
+		}
+	}
+	for _, p := range f.Parameters {
+		if err := exec(`INSERT INTO ProductTypeParameters(ProductTypeId, Position, Name, Unit, MinValue, MaxValue) VALUES (?, ?, ?, ?, ?, ?)`,
+			p.ProductTypeId, p.Position, p.Name, p.Unit, p.MinValue, p.MaxValue); err != nil {
+			return err
+		}
+	}
+	for _, p := range f.Partners {
+		if err := exec(`INSERT INTO Partners(PartnerId, PartnerType, PartnerName, Director, Email, Phone, LegalAddress, Rating) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			p.Id, p.PartnerType, p.CompanyName, p.Director, p.Email, p.Phone, p.Address, p.Rating); err != nil {
+			return err
+		}
+	}
+	for _, s := range f.Sales {
+		if err := exec(`INSERT INTO PartnerProducts(ProductId, PartnerId, Quantity, SaleDate) VALUES (?, ?, ?, ?)`,
+			s.ProductId, s.PartnerId, s.Quantity, s.SaleDate); err != nil {
+			return err
+		}
+	}
+	for _, c := range f.Contacts {
+		if err := exec(`INSERT INTO PartnerContacts(ContactId, PartnerId, Role, Name, Phone, Email) VALUES (?, ?, ?, ?, ?, ?)`,
+			c.Id, c.PartnerId, c.Role, c.Name, c.Phone, c.Email); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func numberPlaceholders(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,1580 @@
+// Package storagetest — общий набор тестов, которому должна соответствовать каждая реализация хранилища.
+// Каждая реализация запускает Run из своего _test.go, передавая функцию, которая
+// создает пустое хранилище и заполняет его фикстурой.
+package storagetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ttrtcixy/demo/internal/models"
+	"github.com/ttrtcixy/demo/internal/money"
+	"github.com/ttrtcixy/demo/internal/storage"
+	"github.com/ttrtcixy/demo/internal/storage/memory"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type Repository interface {
+	storage.PartnerRepository
+	storage.ContactRepository
+	storage.NoteRepository
+	storage.SalesRepository
+	storage.DashboardRepository
+	storage.RatingRepository
+	storage.CatalogRepository
+	storage.MaterialCalculator
+	storage.PlanRepository
+	storage.AuditRepository
+	storage.UserRepository
+	storage.EncryptionRepository
+	storage.PrivacyRepository
+}
+
+// Passphrase — пароль шифрования, которым набор включает шифрование. Тот же пароль должна
+// использовать open, если возвращает уже зашифрованное хранилище.
+const Passphrase = "пароль шифрования"
+
+// As возвращает контекст операций от имени пользователя с ролью role.
+func As(t *testing.T, role string) context.Context {
+	return storage.WithUser(t.Context(), models.User{Login: role, Role: role})
+}
+
+// Fixture — начальные данные хранилища. Идентификаторы партнеров начинаются с 300, чтобы не пересекаться
+// с идентификаторами, которые выдает AddPartner.
+type Fixture struct {
+	ProductTypes  []memory.ProductType
+	Products      []memory.Product
+	MaterialTypes []memory.MaterialType
+	Parameters    []models.ProductParameter
+	Partners      []models.Partner
+	Sales         []memory.Sale
+	Contacts      []models.Contact
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+// Default покрывает все пороги скидок: 0, 5, 10 и 15 процентов, и партнера без продаж.
+var Default = Fixture{
+	ProductTypes: []memory.ProductType{
+		{Id: 1, Name: "Паркетная доска", Coefficient: 4.34},
+		{Id: 2, Name: "Ламинат", Coefficient: 2.35},
+	},
+	Products: []memory.Product{
+		{Id: 1, ProductTypeId: 1, Name: "Паркетная доска Ясень", MinCost: 445690},
+		{Id: 2, ProductTypeId: 2, Name: "Ламинат Дуб", MinCost: 179933},
+		{Id: 3, ProductTypeId: 2, Name: "Ламинат Орех", MinCost: 200000},
+	},
+	MaterialTypes: []memory.MaterialType{
+		{Id: 1, Name: "Тип материала 1", DefectPercentage: 0.1},
+		{Id: 2, Name: "Тип материала 2", DefectPercentage: 0.95},
+		{Id: 3, Name: "Без брака", DefectPercentage: 0},
+	},
+	Parameters: []models.ProductParameter{
+		{ProductTypeId: 1, Position: 1, Name: "Длина", Unit: "м", MinValue: float(0.01), MaxValue: float(100)},
+		{ProductTypeId: 1, Position: 2, Name: "Ширина", Unit: "м", MinValue: float(0.01), MaxValue: float(10)},
+	},
+	Partners: []models.Partner{
+		{Id: 300, PartnerType: "ЗАО", CompanyName: "База Строитель", Director: "Иванова А. И.", Phone: "493 123 45 67", Rating: 7, Email: "ivanova@ml.ru", Address: "Юрга"},
+		{Id: 301, PartnerType: "ООО", CompanyName: "Паркет 29", Director: "Петров В. П.", Phone: "987 123 56 78", Rating: 7, Email: "petrov@vl.ru", Address: "Северодвинск"},
+		{Id: 302, PartnerType: "ПАО", CompanyName: "Стройсервис", Director: "Соловьев А. Н.", Phone: "812 223 32 00", Rating: 7, Email: "solovev@st.ru", Address: "Приморск"},
+		{Id: 303, PartnerType: "ОАО", CompanyName: "Ремонт и отделка", Director: "Воробьева Е. В.", Phone: "444 222 33 11", Rating: 5, Email: "vorobeva@ml.ru", Address: "Реутов"},
+		{Id: 304, PartnerType: "ЗАО", CompanyName: "МонтажПро", Director: "Степанов С. С.", Phone: "912 888 33 33", Rating: 10, Email: "stepanov@stepan.ru", Address: "Старый Оскол"},
+		{Id: 305, PartnerType: "ИП", CompanyName: "Без продаж", Director: "Сидоров С. С.", Phone: "900 000 00 00", Rating: 1, Email: "none@ml.ru", Address: "Москва"},
+	},
+	Sales: []memory.Sale{
+		{PartnerId: 300, ProductId: 1, Quantity: 5000, SaleDate: "2023-03-23"},
+		{PartnerId: 301, ProductId: 1, Quantity: 4000, SaleDate: "2023-01-10"},
+		{PartnerId: 301, ProductId: 2, Quantity: 6000, SaleDate: "2024-02-01"},
+		{PartnerId: 301, ProductId: 3, Quantity: 0, SaleDate: "2023-06-15"},
+		{PartnerId: 302, ProductId: 2, Quantity: 49999, SaleDate: "2024-01-01"},
+		{PartnerId: 303, ProductId: 2, Quantity: 50000, SaleDate: "2024-01-01"},
+		{PartnerId: 304, ProductId: 3, Quantity: 300000, SaleDate: "2024-01-01"},
+	},
+	Contacts: []models.Contact{
+		{Id: 100, PartnerId: 301, Role: models.ContactBuyer, Name: "Смирнова О. Л.", Phone: "+79001112233", Email: "zakupki@parket29.ru"},
+		{Id: 101, PartnerId: 301, Role: models.ContactAccountant, Name: "Кузнецов П. Р.", Email: "buh@parket29.ru"},
+		{Id: 102, PartnerId: 303, Role: models.ContactLogistics, Name: "Орлов Д. А.", Phone: "+79005556677"},
+	},
+}
+
+// Run выполняет общий набор тестов. open должна возвращать новое хранилище с данными f.
+func Run(t *testing.T, open func(t *testing.T, f Fixture) Repository) {
+	t.Run("GetPartnersDiscounts", func(t *testing.T) { testGetPartnersDiscounts(t, open(t, Default)) })
+	t.Run("GetPartnersEmpty", func(t *testing.T) { testGetPartnersEmpty(t, open(t, Fixture{})) })
+	t.Run("AddUpdatePartner", func(t *testing.T) { testAddUpdatePartner(t, open(t, Default)) })
+	t.Run("DeletePartner", func(t *testing.T) { testDeletePartner(t, open(t, Default)) })
+	t.Run("FindPartner", func(t *testing.T) { testFindPartner(t, open(t, Default)) })
+	t.Run("UnparsedPhones", func(t *testing.T) { testUnparsedPhones(t, open(t, Default)) })
+	t.Run("Contacts", func(t *testing.T) { testContacts(t, open(t, Default)) })
+	t.Run("Notes", func(t *testing.T) { testNotes(t, open(t, Default)) })
+	t.Run("Attachments", func(t *testing.T) { testAttachments(t, open(t, Default)) })
+	t.Run("FindContactDuplicates", func(t *testing.T) { testFindContactDuplicates(t, open(t, Default)) })
+	t.Run("GetPartnerSales", func(t *testing.T) { testGetPartnerSales(t, open(t, Default)) })
+	t.Run("SalesSummary", func(t *testing.T) { testSalesSummary(t, open(t, withSummarySales(Default))) })
+	t.Run("Dashboard", func(t *testing.T) { testDashboard(t, open(t, Default)) })
+	t.Run("DashboardEmpty", func(t *testing.T) { testDashboardEmpty(t, open(t, Fixture{})) })
+	t.Run("Catalog", func(t *testing.T) { testCatalog(t, open(t, Default)) })
+	t.Run("CalculateMaterial", func(t *testing.T) { testCalculateMaterial(t, open(t, Default)) })
+	t.Run("CalculateMaxProducts", func(t *testing.T) { testCalculateMaxProducts(t, open(t, Default)) })
+	t.Run("Plans", func(t *testing.T) { testPlans(t, open(t, Default)) })
+	t.Run("CalculationHistory", func(t *testing.T) { testCalculationHistory(t, open(t, Default)) })
+	t.Run("Audit", func(t *testing.T) { testAudit(t, open(t, Default)) })
+	t.Run("Ratings", func(t *testing.T) { testRatings(t, open(t, Default)) })
+	t.Run("Permissions", func(t *testing.T) { testPermissions(t, open(t, Default)) })
+	t.Run("Users", func(t *testing.T) { testUsers(t, open(t, Default)) })
+	t.Run("Privacy", func(t *testing.T) { testPrivacy(t, open(t, Default)) })
+	t.Run("Encryption", func(t *testing.T) { testEncryption(t, open(t, Default)) })
+	t.Run("Canceled", func(t *testing.T) { testCanceled(t, open(t, Default)) })
+}
+
+func testGetPartnersDiscounts(t *testing.T, repo Repository) {
+	partners, err := repo.GetPartners(t.Context())
+	if err != nil {
+		t.Fatalf("GetPartners: %v", err)
+	}
+
+	want := map[int]int{300: 0, 301: 5, 302: 5, 303: 10, 304: 15}
+	if len(*partners) != len(want) {
+		t.Fatalf("GetPartners вернул %d партнеров, ожидалось %d: %+v", len(*partners), len(want), *partners)
+	}
+	for i, p := range *partners {
+		discount, ok := want[p.Id]
+		if !ok {
+			t.Errorf("неожиданный партнер %d", p.Id)
+			continue
+		}
+		if p.Discount != discount {
+			t.Errorf("партнер %d: скидка %d%%, ожидалось %d%%", p.Id, p.Discount, discount)
+		}
+		if i > 0 && (*partners)[i-1].Id >= p.Id {
+			t.Errorf("партнеры не упорядочены по id: %d после %d", p.Id, (*partners)[i-1].Id)
+		}
+	}
+
+	first := (*partners)[0]
+	expected := Default.Partners[0]
+	expected.Discount = 0
+	if first != expected {
+		t.Errorf("GetPartners()[0] = %+v, ожидалось %+v", first, expected)
+	}
+}
+
+func testGetPartnersEmpty(t *testing.T, repo Repository) {
+	partners, err := repo.GetPartners(t.Context())
+	if !errors.Is(err, storage.ErrPartnersNoFound) {
+		t.Fatalf("GetPartners: ошибка %v, ожидалась ErrPartnersNoFound", err)
+	}
+	if partners == nil || len(*partners) != 0 {
+		t.Errorf("GetPartners = %v, ожидался пустой список", partners)
+	}
+}
+
+func testAddUpdatePartner(t *testing.T, repo Repository) {
+	added := models.Partner{PartnerType: "ООО", CompanyName: "Новый Партнер", Director: "Новиков Н. Н.", Phone: "111", Rating: 3, Email: "new@ml.ru", Address: "Казань"}
+	if err := repo.AddPartner(As(t, models.RoleAdmin), added); err != nil {
+		t.Fatalf("AddPartner: %v", err)
+	}
+
+	id, name, err := repo.FindPartner(t.Context(), "Новый Партнер")
+	if err != nil {
+		t.Fatalf("FindPartner после AddPartner: %v", err)
+	}
+	if name != added.CompanyName || id == 0 {
+		t.Errorf("FindPartner = (%d, %q)", id, name)
+	}
+
+	updated := Default.Partners[0]
+	updated.PartnerType = "ООО"
+	updated.CompanyName = "База Строитель 2"
+	updated.Director = "Другой директор"
+	updated.Phone = "222"
+	updated.Rating = 9
+	updated.Email = "other@ml.ru"
+	updated.Address = "Томск"
+	if err := repo.UpdatePartner(As(t, models.RoleAdmin), updated); err != nil {
+		t.Fatalf("UpdatePartner: %v", err)
+	}
+
+	got := partnerById(t, repo, updated.Id)
+	updated.Discount = 0
+	if got != updated {
+		t.Errorf("после UpdatePartner: %+v, ожидалось %+v", got, updated)
+	}
+}
+
+func testDeletePartner(t *testing.T, repo Repository) {
+	if err := repo.DeletePartner(As(t, models.RoleAdmin), 301); err != nil {
+		t.Fatalf("DeletePartner: %v", err)
+	}
+
+	partners, err := repo.GetPartners(t.Context())
+	if err != nil {
+		t.Fatalf("GetPartners: %v", err)
+	}
+	for _, p := range *partners {
+		if p.Id == 301 {
+			t.Errorf("партнер 301 остался после удаления")
+		}
+	}
+
+	sales, err := repo.GetPartnerSales(t.Context(), 301)
+	if err != nil {
+		t.Fatalf("GetPartnerSales: %v", err)
+	}
+	if len(sales) != 0 {
+		t.Errorf("продажи удаленного партнера не удалены: %+v", sales)
+	}
+
+	// Продажи удаляются каскадом, но каждая остается в журнале.
+	log, err := repo.GetAuditLog(As(t, models.RoleAdmin))
+	if err != nil {
+		t.Fatalf("GetAuditLog: %v", err)
+	}
+	deleted := map[int]models.Sale{}
+	for _, e := range log {
+		if e.Entity != models.EntitySale || e.Action != models.AuditDelete {
+			continue
+		}
+		var sale models.Sale
+		if err := json.Unmarshal([]byte(e.Before), &sale); err != nil {
+			t.Fatalf("снимок продажи %q: %v", e.Before, err)
+		}
+		deleted[e.EntityId] = sale
+	}
+	want := models.Sale{Id: 3, PartnerId: 301, ProductName: "Ламинат Дуб", Quantity: 6000, SaleDate: "2024-02-01"}
+	if len(deleted) != 3 || deleted[3] != want {
+		t.Errorf("удаленные продажи в журнале = %+v", deleted)
+	}
+
+	if _, _, err := repo.FindPartner(t.Context(), "301"); err == nil {
+		t.Errorf("FindPartner нашел удаленного партнера")
+	}
+}
+
+func testUnparsedPhones(t *testing.T, repo Repository) {
+	if problems, err := repo.UnparsedPhones(t.Context()); err != nil || len(problems) != 0 {
+		t.Fatalf("UnparsedPhones = %v, %v", problems, err)
+	}
+
+	admin := As(t, models.RoleAdmin)
+	partner := Default.Partners[2]
+	partner.Phone = "звонить секретарю"
+	if err := repo.UpdatePartner(admin, partner); err != nil {
+		t.Fatal(err)
+	}
+	want := storage.PhoneProblem{PartnerId: partner.Id, PartnerName: partner.CompanyName, Phone: partner.Phone}
+	if problems, err := repo.UnparsedPhones(t.Context()); err != nil || len(problems) != 1 || problems[0] != want {
+		t.Errorf("UnparsedPhones = %v, %v, ожидалось %v", problems, err, want)
+	}
+
+	// Исправленный телефон из списка пропадает.
+	partner.Phone = "+79122233200"
+	if err := repo.UpdatePartner(admin, partner); err != nil {
+		t.Fatal(err)
+	}
+	if problems, err := repo.UnparsedPhones(t.Context()); err != nil || len(problems) != 0 {
+		t.Errorf("UnparsedPhones после исправления = %v, %v", problems, err)
+	}
+}
+
+func testFindPartner(t *testing.T, repo Repository) {
+	tests := []struct {
+		term    string
+		wantId  int
+		wantErr bool
+	}{
+		{term: "300", wantId: 300},
+		{term: "МонтажПро", wantId: 304},
+		{term: "Паркет", wantId: 301},
+		{term: "отделка", wantId: 303},
+		{term: "Строй", wantId: 302},
+		{term: "Несуществующий", wantErr: true},
+		{term: "999", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		id, name, err := repo.FindPartner(t.Context(), tt.term)
+		if tt.wantErr {
+			if !errors.Is(err, storage.ErrPartnerNotFound) {
+				t.Errorf("FindPartner(%q) = (%d, %q, %v), ожидалась ErrPartnerNotFound", tt.term, id, name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("FindPartner(%q): %v", tt.term, err)
+			continue
+		}
+		if id != tt.wantId {
+			t.Errorf("FindPartner(%q) = %d, ожидалось %d", tt.term, id, tt.wantId)
+		}
+	}
+}
+
+func testContacts(t *testing.T, repo Repository) {
+	contactIds := func(contacts []models.Contact) []string {
+		var ids []string
+		for _, c := range contacts {
+			ids = append(ids, strconv.Itoa(c.Id))
+		}
+		return ids
+	}
+
+	contacts, err := repo.GetContacts(t.Context(), 301)
+	if err != nil || !equalStrings(contactIds(contacts), []string{"100", "101"}) {
+		t.Fatalf("GetContacts(301) = %+v, %v", contacts, err)
+	}
+	if contacts[0] != Default.Contacts[0] {
+		t.Errorf("GetContacts(301)[0] = %+v, ожидалось %+v", contacts[0], Default.Contacts[0])
+	}
+	if contacts, err := repo.GetContacts(t.Context(), 300); err != nil || len(contacts) != 0 {
+		t.Errorf("GetContacts(300) = %+v, %v", contacts, err)
+	}
+
+	for term, want := range map[string]int{"Смирнова": 301, "ZAKUPKI": 301, "Орлов": 303, "8 900 555-66-77": 303} {
+		if id, _, err := repo.FindPartner(t.Context(), term); err != nil || id != want {
+			t.Errorf("FindPartner(%q) = %d, %v, ожидалось %d", term, id, err, want)
+		}
+	}
+
+	ctx := As(t, models.RoleManager)
+	for _, c := range []struct {
+		contact models.Contact
+		want    error
+	}{
+		{models.Contact{PartnerId: 999, Role: models.ContactBuyer, Name: "Нет партнера"}, storage.ErrPartnerNotFound},
+		{models.Contact{PartnerId: 300, Role: models.ContactBuyer}, storage.ErrContactNameEmpty},
+		{models.Contact{PartnerId: 300, Role: "director", Name: "Иванов"}, storage.ErrContactRole},
+	} {
+		if _, err := repo.AddContact(ctx, c.contact); !errors.Is(err, c.want) {
+			t.Errorf("AddContact(%+v): ошибка %v, ожидалась %v", c.contact, err, c.want)
+		}
+	}
+	if err := repo.UpdateContact(ctx, models.Contact{Id: 999, Role: models.ContactBuyer, Name: "Нет"}); !errors.Is(err, storage.ErrContactNotFound) {
+		t.Errorf("UpdateContact несуществующего: ошибка %v", err)
+	}
+
+	added := models.Contact{PartnerId: 300, Role: models.ContactBuyer, Name: "Новикова Н. Н.", Phone: "+79990001122", Email: "novikova@ml.ru"}
+	id, err := repo.AddContact(ctx, added)
+	if err != nil {
+		t.Fatalf("AddContact: %v", err)
+	}
+	added.Id = id
+	updated := added
+	updated.PartnerId = 304
+	updated.Role = models.ContactLogistics
+	updated.Name = "Новикова Н. Н. (склад)"
+	if err := repo.UpdateContact(ctx, updated); err != nil {
+		t.Fatalf("UpdateContact: %v", err)
+	}
+	contacts, err = repo.GetContacts(t.Context(), 300)
+	updated.PartnerId = 300
+	if err != nil || len(contacts) != 1 || contacts[0] != updated {
+		t.Fatalf("после изменения GetContacts(300) = %+v, %v, ожидалось %+v", contacts, err, updated)
+	}
+
+	if err := repo.DeleteContact(ctx, id); err != nil {
+		t.Fatalf("DeleteContact: %v", err)
+	}
+	if contacts, _ := repo.GetContacts(t.Context(), 300); len(contacts) != 0 {
+		t.Errorf("контакт не удален: %+v", contacts)
+	}
+	history, err := repo.GetEntityHistory(t.Context(), models.EntityContact, id)
+	if err != nil || len(history) != 3 {
+		t.Fatalf("история контакта = %+v, %v", history, err)
+	}
+	if history[0].Action != models.AuditDelete || history[1].Action != models.AuditUpdate || history[2].Action != models.AuditCreate {
+		t.Errorf("действия в истории контакта: %s, %s, %s", history[0].Action, history[1].Action, history[2].Action)
+	}
+
+	if err := repo.DeletePartner(As(t, models.RoleAdmin), 301); err != nil {
+		t.Fatalf("DeletePartner: %v", err)
+	}
+	all, err := repo.GetAllContacts(t.Context())
+	if err != nil || !equalStrings(contactIds(all), []string{"102"}) {
+		t.Errorf("после удаления партнера GetAllContacts = %+v, %v", all, err)
+	}
+}
+
+func testNotes(t *testing.T, repo Repository) {
+	ctx := As(t, models.RoleManager)
+
+	if _, err := repo.AddNote(ctx, 300, "  "); !errors.Is(err, storage.ErrEmptyNote) {
+		t.Errorf("AddNote пустой: ошибка %v", err)
+	}
+	if _, err := repo.AddNote(ctx, 999, "Заметка"); !errors.Is(err, storage.ErrPartnerNotFound) {
+		t.Errorf("AddNote несуществующему партнеру: ошибка %v", err)
+	}
+
+	first, err := repo.AddNote(ctx, 300, " Позвонить в понедельник ")
+	if err != nil {
+		t.Fatalf("AddNote: %v", err)
+	}
+	second, err := repo.AddNote(ctx, 300, "Договор подписан")
+	if err != nil {
+		t.Fatalf("AddNote: %v", err)
+	}
+	notes, err := repo.GetNotes(t.Context(), 300)
+	if err != nil || len(notes) != 2 {
+		t.Fatalf("GetNotes(300) = %+v, %v", notes, err)
+	}
+	if notes[0].Id != second || notes[1].Id != first {
+		t.Errorf("заметки не по убыванию даты: %+v", notes)
+	}
+	if n := notes[1]; n.PartnerId != 300 || n.Text != "Позвонить в понедельник" || n.User != models.RoleManager || n.CreatedAt == "" {
+		t.Errorf("заметка %+v", n)
+	}
+	if notes, _ := repo.GetNotes(t.Context(), 301); len(notes) != 0 {
+		t.Errorf("GetNotes(301) = %+v", notes)
+	}
+
+	if err := repo.DeleteNote(ctx, first); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+	if notes, _ := repo.GetNotes(t.Context(), 300); len(notes) != 1 || notes[0].Id != second {
+		t.Errorf("после удаления GetNotes(300) = %+v", notes)
+	}
+	history, err := repo.GetEntityHistory(t.Context(), models.EntityNote, first)
+	if err != nil || len(history) != 2 || history[0].Action != models.AuditDelete {
+		t.Errorf("история заметки = %+v, %v", history, err)
+	}
+
+	if err := repo.DeletePartner(As(t, models.RoleAdmin), 300); err != nil {
+		t.Fatalf("DeletePartner: %v", err)
+	}
+	if notes, _ := repo.GetNotes(t.Context(), 300); len(notes) != 0 {
+		t.Errorf("заметки удаленного партнера: %+v", notes)
+	}
+}
+
+func testAttachments(t *testing.T, repo Repository) {
+	ctx := As(t, models.RoleManager)
+	contract := []byte("%PDF-1.4 договор поставки")
+
+	if _, err := repo.AddAttachment(ctx, 300, "пустой.txt", nil); !errors.Is(err, storage.ErrEmptyAttachment) {
+		t.Errorf("AddAttachment пустого файла: ошибка %v", err)
+	}
+	if _, err := repo.AddAttachment(ctx, 300, "большой.bin", make([]byte, storage.MaxAttachmentSize+1)); !errors.Is(err, storage.ErrAttachmentTooLarge) {
+		t.Errorf("AddAttachment большого файла: ошибка %v", err)
+	}
+	if _, err := repo.AddAttachment(ctx, 999, "договор.pdf", contract); !errors.Is(err, storage.ErrPartnerNotFound) {
+		t.Errorf("AddAttachment несуществующему партнеру: ошибка %v", err)
+	}
+
+	id, err := repo.AddAttachment(ctx, 300, "docs/Договор.pdf", contract)
+	if err != nil {
+		t.Fatalf("AddAttachment: %v", err)
+	}
+	letter, err := repo.AddAttachment(ctx, 300, "письмо.txt", []byte("Добрый день"))
+	if err != nil {
+		t.Fatalf("AddAttachment: %v", err)
+	}
+	var exists *storage.Error
+	if _, err := repo.AddAttachment(ctx, 300, "копия.pdf", contract); !errors.As(err, &exists) || exists.Code != "error.attachment_exists" || exists.Data["Name"] != "Договор.pdf" {
+		t.Errorf("AddAttachment того же содержимого: ошибка %v", err)
+	}
+	if _, err := repo.AddAttachment(ctx, 301, "договор.pdf", contract); err != nil {
+		t.Errorf("тот же файл другому партнеру: %v", err)
+	}
+
+	attachments, err := repo.GetAttachments(t.Context(), 300)
+	if err != nil || len(attachments) != 2 || attachments[0].Id != letter || attachments[1].Id != id {
+		t.Fatalf("GetAttachments(300) = %+v, %v", attachments, err)
+	}
+	want := models.Attachment{
+		Id: id, PartnerId: 300, FileName: "Договор.pdf", Size: int64(len(contract)),
+		Hash: storage.AttachmentHash(contract), User: models.RoleManager, CreatedAt: attachments[1].CreatedAt,
+	}
+	if attachments[1] != want || want.CreatedAt == "" {
+		t.Errorf("GetAttachments(300)[1] = %+v, ожидалось %+v", attachments[1], want)
+	}
+
+	f, data, err := repo.ReadAttachment(t.Context(), id)
+	if err != nil || f != want || string(data) != string(contract) {
+		t.Errorf("ReadAttachment = %+v, %q, %v", f, data, err)
+	}
+	if _, _, err := repo.ReadAttachment(t.Context(), 999); !errors.Is(err, storage.ErrAttachmentNotFound) {
+		t.Errorf("ReadAttachment несуществующего: ошибка %v", err)
+	}
+
+	if err := repo.DeleteAttachment(ctx, letter); err != nil {
+		t.Fatalf("DeleteAttachment: %v", err)
+	}
+	history, err := repo.GetEntityHistory(t.Context(), models.EntityAttachment, letter)
+	if err != nil || len(history) != 2 || history[0].Action != models.AuditDelete || history[1].Action != models.AuditCreate {
+		t.Fatalf("история файла = %+v, %v", history, err)
+	}
+	if strings.Contains(history[1].After, "Data") {
+		t.Errorf("содержимое файла попало в журнал: %s", history[1].After)
+	}
+
+	if err := repo.DeletePartner(As(t, models.RoleAdmin), 300); err != nil {
+		t.Fatalf("DeletePartner: %v", err)
+	}
+	if _, _, err := repo.ReadAttachment(t.Context(), id); !errors.Is(err, storage.ErrAttachmentNotFound) {
+		t.Errorf("файл удаленного партнера: ошибка %v", err)
+	}
+}
+
+func testFindContactDuplicates(t *testing.T, repo Repository) {
+	type match struct {
+		id, discount         int
+		sameEmail, samePhone bool
+	}
+	tests := []struct {
+		name    string
+		partner models.Partner
+		want    []match
+	}{
+		{
+			name:    "email без учета регистра домена и телефон",
+			partner: models.Partner{Email: " ivanova@ML.RU ", Phone: "912 888 33 33"},
+			want:    []match{{id: 300, discount: 0, sameEmail: true}, {id: 304, discount: 15, samePhone: true}},
+		},
+		{
+			name:    "локальная часть email с учетом регистра",
+			partner: models.Partner{Email: "Ivanova@ml.ru"},
+		},
+		{
+			name:    "email и телефон одного партнера",
+			partner: models.Partner{Email: "stepanov@stepan.ru", Phone: "912 888 33 33"},
+			want:    []match{{id: 304, discount: 15, sameEmail: true, samePhone: true}},
+		},
+		{
+			name:    "партнер без продаж",
+			partner: models.Partner{Email: "none@ml.ru"},
+			want:    []match{{id: 305, discount: 0, sameEmail: true}},
+		},
+		{
+			name:    "сам партнер не дубликат",
+			partner: models.Partner{Id: 300, Email: "ivanova@ml.ru", Phone: "493 123 45 67"},
+		},
+		{
+			name:    "пустые контакты",
+			partner: models.Partner{},
+		},
+	}
+
+	for _, tt := range tests {
+		duplicates, err := repo.FindContactDuplicates(t.Context(), tt.partner)
+		if err != nil {
+			t.Errorf("%s: FindContactDuplicates: %v", tt.name, err)
+			continue
+		}
+		var got []match
+		for _, d := range duplicates {
+			got = append(got, match{id: d.Partner.Id, discount: d.Partner.Discount, sameEmail: d.SameEmail, samePhone: d.SamePhone})
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: FindContactDuplicates = %+v, ожидалось %+v", tt.name, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: FindContactDuplicates = %+v, ожидалось %+v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+
+	duplicates, err := repo.FindContactDuplicates(t.Context(), models.Partner{Phone: "912 888 33 33"})
+	if err != nil || len(duplicates) != 1 {
+		t.Fatalf("FindContactDuplicates по телефону = %+v, %v", duplicates, err)
+	}
+	want := Default.Partners[4]
+	want.Discount = 15
+	if duplicates[0].Partner != want {
+		t.Errorf("партнер-дубликат = %+v, ожидалось %+v", duplicates[0].Partner, want)
+	}
+}
+
+func testGetPartnerSales(t *testing.T, repo Repository) {
+	sales, err := repo.GetPartnerSales(t.Context(), 301)
+	if err != nil {
+		t.Fatalf("GetPartnerSales: %v", err)
+	}
+
+	want := []models.PartnerSale{
+		{ProductName: "Ламинат Дуб", Quantity: 6000, SaleDate: "2024-02-01", ProductType: "Ламинат", TotalSum: 6000 * 179933},
+		{ProductName: "Ламинат Орех", Quantity: 0, SaleDate: "2023-06-15", ProductType: "Ламинат", TotalSum: 0},
+		{ProductName: "Паркетная доска Ясень", Quantity: 4000, SaleDate: "2023-01-10", ProductType: "Паркетная доска", TotalSum: 4000 * 445690},
+	}
+	if len(sales) != len(want) {
+		t.Fatalf("GetPartnerSales вернул %d продаж, ожидалось %d: %+v", len(sales), len(want), sales)
+	}
+	for i := range want {
+		if sales[i] != want[i] {
+			t.Errorf("продажа %d = %+v, ожидалось %+v", i, sales[i], want[i])
+		}
+	}
+
+	sales, err = repo.GetPartnerSales(t.Context(), 305)
+	if err != nil {
+		t.Fatalf("GetPartnerSales: %v", err)
+	}
+	if len(sales) != 0 {
+		t.Errorf("у партнера без продаж найдены продажи: %+v", sales)
+	}
+}
+
+// withSummarySales добавляет партнеру без продаж две продажи в одном месяце и одну в другом.
+func withSummarySales(f Fixture) Fixture {
+	f.Sales = append(append([]memory.Sale{}, f.Sales...),
+		memory.Sale{PartnerId: 305, ProductId: 3, Quantity: 10, SaleDate: "2024-03-01"},
+		memory.Sale{PartnerId: 305, ProductId: 2, Quantity: 20, SaleDate: "2024-03-20 10:00:00"},
+		memory.Sale{PartnerId: 305, ProductId: 3, Quantity: 5, SaleDate: "2023-11-05"},
+	)
+	return f
+}
+
+func testSalesSummary(t *testing.T, repo Repository) {
+	months, err := repo.GetMonthlySales(t.Context(), 305)
+	if err != nil {
+		t.Fatalf("GetMonthlySales: %v", err)
+	}
+	wantMonths := []models.MonthlySales{
+		{Month: "2023-11", Quantity: 5, TotalSum: 5 * 200000},
+		{Month: "2024-03", Quantity: 30, TotalSum: 10*200000 + 20*179933},
+	}
+	if len(months) != len(wantMonths) {
+		t.Fatalf("GetMonthlySales вернул %d месяцев, ожидалось %d: %+v", len(months), len(wantMonths), months)
+	}
+	for i, want := range wantMonths {
+		if months[i] != want {
+			t.Errorf("месяц %d = %+v, ожидалось %+v", i, months[i], want)
+		}
+	}
+
+	products, err := repo.GetTopProducts(t.Context(), 305, 1)
+	if err != nil {
+		t.Fatalf("GetTopProducts: %v", err)
+	}
+	if len(products) != 1 || products[0].ProductName != "Ламинат Дуб" || products[0].Quantity != 20 {
+		t.Errorf("GetTopProducts(limit 1) = %+v, ожидался Ламинат Дуб, 20", products)
+	}
+
+	products, err = repo.GetTopProducts(t.Context(), 301, 10)
+	if err != nil {
+		t.Fatalf("GetTopProducts: %v", err)
+	}
+	wantNames := []string{"Ламинат Дуб", "Паркетная доска Ясень", "Ламинат Орех"}
+	var names []string
+	for _, p := range products {
+		names = append(names, p.ProductName)
+	}
+	if !equalStrings(names, wantNames) {
+		t.Errorf("GetTopProducts = %v, ожидалось %v", names, wantNames)
+	}
+
+	months, err = repo.GetMonthlySales(t.Context(), 400)
+	if err != nil {
+		t.Fatalf("GetMonthlySales: %v", err)
+	}
+	if len(months) != 0 {
+		t.Errorf("у несуществующего партнера найдены продажи: %+v", months)
+	}
+}
+
+func testDashboard(t *testing.T, repo Repository) {
+	totals, err := repo.GetSalesTotals(t.Context())
+	if err != nil {
+		t.Fatalf("GetSalesTotals: %v", err)
+	}
+	wantRevenue := money.Money(9000*445690 + (6000+49999+50000)*179933 + 300000*200000)
+	if totals != (models.SalesTotals{Revenue: wantRevenue, Quantity: 414999, Partners: 5}) {
+		t.Errorf("GetSalesTotals = %+v, ожидалось 414999 шт., 5 партнеров, выручка %s", totals, wantRevenue)
+	}
+
+	months, err := repo.GetMonthlyTotals(t.Context())
+	if err != nil {
+		t.Fatalf("GetMonthlyTotals: %v", err)
+	}
+	wantMonths := map[string]int{"2023-01": 4000, "2023-03": 5000, "2023-06": 0, "2024-01": 399999, "2024-02": 6000}
+	var monthNames []string
+	for _, m := range months {
+		monthNames = append(monthNames, m.Month)
+		if m.Quantity != wantMonths[m.Month] {
+			t.Errorf("продажи за %s: %d, ожидалось %d", m.Month, m.Quantity, wantMonths[m.Month])
+		}
+	}
+	if !equalStrings(monthNames, []string{"2023-01", "2023-03", "2023-06", "2024-01", "2024-02"}) {
+		t.Errorf("месяцы GetMonthlyTotals: %v", monthNames)
+	}
+
+	top, err := repo.GetTopPartners(t.Context(), 3)
+	if err != nil {
+		t.Fatalf("GetTopPartners: %v", err)
+	}
+	var topIds []int
+	for _, p := range top {
+		topIds = append(topIds, p.PartnerId)
+	}
+	if len(top) != 3 || topIds[0] != 304 || topIds[1] != 303 || topIds[2] != 302 || top[0].CompanyName != "МонтажПро" || top[0].Quantity != 300000 || top[0].TotalSum != 300000*200000 {
+		t.Errorf("GetTopPartners(3) = %+v, ожидались партнеры 304, 303, 302", top)
+	}
+
+	tiers, err := repo.GetDiscountDistribution(t.Context())
+	if err != nil {
+		t.Fatalf("GetDiscountDistribution: %v", err)
+	}
+	wantTiers := []models.DiscountTierCount{{Percentage: 0, Partners: 2}, {Percentage: 5, Partners: 2}, {Percentage: 10, Partners: 1}, {Percentage: 15, Partners: 1}}
+	if len(tiers) != len(wantTiers) {
+		t.Fatalf("GetDiscountDistribution = %+v, ожидалось %+v", tiers, wantTiers)
+	}
+	for i := range wantTiers {
+		if tiers[i] != wantTiers[i] {
+			t.Errorf("GetDiscountDistribution = %+v, ожидалось %+v", tiers, wantTiers)
+			break
+		}
+	}
+
+	types, err := repo.GetPartnerTypeDistribution(t.Context())
+	if err != nil {
+		t.Fatalf("GetPartnerTypeDistribution: %v", err)
+	}
+	wantTypes := []models.PartnerTypeCount{{PartnerType: "ЗАО", Partners: 2}, {PartnerType: "ИП", Partners: 1}, {PartnerType: "ОАО", Partners: 1}, {PartnerType: "ООО", Partners: 1}, {PartnerType: "ПАО", Partners: 1}}
+	if len(types) != len(wantTypes) {
+		t.Fatalf("GetPartnerTypeDistribution = %+v, ожидалось %+v", types, wantTypes)
+	}
+	for i := range wantTypes {
+		if types[i] != wantTypes[i] {
+			t.Errorf("GetPartnerTypeDistribution = %+v, ожидалось %+v", types, wantTypes)
+			break
+		}
+	}
+}
+
+func testDashboardEmpty(t *testing.T, repo Repository) {
+	totals, err := repo.GetSalesTotals(t.Context())
+	if err != nil {
+		t.Fatalf("GetSalesTotals: %v", err)
+	}
+	if totals != (models.SalesTotals{}) {
+		t.Errorf("GetSalesTotals без продаж = %+v", totals)
+	}
+	months, err := repo.GetMonthlyTotals(t.Context())
+	if err != nil || len(months) != 0 {
+		t.Errorf("GetMonthlyTotals без продаж = %+v, %v", months, err)
+	}
+	top, err := repo.GetTopPartners(t.Context(), 10)
+	if err != nil || len(top) != 0 {
+		t.Errorf("GetTopPartners без продаж = %+v, %v", top, err)
+	}
+	tiers, err := repo.GetDiscountDistribution(t.Context())
+	if err != nil || len(tiers) != 0 {
+		t.Errorf("GetDiscountDistribution без партнеров = %+v, %v", tiers, err)
+	}
+}
+
+func testCatalog(t *testing.T, repo Repository) {
+	products, err := repo.GetProducts(t.Context())
+	if err != nil {
+		t.Fatalf("GetProducts: %v", err)
+	}
+	wantProducts := []string{"1 - Паркетная доска Ясень", "2 - Ламинат Дуб", "3 - Ламинат Орех"}
+	if !equalStrings(products, wantProducts) {
+		t.Errorf("GetProducts = %q, ожидалось %q", products, wantProducts)
+	}
+
+	materials, err := repo.GetMaterialTypes(t.Context())
+	if err != nil {
+		t.Fatalf("GetMaterialTypes: %v", err)
+	}
+	wantMaterials := []string{"1 - Тип материала 1", "2 - Тип материала 2", "3 - Без брака"}
+	if !equalStrings(materials, wantMaterials) {
+		t.Errorf("GetMaterialTypes = %q, ожидалось %q", materials, wantMaterials)
+	}
+
+	params, err := repo.GetProductParameters(t.Context(), "1")
+	if err != nil {
+		t.Fatalf("GetProductParameters: %v", err)
+	}
+	if len(params) != 2 || params[0].Name != "Длина" || params[1].Name != "Ширина" || *params[1].MaxValue != 10 {
+		t.Errorf("GetProductParameters(1) = %+v", params)
+	}
+
+	params, err = repo.GetProductParameters(t.Context(), "2")
+	if err != nil {
+		t.Fatalf("GetProductParameters: %v", err)
+	}
+	if len(params) != 0 {
+		t.Errorf("GetProductParameters(2) = %+v, ожидался пустой список", params)
+	}
+}
+
+func testCalculateMaterial(t *testing.T, repo Repository) {
+	tests := []struct {
+		name                  string
+		product, material     string
+		quantity              int
+		param1, param2        float64
+		wantRequired          int
+		wantPerUnit, wantBase float64
+		wantErr               bool
+	}{
+		{name: "с браком", product: "1", material: "1", quantity: 10, param1: 2, param2: 3, wantRequired: 261, wantPerUnit: 2 * 3 * 4.34, wantBase: 10 * 2 * 3 * 4.34},
+		{name: "без брака", product: "2", material: "3", quantity: 4, param1: 0.5, param2: 2, wantRequired: 10, wantPerUnit: 0.5 * 2 * 2.35, wantBase: 4 * 0.5 * 2 * 2.35},
+		{name: "нулевое количество", product: "2", material: "2", quantity: 0, param1: 1, param2: 1, wantRequired: 0, wantPerUnit: 2.35},
+		{name: "неизвестный продукт", product: "99", material: "1", quantity: 1, param1: 1, param2: 1, wantErr: true},
+		{name: "неизвестный материал", product: "1", material: "99", quantity: 1, param1: 1, param2: 1, wantErr: true},
+		{name: "параметр вне диапазона", product: "1", material: "1", quantity: 1, param1: 1, param2: 11, wantErr: true},
+		{name: "параметр не больше нуля", product: "1", material: "1", quantity: 1, param1: 0, param2: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calc, err := repo.CalculateMaterial(t.Context(), tt.product, tt.material, tt.quantity, tt.param1, tt.param2)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ожидалась ошибка, получено %+v", calc)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CalculateMaterial: %v", err)
+			}
+			if calc.Required != tt.wantRequired {
+				t.Errorf("Required = %d, ожидалось %d", calc.Required, tt.wantRequired)
+			}
+			if calc.MaterialPerUnit != tt.wantPerUnit || calc.BaseTotal != tt.wantBase {
+				t.Errorf("MaterialPerUnit = %v, BaseTotal = %v, ожидалось %v, %v", calc.MaterialPerUnit, calc.BaseTotal, tt.wantPerUnit, tt.wantBase)
+			}
+			if calc.Rounding < 0 || calc.Rounding >= 1 {
+				t.Errorf("Rounding = %v вне [0, 1)", calc.Rounding)
+			}
+			if got := calc.BaseTotal + calc.DefectAllowance + calc.Rounding; got-float64(calc.Required) > 1e-9 || float64(calc.Required)-got > 1e-9 {
+				t.Errorf("BaseTotal + DefectAllowance + Rounding = %v, ожидалось %d", got, calc.Required)
+			}
+		})
+	}
+}
+
+func testCalculateMaxProducts(t *testing.T, repo Repository) {
+	for _, available := range []int{1, 26, 27, 100, 261, 262, 5000} {
+		quantity, err := repo.CalculateMaxProducts(t.Context(), "1", "1", available, 2, 3)
+		if err != nil {
+			t.Fatalf("CalculateMaxProducts(%d): %v", available, err)
+		}
+
+		calc, err := repo.CalculateMaterial(t.Context(), "1", "1", quantity, 2, 3)
+		if err != nil {
+			t.Fatalf("CalculateMaterial: %v", err)
+		}
+		next, err := repo.CalculateMaterial(t.Context(), "1", "1", quantity+1, 2, 3)
+		if err != nil {
+			t.Fatalf("CalculateMaterial: %v", err)
+		}
+		if calc.Required > available || next.Required <= available {
+			t.Errorf("CalculateMaxProducts(%d) = %d: на %d нужно %d, на %d нужно %d",
+				available, quantity, quantity, calc.Required, quantity+1, next.Required)
+		}
+	}
+
+	if _, err := repo.CalculateMaxProducts(t.Context(), "99", "1", 100, 1, 1); err == nil {
+		t.Errorf("ожидалась ошибка для неизвестного продукта")
+	}
+}
+
+func testPlans(t *testing.T, repo Repository) {
+	plan := models.ProductionPlan{
+		Name: "Неделя 1",
+		Rows: []models.ProductionPlanRow{
+			{ProductId: 1, MaterialTypeId: 1, Quantity: 10, Param1: 2, Param2: 3},
+			{ProductId: 2, MaterialTypeId: 1, Quantity: 5, Param1: 1, Param2: 1},
+			{ProductId: 3, MaterialTypeId: 2, Quantity: 1, Param1: 1, Param2: 1},
+			{ProductId: 2, MaterialTypeId: 1, Quantity: 1, Param1: 0.1, Param2: 0.1},
+		},
+	}
+
+	id, err := repo.SavePlan(As(t, models.RoleAdmin), plan)
+	if err != nil {
+		t.Fatalf("SavePlan: %v", err)
+	}
+
+	saved, err := repo.GetPlan(t.Context(), id)
+	if err != nil {
+		t.Fatalf("GetPlan: %v", err)
+	}
+	if saved.Name != plan.Name || saved.CreatedAt == "" || len(saved.Rows) != 4 {
+		t.Fatalf("GetPlan = %+v", saved)
+	}
+	if saved.Rows[0].ProductName != "Паркетная доска Ясень" || saved.Rows[2].MaterialType != "Тип материала 2" {
+		t.Errorf("строки плана без названий: %+v", saved.Rows)
+	}
+
+	requirements, err := repo.CalculatePlan(t.Context(), *saved)
+	if err != nil {
+		t.Fatalf("CalculatePlan: %v", err)
+	}
+	// Материал 1: 260,6604 + 11,76175 + 0,0235235 ≈ 272,45 → 273, хотя по строкам с округлением
+	// вышло бы 261 + 12 + 1 = 274. Материал 2: 2,372325 → 3.
+	want := map[int]int{1: 273, 2: 3}
+	if len(requirements) != 2 || requirements[0].MaterialTypeId != 1 || requirements[0].Quantity != want[1] || requirements[1].Quantity != want[2] {
+		t.Errorf("CalculatePlan = %+v, ожидалось %v", requirements, want)
+	}
+
+	saved.Name = "Неделя 1 (изм.)"
+	saved.Rows = saved.Rows[:1]
+	if _, err := repo.SavePlan(As(t, models.RoleAdmin), *saved); err != nil {
+		t.Fatalf("SavePlan: %v", err)
+	}
+	updated, err := repo.GetPlan(t.Context(), id)
+	if err != nil {
+		t.Fatalf("GetPlan: %v", err)
+	}
+	if updated.Name != saved.Name || len(updated.Rows) != 1 {
+		t.Errorf("после обновления GetPlan = %+v", updated)
+	}
+
+	plans, err := repo.GetPlans(t.Context())
+	if err != nil {
+		t.Fatalf("GetPlans: %v", err)
+	}
+	if len(plans) != 1 || plans[0].Id != id {
+		t.Errorf("GetPlans = %+v", plans)
+	}
+
+	if err := repo.DeletePlan(As(t, models.RoleAdmin), id); err != nil {
+		t.Fatalf("DeletePlan: %v", err)
+	}
+	if _, err := repo.GetPlan(t.Context(), id); !errors.Is(err, storage.ErrPlanNotFound) {
+		t.Errorf("GetPlan удаленного плана: ошибка %v, ожидалась ErrPlanNotFound", err)
+	}
+}
+
+func testCalculationHistory(t *testing.T, repo Repository) {
+	first, err := repo.CalculateMaterial(t.Context(), "1", "1", 10, 2, 3)
+	if err != nil {
+		t.Fatalf("CalculateMaterial: %v", err)
+	}
+	second, err := repo.CalculateMaterial(t.Context(), "2", "3", 4, 0.5, 2)
+	if err != nil {
+		t.Fatalf("CalculateMaterial: %v", err)
+	}
+
+	for _, calc := range []models.MaterialCalculation{first, second} {
+		if err := repo.SaveCalculation(As(t, models.RoleAdmin), calc); err != nil {
+			t.Fatalf("SaveCalculation: %v", err)
+		}
+	}
+
+	history, err := repo.GetCalculationHistory(t.Context())
+	if err != nil {
+		t.Fatalf("GetCalculationHistory: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("GetCalculationHistory вернул %d записей", len(history))
+	}
+
+	got := history[0]
+	if got.Id == 0 || got.CreatedAt == "" {
+		t.Errorf("запись истории без id или даты: %+v", got)
+	}
+	got.Id, got.CreatedAt = 0, ""
+	if got != second {
+		t.Errorf("последний расчет = %+v, ожидалось %+v", got, second)
+	}
+}
+
+func testAudit(t *testing.T, repo Repository) {
+	ctx := As(t, models.RoleManager)
+
+	if err := repo.AddPartner(ctx, models.Partner{PartnerType: "ООО", CompanyName: "Аудит", Rating: 3}); err != nil {
+		t.Fatalf("AddPartner: %v", err)
+	}
+	id, _, err := repo.FindPartner(ctx, "Аудит")
+	if err != nil {
+		t.Fatalf("FindPartner: %v", err)
+	}
+
+	updated := models.Partner{Id: id, PartnerType: "ООО", CompanyName: "Аудит", Rating: 9}
+	if err := repo.UpdatePartner(ctx, updated); err != nil {
+		t.Fatalf("UpdatePartner: %v", err)
+	}
+	admin := As(t, models.RoleAdmin)
+	if err := repo.DeletePartner(admin, id); err != nil {
+		t.Fatalf("DeletePartner: %v", err)
+	}
+	if err := repo.DeletePartner(admin, id); err != nil {
+		t.Fatalf("повторный DeletePartner: %v", err)
+	}
+
+	history, err := repo.GetEntityHistory(ctx, models.EntityPartner, id)
+	if err != nil {
+		t.Fatalf("GetEntityHistory: %v", err)
+	}
+	wantActions := []string{models.AuditDelete, models.AuditUpdate, models.AuditCreate}
+	wantUsers := []string{models.RoleAdmin, models.RoleManager, models.RoleManager}
+	if len(history) != len(wantActions) {
+		t.Fatalf("GetEntityHistory вернул %d записей, ожидалось %d: %+v", len(history), len(wantActions), history)
+	}
+	for i, e := range history {
+		if e.Action != wantActions[i] || e.User != wantUsers[i] || e.CreatedAt == "" {
+			t.Errorf("запись %d = %+v, ожидалось действие %s пользователя %s", i, e, wantActions[i], wantUsers[i])
+		}
+	}
+
+	var before, after models.Partner
+	if err := json.Unmarshal([]byte(history[1].Before), &before); err != nil {
+		t.Fatalf("Before: %v", err)
+	}
+	if err := json.Unmarshal([]byte(history[1].After), &after); err != nil {
+		t.Fatalf("After: %v", err)
+	}
+	if before.Rating != 3 || after.Rating != 9 {
+		t.Errorf("рейтинг до %d и после %d, ожидалось 3 и 9", before.Rating, after.Rating)
+	}
+	if history[0].After != "" || history[0].Before == "" || history[2].Before != "" || history[2].After == "" {
+		t.Errorf("создание и удаление должны иметь только After и только Before: %+v", history)
+	}
+
+	planId, err := repo.SavePlan(ctx, models.ProductionPlan{Name: "План"})
+	if err != nil {
+		t.Fatalf("SavePlan: %v", err)
+	}
+	log, err := repo.GetAuditLog(ctx)
+	if err != nil {
+		t.Fatalf("GetAuditLog: %v", err)
+	}
+	if len(log) != 4 || log[0].Entity != models.EntityPlan || log[0].EntityId != planId || log[0].User != models.RoleManager {
+		t.Errorf("GetAuditLog = %+v", log)
+	}
+}
+
+// ratingsAsOf — дата расчета предложений: продажа партнера 300 старше года, остальные свежие.
+var ratingsAsOf = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func testRatings(t *testing.T, repo Repository) {
+	ctx := As(t, models.RoleManager)
+
+	for _, rating := range []int{models.MinRating - 1, models.MaxRating + 1} {
+		p := models.Partner{PartnerType: "ООО", CompanyName: "Вне шкалы", Rating: rating}
+		if err := repo.AddPartner(ctx, p); !errors.Is(err, storage.ErrRatingOutOfRange) {
+			t.Errorf("AddPartner с рейтингом %d: ошибка %v", rating, err)
+		}
+		p.Id = 300
+		if err := repo.UpdatePartner(ctx, p); !errors.Is(err, storage.ErrRatingOutOfRange) {
+			t.Errorf("UpdatePartner с рейтингом %d: ошибка %v", rating, err)
+		}
+	}
+
+	suggestions, err := repo.GetRatingSuggestions(t.Context(), ratingsAsOf)
+	if err != nil {
+		t.Fatalf("GetRatingSuggestions: %v", err)
+	}
+	want := []models.RatingSuggestion{
+		{PartnerId: 300, CompanyName: "База Строитель", CurrentRating: 7, SuggestedRating: 0, Reason: models.RatingReasonInactive, TotalQuantity: 5000, LastSaleDate: "2023-03-23"},
+		{PartnerId: 301, CompanyName: "Паркет 29", CurrentRating: 7, SuggestedRating: 5, Reason: models.RatingReasonVolume, TotalQuantity: 10000, LastSaleDate: "2024-02-01"},
+		{PartnerId: 302, CompanyName: "Стройсервис", CurrentRating: 7, SuggestedRating: 5, Reason: models.RatingReasonVolume, TotalQuantity: 49999, LastSaleDate: "2024-01-01"},
+		{PartnerId: 303, CompanyName: "Ремонт и отделка", CurrentRating: 5, SuggestedRating: 7, Reason: models.RatingReasonVolume, TotalQuantity: 50000, LastSaleDate: "2024-01-01"},
+	}
+	if len(suggestions) != len(want) {
+		t.Fatalf("GetRatingSuggestions = %+v, ожидалось %+v", suggestions, want)
+	}
+	for i := range want {
+		if suggestions[i] != want[i] {
+			t.Errorf("предложение %d = %+v, ожидалось %+v", i, suggestions[i], want[i])
+		}
+	}
+
+	if err := repo.RejectRatingSuggestion(ctx, want[2]); err != nil {
+		t.Fatalf("RejectRatingSuggestion: %v", err)
+	}
+	if err := repo.AcceptRatingSuggestion(ctx, want[3]); err != nil {
+		t.Fatalf("AcceptRatingSuggestion: %v", err)
+	}
+	stale := want[1]
+	stale.CurrentRating = 4
+	if err := repo.AcceptRatingSuggestion(ctx, stale); !errors.Is(err, storage.ErrSuggestionOutdated) {
+		t.Errorf("принято устаревшее предложение: ошибка %v", err)
+	}
+
+	suggestions, err = repo.GetRatingSuggestions(t.Context(), ratingsAsOf)
+	if err != nil {
+		t.Fatalf("GetRatingSuggestions: %v", err)
+	}
+	var ids []string
+	for _, s := range suggestions {
+		ids = append(ids, strconv.Itoa(s.PartnerId))
+	}
+	if !equalStrings(ids, []string{"300", "301"}) {
+		t.Errorf("после решений остались предложения для %v, ожидалось 300, 301", ids)
+	}
+	if p := partnerById(t, repo, 303); p.Rating != 7 {
+		t.Errorf("рейтинг после принятия предложения: %d", p.Rating)
+	}
+
+	p := partnerById(t, repo, 300)
+	p.Rating = 9
+	if err := repo.UpdatePartner(ctx, p); err != nil {
+		t.Fatalf("UpdatePartner: %v", err)
+	}
+	p.Director = "Другой директор"
+	if err := repo.UpdatePartner(ctx, p); err != nil {
+		t.Fatalf("UpdatePartner: %v", err)
+	}
+	history, err := repo.GetRatingHistory(t.Context(), 300)
+	if err != nil {
+		t.Fatalf("GetRatingHistory: %v", err)
+	}
+	if len(history) != 1 || history[0].OldRating != 7 || history[0].NewRating != 9 || history[0].Reason != models.RatingReasonManual || history[0].User != models.RoleManager {
+		t.Errorf("история рейтинга партнера 300: %+v", history)
+	}
+	history, err = repo.GetRatingHistory(t.Context(), 303)
+	if err != nil {
+		t.Fatalf("GetRatingHistory: %v", err)
+	}
+	if len(history) != 1 || history[0].OldRating != 5 || history[0].NewRating != 7 || history[0].Reason != models.RatingReasonVolume {
+		t.Errorf("история рейтинга партнера 303: %+v", history)
+	}
+}
+
+func testPermissions(t *testing.T, repo Repository) {
+	partner := models.Partner{Id: 300, PartnerType: "ЗАО", CompanyName: "Чужое имя", Rating: 1}
+	plan := models.ProductionPlan{Name: "План"}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want error
+	}{
+		{"без пользователя", t.Context(), storage.ErrNotAuthenticated},
+		{"наблюдатель", As(t, models.RoleViewer), storage.ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := repo.AddPartner(tt.ctx, partner); !errors.Is(err, tt.want) {
+				t.Errorf("AddPartner: ошибка %v, ожидалась %v", err, tt.want)
+			}
+			if err := repo.UpdatePartner(tt.ctx, partner); !errors.Is(err, tt.want) {
+				t.Errorf("UpdatePartner: ошибка %v, ожидалась %v", err, tt.want)
+			}
+			if err := repo.DeletePartner(tt.ctx, 300); !errors.Is(err, tt.want) {
+				t.Errorf("DeletePartner: ошибка %v, ожидалась %v", err, tt.want)
+			}
+			if _, err := repo.SavePlan(tt.ctx, plan); !errors.Is(err, tt.want) {
+				t.Errorf("SavePlan: ошибка %v, ожидалась %v", err, tt.want)
+			}
+			suggestion := models.RatingSuggestion{PartnerId: 300, CurrentRating: 7, SuggestedRating: 0}
+			if err := repo.AcceptRatingSuggestion(tt.ctx, suggestion); !errors.Is(err, tt.want) {
+				t.Errorf("AcceptRatingSuggestion: ошибка %v, ожидалась %v", err, tt.want)
+			}
+			if err := repo.RejectRatingSuggestion(tt.ctx, suggestion); !errors.Is(err, tt.want) {
+				t.Errorf("RejectRatingSuggestion: ошибка %v, ожидалась %v", err, tt.want)
+			}
+			contact := models.Contact{Id: 100, PartnerId: 301, Role: models.ContactBuyer, Name: "Чужое имя"}
+			if _, err := repo.AddContact(tt.ctx, contact); !errors.Is(err, tt.want) {
+				t.Errorf("AddContact: ошибка %v, ожидалась %v", err, tt.want)
+			}
+			if err := repo.UpdateContact(tt.ctx, contact); !errors.Is(err, tt.want) {
+				t.Errorf("UpdateContact: ошибка %v, ожидалась %v", err, tt.want)
+			}
+			if err := repo.DeleteContact(tt.ctx, 100); !errors.Is(err, tt.want) {
+				t.Errorf("DeleteContact: ошибка %v, ожидалась %v", err, tt.want)
+			}
+			if _, err := repo.AddNote(tt.ctx, 300, "Заметка"); !errors.Is(err, tt.want) {
+				t.Errorf("AddNote: ошибка %v, ожидалась %v", err, tt.want)
+			}
+			if err := repo.DeleteNote(tt.ctx, 1); !errors.Is(err, tt.want) {
+				t.Errorf("DeleteNote: ошибка %v, ожидалась %v", err, tt.want)
+			}
+			if _, err := repo.AddAttachment(tt.ctx, 300, "договор.pdf", []byte("договор")); !errors.Is(err, tt.want) {
+				t.Errorf("AddAttachment: ошибка %v, ожидалась %v", err, tt.want)
+			}
+			if err := repo.DeleteAttachment(tt.ctx, 1); !errors.Is(err, tt.want) {
+				t.Errorf("DeleteAttachment: ошибка %v, ожидалась %v", err, tt.want)
+			}
+			if _, err := repo.GetUsers(tt.ctx); !errors.Is(err, tt.want) {
+				t.Errorf("GetUsers: ошибка %v, ожидалась %v", err, tt.want)
+			}
+		})
+	}
+
+	if err := repo.DeletePartner(As(t, models.RoleManager), 300); !errors.Is(err, storage.ErrForbidden) {
+		t.Errorf("менеджер удалил партнера: ошибка %v", err)
+	}
+	if err := repo.SaveCalculation(As(t, models.RoleViewer), models.MaterialCalculation{ProductId: 1, MaterialTypeId: 1}); err != nil {
+		t.Errorf("наблюдатель не смог сохранить расчет: %v", err)
+	}
+	if p := partnerById(t, repo, 300); p.CompanyName != "База Строитель" {
+		t.Errorf("партнер изменен без прав: %+v", p)
+	}
+	log, err := repo.GetAuditLog(t.Context())
+	if err != nil {
+		t.Fatalf("GetAuditLog: %v", err)
+	}
+	if len(log) != 1 || log[0].Entity != models.EntityCalculation {
+		t.Errorf("в журнале записи запрещенных операций: %+v", log)
+	}
+}
+
+func testUsers(t *testing.T, repo Repository) {
+	has, err := repo.HasUsers(t.Context())
+	if err != nil || has {
+		t.Fatalf("HasUsers = %v, %v для пустого хранилища", has, err)
+	}
+	if err := repo.CreateUser(t.Context(), "manager", "secret1", models.RoleManager); !errors.Is(err, storage.ErrNotAuthenticated) {
+		t.Errorf("первый пользователь не администратор: ошибка %v", err)
+	}
+	if err := repo.CreateUser(t.Context(), "admin", "secret1", models.RoleAdmin); err != nil {
+		t.Fatalf("CreateUser первого администратора: %v", err)
+	}
+	if err := repo.CreateUser(t.Context(), "admin2", "secret1", models.RoleAdmin); !errors.Is(err, storage.ErrNotAuthenticated) {
+		t.Errorf("второй администратор без авторизации: ошибка %v", err)
+	}
+
+	if _, err := repo.Authenticate(t.Context(), "admin", "wrong"); !errors.Is(err, storage.ErrInvalidCredentials) {
+		t.Errorf("Authenticate с неверным паролем: ошибка %v", err)
+	}
+	if _, err := repo.Authenticate(t.Context(), "nobody", "secret1"); !errors.Is(err, storage.ErrInvalidCredentials) {
+		t.Errorf("Authenticate неизвестного пользователя: ошибка %v", err)
+	}
+	admin, err := repo.Authenticate(t.Context(), "admin", "secret1")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if admin.Login != "admin" || admin.Role != models.RoleAdmin || admin.Id == 0 {
+		t.Errorf("Authenticate = %+v", admin)
+	}
+
+	ctx := storage.WithUser(t.Context(), *admin)
+	if err := repo.CreateUser(ctx, "manager", "secret2", models.RoleManager); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := repo.CreateUser(ctx, "manager", "secret2", models.RoleViewer); !errors.Is(err, storage.ErrUserExists) {
+		t.Errorf("повторный логин: ошибка %v", err)
+	}
+	if err := repo.CreateUser(ctx, "short", "123", models.RoleViewer); err == nil {
+		t.Error("CreateUser принял короткий пароль")
+	}
+	if err := repo.CreateUser(ctx, "guest", "secret3", "guest"); err == nil {
+		t.Error("CreateUser принял неизвестную роль")
+	}
+
+	users, err := repo.GetUsers(ctx)
+	if err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+	if len(users) != 2 || users[0].Login != "admin" || users[1].Login != "manager" || users[1].Role != models.RoleManager {
+		t.Fatalf("GetUsers = %+v", users)
+	}
+
+	if err := repo.DeleteUser(ctx, admin.Id); err == nil {
+		t.Error("администратор удалил сам себя")
+	}
+	if err := repo.DeleteUser(As(t, models.RoleManager), users[1].Id); !errors.Is(err, storage.ErrForbidden) {
+		t.Errorf("DeleteUser менеджером: ошибка %v", err)
+	}
+	if err := repo.DeleteUser(ctx, users[1].Id); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if _, err := repo.Authenticate(t.Context(), "manager", "secret2"); !errors.Is(err, storage.ErrInvalidCredentials) {
+		t.Errorf("удаленный пользователь вошел: ошибка %v", err)
+	}
+
+	history, err := repo.GetEntityHistory(ctx, models.EntityUser, users[1].Id)
+	if err != nil {
+		t.Fatalf("GetEntityHistory: %v", err)
+	}
+	if len(history) != 2 || history[0].User != "admin" {
+		t.Fatalf("история пользователя = %+v", history)
+	}
+	for _, e := range history {
+		if strings.Contains(e.Before+e.After, "secret") || strings.Contains(e.Before+e.After, "Hash") {
+			t.Errorf("пароль попал в журнал: %+v", e)
+		}
+	}
+}
+
+func testPrivacy(t *testing.T, repo Repository) {
+	if _, err := repo.ExportPartnerData(As(t, models.RoleManager), 301); !errors.Is(err, storage.ErrForbidden) {
+		t.Errorf("ExportPartnerData менеджером: ошибка %v", err)
+	}
+	if err := repo.AnonymizePartner(As(t, models.RoleManager), 301, false); !errors.Is(err, storage.ErrForbidden) {
+		t.Errorf("AnonymizePartner менеджером: ошибка %v", err)
+	}
+	admin := As(t, models.RoleAdmin)
+	if _, err := repo.ExportPartnerData(admin, 999); !errors.Is(err, storage.ErrPartnerNotFound) {
+		t.Errorf("ExportPartnerData(999): ошибка %v", err)
+	}
+	if err := repo.AnonymizePartner(admin, 999, false); !errors.Is(err, storage.ErrPartnerNotFound) {
+		t.Errorf("AnonymizePartner(999): ошибка %v", err)
+	}
+
+	partner := Default.Partners[1]
+	partner.Rating = 9
+	if err := repo.UpdatePartner(admin, partner); err != nil {
+		t.Fatal(err)
+	}
+	contact := Default.Contacts[0]
+	contact.Phone = "+79007778899"
+	if err := repo.UpdateContact(admin, contact); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.AddNote(admin, 301, "Обсудили поставку"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.AddAttachment(admin, 301, "договор.txt", []byte("договор")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := repo.ExportPartnerData(admin, 301)
+	if err != nil {
+		t.Fatalf("ExportPartnerData: %v", err)
+	}
+	if data.Partner.Director != partner.Director || data.Partner.Rating != 9 || data.ExportedAt == "" {
+		t.Errorf("выгрузка партнера = %+v", data.Partner)
+	}
+	if len(data.Contacts) != 2 || len(data.Notes) != 1 || len(data.Sales) != 3 || len(data.Ratings) != 1 {
+		t.Errorf("выгрузка: контактов %d, заметок %d, продаж %d, рейтингов %d", len(data.Contacts), len(data.Notes), len(data.Sales), len(data.Ratings))
+	}
+	if len(data.Attachments) != 1 || string(data.Attachments[0].Data) != "договор" {
+		t.Errorf("файлы в выгрузке = %+v", data.Attachments)
+	}
+	entities := map[string]int{}
+	for _, e := range data.History {
+		entities[e.Entity]++
+	}
+	if entities[models.EntityPartner] != 1 || entities[models.EntityContact] != 1 || entities[models.EntityNote] != 1 || entities[models.EntityAttachment] != 1 {
+		t.Errorf("история в выгрузке по сущностям = %v", entities)
+	}
+	if encoded, err := json.Marshal(data); err != nil || !strings.Contains(string(encoded), partner.Email) {
+		t.Errorf("выгрузка в JSON: %v", err)
+	}
+
+	if err := repo.AnonymizePartner(admin, 301, false); err != nil {
+		t.Fatalf("AnonymizePartner: %v", err)
+	}
+	data, err = repo.ExportPartnerData(admin, 301)
+	if err != nil {
+		t.Fatalf("ExportPartnerData после обезличивания: %v", err)
+	}
+	if p := data.Partner; p.Director != storage.Pseudonym(301) || p.Phone != "" || p.Email != "" || p.Address != "" || p.CompanyName != partner.CompanyName || p.Rating != 9 {
+		t.Errorf("обезличенный партнер = %+v", p)
+	}
+	for _, c := range data.Contacts {
+		if c.Name != storage.Pseudonym(c.Id) || c.Phone != "" || c.Email != "" || c.Role == "" {
+			t.Errorf("обезличенный контакт = %+v", c)
+		}
+	}
+	if len(data.Sales) != 3 || len(data.Notes) != 1 {
+		t.Errorf("после обезличивания продаж %d, заметок %d", len(data.Sales), len(data.Notes))
+	}
+	if len(data.History) == 0 || data.History[0].Action != models.AuditAnonymize {
+		t.Fatalf("последняя запись истории = %+v", data.History)
+	}
+	for _, e := range data.History {
+		for _, personal := range []string{partner.Director, partner.Email, partner.Address, contact.Name, contact.Phone, Default.Contacts[0].Email} {
+			if strings.Contains(e.Before+e.After, personal) {
+				t.Errorf("в журнале остались личные данные %q: %+v", personal, e)
+			}
+		}
+	}
+
+	duplicates, err := repo.FindContactDuplicates(admin, models.Partner{Email: Default.Partners[0].Email})
+	if err != nil || len(duplicates) != 1 || duplicates[0].Partner.Director != Default.Partners[0].Director {
+		t.Errorf("другой партнер изменился: %+v, %v", duplicates, err)
+	}
+
+	// С deleteNotes заметки и файлы удаляются, а их текст и имена пропадают и из журнала.
+	if _, err := repo.AddNote(admin, 302, "Звонить Иванову после 18:00"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.AddAttachment(admin, 302, "паспорт_иванова.pdf", []byte("скан")); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.AnonymizePartner(admin, 302, true); err != nil {
+		t.Fatalf("AnonymizePartner с удалением заметок: %v", err)
+	}
+	data, err = repo.ExportPartnerData(admin, 302)
+	if err != nil {
+		t.Fatalf("ExportPartnerData после удаления заметок: %v", err)
+	}
+	if len(data.Notes) != 0 || len(data.Attachments) != 0 {
+		t.Errorf("после удаления заметок: заметок %d, файлов %d", len(data.Notes), len(data.Attachments))
+	}
+	deleted := map[string]int{}
+	for _, e := range data.History {
+		if strings.Contains(e.Before+e.After, "Иванов") || strings.Contains(e.Before+e.After, "паспорт") {
+			t.Errorf("в журнале остался текст заметки или имя файла: %+v", e)
+		}
+		if e.Action == models.AuditDelete {
+			deleted[e.Entity]++
+		}
+	}
+	if deleted[models.EntityNote] != 1 || deleted[models.EntityAttachment] != 1 {
+		t.Errorf("удаления в журнале по сущностям = %v", deleted)
+	}
+	if notes, err := repo.GetNotes(admin, 301); err != nil || len(notes) != 1 {
+		t.Errorf("заметки другого партнера = %+v, %v", notes, err)
+	}
+}
+
+func testEncryption(t *testing.T, repo Repository) {
+	status, err := repo.EncryptionStatus(t.Context())
+	if err != nil {
+		t.Fatalf("EncryptionStatus: %v", err)
+	}
+	if status == storage.EncryptionOff {
+		if err := repo.Unlock(t.Context(), Passphrase); !errors.Is(err, storage.ErrEncryptionOff) {
+			t.Errorf("Unlock без шифрования: ошибка %v", err)
+		}
+		if err := repo.EnableEncryption(t.Context(), "short"); !errors.Is(err, storage.ErrShortPassphrase) {
+			t.Errorf("EnableEncryption с коротким паролем: ошибка %v", err)
+		}
+		if err := repo.EnableEncryption(t.Context(), Passphrase); err != nil {
+			t.Fatalf("EnableEncryption: %v", err)
+		}
+	}
+	if status, err := repo.EncryptionStatus(t.Context()); err != nil || status != storage.EncryptionUnlocked {
+		t.Fatalf("EncryptionStatus после включения = %v, %v", status, err)
+	}
+	if err := repo.EnableEncryption(t.Context(), Passphrase); !errors.Is(err, storage.ErrEncryptionEnabled) {
+		t.Errorf("повторный EnableEncryption: ошибка %v", err)
+	}
+	if err := repo.Unlock(t.Context(), "неверный пароль"); !errors.Is(err, storage.ErrWrongPassphrase) {
+		t.Errorf("Unlock с неверным паролем: ошибка %v", err)
+	}
+	if err := repo.Unlock(t.Context(), Passphrase); err != nil {
+		t.Errorf("Unlock: %v", err)
+	}
+
+	admin := As(t, models.RoleAdmin)
+	partner := models.Partner{PartnerType: "ООО", CompanyName: "Шифр", Director: "Петров Петр Петрович", Phone: "+79001234567", Email: "petrov@shifr.ru", Address: "г. Москва", Rating: 5}
+	if err := repo.AddPartner(admin, partner); err != nil {
+		t.Fatalf("AddPartner: %v", err)
+	}
+
+	const next = "новый пароль шифрования"
+	if err := repo.RotateKey(As(t, models.RoleManager), Passphrase, next); !errors.Is(err, storage.ErrForbidden) {
+		t.Errorf("RotateKey менеджером: ошибка %v", err)
+	}
+	if err := repo.RotateKey(admin, "неверный пароль", next); !errors.Is(err, storage.ErrWrongPassphrase) {
+		t.Errorf("RotateKey с неверным текущим паролем: ошибка %v", err)
+	}
+	if err := repo.RotateKey(admin, Passphrase, "short"); !errors.Is(err, storage.ErrShortPassphrase) {
+		t.Errorf("RotateKey с коротким паролем: ошибка %v", err)
+	}
+	if err := repo.RotateKey(admin, Passphrase, next); err != nil {
+		t.Fatalf("RotateKey: %v", err)
+	}
+	if err := repo.Unlock(t.Context(), Passphrase); !errors.Is(err, storage.ErrWrongPassphrase) {
+		t.Errorf("Unlock старым паролем после смены: ошибка %v", err)
+	}
+	if err := repo.Unlock(t.Context(), next); err != nil {
+		t.Errorf("Unlock новым паролем: %v", err)
+	}
+
+	id, _, err := repo.FindPartner(admin, "Шифр")
+	if err != nil {
+		t.Fatalf("FindPartner: %v", err)
+	}
+	duplicates, err := repo.FindContactDuplicates(admin, models.Partner{Email: "petrov@SHIFR.RU"})
+	if err != nil || len(duplicates) != 1 || !duplicates[0].SameEmail {
+		t.Fatalf("FindContactDuplicates по зашифрованному email = %+v, %v", duplicates, err)
+	}
+	if p := duplicates[0].Partner; p.Id != id || p.Director != partner.Director || p.Phone != partner.Phone || p.Address != partner.Address {
+		t.Errorf("партнер после смены ключа = %+v", p)
+	}
+	partners, err := repo.GetPartners(admin)
+	if err != nil {
+		t.Fatalf("GetPartners: %v", err)
+	}
+	for _, p := range *partners {
+		if p.Id == 301 && (p.Director != "Петров В. П." || p.Email != "petrov@vl.ru") {
+			t.Errorf("партнер 301 после смены ключа = %+v", p)
+		}
+	}
+	history, err := repo.GetEntityHistory(admin, models.EntityPartner, id)
+	if err != nil || len(history) != 1 || !strings.Contains(history[0].After, partner.Director) {
+		t.Errorf("история партнера после смены ключа = %+v, %v", history, err)
+	}
+}
+
+func testCanceled(t *testing.T, repo Repository) {
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if _, err := repo.GetPartners(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetPartners: ошибка %v, ожидалась context.Canceled", err)
+	}
+	if _, _, err := repo.FindPartner(ctx, "300"); !errors.Is(err, context.Canceled) {
+		t.Errorf("FindPartner: ошибка %v, ожидалась context.Canceled", err)
+	}
+	if _, err := repo.CalculateMaterial(ctx, "1", "1", 10, 2, 3); !errors.Is(err, context.Canceled) {
+		t.Errorf("CalculateMaterial: ошибка %v, ожидалась context.Canceled", err)
+	}
+	if _, err := repo.GetPlan(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetPlan: ошибка %v, ожидалась context.Canceled", err)
+	}
+}
+
+func partnerById(t *testing.T, repo Repository, id int) models.Partner {
+	t.Helper()
+
+	partners, err := repo.GetPartners(t.Context())
+	if err != nil {
+		t.Fatalf("GetPartners: %v", err)
+	}
+	for _, p := range *partners {
+		if p.Id == id {
+			return p
+		}
+	}
+	t.Fatalf("партнер %d не найден", id)
+	return models.Partner{}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// FirstAdminConcurrent создает первого администратора в пустом хранилище несколькими вызовами
+// сразу: без авторизации проходит только один, остальные получают ErrNotAuthenticated. Вызовы
+// распределяются по клиентам repos одной базы. Тест не входит в Run: SQLite в памяти с общим
+// кэшем не ждет чужую блокировку, а сразу возвращает ошибку.
+func FirstAdminConcurrent(t *testing.T, repos ...Repository) {
+	const attempts = 6
+	errs := make(chan error, attempts)
+	var wg sync.WaitGroup
+	for i := range attempts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- repos[i%len(repos)].CreateUser(t.Context(), fmt.Sprintf("admin%d", i), "secret1", models.RoleAdmin)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, storage.ErrNotAuthenticated):
+			t.Errorf("CreateUser: %v", err)
+		}
+	}
+	users, err := repos[0].GetUsers(As(t, models.RoleAdmin))
+	if err != nil {
+		t.Fatalf("GetUsers: %v", err)
+	}
+	if created != 1 || len(users) != 1 {
+		t.Errorf("создано администраторов: %d, пользователи: %+v, ожидался один", created, users)
+	}
+}
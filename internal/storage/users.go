@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/ttrtcixy/demo/internal/models"
+	"golang.org/x/crypto/bcrypt"
+	"slices"
+	"strings"
+)
+
+type userKey struct{}
+
+// WithUser возвращает контекст, от имени пользователя которого выполняются операции.
+func WithUser(ctx context.Context, user models.User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+func UserFromContext(ctx context.Context) (models.User, bool) {
+	user, ok := ctx.Value(userKey{}).(models.User)
+	return user, ok
+}
+
+// Authorize проверяет, что пользователь из контекста может выполнить действие p.
+// Вызывается каждой изменяющей операцией хранилища.
+func Authorize(ctx context.Context, p models.Permission) error {
+	user, ok := UserFromContext(ctx)
+	if !ok {
+		return ErrNotAuthenticated
+	}
+	if !user.Can(p) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("ошибка хеширования пароля: %v", err)
+	}
+	return string(hash), nil
+}
+
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// ValidateNewUser проверяет логин, пароль и роль нового пользователя.
+func ValidateNewUser(login, password, role string) error {
+	if strings.TrimSpace(login) == "" {
+		return ErrEmptyLogin
+	}
+	if len(password) < 6 {
+		return ErrShortPassword
+	}
+	if !slices.Contains(models.Roles, role) {
+		return ErrUnknownRole(role)
+	}
+	return nil
+}
+
+func (db *DB) HasUsers(ctx context.Context) (bool, error) {
+	var count int
+	if err := db.connect.QueryRowContext(ctx, "SELECT COUNT(*) FROM Users").Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (db *DB) Authenticate(ctx context.Context, login, password string) (*models.User, error) {
+	var user models.User
+	var hash string
+	err := db.connect.QueryRowContext(ctx, "SELECT UserId, Login, Role, PasswordHash FROM Users WHERE Login = ?", login).
+		Scan(&user.Id, &user.Login, &user.Role, &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !CheckPassword(hash, password) {
+		return nil, ErrInvalidCredentials
+	}
+	return &user, nil
+}
+
+func (db *DB) GetUsers(ctx context.Context) ([]models.User, error) {
+	if err := Authorize(ctx, models.PermManageUsers); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.connect.QueryContext(ctx, "SELECT UserId, Login, Role FROM Users ORDER BY Login")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения пользователей: %v", err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.Id, &u.Login, &u.Role); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования строки: %v", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+var addUser = `insert into Users(Login, PasswordHash, Role) values(?, ?, ?) returning UserId`
+
+// CreateUser добавляет пользователя. Пока пользователей нет, первого администратора
+// можно создать без авторизации. Таблица пользователей блокируется до конца транзакции,
+// поэтому два клиента не создадут первого администратора одновременно.
+func (db *DB) CreateUser(ctx context.Context, login, password, role string) error {
+	if err := ValidateNewUser(login, password, role); err != nil {
+		return err
+	}
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.connect.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tx.lockTable(ctx, "Users"); err != nil {
+		return err
+	}
+	var count int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM Users").Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 || role != models.RoleAdmin {
+		if err := Authorize(ctx, models.PermManageUsers); err != nil {
+			return err
+		}
+	}
+
+	var exists int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM Users WHERE Login = ?", login).Scan(&exists); err != nil {
+		return err
+	}
+	if exists > 0 {
+		return ErrUserExists
+	}
+
+	user := models.User{Login: login, Role: role}
+	if err := tx.QueryRowContext(ctx, addUser, login, hash, role).Scan(&user.Id); err != nil {
+		return err
+	}
+	if err := tx.audit(ctx, models.EntityUser, user.Id, models.AuditCreate, nil, user); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *DB) DeleteUser(ctx context.Context, id int) error {
+	if err := Authorize(ctx, models.PermManageUsers); err != nil {
+		return err
+	}
+	if current, _ := UserFromContext(ctx); current.Id == id {
+		return ErrDeleteSelf
+	}
+
+	tx, err := db.connect.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var before models.User
+	err = tx.QueryRowContext(ctx, "SELECT UserId, Login, Role FROM Users WHERE UserId = ?", id).Scan(&before.Id, &before.Login, &before.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM Users WHERE UserId = ?", id); err != nil {
+		return err
+	}
+	if err := tx.audit(ctx, models.EntityUser, id, models.AuditDelete, before, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
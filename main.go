@@ -1,8 +1,55 @@
 package main
 
-import "github.com/ttrtcixy/demo/internal/app"
+import (
+	"context"
+	fyneapp "fyne.io/fyne/v2/app"
+	"github.com/ttrtcixy/demo/internal/app"
+	"github.com/ttrtcixy/demo/internal/app/theme"
+	"github.com/ttrtcixy/demo/internal/backup"
+	"github.com/ttrtcixy/demo/internal/config"
+	"github.com/ttrtcixy/demo/internal/storage"
+	"log"
+)
 
 func main() {
-	app := application.NewApp()
+	cfg := config.Load()
+	theme.SetBrandDir(cfg.BrandDir)
+
+	db, err := storage.NewDB(context.Background(), cfg.DBDriver, cfg.DBDSN)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer db.Close()
+
+	// Копии при запуске и по расписанию делаются в фоне и не задерживают открытие окна.
+	var backups *backup.Manager
+	if cfg.DBDriver == storage.DriverSQLite {
+		backups = backup.NewManager(db, backup.Config{
+			Dir:      cfg.BackupDir,
+			Interval: cfg.BackupInterval,
+			Keep:     cfg.BackupKeep,
+			MaxAge:   cfg.BackupMaxAge,
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go backups.Run(ctx, func(err error) { log.Println("резервная копия:", err) })
+	}
+
+	app := application.NewApp(fyneapp.New(), application.Repositories{
+		Partners:   db,
+		Sales:      db,
+		Dashboard:  db,
+		Ratings:    db,
+		Contacts:   db,
+		Notes:      db,
+		Catalog:    db,
+		Materials:  db,
+		Plans:      db,
+		Audit:      db,
+		Users:      db,
+		Encryption: db,
+		Privacy:    db,
+		Backups:    backups,
+	})
 	app.Run()
 }